@@ -0,0 +1,190 @@
+package api
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Bounds of the standing accommodations of a student.
+const (
+	// Largest factor the quiz time limits of a student are multiplied by.
+	MaxTimeMultiplier = 4
+
+	// Longest extension of the homework deadlines of a student, in hours.
+	MaxExtensionHours = 30 * 24
+)
+
+// DeadlineOverride represents different times of a homework, quiz or
+// attendance for a single student. Zero fields keep the times of the item.
+type DeadlineOverride struct {
+	ID int `json:"ID"`
+
+	ItemKind string `json:"ItemKind"`
+	ItemID   int    `json:"ItemID"`
+
+	OpenedAt  time.Time `json:"OpenedAt"`
+	DueAt     time.Time `json:"DueAt"` // homeworks only
+	ClosedAt  time.Time `json:"ClosedAt"`
+	TimeLimit int       `json:"TimeLimit"` // in minutes, quizzes only
+
+	Reason    string    `json:"Reason"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	StudentID int   `json:"StudentID"`
+	Student   *User `json:"Student,omitempty"`
+}
+
+// Validate returns an error if the override contains invalid fields.
+func (o *DeadlineOverride) Validate() error {
+	if o.ItemKind != GradeItemHomework && o.ItemKind != GradeItemQuiz && o.ItemKind != GradeItemAttendance {
+		return Errorf(EINVALID, "Item kind is incorrect.")
+	} else if o.StudentID == 0 {
+		return Errorf(EINVALID, "Student required.")
+	} else if !o.DueAt.IsZero() && o.ItemKind != GradeItemHomework {
+		return Errorf(EINVALID, "Only homeworks have due dates.")
+	} else if o.TimeLimit != 0 && o.ItemKind != GradeItemQuiz {
+		return Errorf(EINVALID, "Only quizzes have time limits.")
+	} else if o.TimeLimit < 0 || o.TimeLimit > MaxTimeLimit {
+		return Errorf(EINVALID, "Time limit must be between 0 and %d minutes.", MaxTimeLimit)
+	} else if !o.OpenedAt.IsZero() && !o.ClosedAt.IsZero() && !o.ClosedAt.After(o.OpenedAt) {
+		return Errorf(EINVALID, "Closing time must be after the opening time.")
+	} else if o.OpenedAt.IsZero() && o.DueAt.IsZero() && o.ClosedAt.IsZero() && o.TimeLimit == 0 {
+		return Errorf(EINVALID, "Override changes nothing.")
+	}
+	return nil
+}
+
+// Accommodation represents the standing accommodations of a student in a
+// group, which apply to every homework and quiz of the group.
+type Accommodation struct {
+	GroupID   int   `json:"GroupID"`
+	StudentID int   `json:"StudentID"`
+	Student   *User `json:"Student,omitempty"`
+
+	// Factor the quiz time limits of the student are multiplied by.
+	TimeMultiplier float64 `json:"TimeMultiplier"`
+
+	// Hours added to the due and closing times of homeworks.
+	ExtensionHours int `json:"ExtensionHours"`
+
+	Notes     string    `json:"Notes"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+}
+
+// Validate returns an error if the accommodation contains invalid fields.
+func (a *Accommodation) Validate() error {
+	if a.TimeMultiplier < 1 || a.TimeMultiplier > MaxTimeMultiplier {
+		return Errorf(EINVALID, "Time multiplier must be between 1 and %d.", MaxTimeMultiplier)
+	} else if a.ExtensionHours < 0 || a.ExtensionHours > MaxExtensionHours {
+		return Errorf(EINVALID, "Extension must be between 0 and %d hours.", MaxExtensionHours)
+	}
+	return nil
+}
+
+// Deadline represents the times in which a student may work on a homework,
+// quiz or attendance.
+type Deadline struct {
+	ItemKind  string `json:"ItemKind"`
+	ItemID    int    `json:"ItemID"`
+	StudentID int    `json:"StudentID,omitempty"`
+
+	OpenedAt  time.Time `json:"OpenedAt"`
+	DueAt     time.Time `json:"DueAt"`
+	ClosedAt  time.Time `json:"ClosedAt"`
+	TimeLimit int       `json:"TimeLimit"` // in minutes
+
+	// Set if an override or accommodation changed the times of the item.
+	Adjusted bool `json:"Adjusted"`
+}
+
+// Apply changes the deadline by an override and an accommodation, either of
+// which may be nil. Times set by the override are kept as they are, the
+// others are extended by the accommodation.
+func (d *Deadline) Apply(o *DeadlineOverride, a *Accommodation) {
+	var openedAt, dueAt, closedAt, timeLimit bool
+	if o != nil {
+		if openedAt = !o.OpenedAt.IsZero(); openedAt {
+			d.OpenedAt = o.OpenedAt
+		}
+		if dueAt = !o.DueAt.IsZero(); dueAt {
+			d.DueAt = o.DueAt
+		}
+		if closedAt = !o.ClosedAt.IsZero(); closedAt {
+			d.ClosedAt = o.ClosedAt
+		}
+		if timeLimit = o.TimeLimit != 0; timeLimit {
+			d.TimeLimit = o.TimeLimit
+		}
+		d.Adjusted = openedAt || dueAt || closedAt || timeLimit
+	}
+	if a == nil {
+		return
+	}
+
+	if d.ItemKind == GradeItemHomework && a.ExtensionHours > 0 {
+		extension := time.Duration(a.ExtensionHours) * time.Hour
+		if !dueAt && !d.DueAt.IsZero() {
+			d.DueAt, d.Adjusted = d.DueAt.Add(extension), true
+		}
+		if !closedAt && !d.ClosedAt.IsZero() {
+			d.ClosedAt, d.Adjusted = d.ClosedAt.Add(extension), true
+		}
+	}
+	if d.ItemKind == GradeItemQuiz && a.TimeMultiplier > 1 && !timeLimit && d.TimeLimit > 0 {
+		d.TimeLimit, d.Adjusted = int(math.Ceil(float64(d.TimeLimit)*a.TimeMultiplier)), true
+	}
+}
+
+// Opened returns true if the item is open at now.
+func (d *Deadline) Opened(now time.Time) bool {
+	return d.OpenedAt.IsZero() || !now.Before(d.OpenedAt)
+}
+
+// Closed returns true if the item is closed at now.
+func (d *Deadline) Closed(now time.Time) bool {
+	return !d.ClosedAt.IsZero() && now.After(d.ClosedAt)
+}
+
+// AttemptEndsAt returns the time an attempt started at startedAt has to be
+// submitted by, which is the earlier of the end of the time limit and the
+// closing time. Returns the zero time if there is neither.
+func (d *Deadline) AttemptEndsAt(startedAt time.Time) time.Time {
+	endsAt := d.ClosedAt
+	if d.TimeLimit > 0 {
+		if t := startedAt.Add(time.Duration(d.TimeLimit) * time.Minute); endsAt.IsZero() || t.Before(endsAt) {
+			endsAt = t
+		}
+	}
+	return endsAt
+}
+
+// AccommodationService represents a service for managing per-student
+// deadlines and standing accommodations.
+type AccommodationService interface {
+	// Retrieves the overrides of a homework, quiz or attendance. Returns
+	// EUNAUTHORIZED if current user is not the teacher of the item.
+	FindDeadlineOverrides(ctx context.Context, kind string, itemID int) ([]*DeadlineOverride, error)
+
+	// Creates the override of a student or replaces the existing one.
+	SetDeadlineOverride(ctx context.Context, o *DeadlineOverride) error
+
+	// Removes the override of a student. Returns ENOTFOUND if it does not exist.
+	DeleteDeadlineOverride(ctx context.Context, kind string, itemID, studentID int) error
+
+	// Retrieves the students of a group with accommodations. Returns
+	// EUNAUTHORIZED if current user is not a teacher of the group.
+	FindAccommodations(ctx context.Context, groupID int) ([]*Accommodation, error)
+
+	// Sets the accommodations of a student of a group. Returns ENOTFOUND if
+	// the student is not a member of the group.
+	SetAccommodation(ctx context.Context, a *Accommodation) error
+
+	// Removes the accommodations of a student of a group.
+	DeleteAccommodation(ctx context.Context, groupID, studentID int) error
+
+	// Retrieves the deadline of a homework, quiz or attendance for the
+	// current user.
+	FindDeadline(ctx context.Context, kind string, itemID int) (*Deadline, error)
+}
@@ -5,14 +5,65 @@ import (
 	"time"
 )
 
+// Ways a student can check in to an attendance. Manual submissions are
+// entered by the teacher or marked absent once the attendance closes.
+const (
+	CheckInPIN    = "pin"
+	CheckInQR     = "qr"
+	CheckInManual = "manual"
+)
+
+// Attendance statuses of a student.
+const (
+	AttPresent = "present"
+	AttLate    = "late"
+	AttExcused = "excused"
+	AttAbsent  = "absent"
+)
+
+// MaxRosterBatch is the most roster entries that can be updated at once.
+const MaxRosterBatch = 500
+
+// Length limits of the device fingerprint of a check-in.
+const (
+	MaxDeviceIDLen  = 64
+	MaxUserAgentLen = 512
+)
+
+// Ways check-ins are linked together in a proxy detection report.
+const (
+	ProxyByDevice = "device"
+	ProxyByIP     = "ip"
+)
+
 // AttSubmission represents a attendance submission in the system.
 type AttSubmission struct {
 	ID int `json:"ID"`
 
-	Present     bool      `json:"Present"`
-	PIN         string    `json:"PIN"`
-	SubmittedAt time.Time `json:"SubmittedAt"`
-	UpdatedAt   time.Time `json:"UpdatedAt"`
+	Present       bool      `json:"Present"` // true when present or late
+	Status        string    `json:"Status"`
+	PIN           string    `json:"PIN"`
+	Token         string    `json:"Token,omitempty"` // QR check-in token
+	CheckInMethod string    `json:"CheckInMethod"`
+	SubmittedAt   time.Time `json:"SubmittedAt"`
+	UpdatedAt     time.Time `json:"UpdatedAt"`
+
+	// Location reported by the device when the attendance is geofenced, its
+	// distance from the fence in meters, and whether it needs teacher review.
+	Location *Location `json:"Location,omitempty"`
+	Distance float64   `json:"Distance,omitempty"`
+	Flagged  bool      `json:"Flagged"`
+
+	// Reason and optional supporting document of an excused absence. The
+	// attachment is referenced by its hash.
+	ExcuseReason     string `json:"ExcuseReason,omitempty"`
+	ExcuseAttachment string `json:"ExcuseAttachment,omitempty"`
+
+	// Fingerprint of the device used to check in: an install ID generated by
+	// the client, its user agent and the IP address of the request.
+	DeviceID  string `json:"DeviceID,omitempty"`
+	UserAgent string `json:"UserAgent,omitempty"`
+	IP        string `json:"IP,omitempty"`
 
 	StudentFullName string `json:"StudentFullName,omitempty"`
 	StudentID       int    `json:"StudentID,omitempty"`
@@ -25,9 +76,58 @@ type AttSubmission struct {
 // Validate returns an error if the attendance submission contains invalid fields.
 // This only performs basic validation.
 func (u *AttSubmission) Validate() error {
+	if u.CheckInMethod != CheckInPIN && u.CheckInMethod != CheckInQR && u.CheckInMethod != CheckInManual {
+		return Errorf(EINVALID, "Check-in method is incorrect.")
+	} else if !IsAttStatus(u.Status) {
+		return Errorf(EINVALID, "Status is incorrect.")
+	} else if u.Status == AttExcused && u.ExcuseReason == "" {
+		return Errorf(EINVALID, "Excuse reason required.")
+	} else if len(u.DeviceID) > MaxDeviceIDLen {
+		return Errorf(EINVALID, "Device ID too long.")
+	} else if u.Location != nil {
+		return u.Location.Validate()
+	}
 	return nil
 }
 
+// SetStatus changes the status of the submission. The excuse is cleared
+// unless the new status is excused.
+func (u *AttSubmission) SetStatus(status string) {
+	u.Status = status
+	u.Present = status == AttPresent || status == AttLate
+	if status != AttExcused {
+		u.ExcuseReason, u.ExcuseAttachment = "", ""
+	}
+}
+
+// IsAttStatus returns true if s is a known attendance status.
+func IsAttStatus(s string) bool {
+	switch s {
+	case AttPresent, AttLate, AttExcused, AttAbsent:
+		return true
+	}
+	return false
+}
+
+// RosterEntry sets the status of a student on the roster of an attendance.
+type RosterEntry struct {
+	StudentID    int    `json:"StudentID"`
+	Status       string `json:"Status"`
+	ExcuseReason string `json:"ExcuseReason"`
+
+	// Hash of an uploaded document. Keeps the current one when blank.
+	ExcuseAttachment string `json:"-"`
+}
+
+// ProxyCluster represents several students who checked in to an attendance
+// from the same device or IP address, which suggests that one of them checked
+// in the others.
+type ProxyCluster struct {
+	Kind        string           `json:"Kind"` // device or ip
+	Value       string           `json:"Value"`
+	Submissions []*AttSubmission `json:"Submissions"`
+}
+
 // AttSubmissionService represents a service for managing attendance submissions.
 type AttSubmissionService interface {
 	// Retrieves a attendance submission by ID.
@@ -49,6 +149,26 @@ type AttSubmissionService interface {
 	// if current attendance submission is not the attendance submission being deleted. Returns ENOTFOUND if
 	// attendance submission does not exist.
 	DeleteAttSubmission(ctx context.Context, id int) error
+
+	// Retrieves the submissions of an attendance along with a blank entry for
+	// every group member who has none yet. Returns EUNAUTHORIZED if current
+	// user is not the teacher of the attendance.
+	FindRoster(ctx context.Context, attendanceID int) ([]*AttSubmission, error)
+
+	// Sets the statuses of several students at once, creating submissions for
+	// group members who have none. Returns EUNAUTHORIZED if current user is
+	// not the teacher of the attendance.
+	UpdateRoster(ctx context.Context, attendanceID int, entries []*RosterEntry) ([]*AttSubmission, error)
+
+	// Creates absent submissions for group members who did not check in to
+	// attendances that closed before now. Returns the number of submissions
+	// created. Meant to be run periodically by the scheduler.
+	MarkAbsences(ctx context.Context, now time.Time) (int, error)
+
+	// Retrieves the groups of check-ins of different students sharing a device
+	// or an IP address, largest first. Returns EUNAUTHORIZED if current user
+	// is not the teacher of the attendance.
+	FindProxyCheckIns(ctx context.Context, attendanceID int) ([]*ProxyCluster, error)
 }
 
 // AttSubmissionFilter represents a filter passed to FindAttSubmissions().
@@ -57,6 +177,8 @@ type AttSubmissionFilter struct {
 	ID                *int       `json:"ID"`
 	BeforeSubmittedAt *time.Time `json:"SubmittedAt"`
 	AfterUpdatedAt    *time.Time `json:"UpdatedAt"`
+	Flagged           *bool      `json:"Flagged"`
+	Status            *string    `json:"Status"`
 
 	StudentFullName *string `json:"StudentFullName" db:"student_fullname"`
 	StudentID       *int    `json:"StudentID"`
@@ -69,7 +191,10 @@ type AttSubmissionFilter struct {
 
 // AttSubmissionUpdate represents a set of fields to be updated via UpdateAttSubmission().
 type AttSubmissionUpdate struct {
-	Present *bool `json:"Present"`
+	Present      *bool   `json:"Present"`
+	Status       *string `json:"Status"`
+	ExcuseReason *string `json:"ExcuseReason"`
+	Flagged      *bool   `json:"Flagged"`
 
 	StudentFullName *string `json:"StudentFullName"`
 	StudentID       *int    `json:"StudentID"`
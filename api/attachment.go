@@ -53,6 +53,11 @@ type AttachmentService interface {
 	// the attachment that is being updated. Returns ENOTFOUND if attachment does not exist.
 	UpdateAttachment(ctx context.Context, hash string, upd AttachmentUpdate) (*Attachment, error)
 
+	// Adds delta to the counter of a attachment in a single statement so that
+	// concurrent changes are not lost, and deletes the attachment once the
+	// counter drops below 1. Returns ENOTFOUND if attachment does not exist.
+	ChangeAttachmentCounter(ctx context.Context, hash string, delta int) (*Attachment, error)
+
 	// Permanently deletes a attachment. Returns EUNAUTHORIZED
 	// if current attachment is not the attachment being deleted. Returns ENOTFOUND if
 	// attachment does not exist.
@@ -92,14 +97,26 @@ type AttachmentUploader struct {
 	FileService       FileService
 }
 
-// UploadAttachment creates the file and adds related row in attachments table
+// UploadAttachment creates the file and adds related row in attachments table.
+// Uploading a file that is already stored increments its counter instead.
 func (u *AttachmentUploader) UploadAttachment(ctx context.Context, header *multipart.FileHeader) (*Attachment, error) {
+	hash, err := u.FileService.GetFileHash(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if img, err := u.AttachmentService.ChangeAttachmentCounter(ctx, hash, 1); err == nil {
+		return img, nil
+	} else if ErrorCode(err) != ENOTFOUND {
+		return nil, err
+	}
+
 	hash, extension, err := u.FileService.CreateFile(ctx, header)
 	if err != nil {
 		return nil, err
 	}
 
-	img := Attachment{Hash: hash, Extension: extension, Counter: 0}
+	img := Attachment{Hash: hash, Extension: extension, Counter: 1}
 
 	err = u.AttachmentService.CreateAttachment(ctx, &img)
 	if err != nil {
@@ -110,14 +127,8 @@ func (u *AttachmentUploader) UploadAttachment(ctx context.Context, header *multi
 
 // DecrementAttachmentCounter updates attachment and if the counter is 0 then deletes the file
 func (u *AttachmentUploader) DecrementAttachmentCounter(ctx context.Context, hash string) error {
-	oldImg, err := u.AttachmentService.FindAttachmentByHash(ctx, hash)
-	if err != nil {
-		return err
-	}
-	newCounter := oldImg.Counter - 1
-
-	// If counter is less than 1, then UpdateAttachment will remove corresponding row
-	img, err := u.AttachmentService.UpdateAttachment(ctx, hash, AttachmentUpdate{Counter: &newCounter})
+	// If counter is less than 1, then ChangeAttachmentCounter will remove corresponding row
+	img, err := u.AttachmentService.ChangeAttachmentCounter(ctx, hash, -1)
 	if err != nil {
 		return err
 	}
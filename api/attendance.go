@@ -2,15 +2,48 @@ package api
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"strconv"
 	"time"
 )
 
+// Attendance PIN modes.
+const (
+	// The PIN stays the same until it is renewed by the teacher.
+	PINStatic = "static"
+
+	// The PIN is derived from a secret and changes every PINPeriod seconds.
+	PINRotating = "rotating"
+)
+
+// Bounds and default of the rotation period of attendance PINs, in seconds.
+const (
+	DefaultPINPeriod = 30
+	MinPINPeriod     = 10
+	MaxPINPeriod     = 3600
+)
+
+// PINLength is the number of digits of attendance PINs.
+const PINLength = 6
+
+// QRPeriod is how long a QR check-in token is shown before it is refreshed.
+const QRPeriod = 10 * time.Second
+
+// MaxLateGrace is the longest grace window of an attendance, in minutes.
+const MaxLateGrace = 24 * 60
+
 // Attendance represents a attendance in the system.
 type Attendance struct {
 	ID int `json:"ID"`
 
 	Title       string    `json:"Title"`
 	PIN         string    `json:"PIN"`
+	PINMode     string    `json:"PINMode"`
+	PINPeriod   int       `json:"PINPeriod"` // in seconds
+	PINSecret   string    `json:"-"`         // signs rotating PINs and QR check-in tokens
 	StudentLink string    `json:"StudentLink"`
 	TeacherLink string    `json:"TeacherLink"`
 	CourseTitle string    `json:"CourseTitle"`
@@ -20,6 +53,26 @@ type Attendance struct {
 	OpenedAt    time.Time `json:"OpenedAt"`
 	ClosedAt    time.Time `json:"ClosedAt"`
 
+	// Minutes after OpenedAt during which check-ins count as present. Later
+	// check-ins count as late. Zero disables late marking.
+	LateGrace int `json:"LateGrace"`
+
+	// Set once absent submissions have been created for group members who
+	// did not check in before the attendance closed.
+	AbsencesMarkedAt time.Time `json:"AbsencesMarkedAt"`
+
+	// Rejects a check-in from a device that was already used to check in.
+	OnePerDevice bool `json:"OnePerDevice"`
+
+	// Optional area students have to check in from.
+	Geofence *Geofence `json:"Geofence,omitempty"`
+
+	// Set on sessions created from a timetable. A cancelled session does not
+	// accept check-ins.
+	SlotID      int    `json:"SlotID,omitempty"`
+	ScheduledOn string `json:"ScheduledOn,omitempty"` // 2006-01-02
+	Cancelled   bool   `json:"Cancelled"`
+
 	TeacherFullName string `json:"TeacherFullName"`
 	TeacherID       int    `json:"TeacherID"`
 	Teacher         *User  `json:"Teacher"`
@@ -37,10 +90,122 @@ func (u *Attendance) Validate() error {
 		return Errorf(EINVALID, "Title required.")
 	} else if u.Mode != All && u.Mode != Registered {
 		return Errorf(EINVALID, "Mode is incorrect.")
+	} else if u.PINMode != PINStatic && u.PINMode != PINRotating {
+		return Errorf(EINVALID, "PIN mode is incorrect.")
+	} else if u.PINMode == PINRotating && (u.PINPeriod < MinPINPeriod || u.PINPeriod > MaxPINPeriod) {
+		return Errorf(EINVALID, "PIN period must be between %d and %d seconds.", MinPINPeriod, MaxPINPeriod)
+	} else if u.PINMode == PINRotating && u.PINSecret == "" {
+		return Errorf(EINVALID, "PIN secret required.")
+	} else if u.LateGrace < 0 || u.LateGrace > MaxLateGrace {
+		return Errorf(EINVALID, "Late grace must be between 0 and %d minutes.", MaxLateGrace)
+	} else if u.Geofence != nil {
+		return u.Geofence.Validate()
 	}
 	return nil
 }
 
+// CheckInStatus returns the status of a check-in at the given time, which is
+// late once the grace window after the opening time has passed.
+func (u *Attendance) CheckInStatus(now time.Time) string {
+	if u.LateGrace > 0 && !u.OpenedAt.IsZero() && now.After(u.OpenedAt.Add(time.Duration(u.LateGrace)*time.Minute)) {
+		return AttLate
+	}
+	return AttPresent
+}
+
+// Deadline returns the opening and closing times of the attendance before any
+// per-student changes.
+func (u *Attendance) Deadline() *Deadline {
+	return &Deadline{
+		ItemKind: GradeItemAttendance,
+		ItemID:   u.ID,
+		OpenedAt: u.OpenedAt,
+		ClosedAt: u.ClosedAt,
+	}
+}
+
+// CurrentPIN returns the PIN that is valid at the given time.
+func (u *Attendance) CurrentPIN(now time.Time) string {
+	if u.PINMode != PINRotating {
+		return u.PIN
+	}
+	return u.rotatingPIN(now)
+}
+
+// PINExpiresAt returns when the PIN valid at the given time stops being shown.
+// Returns the zero time for static PINs.
+func (u *Attendance) PINExpiresAt(now time.Time) time.Time {
+	if u.PINMode != PINRotating {
+		return time.Time{}
+	}
+	period := int64(u.PINPeriod)
+	return time.Unix((now.Unix()/period+1)*period, 0)
+}
+
+// CheckPIN returns true if pin is valid at the given time. Rotating PINs of
+// the previous period are accepted as well so that a code read just before
+// it changed still works.
+func (u *Attendance) CheckPIN(pin string, now time.Time) bool {
+	if pin == "" {
+		return false
+	} else if u.PINMode != PINRotating {
+		return subtle.ConstantTimeCompare([]byte(u.PIN), []byte(pin)) == 1
+	}
+
+	period := time.Duration(u.PINPeriod) * time.Second
+	for _, t := range []time.Time{now, now.Add(-period)} {
+		if subtle.ConstantTimeCompare([]byte(u.rotatingPIN(t)), []byte(pin)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckInToken returns the QR check-in token that is valid at the given time.
+func (u *Attendance) CheckInToken(now time.Time) string {
+	return u.checkInToken(now.Unix() / int64(QRPeriod/time.Second))
+}
+
+// CheckInTokenExpiresAt returns when the token valid at the given time is refreshed.
+func (u *Attendance) CheckInTokenExpiresAt(now time.Time) time.Time {
+	period := int64(QRPeriod / time.Second)
+	return time.Unix((now.Unix()/period+1)*period, 0)
+}
+
+// CheckCheckInToken returns true if token is a QR check-in token of the
+// attendance issued in the current or the previous period.
+func (u *Attendance) CheckCheckInToken(token string, now time.Time) bool {
+	if token == "" {
+		return false
+	}
+	window := now.Unix() / int64(QRPeriod/time.Second)
+	for _, w := range []int64{window, window - 1} {
+		if subtle.ConstantTimeCompare([]byte(u.checkInToken(w)), []byte(token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// checkInToken signs the student link and the time window with the secret.
+func (u *Attendance) checkInToken(window int64) string {
+	if u.PINSecret == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, []byte(u.PINSecret))
+	mac.Write([]byte(u.StudentLink + ":" + strconv.FormatInt(window, 10)))
+	return hex.EncodeToString(mac.Sum(nil)[:10])
+}
+
+// rotatingPIN returns the rotating PIN of the period containing t.
+func (u *Attendance) rotatingPIN(t time.Time) string {
+	secret, err := hex.DecodeString(u.PINSecret)
+	if err != nil || len(secret) == 0 {
+		return ""
+	}
+	return TOTP(secret, t, time.Duration(u.PINPeriod)*time.Second, PINLength)
+}
+
 // AttendanceService represents a service for managing attendances.
 type AttendanceService interface {
 	// Retrieves a attendance by ID.
@@ -82,6 +247,12 @@ type AttendanceFilter struct {
 	TeacherID       *int    `json:"TeacherID"`
 	GroupID         *int    `json:"GroupID"`
 
+	// Restrict to sessions created from a timetable, scheduled on the dates
+	// from and to, inclusive.
+	Scheduled     *bool   `json:"Scheduled"`
+	ScheduledFrom *string `json:"ScheduledFrom"`
+	ScheduledTo   *string `json:"ScheduledTo"`
+
 	// Restrict to subset of results.
 	Offset int `json:"Offset"`
 	Limit  int `json:"Limit"`
@@ -93,8 +264,18 @@ type AttendanceUpdate struct {
 	CourseTitle *string    `json:"CourseTitle"`
 	Mode        *string    `json:"Mode"`
 	PIN         *string    `json:"PIN"`
+	PINMode     *string    `json:"PINMode"`
+	PINPeriod   *int       `json:"PINPeriod"`
+	PINSecret   *string    `json:"-"`
 	OpenedAt    *time.Time `json:"OpenedAt"`
 	ClosedAt    *time.Time `json:"ClosedAt"`
+	LateGrace   *int       `json:"LateGrace"`
+
+	// Replaces the geofence. A fence with zero radius removes it.
+	Geofence *Geofence `json:"Geofence"`
+
+	Cancelled    *bool `json:"Cancelled"`
+	OnePerDevice *bool `json:"OnePerDevice"`
 
 	TeacherFullName *string `json:"TeacherFullName"`
 	TeacherID       *int    `json:"TeacherID"`
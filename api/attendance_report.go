@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultAtRiskThreshold is the attendance rate, in percent, below which a
+// student is flagged as at risk.
+const DefaultAtRiskThreshold = 75
+
+// AttendanceReport represents the attendance register of a group: a row of
+// statuses per student with one column per session.
+type AttendanceReport struct {
+	GroupID   int     `json:"GroupID"`
+	Threshold float64 `json:"Threshold"` // in percent
+
+	Sessions []*Attendance          `json:"Sessions"`
+	Rows     []*AttendanceReportRow `json:"Rows"`
+}
+
+// AttendanceReportRow represents the attendance of a single student.
+type AttendanceReportRow struct {
+	Student *User `json:"Student"`
+
+	// Statuses of the student, in the order of the report sessions. Blank
+	// when the student has no submission for the session.
+	Statuses []string `json:"Statuses"`
+
+	Counts map[string]int `json:"Counts"`
+	Rate   float64        `json:"Rate"` // in percent
+	AtRisk bool           `json:"AtRisk"`
+}
+
+// Compute counts the statuses of every row and rates the attendance of the
+// students. Excused sessions are left out of the rate, as are sessions a
+// student has no submission for while they are still open. Students with no
+// rated sessions have a rate of 100.
+func (r *AttendanceReport) Compute(now time.Time) {
+	for _, row := range r.Rows {
+		row.Counts = make(map[string]int)
+
+		var rated, attended int
+		for i, status := range row.Statuses {
+			if status == "" {
+				if s := r.Sessions[i]; s.ClosedAt.IsZero() || now.Before(s.ClosedAt) {
+					continue
+				}
+				status = AttAbsent
+			}
+
+			row.Counts[status]++
+			switch status {
+			case AttPresent, AttLate:
+				attended++
+				rated++
+			case AttAbsent:
+				rated++
+			}
+		}
+
+		row.Rate = 100
+		if rated > 0 {
+			row.Rate = float64(attended) * 100 / float64(rated)
+		}
+		row.AtRisk = row.Rate < r.Threshold
+	}
+}
+
+// AttendanceReportFilter represents a filter passed to FindAttendanceReport().
+type AttendanceReportFilter struct {
+	// Restrict to sessions opened within the range, inclusive.
+	From *time.Time `json:"From"`
+	To   *time.Time `json:"To"`
+
+	Threshold float64 `json:"Threshold"`
+}
+
+// AttendanceReportService represents a service for building attendance reports.
+type AttendanceReportService interface {
+	// Builds the attendance report of a group from its sessions that are not
+	// cancelled and have opened. Returns EUNAUTHORIZED if current user is not
+	// a teacher of the group.
+	FindAttendanceReport(ctx context.Context, groupID int, filter AttendanceReportFilter) (*AttendanceReport, error)
+}
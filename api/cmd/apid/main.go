@@ -5,14 +5,20 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"os"
 	"os/signal"
 	"runtime/debug"
+	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/dori7879/senior-project/api"
+	"github.com/dori7879/senior-project/api/fs"
 	"github.com/dori7879/senior-project/api/http"
 	"github.com/dori7879/senior-project/api/jwt"
 	"github.com/dori7879/senior-project/api/pg"
+	"github.com/dori7879/senior-project/api/scheduler"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 )
@@ -67,6 +73,9 @@ type Main struct {
 	// HTTP server for handling HTTP communication.
 	// SQLite services are attached to it before running.
 	HTTPServer *http.Server
+
+	// Runs periodic background tasks such as generating timetable sessions.
+	Scheduler *scheduler.Scheduler
 }
 
 // NewMain returns a new instance of Main.
@@ -77,11 +86,17 @@ func NewMain() *Main {
 
 		DB:         pg.NewDB(""),
 		HTTPServer: http.NewServer(),
+		Scheduler:  scheduler.NewScheduler(),
 	}
 }
 
 // Close gracefully stops the program.
 func (m *Main) Close() error {
+	if m.Scheduler != nil {
+		if err := m.Scheduler.Close(); err != nil {
+			return err
+		}
+	}
 	if m.HTTPServer != nil {
 		if err := m.HTTPServer.Close(); err != nil {
 			return err
@@ -100,7 +115,7 @@ func (m *Main) Close() error {
 // This exists separately from the Run() function so that we can skip it
 // during end-to-end tests. Those tests will configure manually and call Run().
 func (m *Main) ParseFlags(ctx context.Context, args []string) error {
-	var host, port, user, password, dbname, sslmode string
+	var host, port, user, password, dbname, sslmode, trustedProxies string
 	flag.StringVar(&host, "db-host", "localhost", "PostgreSQL database: host")
 	flag.StringVar(&port, "db-port", "5432", "PostgreSQL database: port")
 	flag.StringVar(&user, "db-user", "main_user", "PostgreSQL database: user")
@@ -111,8 +126,27 @@ func (m *Main) ParseFlags(ctx context.Context, args []string) error {
 	flag.StringVar(&m.Config.HTTP.Domain, "domain", "", "HTTP network address")
 	flag.StringVar(&m.Config.SignKey, "sign-key", "000000000000000000000000000000000000000000000000000000000000000", "Sign key for JWT")
 	flag.StringVar(&m.Config.VerifyKey, "verify-key", "000000000000000000000000000000000000000000000000000000000000000", "Verification key for JWT")
+	flag.StringVar(&m.Config.SurveyKey, "survey-key", "", "Secret key for anonymous survey tokens")
+	flag.StringVar(&trustedProxies, "trusted-proxies", "", "Comma-separated CIDR ranges of reverse proxies allowed to set X-Forwarded-For")
 	flag.Parse()
 
+	for _, v := range strings.Split(trustedProxies, ",") {
+		if v = strings.TrimSpace(v); v == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return fmt.Errorf("invalid trusted proxy range %q: %w", v, err)
+		}
+		m.Config.HTTP.TrustedProxies = append(m.Config.HTTP.TrustedProxies, n)
+	}
+
+	// Anyone knowing the key can tell who responded to a survey, so a
+	// secret one is required.
+	if strings.Trim(m.Config.SurveyKey, "0") == "" {
+		return fmt.Errorf("a secret -survey-key is required")
+	}
+
 	if host != "localhost" {
 		sslmode = "require"
 	} else {
@@ -136,7 +170,7 @@ func (m *Main) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("cannot open db: %w", err)
 	}
 
-	_, err = os.Getwd()
+	wd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("cannot get current directory: %w", err)
 	}
@@ -153,10 +187,30 @@ func (m *Main) Run(ctx context.Context) (err error) {
 	responseService := pg.NewResponseService(m.DB)
 	attendanceService := pg.NewAttendanceService(m.DB)
 	attSubmissionService := pg.NewAttSubmissionService(m.DB)
+	liveSessionService := pg.NewLiveSessionService(m.DB)
+	surveyService := pg.NewSurveyService(m.DB, []byte(m.Config.SurveyKey))
+	outcomeService := pg.NewOutcomeService(m.DB)
+	practiceService := pg.NewPracticeService(m.DB)
+	quizAttemptService := pg.NewQuizAttemptService(m.DB)
+	integrityService := pg.NewIntegrityService(m.DB)
+	timetableService := pg.NewTimetableService(m.DB)
+	attachmentService := pg.NewAttachmentService(m.DB)
+	attendanceReportService := pg.NewAttendanceReportService(m.DB)
+	gradebookService := pg.NewGradebookService(m.DB)
+	gradingService := pg.NewGradingService(m.DB)
+	notificationService := pg.NewNotificationService(m.DB)
+	accommodationService := pg.NewAccommodationService(m.DB)
+	rubricService := pg.NewRubricService(m.DB)
+	hwCommentService := pg.NewHWCommentService(m.DB)
+	hwAnnotationService := pg.NewHWAnnotationService(m.DB)
+
+	// Instantiate file-backed services.
+	fileService := fs.NewFileService(wd, []byte(m.Config.FS.HashKey))
 
 	// Copy configuration settings to the HTTP server.
 	m.HTTPServer.Addr = m.Config.HTTP.Addr
 	m.HTTPServer.Domain = m.Config.HTTP.Domain
+	m.HTTPServer.TrustedProxies = m.Config.HTTP.TrustedProxies
 
 	// Attach underlying services to the HTTP server.
 	m.HTTPServer.AuthService = authService
@@ -170,12 +224,51 @@ func (m *Main) Run(ctx context.Context) (err error) {
 	m.HTTPServer.ResponseService = responseService
 	m.HTTPServer.AttendanceService = attendanceService
 	m.HTTPServer.AttSubmissionService = attSubmissionService
+	m.HTTPServer.LiveSessionService = liveSessionService
+	m.HTTPServer.LiveBroker = pg.NewLiveBroker(m.DB)
+	m.HTTPServer.SurveyService = surveyService
+	m.HTTPServer.OutcomeService = outcomeService
+	m.HTTPServer.PracticeService = practiceService
+	m.HTTPServer.QuizAttemptService = quizAttemptService
+	m.HTTPServer.IntegrityService = integrityService
+	m.HTTPServer.TimetableService = timetableService
+	m.HTTPServer.AttendanceReportService = attendanceReportService
+	m.HTTPServer.GradebookService = gradebookService
+	m.HTTPServer.GradingService = gradingService
+	m.HTTPServer.NotificationService = notificationService
+	m.HTTPServer.AccommodationService = accommodationService
+	m.HTTPServer.RubricService = rubricService
+	m.HTTPServer.HWCommentService = hwCommentService
+	m.HTTPServer.HWAnnotationService = hwAnnotationService
+	m.HTTPServer.AttachmentUploader = &api.AttachmentUploader{
+		AttachmentService: attachmentService,
+		FileService:       fileService,
+	}
+
+	// Register background tasks.
+	m.Scheduler.Add("timetable sessions", 10*time.Minute, func(ctx context.Context, now time.Time) error {
+		_, err := timetableService.GenerateSessions(ctx, now)
+		return err
+	})
+	m.Scheduler.Add("attendance absences", time.Minute, func(ctx context.Context, now time.Time) error {
+		_, err := attSubmissionService.MarkAbsences(ctx, now)
+		return err
+	})
+	m.Scheduler.Add("grade release notifications", time.Minute, func(ctx context.Context, now time.Time) error {
+		_, err := gradingService.NotifyGradeReleases(ctx, now)
+		return err
+	})
 
 	// Start the HTTP server.
 	if err := m.HTTPServer.Open(); err != nil {
 		return err
 	}
 
+	// Start running background tasks.
+	if err := m.Scheduler.Open(); err != nil {
+		return err
+	}
+
 	log.Printf("running: url=%q dsn=%q", m.HTTPServer.URL(), m.Config.DB.DSN)
 
 	return nil
@@ -193,10 +286,12 @@ type Config struct {
 
 	SignKey   string
 	VerifyKey string
+	SurveyKey string
 
 	HTTP struct {
-		Addr   string
-		Domain string
+		Addr           string
+		Domain         string
+		TrustedProxies []*net.IPNet
 	}
 }
 
@@ -0,0 +1,78 @@
+package api
+
+import "strings"
+
+// MaxDiffLines is the most lines of a text that can be compared.
+const MaxDiffLines = 2000
+
+// Operations of a line in a diff.
+const (
+	DiffEqual  = "="
+	DiffInsert = "+"
+	DiffDelete = "-"
+)
+
+// DiffLine represents a line kept, added or removed between two texts.
+type DiffLine struct {
+	Op   string `json:"Op"`
+	Text string `json:"Text"`
+}
+
+// Diff returns the lines of a and b in order, marking those only in a as
+// deleted and those only in b as inserted. Kept lines are a longest common
+// subsequence of both texts. Returns EINVALID if either text has more than
+// MaxDiffLines lines.
+func Diff(a, b string) ([]*DiffLine, error) {
+	x, y := splitLines(a), splitLines(b)
+	if len(x) > MaxDiffLines || len(y) > MaxDiffLines {
+		return nil, Errorf(EINVALID, "Texts are too long to compare.")
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of x[i:] and y[j:].
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	lines := make([]*DiffLine, 0, len(x)+len(y))
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			lines = append(lines, &DiffLine{Op: DiffEqual, Text: x[i]})
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, &DiffLine{Op: DiffDelete, Text: x[i]})
+			i++
+		default:
+			lines = append(lines, &DiffLine{Op: DiffInsert, Text: y[j]})
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		lines = append(lines, &DiffLine{Op: DiffDelete, Text: x[i]})
+	}
+	for ; j < len(y); j++ {
+		lines = append(lines, &DiffLine{Op: DiffInsert, Text: y[j]})
+	}
+	return lines, nil
+}
+
+// splitLines splits a text into lines, ignoring a trailing newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
@@ -17,6 +17,8 @@ const (
 	ENOTFOUND       = "not_found"
 	ENOTIMPLEMENTED = "not_implemented"
 	EUNAUTHORIZED   = "unauthorized"
+	EFORBIDDEN      = "forbidden"
+	ERATELIMIT      = "rate_limit"
 )
 
 // Error represents an application-specific error. Application errors can be
@@ -125,7 +125,10 @@ func createFile(ctx context.Context, baseDir string, hashKey []byte, header *mul
 		return "", "", api.Errorf(api.EINTERNAL, "Could not hash file")
 	}
 
-	extension := strings.Split(header.Filename, ".")[1]
+	extension := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+	if extension == "" || len(extension) > api.MaxExtensionLen {
+		return "", "", api.Errorf(api.EINVALID, "File extension is incorrect")
+	}
 
 	filename := hash + "." + extension
 	imgDir, err := createDirs(hash, baseDir)
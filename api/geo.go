@@ -0,0 +1,76 @@
+package api
+
+import "math"
+
+// EarthRadius is the mean radius of the Earth in meters.
+const EarthRadius = 6371008.8
+
+// MaxLocationAccuracy is the largest reported accuracy, in meters, for which a
+// location is trusted by a geofence.
+const MaxLocationAccuracy = 100
+
+// Geofence policies deciding what happens to check-ins outside of the fence.
+const (
+	GeoReject = "reject"
+	GeoFlag   = "flag"
+)
+
+// Location represents coordinates reported by a client device.
+type Location struct {
+	Latitude  float64 `json:"Latitude"`
+	Longitude float64 `json:"Longitude"`
+	Accuracy  float64 `json:"Accuracy"` // in meters
+}
+
+// Validate returns an error if the location contains invalid fields.
+func (l *Location) Validate() error {
+	if l.Latitude < -90 || l.Latitude > 90 {
+		return Errorf(EINVALID, "Latitude is incorrect.")
+	} else if l.Longitude < -180 || l.Longitude > 180 {
+		return Errorf(EINVALID, "Longitude is incorrect.")
+	} else if l.Accuracy < 0 {
+		return Errorf(EINVALID, "Accuracy is incorrect.")
+	}
+	return nil
+}
+
+// Geofence represents a circular area an attendance can be checked in from.
+type Geofence struct {
+	Latitude  float64 `json:"Latitude"`
+	Longitude float64 `json:"Longitude"`
+	Radius    float64 `json:"Radius"` // in meters
+	Policy    string  `json:"Policy"`
+}
+
+// Validate returns an error if the geofence contains invalid fields.
+func (g *Geofence) Validate() error {
+	if err := (&Location{Latitude: g.Latitude, Longitude: g.Longitude}).Validate(); err != nil {
+		return err
+	} else if g.Radius <= 0 {
+		return Errorf(EINVALID, "Radius must be positive.")
+	} else if g.Policy != GeoReject && g.Policy != GeoFlag {
+		return Errorf(EINVALID, "Geofence policy is incorrect.")
+	}
+	return nil
+}
+
+// Check returns the distance of the location from the center of the fence and
+// whether it is inside. The accuracy of the location is given the benefit of
+// the doubt unless it is too coarse to be trusted.
+func (g *Geofence) Check(l *Location) (distance float64, inside bool) {
+	distance = Haversine(g.Latitude, g.Longitude, l.Latitude, l.Longitude)
+	if l.Accuracy > MaxLocationAccuracy {
+		return distance, false
+	}
+	return distance, distance-l.Accuracy <= g.Radius
+}
+
+// Haversine returns the great-circle distance in meters between two points
+// given in degrees.
+func Haversine(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dPhi, dLambda := (lat2-lat1)*math.Pi/180, (lng2-lng1)*math.Pi/180
+
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// Kinds of gradebook items.
+const (
+	GradeItemHomework   = "homework"
+	GradeItemQuiz       = "quiz"
+	GradeItemAttendance = "attendance"
+)
+
+// GradeCategory represents a weighted group of gradebook items, such as all
+// homeworks of a course. Items are placed in the first category of their kind
+// unless they are assigned to another one.
+type GradeCategory struct {
+	ID int `json:"ID"`
+
+	Name       string  `json:"Name"`
+	Kind       string  `json:"Kind"`
+	Weight     float64 `json:"Weight"`
+	DropLowest int     `json:"DropLowest"` // number of lowest grades left out
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	GroupID int `json:"GroupID"`
+}
+
+// Validate returns an error if the category contains invalid fields.
+// This only performs basic validation.
+func (c *GradeCategory) Validate() error {
+	if c.Name == "" {
+		return Errorf(EINVALID, "Name required.")
+	} else if !IsGradeItemKind(c.Kind) {
+		return Errorf(EINVALID, "Kind is incorrect.")
+	} else if c.Weight < 0 {
+		return Errorf(EINVALID, "Weight cannot be negative.")
+	} else if c.DropLowest < 0 {
+		return Errorf(EINVALID, "Number of dropped grades cannot be negative.")
+	}
+	return nil
+}
+
+// IsGradeItemKind returns true if s is a known kind of gradebook item.
+func IsGradeItemKind(s string) bool {
+	switch s {
+	case GradeItemHomework, GradeItemQuiz, GradeItemAttendance:
+		return true
+	}
+	return false
+}
+
+// DefaultGradeCategories returns the categories used by groups that have not
+// defined any: one per kind of item, weighted equally.
+func DefaultGradeCategories() []*GradeCategory {
+	return []*GradeCategory{
+		{Name: "Homework", Kind: GradeItemHomework, Weight: 1},
+		{Name: "Quizzes", Kind: GradeItemQuiz, Weight: 1},
+		{Name: "Attendance", Kind: GradeItemAttendance, Weight: 1},
+	}
+}
+
+// GradeItem represents a homework, quiz or attendance session of a group.
+// Attendance sessions are worth one point for present or late students.
+type GradeItem struct {
+	Kind       string    `json:"Kind"`
+	ID         int       `json:"ID"`
+	Title      string    `json:"Title"`
+	MaxGrade   float64   `json:"MaxGrade"`
+	Date       time.Time `json:"Date"`
+	CategoryID int       `json:"CategoryID,omitempty"` // explicitly assigned category
+
+	// Grades of locked items can only be changed with an override.
+	Locked bool `json:"Locked"`
+}
+
+// Gradebook represents the grades of every student of a group on every item.
+type Gradebook struct {
+	GroupID int `json:"GroupID"`
+
+	Categories []*GradeCategory `json:"Categories"`
+	Items      []*GradeItem     `json:"Items"`
+	Rows       []*GradebookRow  `json:"Rows"`
+}
+
+// GradebookRow represents the grades of a single student.
+type GradebookRow struct {
+	Student *User `json:"Student"`
+
+	// Grades of the latest submissions in the order of the gradebook items.
+	// Nil when not graded.
+	Grades []*float64 `json:"Grades"`
+
+	// Whether each grade was dropped from its category.
+	Dropped []bool `json:"Dropped"`
+
+	// Scores in percent in the order of the categories, nil when nothing is
+	// graded in the category, and the weighted course total.
+	CategoryScores []*float64 `json:"CategoryScores"`
+	Total          *float64   `json:"Total"`
+
+	// Letter grade of the total on the grading scale of the group.
+	Letter string `json:"Letter,omitempty"`
+}
+
+// Compute drops the lowest grades of every category and computes the category
+// scores and course totals. Category scores are the sum of points over the sum
+// of maximum points. Categories without grades are left out of the total.
+func (g *Gradebook) Compute() {
+	// Place every item in a category.
+	category := make([]int, len(g.Items))
+	for i, item := range g.Items {
+		category[i] = g.categoryOf(item)
+	}
+
+	for _, row := range g.Rows {
+		row.Dropped = make([]bool, len(g.Items))
+		row.CategoryScores = make([]*float64, len(g.Categories))
+
+		var total, weights float64
+		for c, cat := range g.Categories {
+			var graded []int
+			for i := range g.Items {
+				if category[i] == c && row.Grades[i] != nil && g.Items[i].MaxGrade > 0 {
+					graded = append(graded, i)
+				}
+			}
+
+			// Drop the lowest grades by percentage, keeping at least one.
+			sort.SliceStable(graded, func(a, b int) bool {
+				return *row.Grades[graded[a]]/g.Items[graded[a]].MaxGrade < *row.Grades[graded[b]]/g.Items[graded[b]].MaxGrade
+			})
+			drop := cat.DropLowest
+			if drop > len(graded)-1 {
+				drop = len(graded) - 1
+			}
+			if drop < 0 {
+				drop = 0
+			}
+			for _, i := range graded[:drop] {
+				row.Dropped[i] = true
+			}
+
+			var points, maxPoints float64
+			for _, i := range graded[drop:] {
+				points += *row.Grades[i]
+				maxPoints += g.Items[i].MaxGrade
+			}
+			if maxPoints == 0 {
+				continue
+			}
+
+			score := points * 100 / maxPoints
+			row.CategoryScores[c] = &score
+			total += score * cat.Weight
+			weights += cat.Weight
+		}
+
+		if weights > 0 {
+			total /= weights
+			row.Total = &total
+		}
+	}
+}
+
+// categoryOf returns the index of the category of an item, or -1 if none fits.
+func (g *Gradebook) categoryOf(item *GradeItem) int {
+	if item.CategoryID != 0 {
+		for c, cat := range g.Categories {
+			if cat.ID == item.CategoryID {
+				return c
+			}
+		}
+	}
+	for c, cat := range g.Categories {
+		if cat.Kind == item.Kind {
+			return c
+		}
+	}
+	return -1
+}
+
+// GradebookService represents a service for managing the gradebooks of groups.
+type GradebookService interface {
+	// Builds the gradebook of a group. Teachers of the group see every student
+	// while students only see their own row. Returns EUNAUTHORIZED if current
+	// user is not a member of the group.
+	FindGradebook(ctx context.Context, groupID int) (*Gradebook, error)
+
+	// Retrieves the categories of a group. Returns EUNAUTHORIZED if current
+	// user is not a member of the group.
+	FindGradeCategories(ctx context.Context, groupID int) ([]*GradeCategory, error)
+
+	// Creates a new category. Returns EUNAUTHORIZED if current user is not a
+	// teacher of the group.
+	CreateGradeCategory(ctx context.Context, category *GradeCategory) error
+
+	// Updates a category. Returns EUNAUTHORIZED if current user is not a
+	// teacher of the group. Returns ENOTFOUND if category does not exist.
+	UpdateGradeCategory(ctx context.Context, id int, upd GradeCategoryUpdate) (*GradeCategory, error)
+
+	// Permanently deletes a category. Its items fall back to the first
+	// category of their kind. Returns EUNAUTHORIZED if current user is not a
+	// teacher of the group. Returns ENOTFOUND if category does not exist.
+	DeleteGradeCategory(ctx context.Context, id int) error
+
+	// Assigns a homework or quiz of a group to a category, or back to the
+	// default one if categoryID is zero. Returns EUNAUTHORIZED if current
+	// user is not a teacher of the group.
+	SetGradeItemCategory(ctx context.Context, groupID int, kind string, itemID, categoryID int) error
+}
+
+// GradeCategoryUpdate represents a set of fields to be updated via UpdateGradeCategory().
+type GradeCategoryUpdate struct {
+	Name       *string  `json:"Name"`
+	Weight     *float64 `json:"Weight"`
+	DropLowest *int     `json:"DropLowest"`
+}
+
+// GradeImport represents grades of a single homework or quiz read from a
+// spreadsheet and matched by email to the submissions of the students. Only
+// the latest submission of every student is changed, which is the one whose
+// grade the gradebook shows.
+type GradeImport struct {
+	Kind   string `json:"Kind"`
+	ItemID int    `json:"ItemID"`
+
+	// Digest of the previewed file which must be sent back to commit it.
+	Token string `json:"Token"`
+
+	Rows      []*GradeImportRow `json:"Rows"`
+	Committed bool              `json:"Committed"`
+}
+
+// GradeImportRow represents the change a single spreadsheet row makes to a
+// submission. Rows with an error are skipped when the import is committed.
+type GradeImportRow struct {
+	Line  int    `json:"Line"`
+	Email string `json:"Email"`
+
+	StudentID    int `json:"StudentID,omitempty"`
+	SubmissionID int `json:"SubmissionID,omitempty"`
+
+	// New values are nil when the cell is blank and the current value is kept.
+	// Grades of late homework submissions are the ones before the penalty.
+	OldGrade    float32  `json:"OldGrade"`
+	NewGrade    *float32 `json:"NewGrade"`
+	OldComments string   `json:"OldComments"`
+	NewComments *string  `json:"NewComments"`
+
+	Changed bool   `json:"Changed"`
+	Error   string `json:"Error,omitempty"`
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Limits of a grading scale and of a grade override.
+const (
+	MaxGradeBands        = 20
+	MaxGradeLetterLen    = 8
+	MaxGradePoints       = 10
+	MaxOverrideReasonLen = 1000
+)
+
+// GradeBand maps the scores from MinPercent up to the next band to a letter
+// grade and its grade points.
+type GradeBand struct {
+	MinPercent float64 `json:"MinPercent"`
+	Letter     string  `json:"Letter"`
+	Points     float64 `json:"Points"`
+}
+
+// GradingScale represents the bands that the course totals of a group are
+// graded on, highest first.
+type GradingScale struct {
+	GroupID int          `json:"GroupID"`
+	Bands   []*GradeBand `json:"Bands"`
+
+	// Default is true when the group has not set up a scale of its own.
+	Default bool `json:"Default"`
+}
+
+// Validate returns an error if the scale contains invalid fields. The lowest
+// band must start at zero so that every score has a grade.
+func (s *GradingScale) Validate() error {
+	if len(s.Bands) == 0 {
+		return Errorf(EINVALID, "Grade bands required.")
+	} else if len(s.Bands) > MaxGradeBands {
+		return Errorf(EINVALID, "Too many grade bands.")
+	}
+	for i, b := range s.Bands {
+		if b.Letter == "" {
+			return Errorf(EINVALID, "Letter required.")
+		} else if len(b.Letter) > MaxGradeLetterLen {
+			return Errorf(EINVALID, "Letter too long.")
+		} else if b.MinPercent < 0 || b.MinPercent > 100 {
+			return Errorf(EINVALID, "Minimum percent must be between 0 and 100.")
+		} else if b.Points < 0 || b.Points > MaxGradePoints {
+			return Errorf(EINVALID, "Grade points must be between 0 and %d.", MaxGradePoints)
+		} else if i > 0 && b.MinPercent >= s.Bands[i-1].MinPercent {
+			return Errorf(EINVALID, "Grade bands must be ordered from highest to lowest.")
+		}
+	}
+	if s.Bands[len(s.Bands)-1].MinPercent != 0 {
+		return Errorf(EINVALID, "Lowest grade band must start at 0.")
+	}
+	return nil
+}
+
+// Band returns the band a score in percent falls in.
+func (s *GradingScale) Band(percent float64) *GradeBand {
+	for _, b := range s.Bands {
+		if percent >= b.MinPercent {
+			return b
+		}
+	}
+	return nil
+}
+
+// DefaultGradingScale returns the scale used by groups without one.
+func DefaultGradingScale(groupID int) *GradingScale {
+	return &GradingScale{
+		GroupID: groupID,
+		Bands: []*GradeBand{
+			{MinPercent: 90, Letter: "A", Points: 4},
+			{MinPercent: 80, Letter: "B", Points: 3},
+			{MinPercent: 70, Letter: "C", Points: 2},
+			{MinPercent: 60, Letter: "D", Points: 1},
+			{MinPercent: 0, Letter: "F", Points: 0},
+		},
+		Default: true,
+	}
+}
+
+// FinalGradeSnapshot represents the course grades of a group as computed from
+// its gradebook at one point in time, e.g. when submitted to the registrar.
+type FinalGradeSnapshot struct {
+	ID int `json:"ID"`
+
+	// Whether the grades of every homework and quiz were locked along with it.
+	Locked    bool      `json:"Locked"`
+	CreatedAt time.Time `json:"CreatedAt"`
+
+	GroupID   int `json:"GroupID"`
+	TeacherID int `json:"TeacherID"`
+
+	Grades []*FinalGrade `json:"Grades"`
+}
+
+// FinalGrade represents the course grade of a student. Percent and points are
+// nil when nothing was graded.
+type FinalGrade struct {
+	StudentID int   `json:"StudentID"`
+	Student   *User `json:"Student,omitempty"`
+
+	Percent *float64 `json:"Percent"`
+	Letter  string   `json:"Letter"`
+	Points  *float64 `json:"Points"`
+}
+
+// GradeOverride records a change to a grade of a homework or quiz whose
+// grades are locked.
+type GradeOverride struct {
+	ID int `json:"ID"`
+
+	Kind         string  `json:"Kind"`
+	ItemID       int     `json:"ItemID"`
+	SubmissionID int     `json:"SubmissionID"`
+	OldGrade     float32 `json:"OldGrade"`
+	NewGrade     float32 `json:"NewGrade"`
+	Reason       string  `json:"Reason"`
+
+	// Whether the submission was deleted rather than regraded.
+	Deleted bool `json:"Deleted,omitempty"`
+
+	// Whether the grades of the whole item were unlocked, in which case there
+	// is no submission or grade.
+	Unlocked bool `json:"Unlocked,omitempty"`
+
+	TeacherID int       `json:"TeacherID"`
+	CreatedAt time.Time `json:"CreatedAt"`
+}
+
+// GradingService represents a service for managing grading scales, final
+// grades and grade locks.
+type GradingService interface {
+	// Retrieves the scale of a group, or the default one if it has none.
+	// Returns EUNAUTHORIZED if current user is not a member of the group.
+	FindGradingScale(ctx context.Context, groupID int) (*GradingScale, error)
+
+	// Replaces the scale of a group. Passing no bands restores the default
+	// scale. Returns EUNAUTHORIZED if current user is not a teacher of the group.
+	UpdateGradingScale(ctx context.Context, groupID int, bands []*GradeBand) (*GradingScale, error)
+
+	// Stores the course grades of every student of a group, optionally
+	// locking the grades of its homeworks and quizzes. Returns EUNAUTHORIZED
+	// if current user is not a teacher of the group.
+	CreateFinalGrades(ctx context.Context, groupID int, lock bool) (*FinalGradeSnapshot, error)
+
+	// Retrieves the snapshots of a group, newest first. Students only see
+	// their own grades. Returns EUNAUTHORIZED if current user is not a member
+	// of the group.
+	FindFinalGradeSnapshots(ctx context.Context, groupID int) ([]*FinalGradeSnapshot, error)
+
+	// Locks or unlocks the grades of a homework or quiz of a group. Unlocking
+	// locked grades requires a reason, which is recorded as an override.
+	// Returns EUNAUTHORIZED if current user is not a teacher of the group.
+	SetGradeItemLocked(ctx context.Context, groupID int, kind string, itemID int, locked bool, reason *string) error
+
+	// Retrieves the overrides of locked grades in a group, newest first.
+	// Returns EUNAUTHORIZED if current user is not a teacher of the group.
+	FindGradeOverrides(ctx context.Context, groupID int) ([]*GradeOverride, error)
+
+	// Lets students see the grades of a homework or quiz from at on, or right
+	// away if at is zero. Students with a submission are notified once the
+	// grades are released if notify is set. Returns EUNAUTHORIZED if current
+	// user is not the teacher of the item.
+	ReleaseGrades(ctx context.Context, kind string, itemID int, at time.Time, notify bool) error
+
+	// Hides the grades of a homework or quiz from students again. Returns
+	// EUNAUTHORIZED if current user is not the teacher of the item.
+	WithholdGrades(ctx context.Context, kind string, itemID int) error
+
+	// Notifies students of the grades released up to now that they have not
+	// been notified of yet. Returns the number of items. Meant to be run
+	// periodically by the scheduler.
+	NotifyGradeReleases(ctx context.Context, now time.Time) (int, error)
+}
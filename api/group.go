@@ -20,6 +20,8 @@ type Group struct {
 	Members struct {
 		Users []*User `json:"Users"`
 	} `json:"Members"`
+
+	Timetable *Timetable `json:"Timetable,omitempty"`
 }
 
 // Validate returns an error if the group contains invalid fields.
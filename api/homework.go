@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"math"
 	"time"
 )
 
@@ -12,6 +13,21 @@ const (
 	Registered = "registered"
 )
 
+// Late policies of a homework. Under the per day policy a late submission
+// loses a percentage of its grade for every started day, up to a cap. Under
+// the zero policy it earns nothing.
+const (
+	LatePolicyNone   = "none"
+	LatePolicyPerDay = "per_day"
+	LatePolicyZero   = "zero"
+)
+
+// MaxGraceMinutes is the longest grace period after a due date.
+const MaxGraceMinutes = 7 * 24 * 60
+
+// MaxHomeworkAttachments is the largest number of files attached to a homework.
+const MaxHomeworkAttachments = 10
+
 // Homework represents a homework in the system.
 type Homework struct {
 	ID int `json:"ID"`
@@ -28,6 +44,27 @@ type Homework struct {
 	OpenedAt    time.Time `json:"OpenedAt"`
 	ClosedAt    time.Time `json:"ClosedAt"`
 
+	// Time from which students see their grades and comments. Zero until the
+	// teacher releases them.
+	GradesReleaseAt time.Time `json:"GradesReleaseAt"`
+
+	// Submissions after the due date and grace period are late and graded
+	// under the late policy, while submissions after ClosedAt are rejected.
+	// Penalties are in percent of the grade.
+	DueAt          time.Time `json:"DueAt"`
+	GraceMinutes   int       `json:"GraceMinutes"`
+	LatePolicy     string    `json:"LatePolicy"`
+	LatePenalty    float32   `json:"LatePenalty"`    // per started day
+	LatePenaltyCap float32   `json:"LatePenaltyCap"` // zero for no cap
+
+	// Number of times a student may change a submitted response. Zero for
+	// no limit.
+	MaxResubmissions int `json:"MaxResubmissions"`
+
+	// Optional rubric submissions are graded with.
+	RubricID int     `json:"RubricID,omitempty"`
+	Rubric   *Rubric `json:"Rubric,omitempty"`
+
 	TeacherFullName string `json:"TeacherFullName"`
 	TeacherID       int    `json:"TeacherID"`
 	Teacher         *User  `json:"Teacher"`
@@ -35,9 +72,24 @@ type Homework struct {
 	GroupID int    `json:"GroupID"`
 	Group   *Group `json:"Group"`
 
+	// Files handed out with the homework, such as a PDF of the assignment.
+	Attachments []*HomeworkAttachment `json:"Attachments,omitempty"`
+
 	Submissions []*HWSubmission `json:"Submissions,omitempty"`
 }
 
+// HomeworkAttachment represents a file attached to a homework. The file is
+// stored once per hash and kept while any homework or comment refers to it.
+type HomeworkAttachment struct {
+	Hash        string    `json:"Hash"`
+	Extension   string    `json:"Extension"`
+	Filename    string    `json:"Filename"`
+	ContentType string    `json:"ContentType"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+
+	HomeworkID int `json:"HomeworkID"`
+}
+
 // Validate returns an error if the homework contains invalid fields.
 // This only performs basic validation.
 func (u *Homework) Validate() error {
@@ -45,10 +97,81 @@ func (u *Homework) Validate() error {
 		return Errorf(EINVALID, "Title required.")
 	} else if u.Mode != All && u.Mode != Registered {
 		return Errorf(EINVALID, "Mode is incorrect.")
+	} else if u.LatePolicy != LatePolicyNone && u.LatePolicy != LatePolicyPerDay && u.LatePolicy != LatePolicyZero {
+		return Errorf(EINVALID, "Late policy is incorrect.")
+	} else if u.GraceMinutes < 0 || u.GraceMinutes > MaxGraceMinutes {
+		return Errorf(EINVALID, "Grace period must be between 0 and %d minutes.", MaxGraceMinutes)
+	} else if u.LatePenalty < 0 || u.LatePenalty > 100 {
+		return Errorf(EINVALID, "Late penalty must be between 0 and 100 percent.")
+	} else if u.LatePenaltyCap < 0 || u.LatePenaltyCap > 100 {
+		return Errorf(EINVALID, "Late penalty cap must be between 0 and 100 percent.")
+	} else if u.MaxResubmissions < 0 {
+		return Errorf(EINVALID, "Max resubmissions cannot be negative.")
+	} else if !u.DueAt.IsZero() && !u.ClosedAt.IsZero() && u.DueAt.After(u.ClosedAt) {
+		return Errorf(EINVALID, "Due date must not be after the close date.")
 	}
 	return nil
 }
 
+// Deadline returns the opening, due and closing times of the homework before
+// any per-student changes.
+func (u *Homework) Deadline() *Deadline {
+	return &Deadline{
+		ItemKind: GradeItemHomework,
+		ItemID:   u.ID,
+		OpenedAt: u.OpenedAt,
+		DueAt:    u.DueAt,
+		ClosedAt: u.ClosedAt,
+	}
+}
+
+// Closed returns true if submissions are no longer accepted at now.
+func (u *Homework) Closed(now time.Time) bool {
+	return !u.ClosedAt.IsZero() && now.After(u.ClosedAt)
+}
+
+// Lateness returns how long after the due date a submission made at t is, or
+// zero if it is on time or within the grace period. Homeworks without a due
+// date are due when they close.
+func (u *Homework) Lateness(t time.Time) time.Duration {
+	due := u.DueAt
+	if due.IsZero() {
+		due = u.ClosedAt
+	}
+	if due.IsZero() || !t.After(due.Add(time.Duration(u.GraceMinutes)*time.Minute)) {
+		return 0
+	}
+	return t.Sub(due)
+}
+
+// LateGrade returns the grade a submission late by the given duration earns
+// for a raw grade, rounded to hundredths.
+func (u *Homework) LateGrade(raw float32, late time.Duration) float32 {
+	if late <= 0 {
+		return raw
+	}
+
+	switch u.LatePolicy {
+	case LatePolicyZero:
+		return 0
+	case LatePolicyPerDay:
+		penalty := float64(u.LatePenalty) * math.Ceil(late.Hours()/24)
+		if u.LatePenaltyCap > 0 && penalty > float64(u.LatePenaltyCap) {
+			penalty = float64(u.LatePenaltyCap)
+		}
+		if penalty > 100 {
+			penalty = 100
+		}
+		return float32(math.Round(float64(raw)*(100-penalty)) / 100)
+	}
+	return raw
+}
+
+// GradesReleased returns true if students may see their grades at now.
+func (u *Homework) GradesReleased(now time.Time) bool {
+	return !u.GradesReleaseAt.IsZero() && !now.Before(u.GradesReleaseAt)
+}
+
 // HomeworkService represents a service for managing homeworks.
 type HomeworkService interface {
 	// Retrieves a homework by ID.
@@ -74,6 +197,15 @@ type HomeworkService interface {
 	// if current homework is not the homework being deleted. Returns ENOTFOUND if
 	// homework does not exist.
 	DeleteHomework(ctx context.Context, id int) error
+
+	// Attaches a stored file to a homework. Returns EUNAUTHORIZED if the
+	// current user does not teach the homework and ECONFLICT if the file is
+	// already attached.
+	AddHomeworkAttachment(ctx context.Context, attachment *HomeworkAttachment) error
+
+	// Detaches a file from a homework. The caller releases the file itself.
+	// Returns ENOTFOUND if the file is not attached to the homework.
+	RemoveHomeworkAttachment(ctx context.Context, homeworkID int, hash string) error
 }
 
 // HomeworkFilter represents a filter passed to FindHomeworks().
@@ -105,6 +237,17 @@ type HomeworkUpdate struct {
 	OpenedAt    *time.Time `json:"OpenedAt"`
 	ClosedAt    *time.Time `json:"ClosedAt"`
 
+	DueAt          *time.Time `json:"DueAt"`
+	GraceMinutes   *int       `json:"GraceMinutes"`
+	LatePolicy     *string    `json:"LatePolicy"`
+	LatePenalty    *float32   `json:"LatePenalty"`
+	LatePenaltyCap *float32   `json:"LatePenaltyCap"`
+
+	MaxResubmissions *int `json:"MaxResubmissions"`
+
+	// Attaches a rubric, or detaches it if zero.
+	RubricID *int `json:"RubricID"`
+
 	TeacherFullName *string `json:"TeacherFullName"`
 	TeacherID       *int    `json:"TeacherID"`
 	GroupID         *int    `json:"GroupID"`
@@ -0,0 +1,210 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerAccommodationRoutes is a helper function for registering the routes
+// of per-student deadlines and accommodations.
+func (s *Server) registerAccommodationRoutes(r *mux.Router) {
+	// Deadline of a homework, quiz or attendance for the current user.
+	r.HandleFunc("/deadlines/{kind}/{itemID}", s.handleDeadlineView).Methods("GET")
+
+	// Per-student overrides of the times of a homework, quiz or attendance.
+	r.HandleFunc("/deadlines/{kind}/{itemID}/overrides", s.handleDeadlineOverrideList).Methods("GET")
+	r.HandleFunc("/deadlines/{kind}/{itemID}/overrides/{studentID}", s.handleDeadlineOverrideSet).Methods("PUT")
+	r.HandleFunc("/deadlines/{kind}/{itemID}/overrides/{studentID}", s.handleDeadlineOverrideDelete).Methods("DELETE")
+
+	// Standing accommodations of the students of a group.
+	r.HandleFunc("/groups/{id}/accommodations", s.handleAccommodationList).Methods("GET")
+	r.HandleFunc("/groups/{id}/accommodations/{studentID}", s.handleAccommodationSet).Methods("PUT")
+	r.HandleFunc("/groups/{id}/accommodations/{studentID}", s.handleAccommodationDelete).Methods("DELETE")
+}
+
+// handleDeadlineView handles the "GET /deadlines/:kind/:itemID" route.
+func (s *Server) handleDeadlineView(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	deadline, err := s.AccommodationService.FindDeadline(r.Context(), mux.Vars(r)["kind"], itemID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(deadline); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleDeadlineOverrideList handles the "GET /deadlines/:kind/:itemID/overrides" route.
+func (s *Server) handleDeadlineOverrideList(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	overrides, err := s.AccommodationService.FindDeadlineOverrides(r.Context(), mux.Vars(r)["kind"], itemID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Overrides []*api.DeadlineOverride `json:"Overrides"`
+		N         int                     `json:"N"`
+	}{
+		Overrides: overrides,
+		N:         len(overrides),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleDeadlineOverrideSet handles the "PUT /deadlines/:kind/:itemID/overrides/:studentID" route.
+func (s *Server) handleDeadlineOverrideSet(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	studentID, err := strconv.Atoi(mux.Vars(r)["studentID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var o api.DeadlineOverride
+	if err := json.NewDecoder(r.Body).Decode(&o); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	o.ItemKind, o.ItemID, o.StudentID = mux.Vars(r)["kind"], itemID, studentID
+
+	if err := s.AccommodationService.SetDeadlineOverride(r.Context(), &o); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(o); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleDeadlineOverrideDelete handles the "DELETE /deadlines/:kind/:itemID/overrides/:studentID" route.
+func (s *Server) handleDeadlineOverrideDelete(w http.ResponseWriter, r *http.Request) {
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	studentID, err := strconv.Atoi(mux.Vars(r)["studentID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.AccommodationService.DeleteDeadlineOverride(r.Context(), mux.Vars(r)["kind"], itemID, studentID); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleAccommodationList handles the "GET /groups/:id/accommodations" route.
+func (s *Server) handleAccommodationList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	accommodations, err := s.AccommodationService.FindAccommodations(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Accommodations []*api.Accommodation `json:"Accommodations"`
+		N              int                  `json:"N"`
+	}{
+		Accommodations: accommodations,
+		N:              len(accommodations),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleAccommodationSet handles the "PUT /groups/:id/accommodations/:studentID" route.
+func (s *Server) handleAccommodationSet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	studentID, err := strconv.Atoi(mux.Vars(r)["studentID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var a api.Accommodation
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	a.GroupID, a.StudentID = id, studentID
+
+	if err := s.AccommodationService.SetAccommodation(r.Context(), &a); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(a); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleAccommodationDelete handles the "DELETE /groups/:id/accommodations/:studentID" route.
+func (s *Server) handleAccommodationDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	studentID, err := strconv.Atoi(mux.Vars(r)["studentID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.AccommodationService.DeleteAccommodation(r.Context(), id, studentID); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
@@ -2,8 +2,10 @@ package http
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/dori7879/senior-project/api"
 	"github.com/gorilla/mux"
@@ -122,6 +124,8 @@ func (s *Server) handleAttSubmissionCreate(w http.ResponseWriter, r *http.Reques
 	}
 
 	sub.AttendanceID = attID
+	sub.UserAgent = r.UserAgent()
+	sub.IP = s.clientIP(r).String()
 
 	att, err := s.AttendanceService.FindAttendanceByID(r.Context(), attID)
 	if err != nil {
@@ -134,9 +138,23 @@ func (s *Server) handleAttSubmissionCreate(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Guessing the PIN is slowed down by limiting failed check-ins.
+	pinKey := fmt.Sprintf("%d:ip:%s", attID, sub.IP)
+	if user != nil {
+		pinKey = fmt.Sprintf("%d:user:%d", attID, user.ID)
+	}
+	isPIN := sub.CheckInMethod == "" || sub.CheckInMethod == api.CheckInPIN
+	if isPIN && s.pinLimiter.Blocked(pinKey, time.Now()) {
+		Error(w, r, api.Errorf(api.ERATELIMIT, "Too many failed check-ins. Try again later."))
+		return
+	}
+
 	// Create submission in the database.
 	err = s.AttSubmissionService.CreateAttSubmission(r.Context(), &sub)
 	if err != nil {
+		if isPIN && api.ErrorCode(err) == api.EINVALID {
+			s.pinLimiter.Allow(pinKey, time.Now())
+		}
 		Error(w, r, err)
 		return
 	}
@@ -172,13 +190,29 @@ func (s *Server) handleAttSubmissionUpdate(w http.ResponseWriter, r *http.Reques
 		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Student attempts to update present field"))
 		return
 	}
+	if user != nil && !user.IsTeacher && upd.Flagged != nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Student attempts to update flagged field"))
+		return
+	}
+	if (user == nil || !user.IsTeacher) && (upd.Status != nil || upd.ExcuseReason != nil) {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Student attempts to update status field"))
+		return
+	}
+
+	// Fetch the current excuse document, which a status change may release.
+	old, err := s.AttSubmissionService.FindAttSubmissionByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
 
 	// Update the attendance submission in the database.
-	_, err = s.AttSubmissionService.UpdateAttSubmission(r.Context(), id, upd)
+	sub, err := s.AttSubmissionService.UpdateAttSubmission(r.Context(), id, upd)
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
+	s.releaseExcuseAttachment(r, old.ExcuseAttachment, sub.ExcuseAttachment)
 
 	w.Header().Set("Content-type", "application/json")
 	w.Write([]byte(`{}`))
@@ -194,11 +228,19 @@ func (s *Server) handleAttSubmissionDelete(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Fetch the excuse document, which is released along with the submission.
+	sub, err := s.AttSubmissionService.FindAttSubmissionByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	// Delete the attendance submission from the database.
 	if err := s.AttSubmissionService.DeleteAttSubmission(r.Context(), id); err != nil {
 		Error(w, r, err)
 		return
 	}
+	s.releaseExcuseAttachment(r, sub.ExcuseAttachment, "")
 
 	// Response part
 	w.Header().Set("Content-type", "application/json")
@@ -19,6 +19,9 @@ func (s *Server) registerAttendancePrivateRoutes(r *mux.Router) {
 
 	// View a single attendance.
 	r.HandleFunc("/attendances/{id}", s.handleAttendanceView).Methods("GET")
+
+	// Current PIN of an attendance, for displaying it to the class.
+	r.HandleFunc("/attendances/{id}/pin", s.handleAttendancePINView).Methods("GET")
 }
 
 // registerAttendancePublicRoutes is a helper function for registering public attendance routes.
@@ -29,6 +32,7 @@ func (s *Server) registerAttendancePublicRoutes(r *mux.Router) {
 	// View a single attendance.
 	r.HandleFunc("/attendances/shared/{link}/teacher", s.handleAttendanceTeacherView).Methods("GET")
 	r.HandleFunc("/attendances/shared/{link}/student", s.handleAttendanceStudentView).Methods("GET")
+	r.HandleFunc("/attendances/shared/{link}/pin", s.handleAttendanceSharedPINView).Methods("GET")
 
 	r.HandleFunc("/attendances/{id}", s.handleAttendanceUpdate).Methods("PATCH")
 	r.HandleFunc("/attendances/{id}/renew", s.handleAttendancePINRenew).Methods("PATCH")
@@ -56,6 +60,9 @@ func (s *Server) handleAttendanceList(w http.ResponseWriter, r *http.Request) {
 		Error(w, r, err)
 		return
 	}
+	for _, v := range attendances {
+		v.PIN = v.CurrentPIN(time.Now())
+	}
 
 	// Render output based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
@@ -97,7 +104,7 @@ func (s *Server) handleAttendanceView(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch associated submissions from the database.
-	attendance.Submissions, _, err = s.AttSubmissionService.FindAttSubmissions(r.Context(), api.AttSubmissionFilter{AttendanceID: &attendance.ID})
+	attendance.Submissions, _, err = s.AttSubmissionService.FindAttSubmissions(r.Context(), attSubmissionFilter(r, attendance.ID))
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -108,6 +115,7 @@ func (s *Server) handleAttendanceView(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(`{}`))
 		return
 	}
+	attendance.PIN = attendance.CurrentPIN(time.Now())
 
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
@@ -134,9 +142,10 @@ func (s *Server) handleAttendanceTeacherView(w http.ResponseWriter, r *http.Requ
 		Error(w, r, err)
 		return
 	}
+	attendance.PIN = attendance.CurrentPIN(time.Now())
 
 	// Fetch associated submissions from the database.
-	attendance.Submissions, _, err = s.AttSubmissionService.FindAttSubmissions(r.Context(), api.AttSubmissionFilter{AttendanceID: &attendance.ID})
+	attendance.Submissions, _, err = s.AttSubmissionService.FindAttSubmissions(r.Context(), attSubmissionFilter(r, attendance.ID))
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -150,6 +159,20 @@ func (s *Server) handleAttendanceTeacherView(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+// attSubmissionFilter returns the filter of the submissions shown to the
+// teacher. Passing "flagged=true" only returns check-ins awaiting review and
+// "status" only returns submissions with that status.
+func attSubmissionFilter(r *http.Request, attendanceID int) api.AttSubmissionFilter {
+	filter := api.AttSubmissionFilter{AttendanceID: &attendanceID}
+	if v, err := strconv.ParseBool(r.URL.Query().Get("flagged")); err == nil {
+		filter.Flagged = &v
+	}
+	if v := r.URL.Query().Get("status"); api.IsAttStatus(v) {
+		filter.Status = &v
+	}
+	return filter
+}
+
 // handleAttendanceStudentView handles the "GET /attendances/shared/:link/studnet" route.
 func (s *Server) handleAttendanceStudentView(w http.ResponseWriter, r *http.Request) {
 	user := api.UserFromContext(r.Context())
@@ -168,6 +191,9 @@ func (s *Server) handleAttendanceStudentView(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	// The PIN is only shown in the classroom.
+	attendance.PIN = ""
+
 	if attendance.GroupID != 0 {
 		if user == nil {
 			Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not in the group. Also, you are not logged in."))
@@ -279,6 +305,8 @@ func (s *Server) handleAttendanceUpdate(w http.ResponseWriter, r *http.Request)
 	w.Write([]byte(`{}`))
 }
 
+// handleAttendancePINRenew handles the "PATCH /attendances/:id/renew" route.
+// It replaces the PIN manually and returns the new one.
 func (s *Server) handleAttendancePINRenew(w http.ResponseWriter, r *http.Request) {
 	// Parse attendance ID from the path.
 	id, err := strconv.Atoi(mux.Vars(r)["id"])
@@ -287,18 +315,92 @@ func (s *Server) handleAttendancePINRenew(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	pin := api.RandDigitSeq(6)
+	// Rotating PINs are renewed by replacing their secret, which invalidates
+	// the code currently on display.
+	pin := api.RandDigitSeq(api.PINLength)
 	upd := api.AttendanceUpdate{PIN: &pin}
 
+	secret, err := api.RandSecret(20)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	upd.PINSecret = &secret
+
 	// Update the attendance in the database.
-	_, err = s.AttendanceService.UpdateAttendance(r.Context(), id, upd)
+	attendance, err := s.AttendanceService.UpdateAttendance(r.Context(), id, upd)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(fmt.Sprintf(`{"pin": "%s"}`, attendance.CurrentPIN(time.Now()))))
+}
+
+// handleAttendancePINView handles the "GET /attendances/:id/pin" route.
+func (s *Server) handleAttendancePINView(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	attendance, err := s.AttendanceService.FindAttendanceByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	} else if attendance.TeacherID != user.ID {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view the PIN of this attendance"))
+		return
+	}
+
+	s.writeAttendancePIN(w, r, attendance)
+}
+
+// handleAttendanceSharedPINView handles the "GET /attendances/shared/:link/pin" route.
+func (s *Server) handleAttendanceSharedPINView(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	attendance, err := s.AttendanceService.FindAttendanceByTeacherLink(r.Context(), mux.Vars(r)["link"])
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
 
+	s.writeAttendancePIN(w, r, attendance)
+}
+
+// writeAttendancePIN writes the current PIN of an attendance and when it
+// expires. ExpiresAt is omitted for static PINs.
+func (s *Server) writeAttendancePIN(w http.ResponseWriter, r *http.Request, attendance *api.Attendance) {
+	now := time.Now()
+
+	var expiresAt *time.Time
+	if t := attendance.PINExpiresAt(now); !t.IsZero() {
+		expiresAt = &t
+	}
+
 	w.Header().Set("Content-type", "application/json")
-	w.Write([]byte(fmt.Sprintf(`{"pin": "%s"}`, pin)))
+	if err := json.NewEncoder(w).Encode(struct {
+		PIN       string     `json:"PIN"`
+		PINMode   string     `json:"PINMode"`
+		ExpiresAt *time.Time `json:"ExpiresAt,omitempty"`
+	}{
+		PIN:       attendance.CurrentPIN(now),
+		PINMode:   attendance.PINMode,
+		ExpiresAt: expiresAt,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
 }
 
 // handleAttendanceDelete handles the "DELETE /attendances/:id" route. This route
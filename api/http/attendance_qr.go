@@ -0,0 +1,215 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Bounds and default of the rendered QR code size, in pixels.
+const (
+	DefaultQRSize = 512
+	MaxQRSize     = 2048
+)
+
+// registerAttendanceQRPrivateRoutes is a helper function for registering private QR check-in routes.
+func (s *Server) registerAttendanceQRPrivateRoutes(r *mux.Router) {
+	// QR code of an attendance owned by the current teacher.
+	r.HandleFunc("/attendances/{id}/qr", s.handleAttendanceQR).Methods("GET")
+}
+
+// registerAttendanceQRPublicRoutes is a helper function for registering public QR check-in routes.
+func (s *Server) registerAttendanceQRPublicRoutes(r *mux.Router) {
+	// QR code of an attendance shared through its teacher link.
+	r.HandleFunc("/attendances/shared/{link}/qr", s.handleAttendanceSharedQR).Methods("GET")
+
+	// Checking in with a scanned QR code.
+	r.HandleFunc("/attendances/shared/{link}/checkin", s.handleAttendanceCheckIn).Methods("POST")
+}
+
+// handleAttendanceQR handles the "GET /attendances/:id/qr" route.
+func (s *Server) handleAttendanceQR(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	attendance, err := s.AttendanceService.FindAttendanceByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	} else if attendance.TeacherID != user.ID {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view the QR code of this attendance"))
+		return
+	}
+
+	s.writeAttendanceQR(w, r, attendance)
+}
+
+// handleAttendanceSharedQR handles the "GET /attendances/shared/:link/qr" route.
+func (s *Server) handleAttendanceSharedQR(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	attendance, err := s.AttendanceService.FindAttendanceByTeacherLink(r.Context(), mux.Vars(r)["link"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	s.writeAttendanceQR(w, r, attendance)
+}
+
+// writeAttendanceQR renders the QR code of the current check-in token. The
+// "format" query parameter selects "png" (default) or "svg" and "size" sets
+// the width in pixels. Clients should fetch it again once the Expires time
+// has passed.
+func (s *Server) writeAttendanceQR(w http.ResponseWriter, r *http.Request, attendance *api.Attendance) {
+	size := DefaultQRSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		var err error
+		if size, err = strconv.Atoi(v); err != nil || size < 64 || size > MaxQRSize {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid size"))
+			return
+		}
+	}
+
+	now := time.Now()
+	q, err := qrcode.New(s.checkInURL(r, attendance, now), qrcode.Medium)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Expires", attendance.CheckInTokenExpiresAt(now).UTC().Format(http.TimeFormat))
+
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "png":
+		b, err := q.PNG(size)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		w.Header().Set("Content-type", "image/png")
+		if _, err := w.Write(b); err != nil {
+			LogError(r, err)
+		}
+	case "svg":
+		w.Header().Set("Content-type", "image/svg+xml")
+		if _, err := w.Write(qrSVG(q.Bitmap(), size)); err != nil {
+			LogError(r, err)
+		}
+	default:
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid format"))
+	}
+}
+
+// handleAttendanceCheckIn handles the "POST /attendances/shared/:link/checkin"
+// route. The body holds the scanned token, the install ID of the device, its
+// location for geofenced attendances and, for anonymous students, their name.
+func (s *Server) handleAttendanceCheckIn(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a student"))
+		return
+	}
+
+	var body struct {
+		Token           string        `json:"Token"`
+		DeviceID        string        `json:"DeviceID"`
+		StudentFullName string        `json:"StudentFullName"`
+		Location        *api.Location `json:"Location"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	att, err := s.AttendanceService.FindAttendanceByStudentLink(r.Context(), mux.Vars(r)["link"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if att.Mode == "registered" && user == nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Log in to be able to submit"))
+		return
+	}
+
+	sub := api.AttSubmission{
+		Token:           body.Token,
+		CheckInMethod:   api.CheckInQR,
+		StudentFullName: body.StudentFullName,
+		Location:        body.Location,
+		DeviceID:        body.DeviceID,
+		UserAgent:       r.UserAgent(),
+		IP:              s.clientIP(r).String(),
+		AttendanceID:    att.ID,
+	}
+	if user != nil {
+		sub.StudentID = user.ID
+	}
+
+	// Create submission in the database.
+	if err := s.AttSubmissionService.CreateAttSubmission(r.Context(), &sub); err != nil {
+		Error(w, r, err)
+		return
+	}
+	sub.Token, sub.Attendance = "", nil
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&sub); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// checkInURL returns the address of the student page of the attendance with
+// the check-in token valid at the given time.
+func (s *Server) checkInURL(r *http.Request, attendance *api.Attendance, now time.Time) string {
+	u := url.URL{
+		Scheme:   "http",
+		Host:     r.Host,
+		Path:     "/attendances/submit/" + attendance.StudentLink,
+		RawQuery: url.Values{"qr": {attendance.CheckInToken(now)}}.Encode(),
+	}
+	if s.Domain != "" {
+		u.Scheme, u.Host = "https", s.Domain
+	} else if r.TLS != nil {
+		u.Scheme = "https"
+	}
+	return u.String()
+}
+
+// qrSVG renders a QR code bitmap as an SVG image of the given width.
+func qrSVG(bitmap [][]bool, size int) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size, len(bitmap), len(bitmap))
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, len(bitmap), len(bitmap))
+	for y, row := range bitmap {
+		for x, v := range row {
+			if v {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+	return buf.Bytes()
+}
@@ -0,0 +1,195 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerAttendanceReportRoutes is a helper function for registering attendance report routes.
+func (s *Server) registerAttendanceReportRoutes(r *mux.Router) {
+	// Attendance register of a group as JSON, CSV or a printable page.
+	r.HandleFunc("/groups/{id}/attendance-report", s.handleAttendanceReport).Methods("GET")
+}
+
+// handleAttendanceReport handles the "GET /groups/:id/attendance-report" route.
+// It accepts optional "from" and "to" dates in the 2006-01-02 format, the
+// at-risk "threshold" in percent and the "format", which is "json" (default),
+// "csv" or "html".
+func (s *Server) handleAttendanceReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	filter := api.AttendanceReportFilter{Threshold: api.DefaultAtRiskThreshold}
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		if filter.Threshold, err = strconv.ParseFloat(v, 64); err != nil || filter.Threshold < 0 || filter.Threshold > 100 {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid threshold"))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("from"); v != "" {
+		from, err := time.Parse(api.DateLayout, v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid date format"))
+			return
+		}
+		filter.From = &from
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		to, err := time.Parse(api.DateLayout, v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid date format"))
+			return
+		}
+		to = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		filter.To = &to
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "html" {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid format"))
+		return
+	}
+
+	report, err := s.AttendanceReportService.FindAttendanceReport(r.Context(), id, filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	switch format {
+	case "csv":
+		writeAttendanceReportCSV(w, r, report)
+	case "html":
+		writeAttendanceReportHTML(w, r, report)
+	default:
+		w.Header().Set("Content-type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			LogError(r, err)
+			return
+		}
+	}
+}
+
+// writeAttendanceReportCSV writes the report as a CSV file with a column per
+// session followed by the totals of every student.
+func writeAttendanceReportCSV(w http.ResponseWriter, r *http.Request, report *api.AttendanceReport) {
+	header := []string{"Student", "Email"}
+	for _, att := range report.Sessions {
+		header = append(header, sessionLabel(att))
+	}
+	header = append(header, "Present", "Late", "Excused", "Absent", "Rate", "At risk")
+
+	w.Header().Set("Content-type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="attendance-%d.csv"`, report.GroupID))
+
+	for i := range header {
+		header[i] = csvCell(header[i])
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write(header)
+	for _, row := range report.Rows {
+		record := []string{csvCell(row.Student.FirstName + " " + row.Student.LastName), csvCell(row.Student.Email)}
+		record = append(record, row.Statuses...)
+		record = append(record,
+			strconv.Itoa(row.Counts[api.AttPresent]),
+			strconv.Itoa(row.Counts[api.AttLate]),
+			strconv.Itoa(row.Counts[api.AttExcused]),
+			strconv.Itoa(row.Counts[api.AttAbsent]),
+			strconv.FormatFloat(row.Rate, 'f', 1, 64),
+			strconv.FormatBool(row.AtRisk),
+		)
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		LogError(r, err)
+	}
+}
+
+// csvCell returns a text cell that spreadsheets will not evaluate as a formula.
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// writeAttendanceReportHTML writes the report as a printable register.
+func writeAttendanceReportHTML(w http.ResponseWriter, r *http.Request, report *api.AttendanceReport) {
+	w.Header().Set("Content-type", "text/html; charset=utf-8")
+	if err := attendanceRegisterTemplate.Execute(w, report); err != nil {
+		LogError(r, err)
+	}
+}
+
+// sessionLabel returns the column title of a session in exported reports.
+func sessionLabel(att *api.Attendance) string {
+	date := att.ScheduledOn
+	if date == "" && !att.OpenedAt.IsZero() {
+		date = att.OpenedAt.Format(api.DateLayout)
+	} else if date == "" {
+		date = att.CreatedAt.Format(api.DateLayout)
+	}
+	return date + " " + att.Title
+}
+
+// attendanceRegisterTemplate renders the printable register. Statuses are
+// abbreviated to their first letter to fit the page.
+var attendanceRegisterTemplate = template.Must(template.New("register").Funcs(template.FuncMap{
+	"label": sessionLabel,
+	"abbr": func(status string) string {
+		if status == "" {
+			return ""
+		}
+		return strings.ToUpper(status[:1])
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Attendance register</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #999; padding: 2px 4px; text-align: center; }
+th.session { writing-mode: vertical-rl; white-space: nowrap; }
+td.name { text-align: left; white-space: nowrap; }
+tr.risk td.name, tr.risk td.rate { color: #b00; font-weight: bold; }
+@media print { @page { size: landscape; } }
+</style>
+</head>
+<body>
+<h1>Attendance register</h1>
+<p>P: present, L: late, E: excused, A: absent. Students below {{printf "%.0f" .Threshold}}% are highlighted.</p>
+<table>
+<thead>
+<tr>
+<th>Student</th>
+{{range .Sessions}}<th class="session">{{label .}}</th>{{end}}
+<th>Rate</th>
+</tr>
+</thead>
+<tbody>
+{{range .Rows}}<tr{{if .AtRisk}} class="risk"{{end}}>
+<td class="name">{{.Student.LastName}} {{.Student.FirstName}}</td>
+{{range .Statuses}}<td>{{abbr .}}</td>{{end}}
+<td class="rate">{{printf "%.1f" .Rate}}%</td>
+</tr>
+{{end}}</tbody>
+</table>
+</body>
+</html>
+`))
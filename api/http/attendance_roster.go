@@ -0,0 +1,282 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// MaxExcuseSize is the largest document that can be attached to an excuse.
+const MaxExcuseSize = 10 << 20
+
+// excuseExtensions lists the file types accepted as excuse documents.
+var excuseExtensions = map[string]bool{
+	"pdf":  true,
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+	"doc":  true,
+	"docx": true,
+}
+
+// registerAttendanceRosterRoutes is a helper function for registering attendance roster routes.
+func (s *Server) registerAttendanceRosterRoutes(r *mux.Router) {
+	// Statuses of every student of an attendance, editable in bulk.
+	r.HandleFunc("/attendances/{id}/roster", s.handleAttendanceRosterView).Methods("GET")
+	r.HandleFunc("/attendances/{id}/roster", s.handleAttendanceRosterUpdate).Methods("PATCH")
+
+	// Excusing a student with a reason and an optional document.
+	r.HandleFunc("/attendances/{id}/excuses", s.handleAttendanceExcuseCreate).Methods("POST")
+	r.HandleFunc("/attendances/submissions/{id}/excuse", s.handleAttendanceExcuseDownload).Methods("GET")
+
+	// Check-ins of different students from the same device or IP address.
+	r.HandleFunc("/attendances/{id}/proxies", s.handleAttendanceProxyReport).Methods("GET")
+}
+
+// handleAttendanceRosterView handles the "GET /attendances/:id/roster" route.
+// Group members who have not checked in are listed with a blank status.
+func (s *Server) handleAttendanceRosterView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	subs, err := s.AttSubmissionService.FindRoster(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Submissions []*api.AttSubmission `json:"Submissions"`
+		N           int                  `json:"N"`
+	}{
+		Submissions: subs,
+		N:           len(subs),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleAttendanceRosterUpdate handles the "PATCH /attendances/:id/roster"
+// route. The body holds the new statuses in an "Entries" list.
+func (s *Server) handleAttendanceRosterUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		Entries []*api.RosterEntry `json:"Entries"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	before, err := s.excuseAttachments(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	subs, err := s.AttSubmissionService.UpdateRoster(r.Context(), id, body.Entries)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Documents of students who are no longer excused are released.
+	for _, sub := range subs {
+		s.releaseExcuseAttachment(r, before[sub.StudentID], sub.ExcuseAttachment)
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Submissions []*api.AttSubmission `json:"Submissions"`
+		N           int                  `json:"N"`
+	}{
+		Submissions: subs,
+		N:           len(subs),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleAttendanceExcuseCreate handles the "POST /attendances/:id/excuses"
+// route. It accepts a multipart form with the "StudentID", the "Reason" and
+// an optional "Attachment" file.
+func (s *Server) handleAttendanceExcuseCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxExcuseSize+1<<20)
+	if err := r.ParseMultipartForm(MaxExcuseSize); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid form body"))
+		return
+	}
+
+	entry := api.RosterEntry{
+		Status:       api.AttExcused,
+		ExcuseReason: strings.TrimSpace(r.FormValue("Reason")),
+	}
+	if entry.StudentID, err = strconv.Atoi(r.FormValue("StudentID")); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid student ID format"))
+		return
+	} else if entry.ExcuseReason == "" {
+		Error(w, r, api.Errorf(api.EINVALID, "Excuse reason required."))
+		return
+	}
+
+	// Make sure the user manages the roster before storing any file.
+	before, err := s.excuseAttachments(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if file, header, err := r.FormFile("Attachment"); err == nil {
+		file.Close()
+
+		if s.AttachmentUploader == nil {
+			Error(w, r, api.Errorf(api.EINVALID, "File uploads are not enabled"))
+			return
+		} else if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), ".")); !excuseExtensions[ext] {
+			Error(w, r, api.Errorf(api.EINVALID, "Unsupported file type"))
+			return
+		}
+
+		attachment, err := s.AttachmentUploader.UploadAttachment(r.Context(), header)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+		entry.ExcuseAttachment = attachment.Hash
+	} else if err != http.ErrMissingFile {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid attachment"))
+		return
+	}
+
+	subs, err := s.AttSubmissionService.UpdateRoster(r.Context(), id, []*api.RosterEntry{&entry})
+	if err != nil {
+		s.releaseExcuseAttachment(r, entry.ExcuseAttachment, "")
+		Error(w, r, err)
+		return
+	}
+	sub := subs[0]
+	if old := before[sub.StudentID]; entry.ExcuseAttachment != "" && old == entry.ExcuseAttachment {
+		// The same document was uploaded again so drop the extra reference.
+		s.releaseExcuseAttachment(r, old, "")
+	} else {
+		s.releaseExcuseAttachment(r, old, sub.ExcuseAttachment)
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleAttendanceExcuseDownload handles the "GET /attendances/submissions/:id/excuse"
+// route. The document is available to the teacher and to the excused student.
+func (s *Server) handleAttendanceExcuseDownload(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	sub, err := s.AttSubmissionService.FindAttSubmissionByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	} else if sub.StudentID != user.ID && sub.Attendance.TeacherID != user.ID {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view this excuse"))
+		return
+	} else if sub.ExcuseAttachment == "" || s.AttachmentUploader == nil {
+		Error(w, r, api.Errorf(api.ENOTFOUND, "Excuse has no attachment"))
+		return
+	}
+
+	attachment, err := s.AttachmentUploader.AttachmentService.FindAttachmentByHash(r.Context(), sub.ExcuseAttachment)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", "attachment; filename=excuse."+attachment.Extension)
+	http.ServeFile(w, r, s.AttachmentUploader.FileService.GetPathByHash(r.Context(), attachment.Hash, attachment.Extension))
+}
+
+// handleAttendanceProxyReport handles the "GET /attendances/:id/proxies" route.
+func (s *Server) handleAttendanceProxyReport(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	clusters, err := s.AttSubmissionService.FindProxyCheckIns(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Clusters []*api.ProxyCluster `json:"Clusters"`
+		N        int                 `json:"N"`
+	}{
+		Clusters: clusters,
+		N:        len(clusters),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// excuseAttachments returns the excuse documents of an attendance by student ID.
+// Returns EUNAUTHORIZED if the current user does not manage the roster.
+func (s *Server) excuseAttachments(ctx context.Context, attendanceID int) (map[int]string, error) {
+	subs, err := s.AttSubmissionService.FindRoster(ctx, attendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[int]string)
+	for _, sub := range subs {
+		if sub.ExcuseAttachment != "" {
+			m[sub.StudentID] = sub.ExcuseAttachment
+		}
+	}
+	return m, nil
+}
+
+// releaseExcuseAttachment decrements the counter of a document that is no
+// longer referenced. Errors are only logged as the submission is already saved.
+func (s *Server) releaseExcuseAttachment(r *http.Request, old, current string) {
+	if old == "" || old == current || s.AttachmentUploader == nil {
+		return
+	}
+	if err := s.AttachmentUploader.DecrementAttachmentCounter(r.Context(), old); err != nil {
+		LogError(r, err)
+	}
+}
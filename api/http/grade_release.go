@@ -0,0 +1,126 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerGradeReleaseRoutes is a helper function for registering the routes
+// releasing the grades of homeworks and quizzes.
+func (s *Server) registerGradeReleaseRoutes(r *mux.Router) {
+	r.HandleFunc("/homeworks/{id}/grades/release", s.handleHomeworkGradesRelease).Methods("PUT")
+	r.HandleFunc("/homeworks/{id}/grades/release", s.handleHomeworkGradesWithhold).Methods("DELETE")
+	r.HandleFunc("/quizzes/{id}/grades/release", s.handleQuizGradesRelease).Methods("PUT")
+	r.HandleFunc("/quizzes/{id}/grades/release", s.handleQuizGradesWithhold).Methods("DELETE")
+}
+
+// handleHomeworkGradesRelease handles the "PUT /homeworks/:id/grades/release" route.
+func (s *Server) handleHomeworkGradesRelease(w http.ResponseWriter, r *http.Request) {
+	s.releaseGrades(w, r, api.GradeItemHomework)
+}
+
+// handleHomeworkGradesWithhold handles the "DELETE /homeworks/:id/grades/release" route.
+func (s *Server) handleHomeworkGradesWithhold(w http.ResponseWriter, r *http.Request) {
+	s.withholdGrades(w, r, api.GradeItemHomework)
+}
+
+// handleQuizGradesRelease handles the "PUT /quizzes/:id/grades/release" route.
+func (s *Server) handleQuizGradesRelease(w http.ResponseWriter, r *http.Request) {
+	s.releaseGrades(w, r, api.GradeItemQuiz)
+}
+
+// handleQuizGradesWithhold handles the "DELETE /quizzes/:id/grades/release" route.
+func (s *Server) handleQuizGradesWithhold(w http.ResponseWriter, r *http.Request) {
+	s.withholdGrades(w, r, api.GradeItemQuiz)
+}
+
+// releaseGrades releases the grades of the item in the path, right away or at
+// the ReleaseAt time of the body. Students are notified when Notify is set.
+func (s *Server) releaseGrades(w http.ResponseWriter, r *http.Request, kind string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		ReleaseAt time.Time `json:"ReleaseAt"`
+		Notify    bool      `json:"Notify"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if err := s.GradingService.ReleaseGrades(r.Context(), kind, id, body.ReleaseAt, body.Notify); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// withholdGrades hides the grades of the item in the path from students.
+func (s *Server) withholdGrades(w http.ResponseWriter, r *http.Request, kind string) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.GradingService.WithholdGrades(r.Context(), kind, id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// redactHWSubmissions clears the grades of submissions to homeworks whose
+// grades are not released yet.
+func (s *Server) redactHWSubmissions(r *http.Request, subs []*api.HWSubmission) error {
+	released := make(map[int]bool)
+	for _, sub := range subs {
+		ok, found := released[sub.HomeworkID]
+		if !found {
+			hw, err := s.HomeworkService.FindHomeworkByID(r.Context(), sub.HomeworkID)
+			if err != nil {
+				return err
+			}
+			ok = hw.GradesReleased(time.Now())
+			released[sub.HomeworkID] = ok
+		}
+		if !ok {
+			sub.RedactGrade()
+		}
+	}
+	return nil
+}
+
+// redactQuizSubmissions clears the grades of submissions to quizzes whose
+// grades are not released yet.
+func (s *Server) redactQuizSubmissions(r *http.Request, subs []*api.QuizSubmission) error {
+	released := make(map[int]bool)
+	for _, sub := range subs {
+		ok, found := released[sub.QuizID]
+		if !found {
+			quiz, err := s.QuizService.FindQuizByID(r.Context(), sub.QuizID)
+			if err != nil {
+				return err
+			}
+			ok = quiz.GradesReleased(time.Now())
+			released[sub.QuizID] = ok
+		}
+		if !ok {
+			sub.RedactGrade()
+		}
+	}
+	return nil
+}
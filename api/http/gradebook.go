@@ -0,0 +1,268 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/dori7879/senior-project/api/xlsx"
+	"github.com/gorilla/mux"
+)
+
+// registerGradebookRoutes is a helper function for registering all gradebook routes.
+func (s *Server) registerGradebookRoutes(r *mux.Router) {
+	// Grades of every student of a group on its homeworks, quizzes and attendances.
+	r.HandleFunc("/groups/{id}/gradebook", s.handleGradebookView).Methods("GET")
+
+	// Weighted categories of a group.
+	r.HandleFunc("/groups/{id}/grade-categories", s.handleGradeCategoryList).Methods("GET")
+	r.HandleFunc("/groups/{id}/grade-categories", s.handleGradeCategoryCreate).Methods("POST")
+	r.HandleFunc("/grade-categories/{id}", s.handleGradeCategoryUpdate).Methods("PATCH")
+	r.HandleFunc("/grade-categories/{id}", s.handleGradeCategoryDelete).Methods("DELETE")
+
+	// Assigning a homework or quiz to a category.
+	r.HandleFunc("/groups/{id}/gradebook/items/{kind}/{itemID}", s.handleGradeItemCategorySet).Methods("PUT")
+
+	// Importing the grades of a homework or quiz from a spreadsheet. The
+	// changes are previewed before they are committed.
+	r.HandleFunc("/groups/{id}/gradebook/import/preview", s.handleGradeImportPreview).Methods("POST")
+	r.HandleFunc("/groups/{id}/gradebook/import/commit", s.handleGradeImportCommit).Methods("POST")
+}
+
+// handleGradebookView handles the "GET /groups/:id/gradebook" route. The
+// gradebook is exported as a spreadsheet when the format is csv or xlsx.
+func (s *Server) handleGradebookView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "xlsx" {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid format"))
+		return
+	}
+
+	gradebook, err := s.GradebookService.FindGradebook(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	switch format {
+	case "csv":
+		writeGradebookCSV(w, r, gradebook)
+	case "xlsx":
+		writeGradebookXLSX(w, r, gradebook)
+	default:
+		w.Header().Set("Content-type", "application/json")
+		if err := json.NewEncoder(w).Encode(gradebook); err != nil {
+			LogError(r, err)
+			return
+		}
+	}
+}
+
+// gradebookTable returns the gradebook as a table with a row per student, a
+// column per item followed by the category scores and the total. Cells are
+// strings, or float pointers which are nil when not graded.
+func gradebookTable(g *api.Gradebook) [][]interface{} {
+	header := []interface{}{"Student", "Email"}
+	for _, item := range g.Items {
+		header = append(header, item.Title)
+	}
+	for _, cat := range g.Categories {
+		header = append(header, cat.Name+" (%)")
+	}
+	header = append(header, "Total (%)")
+
+	table := [][]interface{}{header}
+	for _, row := range g.Rows {
+		record := []interface{}{row.Student.FirstName + " " + row.Student.LastName, row.Student.Email}
+		for _, grade := range row.Grades {
+			record = append(record, grade)
+		}
+		for _, score := range row.CategoryScores {
+			record = append(record, roundPercent(score))
+		}
+		record = append(record, roundPercent(row.Total))
+		table = append(table, record)
+	}
+	return table
+}
+
+// roundPercent rounds a score to one decimal place.
+func roundPercent(v *float64) *float64 {
+	if v == nil {
+		return nil
+	}
+	rounded := math.Round(*v*10) / 10
+	return &rounded
+}
+
+// writeGradebookCSV writes the gradebook as a CSV file.
+func writeGradebookCSV(w http.ResponseWriter, r *http.Request, g *api.Gradebook) {
+	w.Header().Set("Content-type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gradebook-%d.csv"`, g.GroupID))
+
+	cw := csv.NewWriter(w)
+	for _, row := range gradebookTable(g) {
+		record := make([]string, len(row))
+		for i, v := range row {
+			switch v := v.(type) {
+			case string:
+				record[i] = csvCell(v)
+			case *float64:
+				if v != nil {
+					record[i] = strconv.FormatFloat(*v, 'f', -1, 64)
+				}
+			}
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		LogError(r, err)
+	}
+}
+
+// writeGradebookXLSX writes the gradebook as an Excel workbook.
+func writeGradebookXLSX(w http.ResponseWriter, r *http.Request, g *api.Gradebook) {
+	w.Header().Set("Content-type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="gradebook-%d.xlsx"`, g.GroupID))
+
+	if err := xlsx.Write(w, "Gradebook", gradebookTable(g)); err != nil {
+		LogError(r, err)
+	}
+}
+
+// handleGradeCategoryList handles the "GET /groups/:id/grade-categories" route.
+func (s *Server) handleGradeCategoryList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	categories, err := s.GradebookService.FindGradeCategories(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Categories []*api.GradeCategory `json:"Categories"`
+		N          int                  `json:"N"`
+	}{
+		Categories: categories,
+		N:          len(categories),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleGradeCategoryCreate handles the "POST /groups/:id/grade-categories" route.
+func (s *Server) handleGradeCategoryCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var category api.GradeCategory
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	category.GroupID = id
+
+	if err := s.GradebookService.CreateGradeCategory(r.Context(), &category); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&category); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleGradeCategoryUpdate handles the "PATCH /grade-categories/:id" route.
+func (s *Server) handleGradeCategoryUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	upd := api.GradeCategoryUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if _, err := s.GradebookService.UpdateGradeCategory(r.Context(), id, upd); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleGradeCategoryDelete handles the "DELETE /grade-categories/:id" route.
+func (s *Server) handleGradeCategoryDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.GradebookService.DeleteGradeCategory(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleGradeItemCategorySet handles the "PUT /groups/:id/gradebook/items/:kind/:itemID"
+// route. A "CategoryID" of zero puts the item back in the default category.
+func (s *Server) handleGradeItemCategorySet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		CategoryID int `json:"CategoryID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if err := s.GradebookService.SetGradeItemCategory(r.Context(), id, mux.Vars(r)["kind"], itemID, body.CategoryID); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
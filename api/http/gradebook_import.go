@@ -0,0 +1,331 @@
+package http
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/dori7879/senior-project/api/xlsx"
+	"github.com/gorilla/mux"
+)
+
+// Limits of a spreadsheet that grades can be imported from.
+const (
+	MaxGradeImportSize = 5 << 20
+	MaxGradeImportRows = 1000
+)
+
+// gradeImportSubmission is the part of a homework or quiz submission that an
+// import reads and changes.
+type gradeImportSubmission struct {
+	ID        int
+	StudentID int
+	Email     string
+	Grade     float32
+	Comments  string
+}
+
+// handleGradeImportPreview handles the "POST /groups/:id/gradebook/import/preview"
+// route. It reads a CSV or XLSX file of emails, grades and optional comments
+// for a homework or quiz and returns the changes it would make.
+func (s *Server) handleGradeImportPreview(w http.ResponseWriter, r *http.Request) {
+	s.handleGradeImport(w, r, false)
+}
+
+// handleGradeImportCommit handles the "POST /groups/:id/gradebook/import/commit"
+// route. It applies the changes of a previewed file, which is identified by the
+// token returned by the preview.
+func (s *Server) handleGradeImportCommit(w http.ResponseWriter, r *http.Request) {
+	s.handleGradeImport(w, r, true)
+}
+
+// handleGradeImport reads the uploaded file and matches it to the submissions
+// of the item. When commit is true, the changed rows are saved through the
+// submission services.
+func (s *Server) handleGradeImport(w http.ResponseWriter, r *http.Request, commit bool) {
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxGradeImportSize+1<<20)
+	if err := r.ParseMultipartForm(MaxGradeImportSize); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid multipart form"))
+		return
+	}
+
+	imp := api.GradeImport{Kind: r.FormValue("Kind")}
+
+	if imp.ItemID, err = strconv.Atoi(r.FormValue("ItemID")); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid item ID format"))
+		return
+	}
+
+	// Grades of locked items are only changed when a reason is given.
+	var reason *string
+	if v := r.FormValue("OverrideReason"); v != "" {
+		reason = &v
+	}
+
+	file, header, err := r.FormFile("File")
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "File required"))
+		return
+	}
+	defer file.Close()
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv":
+		cr := csv.NewReader(bytes.NewReader(data))
+		cr.FieldsPerRecord = -1
+		if records, err = cr.ReadAll(); err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid CSV file"))
+			return
+		}
+	case ".xlsx":
+		if records, err = xlsx.Read(bytes.NewReader(data), int64(len(data))); err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid XLSX file"))
+			return
+		}
+	default:
+		Error(w, r, api.Errorf(api.EINVALID, "File must be a CSV or XLSX file"))
+		return
+	}
+
+	// The token ties a commit to the file and item that were previewed.
+	sum := sha256.Sum256([]byte(imp.Kind + ":" + strconv.Itoa(imp.ItemID) + ":" + string(data)))
+	imp.Token = hex.EncodeToString(sum[:])
+	if commit && r.FormValue("Token") != imp.Token {
+		Error(w, r, api.Errorf(api.EINVALID, "File differs from the previewed one"))
+		return
+	}
+
+	subs, maxGrade, err := s.gradeImportSubmissions(r, user, groupID, imp.Kind, imp.ItemID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	imp.Rows, err = diffGradeImport(records, subs, maxGrade)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if commit {
+		for _, row := range imp.Rows {
+			if row.Error != "" || !row.Changed {
+				continue
+			}
+
+			switch imp.Kind {
+			case api.GradeItemHomework:
+				_, err = s.HWSubmissionService.UpdateHWSubmission(r.Context(), row.SubmissionID, api.HWSubmissionUpdate{Grade: row.NewGrade, Comments: row.NewComments, OverrideReason: reason})
+			case api.GradeItemQuiz:
+				_, err = s.QuizSubmissionService.UpdateQuizSubmission(r.Context(), row.SubmissionID, api.QuizSubmissionUpdate{Grade: row.NewGrade, Comments: row.NewComments, OverrideReason: reason})
+			}
+			if err != nil {
+				row.Error = api.ErrorMessage(err)
+			}
+		}
+		imp.Committed = true
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(&imp); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// gradeImportSubmissions returns the latest submission of every student on a
+// homework or quiz of the group, and the maximum grade of the item. Only the
+// teacher of the item may import its grades.
+func (s *Server) gradeImportSubmissions(r *http.Request, user *api.User, groupID int, kind string, itemID int) ([]*gradeImportSubmission, float32, error) {
+	var subs []*gradeImportSubmission
+	switch kind {
+	case api.GradeItemHomework:
+		hw, err := s.HomeworkService.FindHomeworkByID(r.Context(), itemID)
+		if err != nil {
+			return nil, 0, err
+		} else if hw.GroupID != groupID || hw.TeacherID != user.ID {
+			return nil, 0, api.Errorf(api.EUNAUTHORIZED, "You are not the teacher of the homework")
+		}
+
+		hwSubs, _, err := s.HWSubmissionService.FindHWSubmissions(r.Context(), api.HWSubmissionFilter{HomeworkID: &itemID})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, sub := range hwSubs {
+			if sub.Student == nil {
+				continue
+			}
+
+			// Imported grades are penalised for lateness again, so compare
+			// them with the grade before the penalty.
+			grade := sub.Grade
+			if sub.Late {
+				grade = sub.RawGrade
+			}
+			subs = append(subs, &gradeImportSubmission{ID: sub.ID, StudentID: sub.StudentID, Email: sub.Student.Email, Grade: grade, Comments: sub.Comments})
+		}
+		return latestGradeImportSubmissions(subs), hw.MaxGrade, nil
+
+	case api.GradeItemQuiz:
+		quiz, err := s.QuizService.FindQuizByID(r.Context(), itemID)
+		if err != nil {
+			return nil, 0, err
+		} else if quiz.GroupID != groupID || quiz.TeacherID != user.ID {
+			return nil, 0, api.Errorf(api.EUNAUTHORIZED, "You are not the teacher of the quiz")
+		}
+
+		quizSubs, _, err := s.QuizSubmissionService.FindQuizSubmissions(r.Context(), api.QuizSubmissionFilter{QuizID: &itemID})
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, sub := range quizSubs {
+			if sub.Student != nil {
+				subs = append(subs, &gradeImportSubmission{ID: sub.ID, StudentID: sub.StudentID, Email: sub.Student.Email, Grade: sub.Grade, Comments: sub.Comments})
+			}
+		}
+		return latestGradeImportSubmissions(subs), quiz.MaxGrade, nil
+	}
+	return nil, 0, api.Errorf(api.EINVALID, "Kind must be homework or quiz")
+}
+
+// latestGradeImportSubmissions keeps the most recent submission of every
+// student, which is the one with the highest ID.
+func latestGradeImportSubmissions(subs []*gradeImportSubmission) []*gradeImportSubmission {
+	latest := make(map[int]*gradeImportSubmission)
+	var students []*gradeImportSubmission
+	for _, sub := range subs {
+		if prev, ok := latest[sub.StudentID]; !ok {
+			students = append(students, sub)
+			latest[sub.StudentID] = sub
+		} else if sub.ID > prev.ID {
+			*prev = *sub
+		}
+	}
+	return students
+}
+
+// diffGradeImport matches the rows of a spreadsheet to submissions by email
+// and returns the change every row makes. A header row naming the email,
+// grade and comments columns is used when present; otherwise they are the
+// first three columns. Blank rows are left out.
+func diffGradeImport(records [][]string, subs []*gradeImportSubmission, maxGrade float32) ([]*api.GradeImportRow, error) {
+	emailCol, gradeCol, commentsCol := 0, 1, 2
+	start := 0
+	if len(records) > 0 {
+		header := make(map[string]int)
+		for i, cell := range records[0] {
+			switch strings.ToLower(strings.TrimSpace(cell)) {
+			case "email", "e-mail":
+				header["email"] = i
+			case "grade", "score", "points":
+				header["grade"] = i
+			case "comments", "comment", "feedback":
+				header["comments"] = i
+			}
+		}
+		if i, ok := header["email"]; ok {
+			emailCol, gradeCol, commentsCol, start = i, -1, -1, 1
+			if i, ok := header["grade"]; ok {
+				gradeCol = i
+			}
+			if i, ok := header["comments"]; ok {
+				commentsCol = i
+			}
+		}
+	}
+
+	byEmail := make(map[string]*gradeImportSubmission, len(subs))
+	for _, sub := range subs {
+		byEmail[strings.ToLower(sub.Email)] = sub
+	}
+
+	cell := func(record []string, i int) string {
+		if i >= 0 && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []*api.GradeImportRow
+	seen := make(map[string]bool)
+	for i := start; i < len(records); i++ {
+		record := records[i]
+		email := cell(record, emailCol)
+		grade := cell(record, gradeCol)
+		comments := cell(record, commentsCol)
+		if email == "" && grade == "" && comments == "" {
+			continue
+		}
+
+		row := &api.GradeImportRow{Line: i + 1, Email: email}
+		rows = append(rows, row)
+
+		key := strings.ToLower(email)
+		sub := byEmail[key]
+		if email == "" {
+			row.Error = "Email required"
+			continue
+		} else if seen[key] {
+			row.Error = "Email appears more than once"
+			continue
+		} else if sub == nil {
+			row.Error = "No submission from this student"
+			continue
+		}
+		seen[key] = true
+
+		row.StudentID, row.SubmissionID = sub.StudentID, sub.ID
+		row.OldGrade, row.OldComments = sub.Grade, sub.Comments
+
+		if grade != "" {
+			v, err := strconv.ParseFloat(strings.Replace(grade, ",", ".", 1), 32)
+			if err != nil {
+				row.Error = "Invalid grade"
+				continue
+			} else if v < 0 || (maxGrade > 0 && float32(v) > maxGrade) {
+				row.Error = "Grade out of range"
+				continue
+			}
+			g := float32(v)
+			row.NewGrade = &g
+		}
+		if comments != "" {
+			row.NewComments = &comments
+		}
+
+		row.Changed = (row.NewGrade != nil && *row.NewGrade != row.OldGrade) ||
+			(row.NewComments != nil && *row.NewComments != row.OldComments)
+	}
+
+	if len(rows) > MaxGradeImportRows {
+		return nil, api.Errorf(api.EINVALID, "Too many rows")
+	}
+	return rows, nil
+}
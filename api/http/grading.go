@@ -0,0 +1,201 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerGradingRoutes is a helper function for registering all grading routes.
+func (s *Server) registerGradingRoutes(r *mux.Router) {
+	// Letter grade scale of a group.
+	r.HandleFunc("/groups/{id}/grading-scale", s.handleGradingScaleView).Methods("GET")
+	r.HandleFunc("/groups/{id}/grading-scale", s.handleGradingScaleUpdate).Methods("PUT")
+
+	// Snapshots of the final course grades of a group.
+	r.HandleFunc("/groups/{id}/final-grades", s.handleFinalGradeList).Methods("GET")
+	r.HandleFunc("/groups/{id}/final-grades", s.handleFinalGradeCreate).Methods("POST")
+
+	// Locking the grades of a homework or quiz and the overrides made since.
+	r.HandleFunc("/groups/{id}/gradebook/items/{kind}/{itemID}/lock", s.handleGradeItemLock).Methods("PUT")
+	r.HandleFunc("/groups/{id}/gradebook/items/{kind}/{itemID}/lock", s.handleGradeItemUnlock).Methods("DELETE")
+	r.HandleFunc("/groups/{id}/grade-overrides", s.handleGradeOverrideList).Methods("GET")
+}
+
+// handleGradingScaleView handles the "GET /groups/:id/grading-scale" route.
+func (s *Server) handleGradingScaleView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	scale, err := s.GradingService.FindGradingScale(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(scale); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleGradingScaleUpdate handles the "PUT /groups/:id/grading-scale" route.
+// Sending no bands restores the default scale.
+func (s *Server) handleGradingScaleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		Bands []*api.GradeBand `json:"Bands"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	scale, err := s.GradingService.UpdateGradingScale(r.Context(), id, body.Bands)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(scale); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleFinalGradeList handles the "GET /groups/:id/final-grades" route.
+func (s *Server) handleFinalGradeList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	snapshots, err := s.GradingService.FindFinalGradeSnapshots(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Snapshots []*api.FinalGradeSnapshot `json:"Snapshots"`
+		N         int                       `json:"N"`
+	}{
+		Snapshots: snapshots,
+		N:         len(snapshots),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleFinalGradeCreate handles the "POST /groups/:id/final-grades" route. The
+// grades of the homeworks and quizzes of the group are locked when Lock is set.
+func (s *Server) handleFinalGradeCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		Lock bool `json:"Lock"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	snapshot, err := s.GradingService.CreateFinalGrades(r.Context(), id, body.Lock)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleGradeItemLock handles the "PUT /groups/:id/gradebook/items/:kind/:itemID/lock" route.
+func (s *Server) handleGradeItemLock(w http.ResponseWriter, r *http.Request) {
+	s.setGradeItemLocked(w, r, true)
+}
+
+// handleGradeItemUnlock handles the "DELETE /groups/:id/gradebook/items/:kind/:itemID/lock" route.
+func (s *Server) handleGradeItemUnlock(w http.ResponseWriter, r *http.Request) {
+	s.setGradeItemLocked(w, r, false)
+}
+
+// setGradeItemLocked locks or unlocks the grades of the item in the path.
+func (s *Server) setGradeItemLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	itemID, err := strconv.Atoi(mux.Vars(r)["itemID"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Locked grades are only unlocked with a reason, which may be given in
+	// the "OverrideReason" query parameter.
+	var reason *string
+	if v := r.URL.Query().Get("OverrideReason"); v != "" {
+		reason = &v
+	}
+
+	if err := s.GradingService.SetGradeItemLocked(r.Context(), id, mux.Vars(r)["kind"], itemID, locked, reason); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleGradeOverrideList handles the "GET /groups/:id/grade-overrides" route.
+func (s *Server) handleGradeOverrideList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	overrides, err := s.GradingService.FindGradeOverrides(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Overrides []*api.GradeOverride `json:"Overrides"`
+		N         int                  `json:"N"`
+	}{
+		Overrides: overrides,
+		N:         len(overrides),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
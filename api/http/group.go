@@ -73,6 +73,14 @@ func (s *Server) handleGroupView(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Attach the timetable, if the group has one and the user may see it.
+	if group.Timetable, err = s.TimetableService.FindTimetable(r.Context(), group.ID); err != nil {
+		if code := api.ErrorCode(err); code != api.ENOTFOUND && code != api.EUNAUTHORIZED {
+			Error(w, r, err)
+			return
+		}
+	}
+
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
 	if err := json.NewEncoder(w).Encode(group); err != nil {
@@ -94,8 +94,12 @@ func (s *Server) handleHomeworkView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Fetch associated submissions from the database.
-	homework.Submissions, _, err = s.HWSubmissionService.FindHWSubmissions(r.Context(), api.HWSubmissionFilter{HomeworkID: &homework.ID})
+	// Fetch associated submissions from the database, optionally by status.
+	filter := api.HWSubmissionFilter{HomeworkID: &homework.ID}
+	if v := r.URL.Query().Get("status"); api.IsHWStatus(v) {
+		filter.Status = &v
+	}
+	homework.Submissions, _, err = s.HWSubmissionService.FindHWSubmissions(r.Context(), filter)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -133,8 +137,12 @@ func (s *Server) handleHomeworkTeacherView(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Fetch associated submissions from the database.
-	homework.Submissions, _, err = s.HWSubmissionService.FindHWSubmissions(r.Context(), api.HWSubmissionFilter{HomeworkID: &homework.ID})
+	// Fetch associated submissions from the database, optionally by status.
+	filter := api.HWSubmissionFilter{HomeworkID: &homework.ID}
+	if v := r.URL.Query().Get("status"); api.IsHWStatus(v) {
+		filter.Status = &v
+	}
+	homework.Submissions, _, err = s.HWSubmissionService.FindHWSubmissions(r.Context(), filter)
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -286,12 +294,31 @@ func (s *Server) handleHomeworkDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Fetch the attached files before they are detached by the deletion,
+	// including those of the comments on its submissions.
+	hw, err := s.HomeworkService.FindHomeworkByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+	commentAttachments, err := s.HWCommentService.FindHWCommentAttachments(r.Context(), api.HWCommentAttachmentFilter{HomeworkID: &id})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	// Delete the homework from the database.
 	if err := s.HomeworkService.DeleteHomework(r.Context(), id); err != nil {
 		Error(w, r, err)
 		return
 	}
 
+	// Release the files that were attached to the homework.
+	for _, a := range hw.Attachments {
+		s.releaseHomeworkAttachment(r, a.Hash)
+	}
+	s.releaseCommentAttachments(r, commentAttachments)
+
 	// Response part
 	w.Header().Set("Content-type", "application/json")
 	w.Write([]byte(`{}`))
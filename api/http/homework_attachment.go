@@ -0,0 +1,210 @@
+package http
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// MaxHomeworkUploadSize is the largest total size of the files attached to a
+// homework in one request.
+const MaxHomeworkUploadSize = 50 << 20
+
+// homeworkExtensions lists the file types that can be attached to homeworks.
+var homeworkExtensions = map[string]bool{
+	"pdf":  true,
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+	"doc":  true,
+	"docx": true,
+	"ppt":  true,
+	"pptx": true,
+	"xls":  true,
+	"xlsx": true,
+	"txt":  true,
+	"zip":  true,
+}
+
+// registerHomeworkAttachmentPrivateRoutes is a helper function for registering
+// the routes that manage the files of a homework.
+func (s *Server) registerHomeworkAttachmentPrivateRoutes(r *mux.Router) {
+	// Multipart form with one or more "Attachments" files.
+	r.HandleFunc("/homeworks/{id}/attachments", s.handleHomeworkAttachmentCreate).Methods("POST")
+
+	r.HandleFunc("/homeworks/{id}/attachments/{hash}", s.handleHomeworkAttachmentDelete).Methods("DELETE")
+}
+
+// registerHomeworkAttachmentPublicRoutes is a helper function for registering
+// the routes that serve the files of a homework.
+func (s *Server) registerHomeworkAttachmentPublicRoutes(r *mux.Router) {
+	// Students opening a shared link download the files without logging in.
+	r.HandleFunc("/homeworks/{id}/attachments/{hash}", s.handleHomeworkAttachmentDownload).Methods("GET")
+}
+
+// handleHomeworkAttachmentCreate handles the "POST /homeworks/:id/attachments" route.
+func (s *Server) handleHomeworkAttachmentCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	} else if s.AttachmentUploader == nil {
+		Error(w, r, api.Errorf(api.EINVALID, "File uploads are not enabled"))
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxHomeworkUploadSize+1<<20)
+	if err := r.ParseMultipartForm(MaxHomeworkUploadSize); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid form body"))
+		return
+	}
+
+	headers := r.MultipartForm.File["Attachments"]
+	if len(headers) == 0 {
+		Error(w, r, api.Errorf(api.EINVALID, "No files uploaded"))
+		return
+	}
+	for _, header := range headers {
+		if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), ".")); !homeworkExtensions[ext] {
+			Error(w, r, api.Errorf(api.EINVALID, "Unsupported file type"))
+			return
+		}
+	}
+
+	hw, err := s.HomeworkService.FindHomeworkByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	} else if len(hw.Attachments)+len(headers) > api.MaxHomeworkAttachments {
+		Error(w, r, api.Errorf(api.EINVALID, "Too many attachments."))
+		return
+	}
+
+	attachments := make([]*api.HomeworkAttachment, 0, len(headers))
+	for _, header := range headers {
+		attachment, err := s.AttachmentUploader.UploadAttachment(r.Context(), header)
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+
+		filename := filepath.Base(header.Filename)
+		if len(filename) > 255 {
+			filename = filename[:255]
+		}
+		a := &api.HomeworkAttachment{
+			Hash:        attachment.Hash,
+			Extension:   attachment.Extension,
+			Filename:    filename,
+			ContentType: attachmentContentType(attachment.Extension),
+			HomeworkID:  id,
+		}
+
+		if err := s.HomeworkService.AddHomeworkAttachment(r.Context(), a); api.ErrorCode(err) == api.ECONFLICT {
+			// The file is already attached so drop the extra reference.
+			s.releaseHomeworkAttachment(r, a.Hash)
+			continue
+		} else if err != nil {
+			s.releaseHomeworkAttachment(r, a.Hash)
+			Error(w, r, err)
+			return
+		}
+		attachments = append(attachments, a)
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(struct {
+		Attachments []*api.HomeworkAttachment `json:"Attachments"`
+		N           int                       `json:"N"`
+	}{
+		Attachments: attachments,
+		N:           len(attachments),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHomeworkAttachmentDelete handles the "DELETE /homeworks/:id/attachments/:hash" route.
+func (s *Server) handleHomeworkAttachmentDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	hash := mux.Vars(r)["hash"]
+	if err := s.HomeworkService.RemoveHomeworkAttachment(r.Context(), id, hash); err != nil {
+		Error(w, r, err)
+		return
+	}
+	s.releaseHomeworkAttachment(r, hash)
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleHomeworkAttachmentDownload handles the "GET /homeworks/:id/attachments/:hash" route.
+func (s *Server) handleHomeworkAttachmentDownload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	hw, err := s.HomeworkService.FindHomeworkByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	hash := mux.Vars(r)["hash"]
+	for _, a := range hw.Attachments {
+		if a.Hash != hash || s.AttachmentUploader == nil {
+			continue
+		}
+
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = attachmentContentType(a.Extension)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		http.ServeFile(w, r, s.AttachmentUploader.FileService.GetPathByHash(r.Context(), a.Hash, a.Extension))
+		return
+	}
+	Error(w, r, api.Errorf(api.ENOTFOUND, "Attachment not found"))
+}
+
+// releaseHomeworkAttachment decrements the counter of a file no longer
+// attached to a homework. Errors are only logged.
+func (s *Server) releaseHomeworkAttachment(r *http.Request, hash string) {
+	if s.AttachmentUploader == nil {
+		return
+	}
+	if err := s.AttachmentUploader.DecrementAttachmentCounter(r.Context(), hash); err != nil {
+		LogError(r, err)
+	}
+}
+
+// attachmentContentType returns the MIME type of a file extension, falling
+// back to a generic binary type.
+func attachmentContentType(ext string) string {
+	if v := mime.TypeByExtension("." + ext); v != "" {
+		return v
+	}
+	return "application/octet-stream"
+}
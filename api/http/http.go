@@ -42,6 +42,8 @@ var codes = map[string]int{
 	api.ENOTFOUND:       http.StatusNotFound,
 	api.ENOTIMPLEMENTED: http.StatusNotImplemented,
 	api.EUNAUTHORIZED:   http.StatusUnauthorized,
+	api.EFORBIDDEN:      http.StatusForbidden,
+	api.ERATELIMIT:      http.StatusTooManyRequests,
 	api.EINTERNAL:       http.StatusInternalServerError,
 }
 
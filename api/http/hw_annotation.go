@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerHWAnnotationRoutes is a helper function for registering the routes
+// of annotations on homework submissions.
+func (s *Server) registerHWAnnotationRoutes(r *mux.Router) {
+	r.HandleFunc("/homeworks/submissions/{id}/annotations", s.handleHWAnnotationList).Methods("GET")
+	r.HandleFunc("/homeworks/submissions/{id}/annotations", s.handleHWAnnotationCreate).Methods("POST")
+
+	r.HandleFunc("/homeworks/annotations/{id}", s.handleHWAnnotationUpdate).Methods("PATCH")
+	r.HandleFunc("/homeworks/annotations/{id}", s.handleHWAnnotationDelete).Methods("DELETE")
+}
+
+// handleHWAnnotationList handles the "GET /homeworks/submissions/:id/annotations" route.
+func (s *Server) handleHWAnnotationList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	annotations, err := s.HWAnnotationService.FindHWAnnotations(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Annotations []*api.HWAnnotation `json:"Annotations"`
+		N           int                 `json:"N"`
+	}{
+		Annotations: annotations,
+		N:           len(annotations),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWAnnotationCreate handles the "POST /homeworks/submissions/:id/annotations" route.
+func (s *Server) handleHWAnnotationCreate(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	annotation := api.HWAnnotation{}
+	if err := json.NewDecoder(r.Body).Decode(&annotation); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	annotation.SubmissionID = id
+
+	if err := s.HWAnnotationService.CreateHWAnnotation(r.Context(), &annotation); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&annotation); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWAnnotationUpdate handles the "PATCH /homeworks/annotations/:id" route.
+func (s *Server) handleHWAnnotationUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	upd := api.HWAnnotationUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	annotation, err := s.HWAnnotationService.UpdateHWAnnotation(r.Context(), id, upd)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(annotation); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWAnnotationDelete handles the "DELETE /homeworks/annotations/:id" route.
+func (s *Server) handleHWAnnotationDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.HWAnnotationService.DeleteHWAnnotation(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
@@ -0,0 +1,260 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// MaxCommentUploadSize is the largest total size of the files attached to a comment.
+const MaxCommentUploadSize = 20 << 20
+
+// commentExtensions lists the file types that can be attached to comments.
+var commentExtensions = map[string]bool{
+	"pdf":  true,
+	"png":  true,
+	"jpg":  true,
+	"jpeg": true,
+	"doc":  true,
+	"docx": true,
+	"txt":  true,
+	"zip":  true,
+}
+
+// registerHWCommentRoutes is a helper function for registering the routes of
+// the feedback threads of homework submissions.
+func (s *Server) registerHWCommentRoutes(r *mux.Router) {
+	// Thread of a submission. Posting accepts JSON or, to attach files, a
+	// multipart form with "Body", "ParentID" and "Attachments" fields.
+	r.HandleFunc("/homeworks/submissions/{id}/comments", s.handleHWCommentList).Methods("GET")
+	r.HandleFunc("/homeworks/submissions/{id}/comments", s.handleHWCommentCreate).Methods("POST")
+
+	r.HandleFunc("/homeworks/comments/{id}", s.handleHWCommentUpdate).Methods("PATCH")
+	r.HandleFunc("/homeworks/comments/{id}", s.handleHWCommentDelete).Methods("DELETE")
+
+	r.HandleFunc("/homeworks/comments/{id}/attachments/{hash}", s.handleHWCommentAttachmentDownload).Methods("GET")
+}
+
+// handleHWCommentList handles the "GET /homeworks/submissions/:id/comments" route.
+// Viewing the thread marks it as read.
+func (s *Server) handleHWCommentList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	comments, err := s.HWCommentService.FindHWComments(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Comments []*api.HWComment `json:"Comments"`
+		N        int              `json:"N"`
+	}{
+		Comments: comments,
+		N:        len(comments),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWCommentCreate handles the "POST /homeworks/submissions/:id/comments" route.
+func (s *Server) handleHWCommentCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	comment := api.HWComment{}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+			return
+		}
+		comment.Attachments = nil
+	} else {
+		r.Body = http.MaxBytesReader(w, r.Body, MaxCommentUploadSize+1<<20)
+		if err := r.ParseMultipartForm(MaxCommentUploadSize); err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid form body"))
+			return
+		}
+
+		comment.Body = strings.TrimSpace(r.FormValue("Body"))
+		if v := r.FormValue("ParentID"); v != "" {
+			if comment.ParentID, err = strconv.Atoi(v); err != nil {
+				Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+				return
+			}
+		}
+
+		headers := r.MultipartForm.File["Attachments"]
+		if len(headers) > 0 && s.AttachmentUploader == nil {
+			Error(w, r, api.Errorf(api.EINVALID, "File uploads are not enabled"))
+			return
+		} else if len(headers) > api.MaxCommentAttachments {
+			Error(w, r, api.Errorf(api.EINVALID, "Too many attachments."))
+			return
+		}
+		for _, header := range headers {
+			if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), ".")); !commentExtensions[ext] {
+				Error(w, r, api.Errorf(api.EINVALID, "Unsupported file type"))
+				return
+			}
+		}
+
+		// Make sure the user takes part in the thread before storing any file.
+		if _, err := s.HWCommentService.FindHWComments(r.Context(), id); err != nil {
+			Error(w, r, err)
+			return
+		}
+
+		for _, header := range headers {
+			attachment, err := s.AttachmentUploader.UploadAttachment(r.Context(), header)
+			if err != nil {
+				s.releaseCommentAttachments(r, comment.Attachments)
+				Error(w, r, err)
+				return
+			}
+
+			if hasCommentAttachment(comment.Attachments, attachment.Hash) {
+				// The same file was attached twice so drop the extra reference.
+				s.releaseCommentAttachments(r, []*api.HWCommentAttachment{{Hash: attachment.Hash}})
+				continue
+			}
+
+			filename := filepath.Base(header.Filename)
+			if len(filename) > 255 {
+				filename = filename[:255]
+			}
+			comment.Attachments = append(comment.Attachments, &api.HWCommentAttachment{
+				Hash:      attachment.Hash,
+				Extension: attachment.Extension,
+				Filename:  filename,
+			})
+		}
+	}
+	comment.SubmissionID = id
+
+	if err := s.HWCommentService.CreateHWComment(r.Context(), &comment); err != nil {
+		s.releaseCommentAttachments(r, comment.Attachments)
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&comment); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWCommentUpdate handles the "PATCH /homeworks/comments/:id" route.
+func (s *Server) handleHWCommentUpdate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	upd := api.HWCommentUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	comment, err := s.HWCommentService.UpdateHWComment(r.Context(), id, upd)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(comment); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWCommentDelete handles the "DELETE /homeworks/comments/:id" route.
+func (s *Server) handleHWCommentDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	comment, err := s.HWCommentService.FindHWCommentByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	} else if err := s.HWCommentService.DeleteHWComment(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+	s.releaseCommentAttachments(r, comment.Attachments)
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleHWCommentAttachmentDownload handles the "GET /homeworks/comments/:id/attachments/:hash" route.
+func (s *Server) handleHWCommentAttachmentDownload(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	comment, err := s.HWCommentService.FindHWCommentByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	hash := mux.Vars(r)["hash"]
+	for _, a := range comment.Attachments {
+		if a.Hash != hash || s.AttachmentUploader == nil {
+			continue
+		}
+
+		w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(a.Filename))
+		http.ServeFile(w, r, s.AttachmentUploader.FileService.GetPathByHash(r.Context(), a.Hash, a.Extension))
+		return
+	}
+	Error(w, r, api.Errorf(api.ENOTFOUND, "Attachment not found"))
+}
+
+// releaseCommentAttachments decrements the counters of files no longer
+// attached to a comment. Errors are only logged.
+func (s *Server) releaseCommentAttachments(r *http.Request, attachments []*api.HWCommentAttachment) {
+	if s.AttachmentUploader == nil {
+		return
+	}
+	for _, a := range attachments {
+		if err := s.AttachmentUploader.DecrementAttachmentCounter(r.Context(), a.Hash); err != nil {
+			LogError(r, err)
+		}
+	}
+}
+
+// hasCommentAttachment returns true if a file is among the attachments.
+func hasCommentAttachment(attachments []*api.HWCommentAttachment, hash string) bool {
+	for _, a := range attachments {
+		if a.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
@@ -14,6 +14,14 @@ import (
 func (s *Server) registerHWSubmissionPrivateRoutes(r *mux.Router) {
 	// Listing of all homework submissions a teacher is an owner of.
 	r.HandleFunc("/homeworks/submissions", s.handleHWSubmissionList).Methods("GET")
+
+	// Versions of the response of a submission and the changes between two
+	// of them, given by the "from" and "to" query parameters.
+	r.HandleFunc("/homeworks/submissions/{id}/versions", s.handleHWSubmissionVersionList).Methods("GET")
+	r.HandleFunc("/homeworks/submissions/{id}/diff", s.handleHWSubmissionDiff).Methods("GET")
+
+	// Returning a submission to its student for revision.
+	r.HandleFunc("/homeworks/submissions/{id}/return", s.handleHWSubmissionReturn).Methods("POST")
 }
 
 // registerHWSubmissionPublicRoutes is a helper function for registering public homework submission routes.
@@ -26,12 +34,15 @@ func (s *Server) registerHWSubmissionPublicRoutes(r *mux.Router) {
 
 	r.HandleFunc("/homeworks/submissions/{id}", s.handleHWSubmissionUpdate).Methods("PATCH")
 
+	// Submitting a draft.
+	r.HandleFunc("/homeworks/submissions/{id}/submit", s.handleHWSubmissionSubmit).Methods("POST")
+
 	// Removing a homework.
 	r.HandleFunc("/homeworks/submissions/{id}", s.handleHWSubmissionDelete).Methods("DELETE")
 }
 
 // handleHWSubmissionList handles the "GET /homeworks/submissions" route. This route can optionally
-// accept filter arguments and outputs a list of all homeworks that the current
+// accept a "status" filter and outputs a list of all homeworks that the current
 // user is related to.
 func (s *Server) handleHWSubmissionList(w http.ResponseWriter, r *http.Request) {
 	user := api.UserFromContext(r.Context())
@@ -43,13 +54,28 @@ func (s *Server) handleHWSubmissionList(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	filter := api.HWSubmissionFilter{StudentID: &user.ID}
+	if v := r.URL.Query().Get("status"); v != "" {
+		if !api.IsHWStatus(v) {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid status"))
+			return
+		}
+		filter.Status = &v
+	}
+
 	// Fetch submissions from database.
-	subs, n, err := s.HWSubmissionService.FindHWSubmissions(r.Context(), api.HWSubmissionFilter{StudentID: &user.ID})
+	subs, n, err := s.HWSubmissionService.FindHWSubmissions(r.Context(), filter)
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
 
+	// Hide grades that have not been released yet.
+	if err := s.redactHWSubmissions(r, subs); err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	// Render output based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
 	if err := json.NewEncoder(w).Encode(struct {
@@ -64,6 +90,72 @@ func (s *Server) handleHWSubmissionList(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// handleHWSubmissionVersionList handles the "GET /homeworks/submissions/:id/versions" route.
+func (s *Server) handleHWSubmissionVersionList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	versions, err := s.HWSubmissionService.FindHWSubmissionVersions(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Versions []*api.HWSubmissionVersion `json:"Versions"`
+		N        int                        `json:"N"`
+	}{
+		Versions: versions,
+		N:        len(versions),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWSubmissionDiff handles the "GET /homeworks/submissions/:id/diff" route.
+func (s *Server) handleHWSubmissionDiff(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	from, err := strconv.Atoi(r.URL.Query().Get("from"))
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid version format"))
+		return
+	}
+	to, err := strconv.Atoi(r.URL.Query().Get("to"))
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid version format"))
+		return
+	}
+
+	lines, err := s.HWSubmissionService.DiffHWSubmissionVersions(r.Context(), id, from, to)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		From  int             `json:"From"`
+		To    int             `json:"To"`
+		Lines []*api.DiffLine `json:"Lines"`
+	}{
+		From:  from,
+		To:    to,
+		Lines: lines,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
 // handleHWSubmissionView handles the "GET /homeworks/submissions/:id" route.
 func (s *Server) handleHWSubmissionView(w http.ResponseWriter, r *http.Request) {
 	user := api.UserFromContext(r.Context())
@@ -88,6 +180,11 @@ func (s *Server) handleHWSubmissionView(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Hide grades that have not been released yet.
+	if (user == nil || !user.IsTeacher) && !sub.Homework.GradesReleased(time.Now()) {
+		sub.RedactGrade()
+	}
+
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
 	if err := json.NewEncoder(w).Encode(sub); err != nil {
@@ -135,11 +232,18 @@ func (s *Server) handleHWSubmissionCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	if time.Now().Before(hw.OpenedAt) {
+	// Students may have their own opening and closing times.
+	deadline, err := s.AccommodationService.FindDeadline(r.Context(), api.GradeItemHomework, hwID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if !deadline.Opened(time.Now()) {
 		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has not yet been started"))
 		return
-	} else if time.Now().After(hw.ClosedAt) {
-		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has not already been started"))
+	} else if deadline.Closed(time.Now()) {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has already been closed"))
 		return
 	}
 
@@ -193,6 +297,67 @@ func (s *Server) handleHWSubmissionUpdate(w http.ResponseWriter, r *http.Request
 	w.Write([]byte(`{}`))
 }
 
+// handleHWSubmissionSubmit handles the "POST /homeworks/submissions/:id/submit" route.
+func (s *Server) handleHWSubmissionSubmit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	sub, err := s.HWSubmissionService.SubmitHWSubmission(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Hide grades that have not been released yet.
+	if !sub.Homework.GradesReleased(time.Now()) {
+		sub.RedactGrade()
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleHWSubmissionReturn handles the "POST /homeworks/submissions/:id/return" route.
+func (s *Server) handleHWSubmissionReturn(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		Feedback string `json:"Feedback"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	sub, err := s.HWSubmissionService.ReturnHWSubmission(r.Context(), id, body.Feedback)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
 // handleHWSubmissionDelete handles the "DELETE /homeworks/submissions/:id" route. This route
 // permanently deletes the homework submission.
 func (s *Server) handleHWSubmissionDelete(w http.ResponseWriter, r *http.Request) {
@@ -203,11 +368,26 @@ func (s *Server) handleHWSubmissionDelete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Submissions of locked grades are only deleted with a reason, which may
+	// be given in the "OverrideReason" query parameter.
+	var reason *string
+	if v := r.URL.Query().Get("OverrideReason"); v != "" {
+		reason = &v
+	}
+
+	// Fetch the files of its comments before they are detached by the deletion.
+	commentAttachments, err := s.HWCommentService.FindHWCommentAttachments(r.Context(), api.HWCommentAttachmentFilter{SubmissionID: &id})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	// Delete the homework submission from the database.
-	if err := s.HWSubmissionService.DeleteHWSubmission(r.Context(), id); err != nil {
+	if err := s.HWSubmissionService.DeleteHWSubmission(r.Context(), id, reason); err != nil {
 		Error(w, r, err)
 		return
 	}
+	s.releaseCommentAttachments(r, commentAttachments)
 
 	// Response part
 	w.Header().Set("Content-type", "application/json")
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerIntegrityPrivateRoutes is a helper function for registering private integrity routes.
+func (s *Server) registerIntegrityPrivateRoutes(r *mux.Router) {
+	// Summary of suspicious attempts of a quiz.
+	r.HandleFunc("/quizzes/{id}/integrity", s.handleIntegritySummary).Methods("GET")
+
+	// Listing of the raw events of a quiz.
+	r.HandleFunc("/quizzes/{id}/integrity/events", s.handleIntegrityEventList).Methods("GET")
+}
+
+// registerIntegrityPublicRoutes is a helper function for registering public integrity routes.
+func (s *Server) registerIntegrityPublicRoutes(r *mux.Router) {
+	// Reporting a batch of events of a quiz attempt.
+	r.HandleFunc("/quizzes/{id}/integrity", s.handleIntegrityEventCreate).Methods("POST")
+}
+
+// handleIntegrityEventCreate handles the "POST /quizzes/:id/integrity" route.
+// The attempt is identified by the X-Attempt-Token header and batches are
+// rate limited per attempt.
+func (s *Server) handleIntegrityEventCreate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	access := api.QuizAccess{
+		QuizID:      id,
+		DeviceToken: r.Header.Get(AttemptTokenHeader),
+		IP:          s.clientIP(r).String(),
+	}
+	if access.DeviceToken == "" {
+		Error(w, r, api.Errorf(api.EFORBIDDEN, "Start the quiz before reporting events."))
+		return
+	} else if !s.integrityLimiter.Allow(access.DeviceToken, time.Now()) {
+		Error(w, r, api.Errorf(api.ERATELIMIT, "Too many events. Try again later."))
+		return
+	}
+
+	var body struct {
+		Events []*api.IntegrityEvent `json:"Events"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	if err := s.IntegrityService.CreateIntegrityEvents(r.Context(), &access, body.Events); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(struct {
+		N int `json:"N"`
+	}{
+		N: len(body.Events),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleIntegritySummary handles the "GET /quizzes/:id/integrity" route. It
+// accepts an optional "threshold" query parameter for the suspicious score.
+func (s *Server) handleIntegritySummary(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	threshold := api.DefaultIntegrityThreshold
+	if v := r.URL.Query().Get("threshold"); v != "" {
+		if threshold, err = strconv.Atoi(v); err != nil || threshold < 1 {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid threshold"))
+			return
+		}
+	}
+
+	summaries, err := s.IntegrityService.SummarizeIntegrity(r.Context(), id, threshold)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	var suspicious int
+	for _, v := range summaries {
+		if v.Suspicious {
+			suspicious++
+		}
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Attempts   []*api.IntegritySummary `json:"Attempts"`
+		Suspicious int                     `json:"Suspicious"`
+		Threshold  int                     `json:"Threshold"`
+	}{
+		Attempts:   summaries,
+		Suspicious: suspicious,
+		Threshold:  threshold,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleIntegrityEventList handles the "GET /quizzes/:id/integrity/events"
+// route. It accepts optional "attemptID" and "type" query parameters.
+func (s *Server) handleIntegrityEventList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	filter := api.IntegrityEventFilter{QuizID: id, Limit: 500}
+	if v := r.URL.Query().Get("attemptID"); v != "" {
+		attemptID, err := strconv.Atoi(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+			return
+		}
+		filter.AttemptID = &attemptID
+	}
+	if v := r.URL.Query().Get("type"); v != "" {
+		filter.Type = &v
+	}
+
+	events, n, err := s.IntegrityService.FindIntegrityEvents(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Events []*api.IntegrityEvent `json:"Events"`
+		N      int                   `json:"N"`
+	}{
+		Events: events,
+		N:      n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
@@ -0,0 +1,559 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+// Live client timing. Pings are sent more often than the read deadline so that
+// a healthy connection never times out.
+const (
+	liveWriteWait  = 10 * time.Second
+	livePongWait   = 60 * time.Second
+	livePingPeriod = (livePongWait * 9) / 10
+	liveMaxMessage = 4096
+)
+
+// Types of messages exchanged with live clients.
+const (
+	// Sent by clients.
+	liveJoin   = "join"
+	liveAnswer = "answer"
+	liveStart  = "start"
+	liveNext   = "next"
+	liveReveal = "reveal"
+	liveFinish = "finish"
+
+	// Sent by the server.
+	liveState  = "state"
+	liveJoined = "joined"
+	liveError  = "error"
+)
+
+var liveUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+
+	// Origins are not restricted, same as for the CORS configuration.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// liveMessage represents a message received from a live client.
+type liveMessage struct {
+	Type     string        `json:"Type"`
+	FullName string        `json:"FullName"`
+	Response *api.Response `json:"Response"`
+}
+
+// liveOutput represents a message sent to a live client.
+type liveOutput struct {
+	Type  string `json:"Type"`
+	Error string `json:"Error,omitempty"`
+
+	// Set on "joined" messages only.
+	Token string `json:"Token,omitempty"`
+
+	Participant *api.LiveParticipant `json:"Participant,omitempty"`
+	Session     *api.LiveSession     `json:"Session,omitempty"`
+	Question    *api.Question        `json:"Question,omitempty"`
+	Stats       *api.LiveStats       `json:"Stats,omitempty"`
+}
+
+// liveClient represents a single WebSocket connection to a live session.
+type liveClient struct {
+	conn *websocket.Conn
+	send chan []byte
+
+	sessionID int
+	teacher   bool
+
+	// Guards participant which is set by the reading goroutine and read
+	// whenever the session is refreshed.
+	mu          sync.Mutex
+	participant *api.LiveParticipant
+}
+
+// Participant returns the participant the client has joined as, if any.
+func (c *liveClient) Participant() *api.LiveParticipant {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.participant
+}
+
+// SetParticipant sets the participant the client has joined as.
+func (c *liveClient) SetParticipant(p *api.LiveParticipant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.participant = p
+}
+
+// liveHub keeps track of the clients connected to every live session running
+// on this instance.
+type liveHub struct {
+	mu    sync.Mutex
+	rooms map[int]map[*liveClient]struct{}
+}
+
+// newLiveHub returns a new instance of liveHub.
+func newLiveHub() *liveHub {
+	return &liveHub{rooms: make(map[int]map[*liveClient]struct{})}
+}
+
+// add registers a client in its session room.
+func (h *liveHub) add(c *liveClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	room := h.rooms[c.sessionID]
+	if room == nil {
+		room = make(map[*liveClient]struct{})
+		h.rooms[c.sessionID] = room
+	}
+	room[c] = struct{}{}
+}
+
+// remove unregisters a client and closes its send channel.
+func (h *liveHub) remove(c *liveClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.removeLocked(c)
+}
+
+// removeLocked unregisters a client. The hub lock must be held.
+func (h *liveHub) removeLocked(c *liveClient) {
+	if room := h.rooms[c.sessionID]; room != nil {
+		if _, ok := room[c]; ok {
+			delete(room, c)
+			close(c.send)
+		}
+		if len(room) == 0 {
+			delete(h.rooms, c.sessionID)
+		}
+	}
+}
+
+// send queues a message for a client. Clients that cannot keep up are
+// disconnected rather than blocking the whole session.
+func (h *liveHub) send(c *liveClient, buf []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.rooms[c.sessionID][c]; !ok {
+		return
+	}
+	select {
+	case c.send <- buf:
+	default:
+		h.removeLocked(c)
+	}
+}
+
+// clients returns a snapshot of the clients connected to a session.
+func (h *liveHub) clients(sessionID int) []*liveClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	a := make([]*liveClient, 0, len(h.rooms[sessionID]))
+	for c := range h.rooms[sessionID] {
+		a = append(a, c)
+	}
+	return a
+}
+
+// registerLivePrivateRoutes is a helper function for registering private live quiz routes.
+func (s *Server) registerLivePrivateRoutes(r *mux.Router) {
+	// Starting a live session for a quiz.
+	r.HandleFunc("/quizzes/{id}/live", s.handleLiveSessionCreate).Methods("POST")
+}
+
+// registerLivePublicRoutes is a helper function for registering public live quiz routes.
+func (s *Server) registerLivePublicRoutes(r *mux.Router) {
+	// WebSocket endpoint for teachers and students taking part in a live session.
+	r.HandleFunc("/live/{code}/ws", s.handleLiveSocket).Methods("GET")
+}
+
+// handleLiveSessionCreate handles the "POST /quizzes/:id/live" route. It opens a
+// new live session in the lobby state and returns its join code.
+func (s *Server) handleLiveSessionCreate(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user == nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You must be logged in"))
+		return
+	} else if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	ls := api.LiveSession{
+		Code:      api.RandDigitSeq(6),
+		TeacherID: user.ID,
+		QuizID:    id,
+	}
+
+	// Create live session in the database.
+	if err := s.LiveSessionService.CreateLiveSession(r.Context(), &ls); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(struct {
+		ID   int    `json:"ID"`
+		Code string `json:"Code"`
+	}{
+		ID:   ls.ID,
+		Code: ls.Code,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleLiveSocket handles the "GET /live/:code/ws" route. Browsers cannot set
+// headers on WebSocket requests, so the access token may be passed in the
+// "token" query parameter. A participant reconnects by passing the token they
+// received on join in the "participant" query parameter.
+func (s *Server) handleLiveSocket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	user := api.UserFromContext(ctx)
+	if v := r.URL.Query().Get("token"); user == nil && v != "" {
+		userID, err := s.AuthService.Validate(v)
+		if err != nil || userID == 0 {
+			Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Token corrupted"))
+			return
+		} else if user, err = s.UserService.FindUserByID(ctx, userID); err != nil {
+			Error(w, r, err)
+			return
+		}
+		ctx = api.NewContextWithUser(ctx, user)
+	}
+
+	ls, err := s.LiveSessionService.FindLiveSessionByCode(ctx, mux.Vars(r)["code"])
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	c := &liveClient{
+		send:      make(chan []byte, 16),
+		sessionID: ls.ID,
+		teacher:   user != nil && user.IsTeacher && user.ID == ls.TeacherID,
+	}
+
+	if v := r.URL.Query().Get("participant"); v != "" {
+		if c.participant, err = s.LiveSessionService.FindLiveParticipantByToken(ctx, v); err != nil {
+			Error(w, r, err)
+			return
+		} else if c.participant.SessionID != ls.ID {
+			Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not in this live session"))
+			return
+		}
+	}
+
+	if c.conn, err = liveUpgrader.Upgrade(w, r, nil); err != nil {
+		LogError(r, err)
+		return
+	}
+
+	s.live.add(c)
+	go s.writeLive(c)
+
+	// Send the current state right away so reconnecting clients catch up.
+	s.refreshLiveClient(ctx, c)
+
+	s.readLive(ctx, user, c)
+}
+
+// readLive processes the messages of a live client until its connection drops.
+func (s *Server) readLive(ctx context.Context, user *api.User, c *liveClient) {
+	defer func() {
+		s.live.remove(c)
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(liveMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(livePongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(livePongWait))
+	})
+
+	for {
+		var msg liveMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Printf("[live] read error: session=%d err=%s", c.sessionID, err)
+			}
+			return
+		}
+
+		if err := s.handleLiveMessage(ctx, user, c, msg); err != nil {
+			if api.ErrorCode(err) == api.EINTERNAL {
+				api.ReportError(ctx, err)
+				log.Printf("[live] error: session=%d err=%s", c.sessionID, err)
+			}
+			s.sendLive(c, &liveOutput{Type: liveError, Error: api.ErrorMessage(err)})
+		}
+	}
+}
+
+// writeLive writes queued messages and keepalive pings to a live client.
+func (s *Server) writeLive(c *liveClient) {
+	ticker := time.NewTicker(livePingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case buf, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			} else if err := c.conn.WriteMessage(websocket.TextMessage, buf); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleLiveMessage applies a single client message to the live session.
+func (s *Server) handleLiveMessage(ctx context.Context, user *api.User, c *liveClient, msg liveMessage) error {
+	switch msg.Type {
+	case liveJoin:
+		if c.teacher {
+			return api.Errorf(api.EINVALID, "Teachers cannot join their own session")
+		} else if p := c.Participant(); p != nil {
+			return s.sendLive(c, &liveOutput{Type: liveJoined, Participant: p})
+		}
+
+		p := api.LiveParticipant{
+			Token:           api.RandStringSeq(32),
+			StudentFullName: msg.FullName,
+			SessionID:       c.sessionID,
+		}
+		if user != nil {
+			if user.IsTeacher {
+				return api.Errorf(api.EUNAUTHORIZED, "You are not a student")
+			}
+			p.StudentID = user.ID
+			p.StudentFullName = user.FirstName + " " + user.LastName
+		}
+
+		if err := s.LiveSessionService.JoinLiveSession(ctx, &p); err != nil {
+			return err
+		}
+		c.SetParticipant(&p)
+
+		if err := s.sendLive(c, &liveOutput{Type: liveJoined, Token: p.Token, Participant: &p}); err != nil {
+			return err
+		}
+		return s.publishLive(ctx, c.sessionID)
+
+	case liveAnswer:
+		p := c.Participant()
+		if p == nil {
+			return api.Errorf(api.EUNAUTHORIZED, "Join the session to answer")
+		} else if msg.Response == nil {
+			return api.Errorf(api.EINVALID, "Response required")
+		}
+
+		if err := s.LiveSessionService.AnswerLiveQuestion(ctx, p.ID, msg.Response); err != nil {
+			return err
+		}
+		return s.publishLive(ctx, c.sessionID)
+
+	case liveStart, liveNext, liveReveal, liveFinish:
+		if !c.teacher {
+			return api.Errorf(api.EUNAUTHORIZED, "You are not the teacher of this session")
+		}
+
+		ls, err := s.LiveSessionService.FindLiveSessionByID(ctx, c.sessionID)
+		if err != nil {
+			return err
+		}
+
+		var upd api.LiveSessionUpdate
+		switch msg.Type {
+		case liveStart:
+			index, state := 0, api.LiveQuestion
+			upd.QuestionIndex, upd.State = &index, &state
+		case liveNext:
+			index, state := ls.QuestionIndex+1, api.LiveQuestion
+			upd.QuestionIndex, upd.State = &index, &state
+		case liveReveal:
+			state := api.LiveReveal
+			upd.State = &state
+		case liveFinish:
+			state := api.LiveFinished
+			upd.State = &state
+		}
+
+		if _, err := s.LiveSessionService.UpdateLiveSession(ctx, c.sessionID, upd); err != nil {
+			return err
+		}
+		return s.publishLive(ctx, c.sessionID)
+	}
+
+	return api.Errorf(api.EINVALID, "Unknown message type")
+}
+
+// publishLive announces a change of a live session. Without a broker only the
+// clients connected to this instance are refreshed.
+func (s *Server) publishLive(ctx context.Context, sessionID int) error {
+	if s.LiveBroker == nil {
+		s.refreshLive(ctx, sessionID)
+		return nil
+	}
+	return s.LiveBroker.Publish(ctx, api.LiveEvent{SessionID: sessionID})
+}
+
+// listenLive refreshes local clients whenever any instance publishes a live event.
+func (s *Server) listenLive(ctx context.Context) error {
+	ch, err := s.LiveBroker.Subscribe(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for e := range ch {
+			s.refreshLive(ctx, e.SessionID)
+		}
+	}()
+	return nil
+}
+
+// refreshLive sends the current state of a session to its local clients. The
+// state is loaded once and only redacted per client.
+func (s *Server) refreshLive(ctx context.Context, sessionID int) {
+	clients := s.live.clients(sessionID)
+	if len(clients) == 0 {
+		return
+	}
+
+	state, err := s.findLiveState(ctx, sessionID)
+	if err != nil {
+		log.Printf("[live] cannot load session: id=%d err=%s", sessionID, err)
+		return
+	}
+	for _, c := range clients {
+		s.sendLive(c, state.output(c))
+	}
+}
+
+// refreshLiveClient sends the current state of its session to a single client.
+func (s *Server) refreshLiveClient(ctx context.Context, c *liveClient) {
+	state, err := s.findLiveState(ctx, c.sessionID)
+	if err != nil {
+		log.Printf("[live] cannot load session: id=%d err=%s", c.sessionID, err)
+		return
+	}
+	s.sendLive(c, state.output(c))
+}
+
+// liveSnapshot is the state of a live session shared by all of its clients.
+type liveSnapshot struct {
+	session  *api.LiveSession
+	stats    *api.LiveStats
+	question *api.Question
+}
+
+// findLiveState loads the session, its stats and its current question.
+func (s *Server) findLiveState(ctx context.Context, sessionID int) (*liveSnapshot, error) {
+	ls, err := s.LiveSessionService.FindLiveSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find session: %w", err)
+	}
+	stats, err := s.LiveSessionService.FindLiveStats(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("find stats: %w", err)
+	}
+
+	state := &liveSnapshot{session: ls, stats: stats}
+	if ls.State != api.LiveWaiting {
+		questions, _, err := s.QuestionService.FindQuestions(ctx, api.QuestionFilter{QuizID: &ls.QuizID})
+		if err != nil {
+			return nil, fmt.Errorf("find questions: %w", err)
+		} else if ls.QuestionIndex < len(questions) {
+			state.question = questions[ls.QuestionIndex]
+		}
+	}
+	return state, nil
+}
+
+// output returns the state as a client sees it. Students never see the
+// answers or the results before the question is revealed.
+func (state *liveSnapshot) output(c *liveClient) *liveOutput {
+	ls, stats := state.session, state.stats
+	participant := c.Participant()
+	out := liveOutput{Type: liveState, Session: ls, Stats: stats, Question: state.question, Participant: participant}
+
+	if !c.teacher {
+		session := *ls
+		session.Quiz = &api.Quiz{ID: ls.Quiz.ID, Title: ls.Quiz.Title, MaxGrade: ls.Quiz.MaxGrade}
+		out.Session = &session
+
+		if ls.State != api.LiveReveal && ls.State != api.LiveFinished {
+			out.Stats = &api.LiveStats{Leaderboard: stats.Leaderboard, Answered: stats.Answered}
+			if out.Question != nil {
+				out.Question = stripAnswers(out.Question)
+			}
+		}
+
+		if participant != nil {
+			for _, p := range stats.Leaderboard {
+				if p.ID == participant.ID {
+					out.Participant = p
+				}
+			}
+		}
+	}
+	return &out
+}
+
+// sendLive queues a message for a live client.
+func (s *Server) sendLive(c *liveClient, out *liveOutput) error {
+	buf, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	s.live.send(c, buf)
+	return nil
+}
+
+// stripAnswers returns a copy of the question without its correct answers.
+func stripAnswers(q *api.Question) *api.Question {
+	other := *q
+	other.OpenAnswer = ""
+	other.TrueFalseAnswer = false
+	other.MultipleChoiceAnswer = nil
+	other.SingleChoiceAnswer = 0
+	return &other
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerNotificationRoutes is a helper function for registering all notification routes.
+func (s *Server) registerNotificationRoutes(r *mux.Router) {
+	// Listing of the notifications of the current user.
+	r.HandleFunc("/notifications", s.handleNotificationList).Methods("GET")
+
+	// Marking a single or all notifications as read.
+	r.HandleFunc("/notifications/read", s.handleNotificationsRead).Methods("POST")
+	r.HandleFunc("/notifications/{id}/read", s.handleNotificationRead).Methods("POST")
+}
+
+// handleNotificationList handles the "GET /notifications" route. Only unread
+// notifications are listed when the unread query parameter is true.
+func (s *Server) handleNotificationList(w http.ResponseWriter, r *http.Request) {
+	var filter api.NotificationFilter
+	if v := r.URL.Query().Get("unread"); v != "" {
+		unread, err := strconv.ParseBool(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid unread format"))
+			return
+		}
+		filter.Unread = &unread
+	}
+
+	notifications, n, err := s.NotificationService.FindNotifications(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Notifications []*api.Notification `json:"Notifications"`
+		N             int                 `json:"N"`
+	}{
+		Notifications: notifications,
+		N:             n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleNotificationsRead handles the "POST /notifications/read" route, which
+// marks every notification of the current user as read.
+func (s *Server) handleNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if err := s.NotificationService.MarkNotificationsRead(r.Context(), nil); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleNotificationRead handles the "POST /notifications/:id/read" route.
+func (s *Server) handleNotificationRead(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.NotificationService.MarkNotificationsRead(r.Context(), []int{id}); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
@@ -0,0 +1,306 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerOutcomeRoutes is a helper function for registering all outcome routes.
+func (s *Server) registerOutcomeRoutes(r *mux.Router) {
+	// Listing and creating outcomes of a group.
+	r.HandleFunc("/groups/{id}/outcomes", s.handleOutcomeList).Methods("GET")
+	r.HandleFunc("/groups/{id}/outcomes", s.handleOutcomeCreate).Methods("POST")
+
+	// Mastery report of a group, as JSON or as CSV with "?format=csv".
+	r.HandleFunc("/groups/{id}/mastery", s.handleMasteryReport).Methods("GET")
+
+	r.HandleFunc("/outcomes/{id}", s.handleOutcomeView).Methods("GET")
+	r.HandleFunc("/outcomes/{id}", s.handleOutcomeUpdate).Methods("PATCH")
+	r.HandleFunc("/outcomes/{id}", s.handleOutcomeDelete).Methods("DELETE")
+
+	// Tagging questions and homeworks with an outcome.
+	r.HandleFunc("/outcomes/{id}/tags", s.handleOutcomeTag).Methods("POST")
+	r.HandleFunc("/outcomes/{id}/questions/{questionID}", s.handleOutcomeUntag).Methods("DELETE")
+	r.HandleFunc("/outcomes/{id}/homeworks/{hwID}", s.handleOutcomeUntag).Methods("DELETE")
+}
+
+// handleOutcomeList handles the "GET /groups/:id/outcomes" route.
+func (s *Server) handleOutcomeList(w http.ResponseWriter, r *http.Request) {
+	// Parse group ID from path.
+	groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Fetch outcomes from database.
+	outcomes, n, err := s.OutcomeService.FindOutcomes(r.Context(), api.OutcomeFilter{GroupID: &groupID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Render output based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Outcomes []*api.Outcome `json:"Outcomes"`
+		N        int            `json:"N"`
+	}{
+		Outcomes: outcomes,
+		N:        n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleOutcomeView handles the "GET /outcomes/:id" route.
+func (s *Server) handleOutcomeView(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Fetch outcome from the database.
+	outcome, err := s.OutcomeService.FindOutcomeByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Format returned data based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(outcome); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleOutcomeCreate handles the "POST /groups/:id/outcomes" route.
+func (s *Server) handleOutcomeCreate(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Parse group ID from path.
+	groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Unmarshal data based on HTTP request's content type.
+	outcome := api.Outcome{}
+	if err := json.NewDecoder(r.Body).Decode(&outcome); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	outcome.GroupID = groupID
+
+	// Create outcome in the database.
+	if err := s.OutcomeService.CreateOutcome(r.Context(), &outcome); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&outcome); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleOutcomeUpdate handles the "PATCH /outcomes/:id" route.
+func (s *Server) handleOutcomeUpdate(w http.ResponseWriter, r *http.Request) {
+	// Parse outcome ID from the path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Parse fields into an update object.
+	upd := api.OutcomeUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Update the outcome in the database.
+	if _, err := s.OutcomeService.UpdateOutcome(r.Context(), id, upd); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleOutcomeDelete handles the "DELETE /outcomes/:id" route.
+func (s *Server) handleOutcomeDelete(w http.ResponseWriter, r *http.Request) {
+	// Parse outcome ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Delete the outcome from the database.
+	if err := s.OutcomeService.DeleteOutcome(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleOutcomeTag handles the "POST /outcomes/:id/tags" route. The body holds
+// either a QuestionID or a HomeworkID and an optional Weight.
+func (s *Server) handleOutcomeTag(w http.ResponseWriter, r *http.Request) {
+	// Parse outcome ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	tag := api.OutcomeTag{}
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	tag.OutcomeID = id
+
+	if err := s.OutcomeService.TagOutcome(r.Context(), &tag); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleOutcomeUntag handles the "DELETE /outcomes/:id/questions/:questionID"
+// and "DELETE /outcomes/:id/homeworks/:hwID" routes.
+func (s *Server) handleOutcomeUntag(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	tag := api.OutcomeTag{}
+	var err error
+	if tag.OutcomeID, err = strconv.Atoi(vars["id"]); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+	if v, ok := vars["questionID"]; ok {
+		tag.QuestionID, err = strconv.Atoi(v)
+	} else {
+		tag.HomeworkID, err = strconv.Atoi(vars["hwID"])
+	}
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.OutcomeService.UntagOutcome(r.Context(), &tag); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleMasteryReport handles the "GET /groups/:id/mastery" route. Teachers get
+// the whole class while students only get their own row.
+func (s *Server) handleMasteryReport(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+
+	// Parse group ID from path.
+	groupID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	filter := api.MasteryFilter{GroupID: groupID}
+	if !user.IsTeacher {
+		filter.StudentID = &user.ID
+	}
+
+	report, err := s.OutcomeService.FindMasteryReport(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mastery-%d.csv"`, groupID))
+		if err := writeMasteryCSV(w, report); err != nil {
+			LogError(r, err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// writeMasteryCSV writes the report as a table with a row per student, a
+// column per outcome and a final row of class averages. Mastery is written as
+// a percentage and left blank where there is no graded work.
+func writeMasteryCSV(w http.ResponseWriter, report *api.MasteryReport) error {
+	cw := csv.NewWriter(w)
+
+	header := []string{"Student ID", "First name", "Last name"}
+	for _, o := range report.Outcomes {
+		header = append(header, o.Code)
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for i, u := range report.Students {
+		record := []string{strconv.Itoa(u.ID), u.FirstName, u.LastName}
+		for _, v := range report.Cells[i] {
+			record = append(record, formatMastery(v))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	record := []string{"", "Class average", ""}
+	for _, v := range report.ClassAverages {
+		record = append(record, formatMastery(v))
+	}
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatMastery formats a mastery value as a percentage.
+func formatMastery(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v*100, 'f', 1, 64)
+}
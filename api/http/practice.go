@@ -0,0 +1,127 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerPracticeRoutes is a helper function for registering all practice routes.
+func (s *Server) registerPracticeRoutes(r *mux.Router) {
+	// Next question to practice from the bank of a group.
+	r.HandleFunc("/practice/next", s.handlePracticeNext).Methods("GET")
+
+	// Checking an answer to a practice question.
+	r.HandleFunc("/practice/answer", s.handlePracticeAnswer).Methods("POST")
+
+	// Practice history of the current student.
+	r.HandleFunc("/practice/history", s.handlePracticeHistory).Methods("GET")
+}
+
+// handlePracticeNext handles the "GET /practice/next?groupID=" route. The
+// question is returned without its answers.
+func (s *Server) handlePracticeNext(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a student"))
+		return
+	}
+
+	groupID, err := strconv.Atoi(r.URL.Query().Get("groupID"))
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	q, item, err := s.PracticeService.NextPracticeQuestion(r.Context(), groupID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Question *api.Question     `json:"Question"`
+		Item     *api.PracticeItem `json:"Item"`
+	}{
+		Question: stripAnswers(q),
+		Item:     item,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handlePracticeAnswer handles the "POST /practice/answer" route. The result
+// contains the question with its answers as feedback.
+func (s *Server) handlePracticeAnswer(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a student"))
+		return
+	}
+
+	var body struct {
+		GroupID  int          `json:"GroupID"`
+		Response api.Response `json:"Response"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	result, err := s.PracticeService.AnswerPracticeQuestion(r.Context(), body.GroupID, &body.Response)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handlePracticeHistory handles the "GET /practice/history" route. It accepts
+// optional "groupID" and "questionID" query parameters.
+func (s *Server) handlePracticeHistory(w http.ResponseWriter, r *http.Request) {
+	filter := api.PracticeAttemptFilter{Limit: 100}
+	if v := r.URL.Query().Get("groupID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+			return
+		}
+		filter.GroupID = &id
+	}
+	if v := r.URL.Query().Get("questionID"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+			return
+		}
+		filter.QuestionID = &id
+	}
+
+	attempts, n, err := s.PracticeService.FindPracticeAttempts(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Attempts []*api.PracticeAttempt `json:"Attempts"`
+		N        int                    `json:"N"`
+	}{
+		Attempts: attempts,
+		N:        n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
@@ -11,6 +11,13 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// teacherQuiz represents a quiz as its teachers see it, along with the access
+// code which is hidden from students.
+type teacherQuiz struct {
+	*api.Quiz
+	AccessCode string `json:"AccessCode,omitempty"`
+}
+
 // registerQuizPrivateRoutes is a helper function for registering private quiz routes.
 func (s *Server) registerQuizPrivateRoutes(r *mux.Router) {
 	// Listing of all quizzes a teacher is an owner of.
@@ -110,7 +117,7 @@ func (s *Server) handleQuizView(w http.ResponseWriter, r *http.Request) {
 
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
-	if err := json.NewEncoder(w).Encode(quiz); err != nil {
+	if err := json.NewEncoder(w).Encode(teacherQuiz{Quiz: quiz, AccessCode: quiz.AccessCode}); err != nil {
 		LogError(r, err)
 		return
 	}
@@ -149,7 +156,7 @@ func (s *Server) handleQuizTeacherView(w http.ResponseWriter, r *http.Request) {
 
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
-	if err := json.NewEncoder(w).Encode(quiz); err != nil {
+	if err := json.NewEncoder(w).Encode(teacherQuiz{Quiz: quiz, AccessCode: quiz.AccessCode}); err != nil {
 		LogError(r, err)
 		return
 	}
@@ -173,11 +180,30 @@ func (s *Server) handleQuizStudentView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	quiz.Questions, _, err = s.QuestionService.FindQuestions(r.Context(), api.QuestionFilter{QuizID: &quiz.ID})
+	// Students may have their own time limit, which is shown instead of the
+	// one of the quiz and also requires an attempt.
+	deadline, err := s.AccommodationService.FindDeadline(r.Context(), api.GradeItemQuiz, quiz.ID)
 	if err != nil {
 		Error(w, r, err)
 		return
 	}
+	quiz.TimeLimit = deadline.TimeLimit
+
+	// Questions of a restricted quiz are only shown once an attempt is started.
+	if !quiz.Restricted() || r.Header.Get(AttemptTokenHeader) != "" {
+		if quiz.Restricted() {
+			if _, err := s.checkQuizAttempt(r, quiz.ID); err != nil {
+				Error(w, r, err)
+				return
+			}
+		}
+
+		quiz.Questions, _, err = s.QuestionService.FindQuestions(r.Context(), api.QuestionFilter{QuizID: &quiz.ID})
+		if err != nil {
+			Error(w, r, err)
+			return
+		}
+	}
 
 	if quiz.GroupID != 0 {
 		if user == nil {
@@ -226,10 +252,12 @@ func (s *Server) handleQuizCreate(w http.ResponseWriter, r *http.Request) {
 
 	// Unmarshal quiz data first
 	quiz := api.Quiz{}
-	if err := json.NewDecoder(r.Body).Decode(&quiz); err != nil {
+	in := teacherQuiz{Quiz: &quiz}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
 		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
 		return
 	}
+	quiz.AccessCode = in.AccessCode
 
 	rand.Seed(time.Now().UnixNano())
 	quiz.StudentLink = api.RandStringSeq(11)
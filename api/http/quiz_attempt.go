@@ -0,0 +1,152 @@
+package http
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// AttemptTokenHeader is the header carrying the device token of a quiz attempt.
+const AttemptTokenHeader = "X-Attempt-Token"
+
+// registerQuizAttemptPrivateRoutes is a helper function for registering private quiz attempt routes.
+func (s *Server) registerQuizAttemptPrivateRoutes(r *mux.Router) {
+	// Access violations of a quiz.
+	r.HandleFunc("/quizzes/{id}/violations", s.handleQuizViolationList).Methods("GET")
+}
+
+// registerQuizAttemptPublicRoutes is a helper function for registering public quiz attempt routes.
+func (s *Server) registerQuizAttemptPublicRoutes(r *mux.Router) {
+	// Starting or resuming an attempt of a quiz.
+	r.HandleFunc("/quizzes/{id}/start", s.handleQuizAttemptStart).Methods("POST")
+}
+
+// handleQuizAttemptStart handles the "POST /quizzes/:id/start" route. The
+// returned attempt contains the device token which must be sent in the
+// X-Attempt-Token header of later requests.
+func (s *Server) handleQuizAttemptStart(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a student"))
+		return
+	}
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var access api.QuizAccess
+	if err := json.NewDecoder(r.Body).Decode(&access); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	access.QuizID = id
+	access.IP = s.clientIP(r).String()
+
+	attempt, err := s.QuizAttemptService.StartQuizAttempt(r.Context(), &access)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(attempt); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleQuizViolationList handles the "GET /quizzes/:id/violations" route. It
+// accepts an optional "studentID" query parameter.
+func (s *Server) handleQuizViolationList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	filter := api.QuizViolationFilter{QuizID: id, Limit: 100}
+	if v := r.URL.Query().Get("studentID"); v != "" {
+		studentID, err := strconv.Atoi(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+			return
+		}
+		filter.StudentID = &studentID
+	}
+
+	violations, n, err := s.QuizAttemptService.FindQuizViolations(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Violations []*api.QuizViolation `json:"Violations"`
+		N          int                  `json:"N"`
+	}{
+		Violations: violations,
+		N:          n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// checkQuizAttempt verifies the attempt token of a request for a restricted quiz.
+func (s *Server) checkQuizAttempt(r *http.Request, quizID int) (*api.QuizAttempt, error) {
+	return s.QuizAttemptService.CheckQuizAttempt(r.Context(), &api.QuizAccess{
+		QuizID:      quizID,
+		DeviceToken: r.Header.Get(AttemptTokenHeader),
+		IP:          s.clientIP(r).String(),
+	})
+}
+
+// clientIP returns the IP address of the client. X-Forwarded-For is only
+// honoured when the request comes from a trusted proxy, in which case the
+// rightmost address that is not a trusted proxy is used.
+func (s *Server) clientIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+
+	if !s.isTrustedProxy(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			break
+		}
+		ip = hop
+		if !s.isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+// isTrustedProxy returns true if ip belongs to one of the trusted proxy ranges.
+func (s *Server) isTrustedProxy(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range s.TrustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
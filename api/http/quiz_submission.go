@@ -50,6 +50,12 @@ func (s *Server) handleQuizSubmissionList(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Hide grades that have not been released yet.
+	if err := s.redactQuizSubmissions(r, subs); err != nil {
+		Error(w, r, err)
+		return
+	}
+
 	// Render output based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
 	if err := json.NewEncoder(w).Encode(struct {
@@ -88,6 +94,11 @@ func (s *Server) handleQuizSubmissionView(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Hide grades that have not been released yet.
+	if (user == nil || !user.IsTeacher) && !sub.Quiz.GradesReleased(time.Now()) {
+		sub.RedactGrade()
+	}
+
 	// Format returned data based on HTTP accept header.
 	w.Header().Set("Content-type", "application/json")
 	if err := json.NewEncoder(w).Encode(sub); err != nil {
@@ -135,16 +146,45 @@ func (s *Server) handleQuizSubmissionCreate(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	if time.Now().Before(quiz.OpenedAt) {
+	// Students may have their own opening and closing times and time limit.
+	deadline, err := s.AccommodationService.FindDeadline(r.Context(), api.GradeItemQuiz, quizID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if !deadline.Opened(time.Now()) {
 		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has not yet been started"))
 		return
-	} else if time.Now().After(quiz.ClosedAt) {
-		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has not already been started"))
+	} else if deadline.Closed(time.Now()) {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Assignment has already been closed"))
 		return
 	}
 
-	// Create submission in the database.
-	err = s.QuizSubmissionService.CreateQuizSubmission(r.Context(), &sub)
+	// Restricted quizzes can only be submitted from the device that started the
+	// attempt, as can quizzes the student has their own time limit for.
+	// Attempts of other quizzes are finished as well so that their integrity
+	// events are attached to the submission.
+	var attempt *api.QuizAttempt
+	if quiz.Restricted() || deadline.TimeLimit > 0 || r.Header.Get(AttemptTokenHeader) != "" {
+		if attempt, err = s.checkQuizAttempt(r, quizID); err != nil {
+			Error(w, r, err)
+			return
+		}
+
+		// Timed quizzes have to be submitted before the time runs out.
+		if endsAt := deadline.AttemptEndsAt(attempt.StartedAt); !endsAt.IsZero() && time.Now().After(endsAt) {
+			Error(w, r, api.Errorf(api.EFORBIDDEN, "Time limit has been exceeded"))
+			return
+		}
+	}
+
+	// Create submission in the database, finishing the attempt along with it.
+	if attempt != nil {
+		err = s.QuizAttemptService.FinishQuizAttempt(r.Context(), attempt.ID, &sub)
+	} else {
+		err = s.QuizSubmissionService.CreateQuizSubmission(r.Context(), &sub)
+	}
 	if err != nil {
 		Error(w, r, err)
 		return
@@ -162,18 +202,7 @@ func (s *Server) handleQuizSubmissionCreate(w http.ResponseWriter, r *http.Reque
 		// TODO: make it faster since there is a O(n^2) complexity
 		for _, q := range questions {
 			if q.ID == rn.QuestionID {
-				switch q.Type {
-				case api.Single:
-					if q.SingleChoiceAnswer == rn.SingleChoiceResponse {
-						rn.IsCorrect = true
-					}
-				case api.Multiple:
-					rn.IsCorrect = api.Equal(q.MultipleChoiceAnswer, rn.MultipleChoiceResponse)
-				case api.Truefalse:
-					if q.TrueFalseAnswer == rn.TrueFalseResponse {
-						rn.IsCorrect = true
-					}
-				}
+				rn.IsCorrect = q.Check(rn)
 			}
 		}
 
@@ -184,6 +213,11 @@ func (s *Server) handleQuizSubmissionCreate(w http.ResponseWriter, r *http.Reque
 		}
 	}
 
+	// Hide the checked answers until the grades are released.
+	if !quiz.GradesReleased(time.Now()) {
+		sub.RedactGrade()
+	}
+
 	// Response part
 	w.Header().Set("Content-type", "application/json")
 	w.WriteHeader(http.StatusCreated)
@@ -245,8 +279,15 @@ func (s *Server) handleQuizSubmissionDelete(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	// Submissions of locked grades are only deleted with a reason, which may
+	// be given in the "OverrideReason" query parameter.
+	var reason *string
+	if v := r.URL.Query().Get("OverrideReason"); v != "" {
+		reason = &v
+	}
+
 	// Delete the quiz submission from the database.
-	if err := s.QuizSubmissionService.DeleteQuizSubmission(r.Context(), id); err != nil {
+	if err := s.QuizSubmissionService.DeleteQuizSubmission(r.Context(), id, reason); err != nil {
 		Error(w, r, err)
 		return
 	}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerRubricRoutes is a helper function for registering rubric routes.
+func (s *Server) registerRubricRoutes(r *mux.Router) {
+	// Listing rubrics of the current user, optionally of a group with "?groupID=".
+	r.HandleFunc("/rubrics", s.handleRubricList).Methods("GET")
+	r.HandleFunc("/rubrics", s.handleRubricCreate).Methods("POST")
+
+	r.HandleFunc("/rubrics/{id}", s.handleRubricView).Methods("GET")
+	r.HandleFunc("/rubrics/{id}", s.handleRubricUpdate).Methods("PATCH")
+	r.HandleFunc("/rubrics/{id}", s.handleRubricDelete).Methods("DELETE")
+
+	// Copying a rubric to a group and, optionally, onto a homework.
+	r.HandleFunc("/rubrics/{id}/copy", s.handleRubricCopy).Methods("POST")
+
+	// Grading a homework submission with the rubric of its homework.
+	r.HandleFunc("/homeworks/submissions/{id}/rubric-scores", s.handleRubricGrade).Methods("PUT")
+
+	// Per-criterion statistics of the submissions to a homework.
+	r.HandleFunc("/homeworks/{id}/rubric-stats", s.handleRubricStats).Methods("GET")
+}
+
+// handleRubricList handles the "GET /rubrics" route.
+func (s *Server) handleRubricList(w http.ResponseWriter, r *http.Request) {
+	var filter api.RubricFilter
+	if v := r.URL.Query().Get("groupID"); v != "" {
+		groupID, err := strconv.Atoi(v)
+		if err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+			return
+		}
+		filter.GroupID = &groupID
+	}
+
+	// Fetch rubrics from database.
+	rubrics, n, err := s.RubricService.FindRubrics(r.Context(), filter)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Rubrics []*api.Rubric `json:"Rubrics"`
+		N       int           `json:"N"`
+	}{
+		Rubrics: rubrics,
+		N:       n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricView handles the "GET /rubrics/:id" route.
+func (s *Server) handleRubricView(w http.ResponseWriter, r *http.Request) {
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Fetch rubric from the database.
+	rubric, err := s.RubricService.FindRubricByID(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(rubric); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricCreate handles the "POST /rubrics" route.
+func (s *Server) handleRubricCreate(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Unmarshal data based on HTTP request's content type.
+	rubric := api.Rubric{}
+	if err := json.NewDecoder(r.Body).Decode(&rubric); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Create rubric in the database.
+	if err := s.RubricService.CreateRubric(r.Context(), &rubric); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(&rubric); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricUpdate handles the "PATCH /rubrics/:id" route.
+func (s *Server) handleRubricUpdate(w http.ResponseWriter, r *http.Request) {
+	// Parse rubric ID from the path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Parse fields into an update object.
+	upd := api.RubricUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Update the rubric in the database.
+	rubric, err := s.RubricService.UpdateRubric(r.Context(), id, upd)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(rubric); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricDelete handles the "DELETE /rubrics/:id" route.
+func (s *Server) handleRubricDelete(w http.ResponseWriter, r *http.Request) {
+	// Parse rubric ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Delete the rubric from the database.
+	if err := s.RubricService.DeleteRubric(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleRubricCopy handles the "POST /rubrics/:id/copy" route.
+func (s *Server) handleRubricCopy(w http.ResponseWriter, r *http.Request) {
+	// Parse rubric ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		GroupID    int `json:"GroupID"`
+		HomeworkID int `json:"HomeworkID"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	rubric, err := s.RubricService.CopyRubric(r.Context(), id, body.GroupID, body.HomeworkID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(rubric); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricGrade handles the "PUT /homeworks/submissions/:id/rubric-scores" route.
+func (s *Server) handleRubricGrade(w http.ResponseWriter, r *http.Request) {
+	// Parse submission ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var body struct {
+		Scores         []*api.RubricScore `json:"Scores"`
+		OverrideReason *string            `json:"OverrideReason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	sub, err := s.RubricService.GradeHWSubmission(r.Context(), id, body.Scores, body.OverrideReason)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(sub); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleRubricStats handles the "GET /homeworks/:id/rubric-stats" route.
+func (s *Server) handleRubricStats(w http.ResponseWriter, r *http.Request) {
+	// Parse homework ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	stats, err := s.RubricService.FindRubricStats(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Criteria []*api.RubricCriterionStats `json:"Criteria"`
+	}{
+		Criteria: stats,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
@@ -29,6 +29,20 @@ type Server struct {
 	server *http.Server
 	router *mux.Router
 
+	// Clients connected to live quiz sessions on this instance.
+	live *liveHub
+
+	// Limits integrity event batches per quiz attempt.
+	integrityLimiter *rateLimiter
+
+	// Limits failed PIN check-ins per student, or per IP address for
+	// anonymous users, and attendance.
+	pinLimiter *rateLimiter
+
+	// Cancels background listeners on close.
+	ctx    context.Context
+	cancel func()
+
 	// Bind address & domain for the server's listener.
 	// If domain is specified, server is run on TLS using acme/autocert.
 	Addr   string
@@ -46,6 +60,33 @@ type Server struct {
 	ResponseService       api.ResponseService
 	AttendanceService     api.AttendanceService
 	AttSubmissionService  api.AttSubmissionService
+	LiveSessionService    api.LiveSessionService
+	SurveyService         api.SurveyService
+	OutcomeService        api.OutcomeService
+	PracticeService       api.PracticeService
+	QuizAttemptService    api.QuizAttemptService
+	IntegrityService      api.IntegrityService
+	TimetableService      api.TimetableService
+
+	AttendanceReportService api.AttendanceReportService
+	GradebookService        api.GradebookService
+	GradingService          api.GradingService
+	NotificationService     api.NotificationService
+	AccommodationService    api.AccommodationService
+	RubricService           api.RubricService
+	HWCommentService        api.HWCommentService
+	HWAnnotationService     api.HWAnnotationService
+
+	// Stores uploaded files such as excuse documents. Uploads are disabled if nil.
+	AttachmentUploader *api.AttachmentUploader
+
+	// Distributes live quiz events between instances. If nil, events are
+	// only delivered to clients connected to this instance.
+	LiveBroker api.LiveBroker
+
+	// Address ranges of reverse proxies whose X-Forwarded-For header is
+	// trusted when determining the client IP address.
+	TrustedProxies []*net.IPNet
 }
 
 // NewServer returns a new instance of Server.
@@ -54,7 +95,12 @@ func NewServer() *Server {
 	s := &Server{
 		server: &http.Server{},
 		router: mux.NewRouter(),
+		live:   newLiveHub(),
+
+		integrityLimiter: newRateLimiter(0.5, 10),
+		pinLimiter:       newRateLimiter(1.0/60, 5),
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 
 	// Report panics to external service.
 	s.router.Use(reportPanic)
@@ -63,7 +109,7 @@ func NewServer() *Server {
 		// AllowedOrigins: []string{"https://foo.com"}, // Use this to allow specific origin hosts
 		AllowedOrigins:   []string{"*"}, // All origins
 		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", AttemptTokenHeader},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
@@ -97,6 +143,12 @@ func NewServer() *Server {
 		s.registerQuizSubmissionPublicRoutes(r)
 		s.registerAttendancePublicRoutes(r)
 		s.registerAttSubmissionPublicRoutes(r)
+		s.registerLivePublicRoutes(r)
+		s.registerSurveyPublicRoutes(r)
+		s.registerQuizAttemptPublicRoutes(r)
+		s.registerIntegrityPublicRoutes(r)
+		s.registerAttendanceQRPublicRoutes(r)
+		s.registerHomeworkAttachmentPublicRoutes(r)
 	}
 
 	// Register authenticated routes.
@@ -113,6 +165,25 @@ func NewServer() *Server {
 		s.registerResponseRoutes(r)
 		s.registerAttSubmissionPrivateRoutes(r)
 		s.registerAttendancePrivateRoutes(r)
+		s.registerLivePrivateRoutes(r)
+		s.registerSurveyPrivateRoutes(r)
+		s.registerOutcomeRoutes(r)
+		s.registerPracticeRoutes(r)
+		s.registerQuizAttemptPrivateRoutes(r)
+		s.registerIntegrityPrivateRoutes(r)
+		s.registerAttendanceQRPrivateRoutes(r)
+		s.registerTimetableRoutes(r)
+		s.registerAttendanceRosterRoutes(r)
+		s.registerAttendanceReportRoutes(r)
+		s.registerGradebookRoutes(r)
+		s.registerGradingRoutes(r)
+		s.registerGradeReleaseRoutes(r)
+		s.registerNotificationRoutes(r)
+		s.registerAccommodationRoutes(r)
+		s.registerRubricRoutes(r)
+		s.registerHWCommentRoutes(r)
+		s.registerHWAnnotationRoutes(r)
+		s.registerHomeworkAttachmentPrivateRoutes(r)
 	}
 
 	// Serve static files
@@ -172,11 +243,20 @@ func (s *Server) Open() (err error) {
 	// as trying to use an already open port) synchronously.
 	go s.server.Serve(s.ln)
 
+	// Receive live quiz events published by other instances.
+	if s.LiveBroker != nil {
+		if err := s.listenLive(s.ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Close gracefully shuts down the server.
 func (s *Server) Close() error {
+	s.cancel()
+
 	ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
 	defer cancel()
 	return s.server.Shutdown(ctx)
@@ -0,0 +1,299 @@
+package http
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerSurveyPrivateRoutes is a helper function for registering private survey routes.
+func (s *Server) registerSurveyPrivateRoutes(r *mux.Router) {
+	// Listing of all surveys a teacher is an owner of.
+	r.HandleFunc("/surveys", s.handleSurveyList).Methods("GET")
+
+	// Aggregated results of a survey.
+	r.HandleFunc("/surveys/{id}/results", s.handleSurveyResults).Methods("GET")
+}
+
+// registerSurveyPublicRoutes is a helper function for registering public survey routes.
+func (s *Server) registerSurveyPublicRoutes(r *mux.Router) {
+	// API endpoint for creating surveys.
+	r.HandleFunc("/surveys", s.handleSurveyCreate).Methods("POST")
+
+	// View a single survey.
+	r.HandleFunc("/surveys/shared/{link}/teacher", s.handleSurveyTeacherView).Methods("GET")
+	r.HandleFunc("/surveys/shared/{link}/student", s.handleSurveyStudentView).Methods("GET")
+
+	// Responding to a survey.
+	r.HandleFunc("/surveys/{id}/responses", s.handleSurveyRespond).Methods("POST")
+
+	r.HandleFunc("/surveys/{id}", s.handleSurveyUpdate).Methods("PATCH")
+
+	// Removing a survey.
+	r.HandleFunc("/surveys/{id}", s.handleSurveyDelete).Methods("DELETE")
+}
+
+// handleSurveyList handles the "GET /surveys" route. It outputs a list of all
+// surveys that the current teacher owns.
+func (s *Server) handleSurveyList(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user == nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You must be logged in"))
+		return
+	} else if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Fetch surveys from database.
+	surveys, n, err := s.SurveyService.FindSurveys(r.Context(), api.SurveyFilter{TeacherID: &user.ID})
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Render output based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Surveys []*api.Survey `json:"Surveys"`
+		N       int           `json:"N"`
+	}{
+		Surveys: surveys,
+		N:       n,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleSurveyResults handles the "GET /surveys/:id/results" route.
+func (s *Server) handleSurveyResults(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user == nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You must be logged in"))
+		return
+	} else if !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Parse ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Aggregate the responses in the database.
+	results, err := s.SurveyService.FindSurveyResults(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Format returned data based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleSurveyTeacherView handles the "GET /surveys/shared/:link/teacher" route.
+// The survey is returned together with its aggregated results.
+func (s *Server) handleSurveyTeacherView(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Parse teacher link from path.
+	link := mux.Vars(r)["link"]
+
+	// Fetch survey from the database.
+	survey, err := s.SurveyService.FindSurveyByTeacherLink(r.Context(), link)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	results, err := s.SurveyService.FindSurveyResults(r.Context(), survey.ID)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Format returned data based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Survey  *api.Survey        `json:"Survey"`
+		Results *api.SurveyResults `json:"Results"`
+	}{
+		Survey:  survey,
+		Results: results,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleSurveyStudentView handles the "GET /surveys/shared/:link/student" route.
+func (s *Server) handleSurveyStudentView(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+
+	// Parse student link from path.
+	link := mux.Vars(r)["link"]
+
+	// Fetch survey from the database.
+	survey, err := s.SurveyService.FindSurveyByStudentLink(r.Context(), link)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	if survey.Mode == api.Registered && user == nil {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "Log in to be able to respond"))
+		return
+	}
+
+	// The teacher link gives access to the results so it is never shown to students.
+	survey.TeacherLink = ""
+
+	// Format returned data based on HTTP accept header.
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(survey); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleSurveyCreate handles the "POST /surveys" route.
+func (s *Server) handleSurveyCreate(w http.ResponseWriter, r *http.Request) {
+	user := api.UserFromContext(r.Context())
+	if user != nil && !user.IsTeacher {
+		Error(w, r, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher"))
+		return
+	}
+
+	// Unmarshal data based on HTTP request's content type.
+	survey := api.Survey{}
+	if err := json.NewDecoder(r.Body).Decode(&survey); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	survey.StudentLink = api.RandStringSeq(11)
+	survey.TeacherLink = api.RandStringSeq(11)
+
+	survey.TeacherID = 0
+	if user != nil {
+		survey.TeacherID = user.ID
+	}
+
+	// Create survey in the database.
+	err := s.SurveyService.CreateSurvey(r.Context(), &survey)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Write new survey content to response based on accept header.
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(struct {
+		StudentLink string `json:"StudentLink"`
+		TeacherLink string `json:"TeacherLink"`
+	}{
+		StudentLink: survey.StudentLink,
+		TeacherLink: survey.TeacherLink,
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleSurveyRespond handles the "POST /surveys/:id/responses" route. The
+// response is stored without any reference to the current user.
+func (s *Server) handleSurveyRespond(w http.ResponseWriter, r *http.Request) {
+	// Parse survey ID from the path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Unmarshal response data.
+	rs := api.SurveyResponse{}
+	if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	rs.SurveyID = id
+
+	// Store response in the database.
+	if err := s.SurveyService.SubmitSurveyResponse(r.Context(), &rs); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Response part
+	w.Header().Set("Content-type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{}`))
+}
+
+// handleSurveyUpdate handles the "PATCH /surveys/:id" route. This route
+// reads in the updated fields and issues an update in the database.
+func (s *Server) handleSurveyUpdate(w http.ResponseWriter, r *http.Request) {
+	// Parse survey ID from the path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Parse fields into an update object.
+	upd := api.SurveyUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+
+	// Update the survey in the database.
+	_, err = s.SurveyService.UpdateSurvey(r.Context(), id, upd)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleSurveyDelete handles the "DELETE /surveys/:id" route. This route
+// permanently deletes the survey along with its responses.
+func (s *Server) handleSurveyDelete(w http.ResponseWriter, r *http.Request) {
+	// Parse survey ID from path.
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	// Delete the survey from the database.
+	if err := s.SurveyService.DeleteSurvey(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	// Response part
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
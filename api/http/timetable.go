@@ -0,0 +1,125 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/gorilla/mux"
+)
+
+// registerTimetableRoutes is a helper function for registering all timetable routes.
+func (s *Server) registerTimetableRoutes(r *mux.Router) {
+	// Weekly timetable of a group.
+	r.HandleFunc("/groups/{id}/timetable", s.handleTimetableView).Methods("GET")
+	r.HandleFunc("/groups/{id}/timetable", s.handleTimetableSet).Methods("PUT")
+	r.HandleFunc("/groups/{id}/timetable", s.handleTimetableDelete).Methods("DELETE")
+
+	// Attendances created from the timetable. A single session is edited or
+	// cancelled through the "PATCH /attendances/:id" route.
+	r.HandleFunc("/groups/{id}/sessions", s.handleSessionList).Methods("GET")
+}
+
+// handleTimetableView handles the "GET /groups/:id/timetable" route.
+func (s *Server) handleTimetableView(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	timetable, err := s.TimetableService.FindTimetable(r.Context(), id)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(timetable); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleTimetableSet handles the "PUT /groups/:id/timetable" route. The body
+// replaces the whole timetable including its slots.
+func (s *Server) handleTimetableSet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	var timetable api.Timetable
+	if err := json.NewDecoder(r.Body).Decode(&timetable); err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid JSON body"))
+		return
+	}
+	timetable.GroupID = id
+
+	if err := s.TimetableService.SetTimetable(r.Context(), &timetable); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(&timetable); err != nil {
+		LogError(r, err)
+		return
+	}
+}
+
+// handleTimetableDelete handles the "DELETE /groups/:id/timetable" route.
+func (s *Server) handleTimetableDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	if err := s.TimetableService.DeleteTimetable(r.Context(), id); err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// handleSessionList handles the "GET /groups/:id/sessions" route. It accepts
+// optional "from" and "to" dates in the 2006-01-02 format.
+func (s *Server) handleSessionList(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		Error(w, r, api.Errorf(api.EINVALID, "Invalid ID format"))
+		return
+	}
+
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	for _, v := range []string{from, to} {
+		if _, err := time.Parse(api.DateLayout, v); v != "" && err != nil {
+			Error(w, r, api.Errorf(api.EINVALID, "Invalid date format"))
+			return
+		}
+	}
+
+	sessions, err := s.TimetableService.FindSessions(r.Context(), id, from, to)
+	if err != nil {
+		Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-type", "application/json")
+	if err := json.NewEncoder(w).Encode(struct {
+		Sessions []*api.Attendance `json:"Sessions"`
+		N        int               `json:"N"`
+	}{
+		Sessions: sessions,
+		N:        len(sessions),
+	}); err != nil {
+		LogError(r, err)
+		return
+	}
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// MaxAnnotationLen is the longest comment of an annotation.
+const MaxAnnotationLen = 5000
+
+// HWAnnotation represents a teacher's note on a span of the response of a
+// homework submission. The span is given by character offsets into the
+// response along with the quoted text, which is used to find the span again
+// if the response changes.
+type HWAnnotation struct {
+	ID int `json:"ID"`
+
+	// Offsets of the first and past the last annotated character.
+	Start int    `json:"Start"`
+	End   int    `json:"End"`
+	Quote string `json:"Quote"`
+
+	Comment   string    `json:"Comment"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	// Whether the quote could not be found in the current response.
+	Orphaned bool `json:"Orphaned,omitempty"`
+
+	TeacherID    int `json:"TeacherID"`
+	SubmissionID int `json:"SubmissionID"`
+}
+
+// Validate returns an error if the annotation contains invalid fields.
+// This only performs basic validation.
+func (a *HWAnnotation) Validate() error {
+	if a.Start < 0 || a.End <= a.Start {
+		return Errorf(EINVALID, "Invalid annotation span.")
+	} else if a.Comment == "" {
+		return Errorf(EINVALID, "Comment required.")
+	} else if len(a.Comment) > MaxAnnotationLen {
+		return Errorf(EINVALID, "Comment too long.")
+	}
+	return nil
+}
+
+// SetQuote sets the quote of the annotation to the span of the response.
+// Returns EINVALID if the span is outside the response.
+func (a *HWAnnotation) SetQuote(response string) error {
+	text := []rune(response)
+	if a.End > len(text) {
+		return Errorf(EINVALID, "Annotation span is outside the response.")
+	}
+	a.Quote = string(text[a.Start:a.End])
+	return nil
+}
+
+// Anchor moves the annotation to its quote in the response. If the span no
+// longer holds the quote, the occurrence closest to it is used, and the
+// annotation is orphaned if there is none.
+func (a *HWAnnotation) Anchor(response string) {
+	text, quote := []rune(response), []rune(a.Quote)
+	if a.End <= len(text) && string(text[a.Start:a.End]) == a.Quote {
+		a.Orphaned = false
+		return
+	}
+
+	best := -1
+	for i := 0; i+len(quote) <= len(text); i++ {
+		if string(text[i:i+len(quote)]) != a.Quote {
+			continue
+		} else if best < 0 || offsetDistance(i, a.Start) < offsetDistance(best, a.Start) {
+			best = i
+		}
+	}
+	if best < 0 || len(quote) == 0 {
+		a.Orphaned = true
+		return
+	}
+	a.Start, a.End, a.Orphaned = best, best+len(quote), false
+}
+
+// offsetDistance returns the absolute difference of two offsets.
+func offsetDistance(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// HWAnnotationService represents a service for managing annotations of
+// homework submissions.
+type HWAnnotationService interface {
+	// Retrieves the annotations of a submission in the order of their spans.
+	// Students only see them once the grades of the homework are released.
+	FindHWAnnotations(ctx context.Context, submissionID int) ([]*HWAnnotation, error)
+
+	// Creates a new annotation. The quote is taken from the response.
+	// Returns EUNAUTHORIZED if the current user does not teach the homework.
+	CreateHWAnnotation(ctx context.Context, annotation *HWAnnotation) error
+
+	// Updates an annotation. Returns EUNAUTHORIZED if the current user is
+	// not its author.
+	UpdateHWAnnotation(ctx context.Context, id int, upd HWAnnotationUpdate) (*HWAnnotation, error)
+
+	// Permanently deletes an annotation. Returns EUNAUTHORIZED if the current
+	// user is not its author.
+	DeleteHWAnnotation(ctx context.Context, id int) error
+}
+
+// HWAnnotationUpdate represents a set of fields to be updated via UpdateHWAnnotation().
+type HWAnnotationUpdate struct {
+	// Moves the annotation to another span of the current response.
+	Start *int `json:"Start"`
+	End   *int `json:"End"`
+
+	Comment *string `json:"Comment"`
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Limits of feedback comments.
+const (
+	MaxCommentLen         = 20000
+	MaxCommentAttachments = 5
+)
+
+// HWComment represents a message in the feedback thread of a homework
+// submission. Threads are visible to the submitting student and the teachers
+// of the homework's group.
+type HWComment struct {
+	ID int `json:"ID"`
+
+	// Markdown text of the comment.
+	Body      string    `json:"Body"`
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	// Comment this one replies to, if any.
+	ParentID int `json:"ParentID,omitempty"`
+
+	Attachments []*HWCommentAttachment `json:"Attachments,omitempty"`
+
+	// Previous versions of the body, oldest first.
+	Edits []*HWCommentEdit `json:"Edits,omitempty"`
+
+	AuthorID int   `json:"AuthorID"`
+	Author   *User `json:"Author,omitempty"`
+
+	SubmissionID int `json:"SubmissionID"`
+}
+
+// Validate returns an error if the comment contains invalid fields.
+// This only performs basic validation.
+func (c *HWComment) Validate() error {
+	if c.Body == "" && len(c.Attachments) == 0 {
+		return Errorf(EINVALID, "Body required.")
+	} else if len(c.Body) > MaxCommentLen {
+		return Errorf(EINVALID, "Body too long.")
+	} else if len(c.Attachments) > MaxCommentAttachments {
+		return Errorf(EINVALID, "Too many attachments.")
+	} else if c.SubmissionID == 0 {
+		return Errorf(EINVALID, "Submission required.")
+	}
+	return nil
+}
+
+// HWCommentAttachment represents a file attached to a comment.
+type HWCommentAttachment struct {
+	Hash      string `json:"Hash"`
+	Extension string `json:"Extension"`
+	Filename  string `json:"Filename"`
+}
+
+// HWCommentEdit represents the body of a comment before it was edited.
+type HWCommentEdit struct {
+	Body     string    `json:"Body"`
+	EditedAt time.Time `json:"EditedAt"`
+}
+
+// HWCommentService represents a service for managing the feedback threads of
+// homework submissions.
+type HWCommentService interface {
+	// Retrieves a comment by ID. Returns ENOTFOUND if comment does not exist.
+	FindHWCommentByID(ctx context.Context, id int) (*HWComment, error)
+
+	// Retrieves the thread of a submission, oldest first, and marks it as
+	// read by the current user.
+	FindHWComments(ctx context.Context, submissionID int) ([]*HWComment, error)
+
+	// Creates a new comment by the current user.
+	CreateHWComment(ctx context.Context, comment *HWComment) error
+
+	// Updates the body of a comment, keeping the previous one in its edit
+	// history. Returns EUNAUTHORIZED if the current user is not the author.
+	UpdateHWComment(ctx context.Context, id int, upd HWCommentUpdate) (*HWComment, error)
+
+	// Permanently deletes a comment. Replies to it are kept. Returns
+	// EUNAUTHORIZED if the current user is not the author.
+	DeleteHWComment(ctx context.Context, id int) error
+
+	// Retrieves the files attached to the comments of a submission, or of
+	// every submission of a homework, so that they can be released before it
+	// is deleted. Returns EUNAUTHORIZED if the current user is not allowed to
+	// delete it.
+	FindHWCommentAttachments(ctx context.Context, filter HWCommentAttachmentFilter) ([]*HWCommentAttachment, error)
+}
+
+// HWCommentAttachmentFilter represents a filter passed to FindHWCommentAttachments().
+type HWCommentAttachmentFilter struct {
+	SubmissionID *int `json:"SubmissionID"`
+	HomeworkID   *int `json:"HomeworkID"`
+}
+
+// HWCommentUpdate represents a set of fields to be updated via UpdateHWComment().
+type HWCommentUpdate struct {
+	Body *string `json:"Body"`
+}
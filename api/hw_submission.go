@@ -5,16 +5,78 @@ import (
 	"time"
 )
 
+// Statuses of a hw submission. A draft becomes submitted once the student
+// submits it. Teachers grade submitted work or return it for revision, after
+// which the student resubmits it.
+const (
+	HWDraft       = "draft"
+	HWSubmitted   = "submitted"
+	HWReturned    = "returned"
+	HWResubmitted = "resubmitted"
+	HWGraded      = "graded"
+)
+
+// hwTransitions lists the statuses a hw submission may move to from each status.
+var hwTransitions = map[string][]string{
+	HWDraft:       {HWSubmitted},
+	HWSubmitted:   {HWSubmitted, HWReturned, HWGraded},
+	HWReturned:    {HWResubmitted, HWGraded},
+	HWResubmitted: {HWResubmitted, HWReturned, HWGraded},
+	HWGraded:      {HWGraded, HWReturned, HWResubmitted},
+}
+
+// IsHWStatus returns true if s is a status of hw submissions.
+func IsHWStatus(s string) bool {
+	_, ok := hwTransitions[s]
+	return ok
+}
+
+// CanTransitionHW returns true if a hw submission may move from one status to another.
+func CanTransitionHW(from, to string) bool {
+	for _, s := range hwTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
 // HWSubmission represents a hw submission in the system.
 type HWSubmission struct {
 	ID int `json:"ID"`
 
 	Response    string    `json:"Response"`
+	Status      string    `json:"Status"`
 	Grade       float32   `json:"Grade,omitempty"`
+	Graded      bool      `json:"Graded"` // whether there is a grade, which may be zero
 	Comments    string    `json:"Comments,omitempty"`
 	SubmittedAt time.Time `json:"SubmittedAt"`
 	UpdatedAt   time.Time `json:"UpdatedAt"`
 
+	// Whether the response came in after the due date and grace period, by
+	// how many seconds past the due date, and the grade before the late
+	// penalty.
+	Late        bool    `json:"Late"`
+	LateSeconds int     `json:"LateSeconds,omitempty"`
+	RawGrade    float32 `json:"RawGrade,omitempty"`
+
+	// Number of the current version of the response and of the version the
+	// grade was given for.
+	Version       int `json:"Version"`
+	GradedVersion int `json:"GradedVersion,omitempty"`
+
+	// Changes of the status, oldest first.
+	Transitions []*HWSubmissionTransition `json:"Transitions,omitempty"`
+
+	// Levels selected per criterion when graded with a rubric.
+	RubricScores []*RubricScore `json:"RubricScores,omitempty"`
+
+	// Teacher's notes on spans of the response.
+	Annotations []*HWAnnotation `json:"Annotations,omitempty"`
+
+	// Number of comments in the feedback thread the current user has not read.
+	UnreadComments int `json:"UnreadComments"`
+
 	StudentFullName string `json:"StudentFullName,omitempty"`
 	StudentID       int    `json:"StudentID,omitempty"`
 	Student         *User  `json:"Student,omitempty"`
@@ -28,10 +90,49 @@ type HWSubmission struct {
 func (u *HWSubmission) Validate() error {
 	if u.Response == "" {
 		return Errorf(EINVALID, "Response required.")
+	} else if !IsHWStatus(u.Status) {
+		return Errorf(EINVALID, "Status is incorrect.")
 	}
 	return nil
 }
 
+// RedactGrade clears the grade, comments, rubric scores and annotations,
+// which students may not see before the grades of the homework are released,
+// and shows graded work as submitted.
+func (u *HWSubmission) RedactGrade() {
+	u.Grade, u.RawGrade, u.Comments, u.Graded = 0, 0, "", false
+	u.RubricScores, u.Annotations = nil, nil
+	u.GradedVersion, u.Transitions = 0, nil
+	if u.Status == HWGraded {
+		u.Status = HWSubmitted
+	}
+}
+
+// HWSubmissionVersion represents a response as it was submitted. Versions
+// are numbered from one and never change.
+type HWSubmissionVersion struct {
+	ID int `json:"ID"`
+
+	Number      int       `json:"Number"`
+	Response    string    `json:"Response"`
+	SubmittedAt time.Time `json:"SubmittedAt"`
+
+	Late        bool `json:"Late"`
+	LateSeconds int  `json:"LateSeconds,omitempty"`
+
+	SubmissionID int `json:"SubmissionID"`
+}
+
+// HWSubmissionTransition represents a change of the status of a hw submission.
+type HWSubmissionTransition struct {
+	From string    `json:"From,omitempty"`
+	To   string    `json:"To"`
+	At   time.Time `json:"At"`
+
+	// User who made the change, if logged in.
+	UserID int `json:"UserID,omitempty"`
+}
+
 // HWSubmissionService represents a service for managing hw submissions.
 type HWSubmissionService interface {
 	// Retrieves a hw submission by ID.
@@ -49,10 +150,26 @@ type HWSubmissionService interface {
 	// the hw submission that is being updated. Returns ENOTFOUND if hw submission does not exist.
 	UpdateHWSubmission(ctx context.Context, id int, upd HWSubmissionUpdate) (*HWSubmission, error)
 
+	// Submits a draft. Returns EFORBIDDEN if the homework has closed.
+	SubmitHWSubmission(ctx context.Context, id int) (*HWSubmission, error)
+
+	// Returns a hw submission to its student for revision, adding the
+	// feedback to its comments. The student may resubmit it even after the
+	// homework closes. Returns EUNAUTHORIZED if the current user does not
+	// teach the homework.
+	ReturnHWSubmission(ctx context.Context, id int, feedback string) (*HWSubmission, error)
+
+	// Retrieves the versions of the response of a hw submission, oldest first.
+	FindHWSubmissionVersions(ctx context.Context, id int) ([]*HWSubmissionVersion, error)
+
+	// Compares two versions of the response of a hw submission line by line.
+	DiffHWSubmissionVersions(ctx context.Context, id, from, to int) ([]*DiffLine, error)
+
 	// Permanently deletes a hw submission and all owned dials. Returns EUNAUTHORIZED
 	// if current hw submission is not the hw submission being deleted. Returns ENOTFOUND if
-	// hw submission does not exist.
-	DeleteHWSubmission(ctx context.Context, id int) error
+	// hw submission does not exist. Submissions of a homework whose grades are
+	// locked are only deleted with an override reason, which is recorded.
+	DeleteHWSubmission(ctx context.Context, id int, overrideReason *string) error
 }
 
 // HWSubmissionFilter represents a filter passed to FindHWSubmissions().
@@ -62,6 +179,8 @@ type HWSubmissionFilter struct {
 	BeforeSubmittedAt *time.Time `json:"SubmittedAt"`
 	AfterUpdatedAt    *time.Time `json:"UpdatedAt"`
 
+	Status *string `json:"Status"`
+
 	StudentFullName *string `json:"StudentFullName" db:"student_fullname"`
 	StudentID       *int    `json:"StudentID"`
 	HomeworkID      *int    `json:"HomeworkID"`
@@ -77,6 +196,12 @@ type HWSubmissionUpdate struct {
 	Grade    *float32 `json:"Grade"`
 	Comments *string  `json:"Comments"`
 
+	// Version the grade is given for, the current one if not set.
+	Version *int `json:"Version"`
+
+	// Required to change the grade when the grades of the homework are locked.
+	OverrideReason *string `json:"OverrideReason"`
+
 	StudentFullName *string `json:"StudentFullName"`
 	StudentID       *int    `json:"StudentID"`
 }
@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Types of integrity events reported by the client during a quiz attempt.
+const (
+	IntegrityFocusLost      = "focus_lost"
+	IntegrityCopy           = "copy"
+	IntegrityPaste          = "paste"
+	IntegrityFullscreenExit = "fullscreen_exit"
+	IntegrityReconnect      = "reconnect"
+)
+
+// IntegrityWeights holds how much each event type counts towards the score of
+// an attempt. Reconnects are logged for context but are not suspicious alone.
+var IntegrityWeights = map[string]int{
+	IntegrityFocusLost:      1,
+	IntegrityCopy:           2,
+	IntegrityPaste:          3,
+	IntegrityFullscreenExit: 2,
+	IntegrityReconnect:      0,
+}
+
+// MaxIntegrityBatch is the largest number of events accepted in one batch.
+const MaxIntegrityBatch = 50
+
+// DefaultIntegrityThreshold is the score from which an attempt is suspicious.
+const DefaultIntegrityThreshold = 5
+
+// IntegrityEvent represents an event reported by the client which may
+// indicate cheating during a quiz attempt.
+type IntegrityEvent struct {
+	ID int `json:"ID"`
+
+	Type   string `json:"Type"`
+	Detail string `json:"Detail"`
+
+	// Time reported by the client and time the server received the event.
+	// Only the latter can be trusted.
+	ClientAt  time.Time `json:"ClientAt"`
+	CreatedAt time.Time `json:"CreatedAt"`
+
+	AttemptID    int `json:"AttemptID"`
+	SubmissionID int `json:"SubmissionID,omitempty"`
+	QuizID       int `json:"QuizID"`
+}
+
+// Validate returns an error if the event contains invalid fields.
+// This only performs basic validation.
+func (e *IntegrityEvent) Validate() error {
+	if _, ok := IntegrityWeights[e.Type]; !ok {
+		return Errorf(EINVALID, "Event type is incorrect.")
+	} else if len(e.Detail) > 255 {
+		return Errorf(EINVALID, "Event detail is too long.")
+	}
+	return nil
+}
+
+// IntegritySummary represents the events of a single quiz attempt.
+type IntegritySummary struct {
+	AttemptID    int   `json:"AttemptID"`
+	SubmissionID int   `json:"SubmissionID,omitempty"`
+	StudentID    int   `json:"StudentID,omitempty"`
+	Student      *User `json:"Student,omitempty"`
+
+	Counts     map[string]int `json:"Counts"`
+	Score      int            `json:"Score"`
+	Suspicious bool           `json:"Suspicious"`
+
+	FirstAt time.Time `json:"FirstAt"`
+	LastAt  time.Time `json:"LastAt"`
+}
+
+// Rate computes the score of the attempt from its event counts and marks it
+// suspicious if the score reaches the threshold.
+func (s *IntegritySummary) Rate(threshold int) {
+	s.Score = 0
+	for typ, n := range s.Counts {
+		s.Score += IntegrityWeights[typ] * n
+	}
+	s.Suspicious = s.Score >= threshold
+}
+
+// IntegrityService represents a service for logging integrity events of quiz attempts.
+type IntegrityService interface {
+	// Stores a batch of events against the attempt identified by the device
+	// token. Events are timestamped by the server. Returns EFORBIDDEN if the
+	// token does not belong to an attempt of the current user.
+	CreateIntegrityEvents(ctx context.Context, access *QuizAccess, events []*IntegrityEvent) error
+
+	// Retrieves a list of events of a quiz. Returns EUNAUTHORIZED if current
+	// user is not the owner of the quiz.
+	FindIntegrityEvents(ctx context.Context, filter IntegrityEventFilter) ([]*IntegrityEvent, int, error)
+
+	// Summarizes the events of every attempt of a quiz, most suspicious first.
+	// Returns EUNAUTHORIZED if current user is not the owner of the quiz.
+	SummarizeIntegrity(ctx context.Context, quizID int, threshold int) ([]*IntegritySummary, error)
+}
+
+// IntegrityEventFilter represents a filter passed to FindIntegrityEvents().
+type IntegrityEventFilter struct {
+	// Filtering fields.
+	QuizID    int     `json:"QuizID"`
+	AttemptID *int    `json:"AttemptID"`
+	Type      *string `json:"Type"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
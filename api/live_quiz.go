@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Live session states. A session starts in the lobby, then alternates between
+// showing a question and revealing its answer until the teacher finishes it.
+const (
+	LiveWaiting  = "waiting"
+	LiveQuestion = "question"
+	LiveReveal   = "reveal"
+	LiveFinished = "finished"
+)
+
+// LiveSession represents an in-class run of a quiz that the teacher drives
+// question by question.
+type LiveSession struct {
+	ID int `json:"ID"`
+
+	Code              string    `json:"Code"`
+	State             string    `json:"State"`
+	QuestionIndex     int       `json:"QuestionIndex"`
+	QuestionStartedAt time.Time `json:"QuestionStartedAt"`
+	CreatedAt         time.Time `json:"CreatedAt"`
+	UpdatedAt         time.Time `json:"UpdatedAt"`
+
+	TeacherID int `json:"TeacherID"`
+
+	QuizID int   `json:"QuizID"`
+	Quiz   *Quiz `json:"Quiz,omitempty"`
+}
+
+// Validate returns an error if the live session contains invalid fields.
+// This only performs basic validation.
+func (u *LiveSession) Validate() error {
+	if u.Code == "" {
+		return Errorf(EINVALID, "Code required.")
+	} else if u.QuizID == 0 {
+		return Errorf(EINVALID, "Quiz required.")
+	}
+	switch u.State {
+	case LiveWaiting, LiveQuestion, LiveReveal, LiveFinished:
+	default:
+		return Errorf(EINVALID, "State is incorrect.")
+	}
+	return nil
+}
+
+// LiveParticipant represents a student who joined a live session. Every
+// participant owns an ordinary quiz submission which collects their answers.
+type LiveParticipant struct {
+	ID int `json:"ID"`
+
+	// Token is a secret handed to the participant on join so that they can
+	// reconnect to the session without joining it twice.
+	Token    string    `json:"-"`
+	Score    float32   `json:"Score"`
+	JoinedAt time.Time `json:"JoinedAt"`
+
+	StudentFullName string `json:"StudentFullName"`
+	StudentID       int    `json:"StudentID,omitempty"`
+
+	SessionID    int `json:"SessionID"`
+	SubmissionID int `json:"SubmissionID"`
+}
+
+// LiveStats represents the live leaderboard and the answer distribution for
+// the current question of a session.
+type LiveStats struct {
+	Leaderboard []*LiveParticipant `json:"Leaderboard"`
+
+	// Number of participants who answered the current question.
+	Answered int `json:"Answered"`
+
+	// Distribution holds the number of answers per choice for choice
+	// questions and the number of false/true answers for true/false questions.
+	Distribution []int `json:"Distribution"`
+}
+
+// LiveSessionService represents a service for managing live quiz sessions.
+type LiveSessionService interface {
+	// Retrieves a live session by ID.
+	// Returns ENOTFOUND if live session does not exist.
+	FindLiveSessionByID(ctx context.Context, id int) (*LiveSession, error)
+
+	// Retrieves an unfinished live session by its join code.
+	// Returns ENOTFOUND if live session does not exist.
+	FindLiveSessionByCode(ctx context.Context, code string) (*LiveSession, error)
+
+	// Creates a new live session.
+	CreateLiveSession(ctx context.Context, session *LiveSession) error
+
+	// Updates a live session object. Returns EUNAUTHORIZED if current user is not
+	// the teacher running the session. Returns ENOTFOUND if live session does not exist.
+	UpdateLiveSession(ctx context.Context, id int, upd LiveSessionUpdate) (*LiveSession, error)
+
+	// Adds a participant to a live session and creates their quiz submission.
+	JoinLiveSession(ctx context.Context, participant *LiveParticipant) error
+
+	// Retrieves a participant by their reconnect token.
+	// Returns ENOTFOUND if participant does not exist.
+	FindLiveParticipantByToken(ctx context.Context, token string) (*LiveParticipant, error)
+
+	// Grades and stores the participant's answer to the current question.
+	// Returns ECONFLICT if the question has already been answered.
+	AnswerLiveQuestion(ctx context.Context, participantID int, response *Response) error
+
+	// Computes the leaderboard and the answer distribution of a session.
+	FindLiveStats(ctx context.Context, id int) (*LiveStats, error)
+}
+
+// LiveSessionUpdate represents a set of fields to be updated via UpdateLiveSession().
+type LiveSessionUpdate struct {
+	State         *string `json:"State"`
+	QuestionIndex *int    `json:"QuestionIndex"`
+}
+
+// LiveEvent is broadcast whenever the state of a live session changes so that
+// every running instance can refresh the clients connected to it.
+type LiveEvent struct {
+	SessionID int `json:"SessionID"`
+}
+
+// LiveBroker represents a service for distributing live events between instances.
+type LiveBroker interface {
+	// Publishes an event to all subscribers, including the current instance.
+	Publish(ctx context.Context, event LiveEvent) error
+
+	// Returns a channel of published events which is closed once ctx is done.
+	Subscribe(ctx context.Context) (<-chan LiveEvent, error)
+}
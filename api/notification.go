@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Kinds of notifications.
+const (
+	NotificationGradesReleased = "grades_released"
+)
+
+// Notification represents a message shown to a user in the app, such as the
+// release of the grades of a homework.
+type Notification struct {
+	ID int `json:"ID"`
+
+	Kind    string `json:"Kind"`
+	Message string `json:"Message"`
+
+	// Homework or quiz the notification is about.
+	ItemKind string `json:"ItemKind,omitempty"`
+	ItemID   int    `json:"ItemID,omitempty"`
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	ReadAt    time.Time `json:"ReadAt"` // zero while unread
+
+	UserID int `json:"UserID"`
+}
+
+// NotificationService represents a service for managing the notifications of
+// the current user.
+type NotificationService interface {
+	// Retrieves the notifications of the current user by filter, newest
+	// first. Also returns the total count of matching notifications.
+	FindNotifications(ctx context.Context, filter NotificationFilter) ([]*Notification, int, error)
+
+	// Marks notifications of the current user as read, or all of them if ids
+	// is empty.
+	MarkNotificationsRead(ctx context.Context, ids []int) error
+}
+
+// NotificationFilter represents a filter passed to FindNotifications().
+type NotificationFilter struct {
+	// Filtering fields.
+	Unread *bool `json:"Unread"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Outcome represents a learning outcome of a group that questions and
+// homeworks can be tagged with.
+type Outcome struct {
+	ID int `json:"ID"`
+
+	Code        string    `json:"Code"`
+	Title       string    `json:"Title"`
+	Description string    `json:"Description"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+
+	GroupID int `json:"GroupID"`
+
+	Tags []*OutcomeTag `json:"Tags,omitempty"`
+}
+
+// Validate returns an error if the outcome contains invalid fields.
+// This only performs basic validation.
+func (u *Outcome) Validate() error {
+	if u.Code == "" {
+		return Errorf(EINVALID, "Code required.")
+	} else if u.Title == "" {
+		return Errorf(EINVALID, "Title required.")
+	} else if u.GroupID == 0 {
+		return Errorf(EINVALID, "Group required.")
+	}
+	return nil
+}
+
+// OutcomeTag links a question or a homework to an outcome. The weight sets how
+// much the work counts towards the outcome compared to other tagged work.
+type OutcomeTag struct {
+	OutcomeID int `json:"OutcomeID"`
+
+	// Exactly one of these is set.
+	QuestionID int `json:"QuestionID,omitempty"`
+	HomeworkID int `json:"HomeworkID,omitempty"`
+
+	Weight float32 `json:"Weight"`
+}
+
+// Validate returns an error if the tag contains invalid fields.
+func (u *OutcomeTag) Validate() error {
+	if u.OutcomeID == 0 {
+		return Errorf(EINVALID, "Outcome required.")
+	} else if (u.QuestionID == 0) == (u.HomeworkID == 0) {
+		return Errorf(EINVALID, "Either question or homework required.")
+	} else if u.Weight <= 0 {
+		return Errorf(EINVALID, "Weight must be positive.")
+	}
+	return nil
+}
+
+// MasteryReport represents the attainment of every student of a group per outcome.
+//
+// Mastery is the weighted mean of the normalized grades (0 to 1) of all graded
+// responses and homework submissions tagged with the outcome.
+type MasteryReport struct {
+	GroupID int `json:"GroupID"`
+
+	Outcomes []*Outcome `json:"Outcomes"`
+	Students []*User    `json:"Students"`
+
+	// Cells[i][j] is the mastery of Students[i] on Outcomes[j], or nil if none
+	// of the tagged work of the student has been graded yet.
+	Cells [][]*float64 `json:"Cells"`
+
+	// ClassAverages[j] is the mean mastery on Outcomes[j] of the students who
+	// have any graded work for it.
+	ClassAverages []*float64 `json:"ClassAverages"`
+}
+
+// OutcomeService represents a service for managing learning outcomes.
+type OutcomeService interface {
+	// Retrieves an outcome by ID along with its tags.
+	// Returns ENOTFOUND if outcome does not exist.
+	FindOutcomeByID(ctx context.Context, id int) (*Outcome, error)
+
+	// Retrieves a list of outcomes by filter. Also returns total count of matching
+	// outcomes which may differ from returned results if filter.Limit is specified.
+	FindOutcomes(ctx context.Context, filter OutcomeFilter) ([]*Outcome, int, error)
+
+	// Creates a new outcome. Returns EUNAUTHORIZED if current user does not
+	// teach in the group.
+	CreateOutcome(ctx context.Context, outcome *Outcome) error
+
+	// Updates an outcome object. Returns EUNAUTHORIZED if current user does not
+	// teach in the group. Returns ENOTFOUND if outcome does not exist.
+	UpdateOutcome(ctx context.Context, id int, upd OutcomeUpdate) (*Outcome, error)
+
+	// Permanently deletes an outcome along with its tags. Returns EUNAUTHORIZED
+	// if current user does not teach in the group.
+	DeleteOutcome(ctx context.Context, id int) error
+
+	// Tags a question or homework with an outcome, replacing the weight if
+	// it is already tagged. The work must belong to the group of the outcome.
+	TagOutcome(ctx context.Context, tag *OutcomeTag) error
+
+	// Removes a tag from a question or homework.
+	UntagOutcome(ctx context.Context, tag *OutcomeTag) error
+
+	// Computes the mastery of the students of a group.
+	FindMasteryReport(ctx context.Context, filter MasteryFilter) (*MasteryReport, error)
+}
+
+// OutcomeFilter represents a filter passed to FindOutcomes().
+type OutcomeFilter struct {
+	// Filtering fields.
+	ID      *int    `json:"ID"`
+	Code    *string `json:"Code"`
+	GroupID *int    `json:"GroupID"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
+
+// OutcomeUpdate represents a set of fields to be updated via UpdateOutcome().
+type OutcomeUpdate struct {
+	Code        *string `json:"Code"`
+	Title       *string `json:"Title"`
+	Description *string `json:"Description"`
+}
+
+// MasteryFilter represents a filter passed to FindMasteryReport().
+type MasteryFilter struct {
+	GroupID int `json:"GroupID"`
+
+	// Restricts the report to a single student.
+	StudentID *int `json:"StudentID"`
+}
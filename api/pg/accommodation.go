@@ -0,0 +1,473 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.AccommodationService = (*AccommodationService)(nil)
+
+// AccommodationService represents a service for managing per-student
+// deadlines and standing accommodations.
+type AccommodationService struct {
+	db *DB
+}
+
+// NewAccommodationService returns a new instance of AccommodationService.
+func NewAccommodationService(db *DB) *AccommodationService {
+	return &AccommodationService{db: db}
+}
+
+// FindDeadlineOverrides retrieves the overrides of a homework, quiz or attendance.
+func (s *AccommodationService) FindDeadlineOverrides(ctx context.Context, kind string, itemID int) ([]*api.DeadlineOverride, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if table, err := deadlineItemTable(kind); err != nil {
+		return nil, err
+	} else if _, err := checkGradeItemTeacher(ctx, tx, table, itemID); err != nil {
+		return nil, err
+	}
+	return findDeadlineOverrides(ctx, tx, kind, itemID, 0)
+}
+
+// SetDeadlineOverride creates the override of a student or replaces the existing one.
+func (s *AccommodationService) SetDeadlineOverride(ctx context.Context, o *api.DeadlineOverride) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setDeadlineOverride(ctx, tx, o); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteDeadlineOverride removes the override of a student.
+func (s *AccommodationService) DeleteDeadlineOverride(ctx context.Context, kind string, itemID, studentID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteDeadlineOverride(ctx, tx, kind, itemID, studentID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindAccommodations retrieves the students of a group with accommodations.
+func (s *AccommodationService) FindAccommodations(ctx context.Context, groupID int) ([]*api.Accommodation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return nil, err
+	}
+	return findAccommodations(ctx, tx, groupID)
+}
+
+// SetAccommodation sets the accommodations of a student of a group.
+func (s *AccommodationService) SetAccommodation(ctx context.Context, a *api.Accommodation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, a.GroupID, true); err != nil {
+		return err
+	} else if err := setAccommodation(ctx, tx, a); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteAccommodation removes the accommodations of a student of a group.
+func (s *AccommodationService) DeleteAccommodation(ctx context.Context, groupID, studentID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return err
+	} else if err := setAccommodation(ctx, tx, &api.Accommodation{
+		GroupID:        groupID,
+		StudentID:      studentID,
+		TimeMultiplier: 1,
+	}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindDeadline retrieves the deadline of a homework, quiz or attendance for
+// the current user.
+func (s *AccommodationService) FindDeadline(ctx context.Context, kind string, itemID int) (*api.Deadline, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findDeadline(ctx, tx, kind, itemID, api.UserIDFromContext(ctx))
+}
+
+// deadlineItemTable returns the table of a kind of item with a deadline.
+func deadlineItemTable(kind string) (string, error) {
+	if kind == api.GradeItemAttendance {
+		return "attendances", nil
+	} else if kind == api.GradeItemHomework || kind == api.GradeItemQuiz {
+		return gradeItemTable(kind)
+	}
+	return "", api.Errorf(api.EINVALID, "Item kind is incorrect.")
+}
+
+// findDeadline returns the deadline of an item for a student, who may be zero
+// for anonymous users.
+func findDeadline(ctx context.Context, tx *Tx, kind string, itemID, studentID int) (*api.Deadline, error) {
+	var d *api.Deadline
+	var groupID int
+	switch kind {
+	case api.GradeItemHomework:
+		hw, err := findHomeworkByID(ctx, tx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		d, groupID = hw.Deadline(), hw.GroupID
+	case api.GradeItemQuiz:
+		qz, err := findQuizByID(ctx, tx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		d, groupID = qz.Deadline(), qz.GroupID
+	case api.GradeItemAttendance:
+		att, err := findAttendanceByID(ctx, tx, itemID)
+		if err != nil {
+			return nil, err
+		}
+		d, groupID = att.Deadline(), att.GroupID
+	default:
+		return nil, api.Errorf(api.EINVALID, "Item kind is incorrect.")
+	}
+
+	if err := applyDeadline(ctx, tx, d, groupID, studentID); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// applyDeadline changes the deadline of an item of a group by the override
+// and the accommodations of a student. Anonymous users get the item's times.
+func applyDeadline(ctx context.Context, tx *Tx, d *api.Deadline, groupID, studentID int) error {
+	d.StudentID = studentID
+	if studentID == 0 {
+		return nil
+	}
+
+	var o *api.DeadlineOverride
+	if overrides, err := findDeadlineOverrides(ctx, tx, d.ItemKind, d.ItemID, studentID); err != nil {
+		return err
+	} else if len(overrides) > 0 {
+		o = overrides[0]
+	}
+
+	var a *api.Accommodation
+	if groupID != 0 {
+		var err error
+		if a, err = findAccommodation(ctx, tx, groupID, studentID); err != nil && api.ErrorCode(err) != api.ENOTFOUND {
+			return err
+		}
+	}
+
+	d.Apply(o, a)
+	return nil
+}
+
+// findDeadlineOverrides returns the overrides of an item, limited to a single
+// student unless studentID is zero.
+func findDeadlineOverrides(ctx context.Context, tx *Tx, kind string, itemID, studentID int) ([]*api.DeadlineOverride, error) {
+	where, args := `o.item_kind = $1 AND o.item_id = $2`, []interface{}{kind, itemID}
+	if studentID != 0 {
+		where, args = where+` AND o.student_id = $3`, append(args, studentID)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			o.id,
+			o.item_kind,
+			o.item_id,
+			o.opened_at,
+			o.due_at,
+			o.closed_at,
+			o.time_limit,
+			o.reason,
+			o.created_at,
+			o.updated_at,
+			o.student_id,
+			u.first_name,
+			u.last_name,
+			u.email
+		FROM deadline_overrides o
+		JOIN users u ON u.id = o.student_id
+		WHERE `+where+`
+		ORDER BY u.last_name, u.first_name, o.id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]*api.DeadlineOverride, 0)
+	for rows.Next() {
+		var openedAt, dueAt, closedAt, updatedAt sql.NullTime
+
+		var o api.DeadlineOverride
+		o.Student = &api.User{}
+		if err := rows.Scan(
+			&o.ID,
+			&o.ItemKind,
+			&o.ItemID,
+			&openedAt,
+			&dueAt,
+			&closedAt,
+			&o.TimeLimit,
+			&o.Reason,
+			&o.CreatedAt,
+			&updatedAt,
+			&o.StudentID,
+			&o.Student.FirstName,
+			&o.Student.LastName,
+			&o.Student.Email,
+		); err != nil {
+			return nil, err
+		}
+		o.OpenedAt, o.DueAt, o.ClosedAt = openedAt.Time, dueAt.Time, closedAt.Time
+		o.UpdatedAt = updatedAt.Time
+		o.Student.ID = o.StudentID
+
+		overrides = append(overrides, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// setDeadlineOverride inserts or replaces the override of a student. The
+// student has to be in the group of the item if it has one.
+func setDeadlineOverride(ctx context.Context, tx *Tx, o *api.DeadlineOverride) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	table, err := deadlineItemTable(o.ItemKind)
+	if err != nil {
+		return err
+	} else if _, err := checkGradeItemTeacher(ctx, tx, table, o.ItemID); err != nil {
+		return err
+	}
+
+	var groupID sql.NullInt32
+	if err := tx.QueryRowContext(ctx, `SELECT group_id FROM `+table+` WHERE id = $1`, o.ItemID).Scan(&groupID); err != nil {
+		return FormatError(err)
+	} else if groupID.Valid {
+		if ok, err := isGroupStudent(ctx, tx, int(groupID.Int32), o.StudentID); err != nil {
+			return err
+		} else if !ok {
+			return api.Errorf(api.EINVALID, "Student is not in the group.")
+		}
+	}
+
+	// These fields are nullable so ensure we store blank fields as NULLs.
+	var openedAt, dueAt, closedAt *time.Time
+	if !o.OpenedAt.IsZero() {
+		openedAt = &o.OpenedAt
+	}
+	if !o.DueAt.IsZero() {
+		dueAt = &o.DueAt
+	}
+	if !o.ClosedAt.IsZero() {
+		closedAt = &o.ClosedAt
+	}
+
+	o.CreatedAt, o.UpdatedAt = tx.now, tx.now
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO deadline_overrides (
+			item_kind,
+			item_id,
+			opened_at,
+			due_at,
+			closed_at,
+			time_limit,
+			reason,
+			created_at,
+			student_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (item_kind, item_id, student_id) DO UPDATE
+		SET opened_at = EXCLUDED.opened_at,
+			due_at = EXCLUDED.due_at,
+			closed_at = EXCLUDED.closed_at,
+			time_limit = EXCLUDED.time_limit,
+			reason = EXCLUDED.reason,
+			updated_at = EXCLUDED.created_at
+		RETURNING id, created_at
+	`,
+		o.ItemKind,
+		o.ItemID,
+		openedAt,
+		dueAt,
+		closedAt,
+		o.TimeLimit,
+		o.Reason,
+		o.CreatedAt,
+		o.StudentID,
+	).Scan(&o.ID, &o.CreatedAt); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// deleteDeadlineOverride removes the override of a student.
+func deleteDeadlineOverride(ctx context.Context, tx *Tx, kind string, itemID, studentID int) error {
+	if table, err := deadlineItemTable(kind); err != nil {
+		return err
+	} else if _, err := checkGradeItemTeacher(ctx, tx, table, itemID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM deadline_overrides WHERE item_kind = $1 AND item_id = $2 AND student_id = $3
+	`, kind, itemID, studentID)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &api.Error{Code: api.ENOTFOUND, Message: "Deadline override not found."}
+	}
+	return nil
+}
+
+// findAccommodation returns the accommodations of a student of a group.
+// Returns ENOTFOUND if the student has none.
+func findAccommodation(ctx context.Context, tx *Tx, groupID, studentID int) (*api.Accommodation, error) {
+	accommodations, err := queryAccommodations(ctx, tx, `sg.group_id = $1 AND sg.student_id = $2`, groupID, studentID)
+	if err != nil {
+		return nil, err
+	} else if len(accommodations) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Accommodation not found."}
+	}
+	return accommodations[0], nil
+}
+
+// findAccommodations returns the students of a group with accommodations.
+func findAccommodations(ctx context.Context, tx *Tx, groupID int) ([]*api.Accommodation, error) {
+	return queryAccommodations(ctx, tx, `sg.group_id = $1`, groupID)
+}
+
+// queryAccommodations returns the memberships matching a condition which
+// differ from the defaults.
+func queryAccommodations(ctx context.Context, tx *Tx, where string, args ...interface{}) ([]*api.Accommodation, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			sg.group_id,
+			sg.student_id,
+			sg.time_multiplier,
+			sg.extension_hours,
+			sg.accommodation_notes,
+			sg.accommodated_at,
+			u.first_name,
+			u.last_name,
+			u.email
+		FROM students_groups sg
+		JOIN users u ON u.id = sg.student_id
+		WHERE `+where+`
+		  AND (sg.time_multiplier <> 1 OR sg.extension_hours <> 0 OR sg.accommodation_notes <> '')
+		ORDER BY u.last_name, u.first_name, sg.student_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accommodations := make([]*api.Accommodation, 0)
+	for rows.Next() {
+		var updatedAt sql.NullTime
+
+		var a api.Accommodation
+		a.Student = &api.User{}
+		if err := rows.Scan(
+			&a.GroupID,
+			&a.StudentID,
+			&a.TimeMultiplier,
+			&a.ExtensionHours,
+			&a.Notes,
+			&updatedAt,
+			&a.Student.FirstName,
+			&a.Student.LastName,
+			&a.Student.Email,
+		); err != nil {
+			return nil, err
+		}
+		a.UpdatedAt = updatedAt.Time
+		a.Student.ID = a.StudentID
+
+		accommodations = append(accommodations, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return accommodations, nil
+}
+
+// setAccommodation stores the accommodations on the membership of a student.
+// Returns ENOTFOUND if the student is not in the group.
+func setAccommodation(ctx context.Context, tx *Tx, a *api.Accommodation) error {
+	if a.TimeMultiplier == 0 {
+		a.TimeMultiplier = 1
+	}
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	a.UpdatedAt = tx.now
+	result, err := tx.ExecContext(ctx, `
+		UPDATE students_groups
+		SET time_multiplier = $1,
+			extension_hours = $2,
+			accommodation_notes = $3,
+			accommodated_at = $4
+		WHERE group_id = $5 AND student_id = $6
+	`,
+		a.TimeMultiplier,
+		a.ExtensionHours,
+		a.Notes,
+		a.UpdatedAt,
+		a.GroupID,
+		a.StudentID,
+	)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &api.Error{Code: api.ENOTFOUND, Message: "Student is not in the group."}
+	}
+	return nil
+}
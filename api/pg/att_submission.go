@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -119,6 +120,63 @@ func (s *AttSubmissionService) DeleteAttSubmission(ctx context.Context, id int)
 	return tx.Commit()
 }
 
+// FindRoster retrieves the submissions of an attendance along with a blank
+// entry for every group member who has not checked in yet.
+func (s *AttSubmissionService) FindRoster(ctx context.Context, attendanceID int) ([]*api.AttSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findRoster(ctx, tx, attendanceID)
+}
+
+// UpdateRoster sets the statuses of several students of an attendance at once.
+func (s *AttSubmissionService) UpdateRoster(ctx context.Context, attendanceID int, entries []*api.RosterEntry) ([]*api.AttSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	subs, err := updateRoster(ctx, tx, attendanceID, entries)
+	if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// MarkAbsences creates absent submissions for group members who did not
+// check in to attendances that closed before now.
+func (s *AttSubmissionService) MarkAbsences(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := markAbsences(ctx, tx, now)
+	if err != nil {
+		return 0, err
+	} else if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// FindProxyCheckIns retrieves the check-ins of different students sharing a
+// device or an IP address.
+func (s *AttSubmissionService) FindProxyCheckIns(ctx context.Context, attendanceID int) ([]*api.ProxyCluster, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findProxyCheckIns(ctx, tx, attendanceID)
+}
+
 // findAttSubmissionByID is a helper function to fetch a submission by ID.
 // Returns ENOTFOUND if submission does not exist.
 func findAttSubmissionByID(ctx context.Context, tx *Tx, id int) (*api.AttSubmission, error) {
@@ -161,14 +219,34 @@ func findAttSubmissions(ctx context.Context, tx *Tx, filter api.AttSubmissionFil
 		where, args = append(where, fmt.Sprintf("attendance_id = $%d", i)), append(args, *v)
 		i++
 	}
+	if v := filter.Flagged; v != nil {
+		where, args = append(where, fmt.Sprintf("flagged = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, fmt.Sprintf("status = $%d", i)), append(args, *v)
+		i++
+	}
 
 	// Execute query to fetch submission rows.
 	rows, err := tx.QueryContext(ctx, `
 		SELECT 
 		    id,
 		    present,
+			status,
+			check_in_method,
 			submitted_at,
 			updated_at,
+			latitude,
+			longitude,
+			accuracy,
+			distance,
+			flagged,
+			excuse_reason,
+			excuse_attachment,
+			device_id,
+			user_agent,
+			ip,
 			student_fullname,
 			student_id,
 			attendance_id,
@@ -188,15 +266,30 @@ func findAttSubmissions(ctx context.Context, tx *Tx, filter api.AttSubmissionFil
 	submissions := make([]*api.AttSubmission, 0)
 	for rows.Next() {
 		var studentFullname sql.NullString
+		var excuseReason, excuseAttachment sql.NullString
+		var deviceID, userAgent, ip sql.NullString
 		var updatedAt sql.NullTime
+		var latitude, longitude, accuracy, distance sql.NullFloat64
 		var studentID sql.NullInt32
 
 		var sub api.AttSubmission
 		if err := rows.Scan(
 			&sub.ID,
 			&sub.Present,
+			&sub.Status,
+			&sub.CheckInMethod,
 			&sub.SubmittedAt,
 			&updatedAt,
+			&latitude,
+			&longitude,
+			&accuracy,
+			&distance,
+			&sub.Flagged,
+			&excuseReason,
+			&excuseAttachment,
+			&deviceID,
+			&userAgent,
+			&ip,
 			&studentFullname,
 			&studentID,
 			&sub.AttendanceID,
@@ -211,6 +304,19 @@ func findAttSubmissions(ctx context.Context, tx *Tx, filter api.AttSubmissionFil
 		if updatedAt.Valid {
 			sub.UpdatedAt = updatedAt.Time
 		}
+		sub.ExcuseReason = excuseReason.String
+		sub.ExcuseAttachment = excuseAttachment.String
+		sub.DeviceID = deviceID.String
+		sub.UserAgent = userAgent.String
+		sub.IP = ip.String
+		if latitude.Valid {
+			sub.Location = &api.Location{
+				Latitude:  latitude.Float64,
+				Longitude: longitude.Float64,
+				Accuracy:  accuracy.Float64,
+			}
+			sub.Distance = distance.Float64
+		}
 		if studentID.Valid {
 			sub.StudentID = int(studentID.Int32)
 		}
@@ -233,15 +339,103 @@ func createAttSubmission(ctx context.Context, tx *Tx, sub *api.AttSubmission) er
 	sub.Present = false
 	if sub.Attendance, err = findAttendanceByID(ctx, tx, sub.AttendanceID); err != nil {
 		return err
-	} else if sub.PIN == sub.Attendance.PIN {
-		sub.Present = true
-	} else if sub.Present == false {
-		return api.Errorf(api.EINVALID, "Invalid PIN")
+	}
+
+	// Students may have their own opening and closing times, which also
+	// decide whether the check-in is late.
+	d := sub.Attendance.Deadline()
+	if err := applyDeadline(ctx, tx, d, sub.Attendance.GroupID, sub.StudentID); err != nil {
+		return err
+	}
+	sub.Attendance.OpenedAt, sub.Attendance.ClosedAt = d.OpenedAt, d.ClosedAt
+
+	if sub.Attendance.Cancelled {
+		return api.Errorf(api.EFORBIDDEN, "This session has been cancelled.")
+	} else if !d.Opened(tx.now) {
+		return api.Errorf(api.EFORBIDDEN, "Attendance has not been opened yet.")
+	} else if d.Closed(tx.now) {
+		return api.Errorf(api.EFORBIDDEN, "Attendance has already been closed.")
+	}
+
+	if sub.CheckInMethod == "" {
+		sub.CheckInMethod = api.CheckInPIN
+	}
+	switch sub.CheckInMethod {
+	case api.CheckInPIN:
+		if !sub.Attendance.CheckPIN(sub.PIN, tx.now) {
+			return api.Errorf(api.EINVALID, "Invalid PIN")
+		}
+	case api.CheckInQR:
+		if !sub.Attendance.CheckCheckInToken(sub.Token, tx.now) {
+			return api.Errorf(api.EINVALID, "QR code is invalid or has expired")
+		}
+	default:
+		return api.Errorf(api.EINVALID, "Check-in method is incorrect.")
+	}
+
+	// Check-ins after the grace window count as late.
+	sub.SetStatus(sub.Attendance.CheckInStatus(tx.now))
+
+	// Students may only check in once.
+	if sub.StudentID != 0 {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM att_submissions WHERE attendance_id = $1 AND student_id = $2)
+		`, sub.AttendanceID, sub.StudentID).Scan(&exists); err != nil {
+			return err
+		} else if exists {
+			return api.Errorf(api.ECONFLICT, "You have already checked in.")
+		}
+	}
+
+	// Each device may only be used once if the attendance requires it.
+	if len(sub.UserAgent) > api.MaxUserAgentLen {
+		sub.UserAgent = sub.UserAgent[:api.MaxUserAgentLen]
+	}
+	if sub.Attendance.OnePerDevice {
+		if sub.DeviceID == "" {
+			return api.Errorf(api.EINVALID, "Device ID is required to check in.")
+		}
+
+		var used bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM att_submissions WHERE attendance_id = $1 AND device_id = $2)
+		`, sub.AttendanceID, sub.DeviceID).Scan(&used); err != nil {
+			return err
+		} else if used {
+			return api.Errorf(api.EFORBIDDEN, "This device has already been used to check in.")
+		}
+	}
+
+	// Check the reported location against the geofence. Depending on the
+	// policy, check-ins outside of it are rejected or flagged for review.
+	sub.Flagged, sub.Distance = false, 0
+	if g := sub.Attendance.Geofence; g != nil {
+		if sub.Location == nil {
+			return api.Errorf(api.EINVALID, "Location is required to check in.")
+		} else if err := sub.Location.Validate(); err != nil {
+			return err
+		}
+
+		var inside bool
+		if sub.Distance, inside = g.Check(sub.Location); !inside {
+			if g.Policy == api.GeoFlag {
+				sub.Flagged = true
+			} else {
+				return api.Errorf(api.EFORBIDDEN, "You are outside of the attendance area.")
+			}
+		}
 	}
 
 	// Set timestamps to the current time.
 	sub.SubmittedAt = tx.now
 
+	return insertAttSubmission(ctx, tx, sub)
+}
+
+// insertAttSubmission validates and inserts a submission. Sets the new
+// database ID to sub.ID.
+func insertAttSubmission(ctx context.Context, tx *Tx, sub *api.AttSubmission) error {
 	// Perform basic field validation.
 	if err := sub.Validate(); err != nil {
 		return err
@@ -260,30 +454,63 @@ func createAttSubmission(ctx context.Context, tx *Tx, sub *api.AttSubmission) er
 	if sub.StudentID != 0 {
 		studentID = &sub.StudentID
 	}
+	var latitude, longitude, accuracy, distance *float64
+	if sub.Location != nil {
+		latitude, longitude, accuracy = &sub.Location.Latitude, &sub.Location.Longitude, &sub.Location.Accuracy
+		distance = &sub.Distance
+	}
+	excuseReason, excuseAttachment := excuseArgs(sub)
+	deviceID, userAgent, ip := deviceArgs(sub)
 
 	// Execute insertion query.
 	row := tx.QueryRowContext(ctx, `
 		INSERT INTO att_submissions (
 			present,
+			status,
+			check_in_method,
 			submitted_at,
 			updated_at,
+			latitude,
+			longitude,
+			accuracy,
+			distance,
+			flagged,
+			excuse_reason,
+			excuse_attachment,
+			device_id,
+			user_agent,
+			ip,
 			student_fullname,
 			student_id,
 			attendance_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (attendance_id, student_id) WHERE student_id IS NOT NULL DO NOTHING
 		RETURNING id
 	`,
 		sub.Present,
+		sub.Status,
+		sub.CheckInMethod,
 		sub.SubmittedAt,
 		updatedAt,
+		latitude,
+		longitude,
+		accuracy,
+		distance,
+		sub.Flagged,
+		excuseReason,
+		excuseAttachment,
+		deviceID,
+		userAgent,
+		ip,
 		studentFullname,
 		studentID,
 		sub.AttendanceID,
 	)
 
-	err = row.Scan(&sub.ID)
-	if err != nil {
+	if err := row.Scan(&sub.ID); err == sql.ErrNoRows {
+		return api.Errorf(api.ECONFLICT, "The student has already checked in.")
+	} else if err != nil {
 		return FormatError(err)
 	}
 
@@ -305,9 +532,26 @@ func updateAttSubmission(ctx context.Context, tx *Tx, id int, upd api.AttSubmiss
 		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this attendance submission.")
 	}
 
-	// Update fields.
-	if v := upd.Present; v != nil {
-		sub.Present = *v
+	// Update fields. Setting Present without a status marks the student
+	// present or absent.
+	if v := upd.Present; v != nil && upd.Status == nil {
+		if *v {
+			sub.SetStatus(api.AttPresent)
+		} else {
+			sub.SetStatus(api.AttAbsent)
+		}
+		sub.UpdatedAt = tx.now
+	}
+	if v := upd.Status; v != nil {
+		sub.SetStatus(*v)
+		sub.UpdatedAt = tx.now
+	}
+	if v := upd.ExcuseReason; v != nil && sub.Status == api.AttExcused {
+		sub.ExcuseReason = *v
+		sub.UpdatedAt = tx.now
+	}
+	if v := upd.Flagged; v != nil {
+		sub.Flagged = *v
 		sub.UpdatedAt = tx.now
 	}
 	if v := upd.StudentFullName; v != nil {
@@ -317,16 +561,20 @@ func updateAttSubmission(ctx context.Context, tx *Tx, id int, upd api.AttSubmiss
 		sub.StudentID = *v
 	}
 
+	if err := saveAttSubmission(ctx, tx, sub); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// saveAttSubmission validates and writes the editable fields of an existing submission.
+func saveAttSubmission(ctx context.Context, tx *Tx, sub *api.AttSubmission) error {
 	// Perform basic field validation.
 	if err := sub.Validate(); err != nil {
-		return sub, err
+		return err
 	}
 
 	// These fields are nullable so ensure we store blank fields as NULLs.
-	var present *bool
-	if sub.Present {
-		present = &sub.Present
-	}
 	var studentFullname *string
 	if sub.StudentFullName != "" {
 		studentFullname = &sub.StudentFullName
@@ -340,25 +588,60 @@ func updateAttSubmission(ctx context.Context, tx *Tx, id int, upd api.AttSubmiss
 		updatedAt = &sub.UpdatedAt
 	}
 
+	excuseReason, excuseAttachment := excuseArgs(sub)
+
 	// Execute update query.
-	if _, err = tx.ExecContext(ctx, `
+	if _, err := tx.ExecContext(ctx, `
 		UPDATE att_submissions
 		SET present = $1,
-			student_fullname = $2,
-			student_id = $3,
-			updated_at = $4
-		WHERE id = $5
+			status = $2,
+			flagged = $3,
+			excuse_reason = $4,
+			excuse_attachment = $5,
+			student_fullname = $6,
+			student_id = $7,
+			updated_at = $8
+		WHERE id = $9
 	`,
-		present,
+		sub.Present,
+		sub.Status,
+		sub.Flagged,
+		excuseReason,
+		excuseAttachment,
 		studentFullname,
 		studentID,
 		updatedAt,
-		id,
+		sub.ID,
 	); err != nil {
-		return sub, FormatError(err)
+		return FormatError(err)
 	}
 
-	return sub, nil
+	return nil
+}
+
+// deviceArgs returns the device fingerprint of a submission, or NULLs when blank.
+func deviceArgs(sub *api.AttSubmission) (deviceID, userAgent, ip *string) {
+	if sub.DeviceID != "" {
+		deviceID = &sub.DeviceID
+	}
+	if sub.UserAgent != "" {
+		userAgent = &sub.UserAgent
+	}
+	if sub.IP != "" {
+		ip = &sub.IP
+	}
+	return deviceID, userAgent, ip
+}
+
+// excuseArgs returns the excuse fields of a submission, or NULLs when blank.
+func excuseArgs(sub *api.AttSubmission) (reason, attachment *string) {
+	if sub.ExcuseReason != "" {
+		reason = &sub.ExcuseReason
+	}
+	if sub.ExcuseAttachment != "" {
+		attachment = &sub.ExcuseAttachment
+	}
+	return reason, attachment
 }
 
 // deleteAttSubmission permanently removes a submission by ID. Returns EUNAUTHORIZED if current
@@ -401,3 +684,259 @@ func attachAttStudents(ctx context.Context, tx *Tx, sub *api.AttSubmission) (err
 	}
 	return nil
 }
+
+// checkRosterAccess returns the attendance if the current user is its teacher.
+func checkRosterAccess(ctx context.Context, tx *Tx, attendanceID int) (*api.Attendance, error) {
+	att, err := findAttendanceByID(ctx, tx, attendanceID)
+	if err != nil {
+		return nil, err
+	} else if att.TeacherID == 0 || att.TeacherID != api.UserIDFromContext(ctx) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to manage the roster of this attendance.")
+	}
+	return att, nil
+}
+
+// findRoster returns the submissions of an attendance followed by a blank
+// entry for every group student without one.
+func findRoster(ctx context.Context, tx *Tx, attendanceID int) ([]*api.AttSubmission, error) {
+	att, err := checkRosterAccess(ctx, tx, attendanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, _, err := findAttSubmissions(ctx, tx, api.AttSubmissionFilter{AttendanceID: &attendanceID})
+	if err != nil {
+		return nil, err
+	}
+	submitted := make(map[int]bool, len(subs))
+	for _, sub := range subs {
+		if err := attachAttStudents(ctx, tx, sub); err != nil {
+			return nil, err
+		}
+		submitted[sub.StudentID] = true
+	}
+
+	if att.GroupID == 0 {
+		return subs, nil
+	}
+
+	isTeacher := false
+	students, _, err := findUsersByGroup(ctx, tx, api.MemberFilter{IsTeacher: &isTeacher, GroupID: &att.GroupID})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range students {
+		if submitted[u.ID] {
+			continue
+		}
+		subs = append(subs, &api.AttSubmission{
+			StudentID:    u.ID,
+			Student:      u,
+			AttendanceID: attendanceID,
+		})
+	}
+	return subs, nil
+}
+
+// updateRoster sets the status of every entry. Students without a submission
+// get a manual one if they are members of the attendance group.
+func updateRoster(ctx context.Context, tx *Tx, attendanceID int, entries []*api.RosterEntry) ([]*api.AttSubmission, error) {
+	att, err := checkRosterAccess(ctx, tx, attendanceID)
+	if err != nil {
+		return nil, err
+	} else if len(entries) > api.MaxRosterBatch {
+		return nil, api.Errorf(api.EINVALID, "At most %d students can be updated at once.", api.MaxRosterBatch)
+	}
+
+	subs := make([]*api.AttSubmission, 0, len(entries))
+	for _, e := range entries {
+		if !api.IsAttStatus(e.Status) {
+			return nil, api.Errorf(api.EINVALID, "Status of student %d is incorrect.", e.StudentID)
+		}
+
+		existing, _, err := findAttSubmissions(ctx, tx, api.AttSubmissionFilter{AttendanceID: &attendanceID, StudentID: &e.StudentID, Limit: 1})
+		if err != nil {
+			return nil, err
+		}
+
+		var sub *api.AttSubmission
+		if len(existing) > 0 {
+			sub = existing[0]
+		} else if att.GroupID == 0 {
+			return nil, api.Errorf(api.EINVALID, "Student %d has not checked in to this attendance.", e.StudentID)
+		} else if ok, err := isGroupStudent(ctx, tx, att.GroupID, e.StudentID); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, api.Errorf(api.EINVALID, "Student %d is not a member of the group.", e.StudentID)
+		} else {
+			sub = &api.AttSubmission{
+				CheckInMethod: api.CheckInManual,
+				SubmittedAt:   tx.now,
+				StudentID:     e.StudentID,
+				AttendanceID:  attendanceID,
+			}
+		}
+
+		sub.SetStatus(e.Status)
+		if e.Status == api.AttExcused {
+			if e.ExcuseReason != "" {
+				sub.ExcuseReason = e.ExcuseReason
+			}
+			if e.ExcuseAttachment != "" {
+				sub.ExcuseAttachment = e.ExcuseAttachment
+			}
+		}
+
+		if sub.ID == 0 {
+			err = insertAttSubmission(ctx, tx, sub)
+		} else {
+			sub.UpdatedAt = tx.now
+			err = saveAttSubmission(ctx, tx, sub)
+		}
+		if err != nil {
+			return nil, err
+		} else if err := attachAttStudents(ctx, tx, sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// markAbsences creates absent submissions for the group students who did not
+// check in to attendances that closed before now, except for students whose
+// attendance closes later, who are marked on a later run. Attendances being
+// marked by another instance are skipped.
+func markAbsences(ctx context.Context, tx *Tx, now time.Time) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, group_id
+		FROM attendances
+		WHERE group_id IS NOT NULL
+		  AND closed_at <= $1
+		  AND absences_marked_at IS NULL
+		  AND NOT cancelled
+		ORDER BY id
+		FOR UPDATE SKIP LOCKED
+	`, now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	type closed struct{ id, groupID int }
+	var attendances []closed
+	for rows.Next() {
+		var c closed
+		if err := rows.Scan(&c.id, &c.groupID); err != nil {
+			return 0, err
+		}
+		attendances = append(attendances, c)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	var n int
+	for _, c := range attendances {
+		result, err := tx.ExecContext(ctx, `
+			INSERT INTO att_submissions (present, status, check_in_method, submitted_at, student_id, attendance_id)
+			SELECT FALSE, $1, $2, $3, sg.student_id, $4
+			FROM students_groups sg
+			WHERE sg.group_id = $5
+			  AND NOT EXISTS (
+				SELECT 1 FROM att_submissions s WHERE s.attendance_id = $4 AND s.student_id = sg.student_id
+			  )
+			  AND NOT EXISTS (
+				SELECT 1 FROM deadline_overrides o
+				WHERE o.item_kind = $6 AND o.item_id = $4 AND o.student_id = sg.student_id AND o.closed_at > $7
+			  )
+			ON CONFLICT (attendance_id, student_id) WHERE student_id IS NOT NULL DO NOTHING
+		`, api.AttAbsent, api.CheckInManual, tx.now, c.id, c.groupID, api.GradeItemAttendance, now.UTC())
+		if err != nil {
+			return n, FormatError(err)
+		}
+		if v, err := result.RowsAffected(); err == nil {
+			n += int(v)
+		}
+
+		// Students whose attendance still closes later are marked once it
+		// does, so the attendance is only done when no such student is left.
+		var pending bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM deadline_overrides
+				WHERE item_kind = $1 AND item_id = $2 AND closed_at > $3
+			)
+		`, api.GradeItemAttendance, c.id, now.UTC()).Scan(&pending); err != nil {
+			return n, err
+		} else if pending {
+			continue
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE attendances SET absences_marked_at = $1 WHERE id = $2`, tx.now, c.id); err != nil {
+			return n, FormatError(err)
+		}
+	}
+	return n, nil
+}
+
+// findProxyCheckIns groups the submissions of an attendance by device and by
+// IP address and returns the groups holding more than one student.
+func findProxyCheckIns(ctx context.Context, tx *Tx, attendanceID int) ([]*api.ProxyCluster, error) {
+	if _, err := checkRosterAccess(ctx, tx, attendanceID); err != nil {
+		return nil, err
+	}
+
+	subs, _, err := findAttSubmissions(ctx, tx, api.AttSubmissionFilter{AttendanceID: &attendanceID})
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*api.ProxyCluster, 0)
+	index := make(map[string]*api.ProxyCluster)
+	for _, sub := range subs {
+		if err := attachAttStudents(ctx, tx, sub); err != nil {
+			return nil, err
+		}
+
+		for _, k := range []struct{ kind, value string }{
+			{api.ProxyByDevice, sub.DeviceID},
+			{api.ProxyByIP, sub.IP},
+		} {
+			if k.value == "" {
+				continue
+			}
+			c := index[k.kind+":"+k.value]
+			if c == nil {
+				c = &api.ProxyCluster{Kind: k.kind, Value: k.value}
+				index[k.kind+":"+k.value] = c
+				clusters = append(clusters, c)
+			}
+			c.Submissions = append(c.Submissions, sub)
+		}
+	}
+
+	// Keep the clusters of different students only. Anonymous students are
+	// told apart by their names.
+	suspicious := clusters[:0]
+	for _, c := range clusters {
+		students := make(map[string]bool)
+		for _, sub := range c.Submissions {
+			if sub.StudentID != 0 {
+				students[fmt.Sprint(sub.StudentID)] = true
+			} else {
+				students["name:"+strings.ToLower(strings.TrimSpace(sub.StudentFullName))] = true
+			}
+		}
+		if len(students) > 1 {
+			suspicious = append(suspicious, c)
+		}
+	}
+	clusters = suspicious
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i].Submissions) > len(clusters[j].Submissions)
+	})
+	return clusters, nil
+}
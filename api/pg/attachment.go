@@ -2,6 +2,7 @@ package pg
 
 import (
 	"context"
+	"database/sql"
 	"strings"
 
 	"github.com/dori7879/senior-project/api"
@@ -82,6 +83,24 @@ func (s *AttachmentService) UpdateAttachment(ctx context.Context, hash string, u
 	return attachment, nil
 }
 
+// ChangeAttachmentCounter adds delta to the counter of a attachment and deletes
+// it once the counter drops below 1. Returns ENOTFOUND if attachment does not exist.
+func (s *AttachmentService) ChangeAttachmentCounter(ctx context.Context, hash string, delta int) (*api.Attachment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	attachment, err := changeAttachmentCounter(ctx, tx, hash, delta)
+	if err != nil {
+		return attachment, err
+	} else if err := tx.Commit(); err != nil {
+		return attachment, err
+	}
+	return attachment, nil
+}
+
 // DeleteAttachment permanently deletes a attachment.
 // Returns EUNAUTHORIZED if current attachment is not the attachment being deleted.
 // Returns ENOTFOUND if attachment does not exist.
@@ -185,7 +204,7 @@ func createAttachment(ctx context.Context, tx *Tx, attachment *api.Attachment) e
 			created_at,
 			counter
 		)
-		VALUES (?, ?, ?, ?)
+		VALUES ($1, $2, $3, $4)
 	`,
 		attachment.Hash,
 		attachment.Extension,
@@ -221,8 +240,8 @@ func updateAttachment(ctx context.Context, tx *Tx, hash string, upd api.Attachme
 	// Execute update query.
 	if _, err := tx.ExecContext(ctx, `
 		UPDATE attachments
-		SET counter = ?
-		WHERE hash = ?
+		SET counter = $1
+		WHERE hash = $2
 	`,
 		attachment.Counter,
 		attachment.Hash,
@@ -240,11 +259,40 @@ func updateAttachment(ctx context.Context, tx *Tx, hash string, upd api.Attachme
 	return attachment, nil
 }
 
+// changeAttachmentCounter adds delta to the counter of a attachment in a single
+// statement, which locks the row against concurrent changes until the
+// transaction ends, and deletes the attachment if the counter drops below 1.
+func changeAttachmentCounter(ctx context.Context, tx *Tx, hash string, delta int) (*api.Attachment, error) {
+	var attachment api.Attachment
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE attachments
+		SET counter = counter + $1
+		WHERE hash = $2
+		RETURNING hash, extension, created_at, counter
+	`, delta, hash).Scan(
+		&attachment.Hash,
+		&attachment.Extension,
+		&attachment.CreatedAt,
+		&attachment.Counter,
+	); err == sql.ErrNoRows {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Выбранная картинка не найдена."}
+	} else if err != nil {
+		return nil, FormatError(err)
+	}
+
+	if attachment.Counter < 1 {
+		if err := deleteAttachment(ctx, tx, hash); err != nil {
+			return &attachment, err
+		}
+	}
+	return &attachment, nil
+}
+
 // deleteAttachment permanently removes a attachment by Hash. Returns EUNAUTHORIZED if current
 // attachment is not the one being deleted.
 func deleteAttachment(ctx context.Context, tx *Tx, hash string) error {
 	// Remove row from database.
-	if _, err := tx.ExecContext(ctx, `DELETE FROM attachments WHERE hash = ?`, hash); err != nil {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM attachments WHERE hash = $1`, hash); err != nil {
 		return FormatError(err)
 	}
 	return nil
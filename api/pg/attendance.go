@@ -223,6 +223,21 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 		where, args = append(where, fmt.Sprintf("group_id = $%d", i)), append(args, *v)
 		i++
 	}
+	if v := filter.Scheduled; v != nil {
+		if *v {
+			where = append(where, "scheduled_on IS NOT NULL")
+		} else {
+			where = append(where, "scheduled_on IS NULL")
+		}
+	}
+	if v := filter.ScheduledFrom; v != nil {
+		where, args = append(where, fmt.Sprintf("scheduled_on >= $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.ScheduledTo; v != nil {
+		where, args = append(where, fmt.Sprintf("scheduled_on <= $%d", i)), append(args, *v)
+		i++
+	}
 
 	// Execute query to fetch attendance rows.
 	rows, err := tx.QueryContext(ctx, `
@@ -230,6 +245,9 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 		    id,
 		    title,
 			pin,
+			pin_mode,
+			pin_secret,
+			pin_period,
 			student_link,
 			teacher_link,
 			course_title,
@@ -238,6 +256,16 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 			updated_at,
 			opened_at,
 			closed_at,
+			late_grace,
+			absences_marked_at,
+			one_per_device,
+			geo_latitude,
+			geo_longitude,
+			geo_radius,
+			geo_policy,
+			slot_id,
+			scheduled_on,
+			cancelled,
 			teacher_fullname,
 			teacher_id,
 			group_id,
@@ -257,9 +285,15 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 	attendances := make([]*api.Attendance, 0)
 	for rows.Next() {
 		var teacherFullname sql.NullString
+		var pinSecret sql.NullString
 		var updatedAt sql.NullTime
 		var openedAt sql.NullTime
 		var closedAt sql.NullTime
+		var absencesMarkedAt sql.NullTime
+		var geoLatitude, geoLongitude, geoRadius sql.NullFloat64
+		var geoPolicy sql.NullString
+		var slotID sql.NullInt32
+		var scheduledOn sql.NullTime
 		var teacherID sql.NullInt32
 		var groupID sql.NullInt32
 
@@ -268,6 +302,9 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 			&att.ID,
 			&att.Title,
 			&att.PIN,
+			&att.PINMode,
+			&pinSecret,
+			&att.PINPeriod,
 			&att.StudentLink,
 			&att.TeacherLink,
 			&att.CourseTitle,
@@ -276,6 +313,16 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 			&updatedAt,
 			&openedAt,
 			&closedAt,
+			&att.LateGrace,
+			&absencesMarkedAt,
+			&att.OnePerDevice,
+			&geoLatitude,
+			&geoLongitude,
+			&geoRadius,
+			&geoPolicy,
+			&slotID,
+			&scheduledOn,
+			&att.Cancelled,
 			&teacherFullname,
 			&teacherID,
 			&groupID,
@@ -287,6 +334,9 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 		if teacherFullname.Valid {
 			att.TeacherFullName = teacherFullname.String
 		}
+		if pinSecret.Valid {
+			att.PINSecret = pinSecret.String
+		}
 		if updatedAt.Valid {
 			att.UpdatedAt = updatedAt.Time
 		}
@@ -296,6 +346,23 @@ func findAttendances(ctx context.Context, tx *Tx, filter api.AttendanceFilter) (
 		if closedAt.Valid {
 			att.ClosedAt = closedAt.Time
 		}
+		if absencesMarkedAt.Valid {
+			att.AbsencesMarkedAt = absencesMarkedAt.Time
+		}
+		if geoRadius.Valid {
+			att.Geofence = &api.Geofence{
+				Latitude:  geoLatitude.Float64,
+				Longitude: geoLongitude.Float64,
+				Radius:    geoRadius.Float64,
+				Policy:    geoPolicy.String,
+			}
+		}
+		if slotID.Valid {
+			att.SlotID = int(slotID.Int32)
+		}
+		if scheduledOn.Valid {
+			att.ScheduledOn = scheduledOn.Time.Format(api.DateLayout)
+		}
 		if teacherID.Valid {
 			att.TeacherID = int(teacherID.Int32)
 		}
@@ -319,6 +386,20 @@ func createAttendance(ctx context.Context, tx *Tx, att *api.Attendance) error {
 	att.CreatedAt = tx.now
 	att.UpdatedAt = att.CreatedAt
 
+	// Default to a static PIN and rejecting check-ins outside of the fence.
+	if att.Geofence != nil && att.Geofence.Policy == "" {
+		att.Geofence.Policy = api.GeoReject
+	}
+	if att.PINMode == "" {
+		att.PINMode = api.PINStatic
+	}
+	if att.PINPeriod == 0 {
+		att.PINPeriod = api.DefaultPINPeriod
+	}
+	if err := ensurePINSecret(att); err != nil {
+		return err
+	}
+
 	// Perform basic field validation.
 	if err := att.Validate(); err != nil {
 		return err
@@ -337,6 +418,19 @@ func createAttendance(ctx context.Context, tx *Tx, att *api.Attendance) error {
 	if att.TeacherFullName != "" {
 		teacherFullname = &att.TeacherFullName
 	}
+	var pinSecret *string
+	if att.PINSecret != "" {
+		pinSecret = &att.PINSecret
+	}
+	geoLatitude, geoLongitude, geoRadius, geoPolicy := geofenceArgs(att.Geofence)
+	var slotID *int
+	if att.SlotID != 0 {
+		slotID = &att.SlotID
+	}
+	var scheduledOn *string
+	if att.ScheduledOn != "" {
+		scheduledOn = &att.ScheduledOn
+	}
 	var teacherID *int
 	if att.TeacherID != 0 {
 		teacherID = &att.TeacherID
@@ -351,6 +445,9 @@ func createAttendance(ctx context.Context, tx *Tx, att *api.Attendance) error {
 		INSERT INTO attendances (
 			title,
 			pin,
+			pin_mode,
+			pin_secret,
+			pin_period,
 			student_link,
 			teacher_link,
 			course_title,
@@ -358,15 +455,27 @@ func createAttendance(ctx context.Context, tx *Tx, att *api.Attendance) error {
 			created_at,
 			opened_at,
 			closed_at,
+			late_grace,
+			one_per_device,
+			geo_latitude,
+			geo_longitude,
+			geo_radius,
+			geo_policy,
+			slot_id,
+			scheduled_on,
+			cancelled,
 			teacher_fullname,
 			teacher_id,
 			group_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24)
 		RETURNING id
 	`,
 		att.Title,
 		att.PIN,
+		att.PINMode,
+		pinSecret,
+		att.PINPeriod,
 		att.StudentLink,
 		att.TeacherLink,
 		att.CourseTitle,
@@ -374,6 +483,15 @@ func createAttendance(ctx context.Context, tx *Tx, att *api.Attendance) error {
 		att.CreatedAt,
 		openedAt,
 		closedAt,
+		att.LateGrace,
+		att.OnePerDevice,
+		geoLatitude,
+		geoLongitude,
+		geoRadius,
+		geoPolicy,
+		slotID,
+		scheduledOn,
+		att.Cancelled,
 		teacherFullname,
 		teacherID,
 		groupID,
@@ -412,12 +530,43 @@ func updateAttendance(ctx context.Context, tx *Tx, id int, upd api.AttendanceUpd
 	if v := upd.PIN; v != nil {
 		att.PIN = *v
 	}
+	if v := upd.PINMode; v != nil {
+		att.PINMode = *v
+	}
+	if v := upd.PINPeriod; v != nil {
+		att.PINPeriod = *v
+	}
+	if v := upd.PINSecret; v != nil {
+		att.PINSecret = *v
+	}
+	if err := ensurePINSecret(att); err != nil {
+		return att, err
+	}
 	if v := upd.OpenedAt; v != nil {
 		att.OpenedAt = *v
 	}
 	if v := upd.ClosedAt; v != nil {
 		att.ClosedAt = *v
 	}
+	if v := upd.LateGrace; v != nil {
+		att.LateGrace = *v
+	}
+	if v := upd.OnePerDevice; v != nil {
+		att.OnePerDevice = *v
+	}
+	if v := upd.Geofence; v != nil {
+		if v.Radius == 0 {
+			att.Geofence = nil
+		} else {
+			if v.Policy == "" {
+				v.Policy = api.GeoReject
+			}
+			att.Geofence = v
+		}
+	}
+	if v := upd.Cancelled; v != nil {
+		att.Cancelled = *v
+	}
 	if v := upd.TeacherFullName; v != nil {
 		att.TeacherFullName = *v
 	}
@@ -449,6 +598,11 @@ func updateAttendance(ctx context.Context, tx *Tx, id int, upd api.AttendanceUpd
 	if att.TeacherFullName != "" {
 		teacherFullname = &att.TeacherFullName
 	}
+	var pinSecret *string
+	if att.PINSecret != "" {
+		pinSecret = &att.PINSecret
+	}
+	geoLatitude, geoLongitude, geoRadius, geoPolicy := geofenceArgs(att.Geofence)
 	var teacherID *int
 	if att.TeacherID != 0 {
 		teacherID = &att.TeacherID
@@ -463,24 +617,46 @@ func updateAttendance(ctx context.Context, tx *Tx, id int, upd api.AttendanceUpd
 		UPDATE attendances
 		SET title = $1,
 		    pin = $2,
-		    course_title = $3,
-		    mode = $4,
-		    opened_at = $5,
-		    closed_at = $6,
-		    teacher_fullname = $7,
-		    teacher_id = $8,
-		    group_id = $9
-		WHERE id = $10
+		    pin_mode = $3,
+		    pin_secret = $4,
+		    pin_period = $5,
+		    course_title = $6,
+		    mode = $7,
+		    opened_at = $8,
+		    closed_at = $9,
+		    late_grace = $10,
+		    one_per_device = $11,
+		    geo_latitude = $12,
+		    geo_longitude = $13,
+		    geo_radius = $14,
+		    geo_policy = $15,
+		    cancelled = $16,
+		    teacher_fullname = $17,
+		    teacher_id = $18,
+		    group_id = $19,
+		    updated_at = $20
+		WHERE id = $21
 	`,
 		att.Title,
 		att.PIN,
+		att.PINMode,
+		pinSecret,
+		att.PINPeriod,
 		att.CourseTitle,
 		att.Mode,
 		openedAt,
 		closedAt,
+		att.LateGrace,
+		att.OnePerDevice,
+		geoLatitude,
+		geoLongitude,
+		geoRadius,
+		geoPolicy,
+		att.Cancelled,
 		teacherFullname,
 		teacherID,
 		groupID,
+		att.UpdatedAt,
 		id,
 	); err != nil {
 		return att, FormatError(err)
@@ -489,6 +665,24 @@ func updateAttendance(ctx context.Context, tx *Tx, id int, upd api.AttendanceUpd
 	return att, nil
 }
 
+// geofenceArgs returns the column values of a geofence, all NULL if there is none.
+func geofenceArgs(g *api.Geofence) (latitude, longitude, radius *float64, policy *string) {
+	if g == nil {
+		return nil, nil, nil, nil
+	}
+	return &g.Latitude, &g.Longitude, &g.Radius, &g.Policy
+}
+
+// ensurePINSecret generates the secret of rotating PINs and QR check-in
+// tokens if the attendance has none yet.
+func ensurePINSecret(att *api.Attendance) (err error) {
+	if att.PINSecret != "" {
+		return nil
+	}
+	att.PINSecret, err = api.RandSecret(20)
+	return err
+}
+
 // deleteAttendance permanently removes a attendance by ID. Returns EUNAUTHORIZED if current
 // attendance is not the one being deleted.
 func deleteAttendance(ctx context.Context, tx *Tx, id int) error {
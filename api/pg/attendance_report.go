@@ -0,0 +1,139 @@
+package pg
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.AttendanceReportService = (*AttendanceReportService)(nil)
+
+// AttendanceReportService represents a service for building attendance reports.
+type AttendanceReportService struct {
+	db *DB
+}
+
+// NewAttendanceReportService returns a new instance of AttendanceReportService.
+func NewAttendanceReportService(db *DB) *AttendanceReportService {
+	return &AttendanceReportService{db: db}
+}
+
+// FindAttendanceReport builds the attendance report of a group.
+func (s *AttendanceReportService) FindAttendanceReport(ctx context.Context, groupID int, filter api.AttendanceReportFilter) (*api.AttendanceReport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findAttendanceReport(ctx, tx, groupID, filter)
+}
+
+// attStatusRank orders statuses so that the best one is kept when a student
+// has several submissions for the same session.
+var attStatusRank = map[string]int{
+	api.AttAbsent:  1,
+	api.AttExcused: 2,
+	api.AttLate:    3,
+	api.AttPresent: 4,
+}
+
+// findAttendanceReport returns the report of the group sessions matching the filter.
+func findAttendanceReport(ctx context.Context, tx *Tx, groupID int, filter api.AttendanceReportFilter) (*api.AttendanceReport, error) {
+	if _, err := findGroupByID(ctx, tx, groupID); err != nil {
+		return nil, err
+	} else if ok, err := isGroupTeacher(ctx, tx, groupID, api.UserIDFromContext(ctx)); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view the attendance report of this group.")
+	}
+
+	report := &api.AttendanceReport{
+		GroupID:   groupID,
+		Threshold: filter.Threshold,
+		Sessions:  make([]*api.Attendance, 0),
+		Rows:      make([]*api.AttendanceReportRow, 0),
+	}
+
+	// Keep sessions that took place within the range, oldest first.
+	attendances, _, err := findAttendances(ctx, tx, api.AttendanceFilter{GroupID: &groupID})
+	if err != nil {
+		return nil, err
+	}
+	for _, att := range attendances {
+		at := sessionTime(att)
+		if att.Cancelled || at.After(tx.now) {
+			continue
+		} else if filter.From != nil && at.Before(*filter.From) {
+			continue
+		} else if filter.To != nil && at.After(*filter.To) {
+			continue
+		}
+		report.Sessions = append(report.Sessions, att)
+	}
+	sort.SliceStable(report.Sessions, func(i, j int) bool {
+		return sessionTime(report.Sessions[i]).Before(sessionTime(report.Sessions[j]))
+	})
+
+	columns := make(map[int]int, len(report.Sessions))
+	for i, att := range report.Sessions {
+		columns[att.ID] = i
+	}
+
+	isTeacher := false
+	students, _, err := findUsersByGroup(ctx, tx, api.MemberFilter{IsTeacher: &isTeacher, GroupID: &groupID})
+	if err != nil {
+		return nil, err
+	}
+	rows := make(map[int]*api.AttendanceReportRow, len(students))
+	for _, u := range students {
+		row := &api.AttendanceReportRow{Student: u, Statuses: make([]string, len(report.Sessions))}
+		rows[u.ID] = row
+		report.Rows = append(report.Rows, row)
+	}
+
+	// Fill in the statuses of the group members.
+	result, err := tx.QueryContext(ctx, `
+		SELECT s.attendance_id, s.student_id, s.status
+		FROM att_submissions s
+		JOIN attendances a ON a.id = s.attendance_id
+		WHERE a.group_id = $1 AND s.student_id IS NOT NULL
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var attendanceID, studentID int
+		var status string
+		if err := result.Scan(&attendanceID, &studentID, &status); err != nil {
+			return nil, err
+		}
+
+		i, ok := columns[attendanceID]
+		row := rows[studentID]
+		if !ok || row == nil {
+			continue
+		}
+		if attStatusRank[status] > attStatusRank[row.Statuses[i]] {
+			row.Statuses[i] = status
+		}
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	report.Compute(tx.now)
+	return report, nil
+}
+
+// sessionTime returns when an attendance took place.
+func sessionTime(att *api.Attendance) time.Time {
+	if !att.OpenedAt.IsZero() {
+		return att.OpenedAt
+	}
+	return att.CreatedAt
+}
@@ -0,0 +1,503 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.GradebookService = (*GradebookService)(nil)
+
+// GradebookService represents a service for managing the gradebooks of groups.
+type GradebookService struct {
+	db *DB
+}
+
+// NewGradebookService returns a new instance of GradebookService.
+func NewGradebookService(db *DB) *GradebookService {
+	return &GradebookService{db: db}
+}
+
+// FindGradebook builds the gradebook of a group.
+func (s *GradebookService) FindGradebook(ctx context.Context, groupID int) (*api.Gradebook, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findGradebook(ctx, tx, groupID)
+}
+
+// FindGradeCategories retrieves the categories of a group.
+func (s *GradebookService) FindGradeCategories(ctx context.Context, groupID int) ([]*api.GradeCategory, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, groupID, false); err != nil {
+		return nil, err
+	}
+	return findGradeCategories(ctx, tx, `group_id = $1`, groupID)
+}
+
+// CreateGradeCategory creates a new category.
+func (s *GradebookService) CreateGradeCategory(ctx context.Context, category *api.GradeCategory) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createGradeCategory(ctx, tx, category); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateGradeCategory updates a category.
+func (s *GradebookService) UpdateGradeCategory(ctx context.Context, id int, upd api.GradeCategoryUpdate) (*api.GradeCategory, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	category, err := updateGradeCategory(ctx, tx, id, upd)
+	if err != nil {
+		return category, err
+	} else if err := tx.Commit(); err != nil {
+		return category, err
+	}
+	return category, nil
+}
+
+// DeleteGradeCategory permanently deletes a category.
+func (s *GradebookService) DeleteGradeCategory(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteGradeCategory(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetGradeItemCategory assigns a homework or quiz of a group to a category.
+func (s *GradebookService) SetGradeItemCategory(ctx context.Context, groupID int, kind string, itemID, categoryID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setGradeItemCategory(ctx, tx, groupID, kind, itemID, categoryID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkGradebookAccess returns an error if the current user is not a member
+// of the group, or not a teacher of it if teacher is set. Returns whether the
+// user teaches in the group.
+func checkGradebookAccess(ctx context.Context, tx *Tx, groupID int, teacher bool) (bool, error) {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return false, api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+
+	if ok, err := isGroupTeacher(ctx, tx, groupID, userID); err != nil {
+		return false, err
+	} else if ok {
+		return true, nil
+	} else if teacher {
+		return false, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of the group.")
+	}
+
+	if ok, err := isGroupStudent(ctx, tx, groupID, userID); err != nil {
+		return false, err
+	} else if !ok {
+		return false, api.Errorf(api.EUNAUTHORIZED, "You are not in the group.")
+	}
+	return false, nil
+}
+
+// findGradebook builds the gradebook of a group with one query for the items
+// and one for the grades.
+func findGradebook(ctx context.Context, tx *Tx, groupID int) (*api.Gradebook, error) {
+	if _, err := findGroupByID(ctx, tx, groupID); err != nil {
+		return nil, err
+	}
+	isTeacher, err := checkGradebookAccess(ctx, tx, groupID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &api.Gradebook{GroupID: groupID}
+	if g.Categories, err = findGradeCategories(ctx, tx, `group_id = $1`, groupID); err != nil {
+		return nil, err
+	} else if len(g.Categories) == 0 {
+		g.Categories = api.DefaultGradeCategories()
+	}
+
+	if g.Items, err = findGradeItems(ctx, tx, groupID); err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int, len(g.Items))
+	for i, item := range g.Items {
+		columns[gradeItemKey(item.Kind, item.ID)] = i
+	}
+
+	// Students only see their own grades.
+	teacher := false
+	filter := api.MemberFilter{IsTeacher: &teacher, GroupID: &groupID}
+	if !isTeacher {
+		userID := api.UserIDFromContext(ctx)
+		filter.UserID = &userID
+	}
+	students, _, err := findUsersByGroup(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	}
+	rows := make(map[int]*api.GradebookRow, len(students))
+	g.Rows = make([]*api.GradebookRow, 0, len(students))
+	for _, u := range students {
+		row := &api.GradebookRow{Student: u, Grades: make([]*float64, len(g.Items))}
+		rows[u.ID] = row
+		g.Rows = append(g.Rows, row)
+	}
+
+	// Fetch the grade of the latest submission of every student on every
+	// item, which is also the one grade imports change. Attendance is worth a
+	// point when present or late and excused sessions are ungraded. Students
+	// do not see grades that have not been released yet.
+	result, err := tx.QueryContext(ctx, `
+		SELECT 'homework', homework_id, student_id, grade
+		FROM (
+			SELECT DISTINCT ON (s.homework_id, s.student_id) s.homework_id, s.student_id, s.grade
+			FROM hw_submissions s
+			JOIN homeworks h ON h.id = s.homework_id
+			WHERE h.group_id = $1 AND s.student_id IS NOT NULL
+				AND ($2 OR h.grades_release_at <= $3)
+			ORDER BY s.homework_id, s.student_id, s.id DESC
+		) latest
+		WHERE grade IS NOT NULL
+		UNION ALL
+		SELECT 'quiz', quiz_id, student_id, grade
+		FROM (
+			SELECT DISTINCT ON (s.quiz_id, s.student_id) s.quiz_id, s.student_id, s.grade
+			FROM quiz_submissions s
+			JOIN quizzes q ON q.id = s.quiz_id
+			WHERE q.group_id = $1 AND s.student_id IS NOT NULL
+				AND ($2 OR q.grades_release_at <= $3)
+			ORDER BY s.quiz_id, s.student_id, s.id DESC
+		) latest
+		WHERE grade IS NOT NULL
+		UNION ALL
+		SELECT 'attendance', s.attendance_id, s.student_id, MAX(CASE WHEN s.status IN ('present', 'late') THEN 1 ELSE 0 END)
+		FROM att_submissions s
+		JOIN attendances a ON a.id = s.attendance_id
+		WHERE a.group_id = $1 AND s.student_id IS NOT NULL AND s.status <> 'excused'
+		GROUP BY s.attendance_id, s.student_id
+	`, groupID, isTeacher, tx.now)
+	if err != nil {
+		return nil, err
+	}
+	defer result.Close()
+
+	for result.Next() {
+		var kind string
+		var itemID, studentID int
+		var grade float64
+		if err := result.Scan(&kind, &itemID, &studentID, &grade); err != nil {
+			return nil, err
+		}
+
+		i, ok := columns[gradeItemKey(kind, itemID)]
+		row := rows[studentID]
+		if !ok || row == nil {
+			continue
+		}
+		row.Grades[i] = &grade
+	}
+	if err := result.Err(); err != nil {
+		return nil, err
+	}
+
+	g.Compute()
+
+	// Grade the totals on the scale of the group.
+	scale, err := findGradingScale(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range g.Rows {
+		if row.Total == nil {
+			continue
+		} else if b := scale.Band(*row.Total); b != nil {
+			row.Letter = b.Letter
+		}
+	}
+	return g, nil
+}
+
+// findGradeItems returns the homeworks, quizzes and attendance sessions that
+// have opened in a group, oldest first.
+func findGradeItems(ctx context.Context, tx *Tx, groupID int) ([]*api.GradeItem, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT 'homework', id, title, max_grade, grade_category_id, grades_locked_at IS NOT NULL, COALESCE(opened_at, created_at) AS date
+		FROM homeworks
+		WHERE group_id = $1
+		UNION ALL
+		SELECT 'quiz', id, title, max_grade, grade_category_id, grades_locked_at IS NOT NULL, COALESCE(opened_at, created_at)
+		FROM quizzes
+		WHERE group_id = $1
+		UNION ALL
+		SELECT 'attendance', id, title, 1, NULL, FALSE, COALESCE(opened_at, created_at)
+		FROM attendances
+		WHERE group_id = $1 AND NOT cancelled AND COALESCE(opened_at, created_at) <= $2
+		ORDER BY date, 1, 2
+	`, groupID, tx.now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := make([]*api.GradeItem, 0)
+	for rows.Next() {
+		var categoryID sql.NullInt32
+
+		var item api.GradeItem
+		if err := rows.Scan(
+			&item.Kind,
+			&item.ID,
+			&item.Title,
+			&item.MaxGrade,
+			&categoryID,
+			&item.Locked,
+			&item.Date,
+		); err != nil {
+			return nil, err
+		}
+		if categoryID.Valid {
+			item.CategoryID = int(categoryID.Int32)
+		}
+
+		items = append(items, &item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// gradeItemKey returns a key identifying an item across kinds.
+func gradeItemKey(kind string, id int) string {
+	return kind + ":" + strconv.Itoa(id)
+}
+
+// findGradeCategories returns the categories matching the condition, in the
+// order they were created.
+func findGradeCategories(ctx context.Context, tx *Tx, where string, args ...interface{}) ([]*api.GradeCategory, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			name,
+			kind,
+			weight,
+			drop_lowest,
+			created_at,
+			updated_at,
+			group_id
+		FROM grade_categories
+		WHERE `+where+`
+		ORDER BY id ASC
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]*api.GradeCategory, 0)
+	for rows.Next() {
+		var updatedAt sql.NullTime
+
+		var c api.GradeCategory
+		if err := rows.Scan(
+			&c.ID,
+			&c.Name,
+			&c.Kind,
+			&c.Weight,
+			&c.DropLowest,
+			&c.CreatedAt,
+			&updatedAt,
+			&c.GroupID,
+		); err != nil {
+			return nil, err
+		}
+		c.UpdatedAt = updatedAt.Time
+
+		categories = append(categories, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// findGradeCategoryByID returns a category by ID.
+// Returns ENOTFOUND if category does not exist.
+func findGradeCategoryByID(ctx context.Context, tx *Tx, id int) (*api.GradeCategory, error) {
+	a, err := findGradeCategories(ctx, tx, `id = $1`, id)
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Grade category not found."}
+	}
+	return a[0], nil
+}
+
+// createGradeCategory inserts a new category. Sets the new database ID to
+// category.ID and sets the timestamps to the current time.
+func createGradeCategory(ctx context.Context, tx *Tx, category *api.GradeCategory) error {
+	if _, err := checkGradebookAccess(ctx, tx, category.GroupID, true); err != nil {
+		return err
+	}
+
+	category.CreatedAt = tx.now
+	category.UpdatedAt = category.CreatedAt
+
+	// Perform basic field validation.
+	if err := category.Validate(); err != nil {
+		return err
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO grade_categories (
+			name,
+			kind,
+			weight,
+			drop_lowest,
+			created_at,
+			updated_at,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		category.Name,
+		category.Kind,
+		category.Weight,
+		category.DropLowest,
+		category.CreatedAt,
+		category.UpdatedAt,
+		category.GroupID,
+	).Scan(&category.ID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// updateGradeCategory updates fields on a category.
+func updateGradeCategory(ctx context.Context, tx *Tx, id int, upd api.GradeCategoryUpdate) (*api.GradeCategory, error) {
+	category, err := findGradeCategoryByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if _, err := checkGradebookAccess(ctx, tx, category.GroupID, true); err != nil {
+		return nil, err
+	}
+
+	// Update fields.
+	if v := upd.Name; v != nil {
+		category.Name = *v
+	}
+	if v := upd.Weight; v != nil {
+		category.Weight = *v
+	}
+	if v := upd.DropLowest; v != nil {
+		category.DropLowest = *v
+	}
+	category.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := category.Validate(); err != nil {
+		return category, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE grade_categories
+		SET name = $1,
+			weight = $2,
+			drop_lowest = $3,
+			updated_at = $4
+		WHERE id = $5
+	`,
+		category.Name,
+		category.Weight,
+		category.DropLowest,
+		category.UpdatedAt,
+		id,
+	); err != nil {
+		return category, FormatError(err)
+	}
+	return category, nil
+}
+
+// deleteGradeCategory permanently removes a category by ID.
+func deleteGradeCategory(ctx context.Context, tx *Tx, id int) error {
+	if category, err := findGradeCategoryByID(ctx, tx, id); err != nil {
+		return err
+	} else if _, err := checkGradebookAccess(ctx, tx, category.GroupID, true); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM grade_categories WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// setGradeItemCategory assigns a homework or quiz to a category of the same
+// group, or clears the assignment if categoryID is zero.
+func setGradeItemCategory(ctx context.Context, tx *Tx, groupID int, kind string, itemID, categoryID int) error {
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return err
+	}
+
+	var table string
+	switch kind {
+	case api.GradeItemHomework:
+		table = "homeworks"
+	case api.GradeItemQuiz:
+		table = "quizzes"
+	default:
+		return api.Errorf(api.EINVALID, "Only homeworks and quizzes can be assigned to a category.")
+	}
+
+	var category *int
+	if categoryID != 0 {
+		if c, err := findGradeCategoryByID(ctx, tx, categoryID); err != nil {
+			return err
+		} else if c.GroupID != groupID {
+			return api.Errorf(api.EINVALID, "Category belongs to another group.")
+		}
+		category = &categoryID
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE `+table+` SET grade_category_id = $1 WHERE id = $2 AND group_id = $3`, category, itemID, groupID)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &api.Error{Code: api.ENOTFOUND, Message: "Item not found."}
+	}
+	return nil
+}
@@ -0,0 +1,704 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.GradingService = (*GradingService)(nil)
+
+// GradingService represents a service for managing grading scales, final
+// grades and grade locks.
+type GradingService struct {
+	db *DB
+}
+
+// NewGradingService returns a new instance of GradingService.
+func NewGradingService(db *DB) *GradingService {
+	return &GradingService{db: db}
+}
+
+// FindGradingScale retrieves the scale of a group.
+func (s *GradingService) FindGradingScale(ctx context.Context, groupID int) (*api.GradingScale, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, groupID, false); err != nil {
+		return nil, err
+	}
+	return findGradingScale(ctx, tx, groupID)
+}
+
+// UpdateGradingScale replaces the scale of a group.
+func (s *GradingService) UpdateGradingScale(ctx context.Context, groupID int, bands []*api.GradeBand) (*api.GradingScale, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	scale, err := updateGradingScale(ctx, tx, groupID, bands)
+	if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return scale, nil
+}
+
+// CreateFinalGrades stores the course grades of every student of a group.
+func (s *GradingService) CreateFinalGrades(ctx context.Context, groupID int, lock bool) (*api.FinalGradeSnapshot, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snapshot, err := createFinalGrades(ctx, tx, groupID, lock)
+	if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// FindFinalGradeSnapshots retrieves the snapshots of a group.
+func (s *GradingService) FindFinalGradeSnapshots(ctx context.Context, groupID int) ([]*api.FinalGradeSnapshot, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findFinalGradeSnapshots(ctx, tx, groupID)
+}
+
+// SetGradeItemLocked locks or unlocks the grades of a homework or quiz.
+func (s *GradingService) SetGradeItemLocked(ctx context.Context, groupID int, kind string, itemID int, locked bool, reason *string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setGradeItemLocked(ctx, tx, groupID, kind, itemID, locked, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindGradeOverrides retrieves the overrides of locked grades in a group.
+func (s *GradingService) FindGradeOverrides(ctx context.Context, groupID int) ([]*api.GradeOverride, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return nil, err
+	}
+	return findGradeOverrides(ctx, tx, groupID)
+}
+
+// ReleaseGrades lets students see the grades of a homework or quiz.
+func (s *GradingService) ReleaseGrades(ctx context.Context, kind string, itemID int, at time.Time, notify bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := releaseGrades(ctx, tx, kind, itemID, at, notify); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// WithholdGrades hides the grades of a homework or quiz from students again.
+func (s *GradingService) WithholdGrades(ctx context.Context, kind string, itemID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := withholdGrades(ctx, tx, kind, itemID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// NotifyGradeReleases notifies students of the grades released up to now.
+func (s *GradingService) NotifyGradeReleases(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := notifyGradeReleases(ctx, tx, now)
+	if err != nil {
+		return 0, err
+	} else if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// findGradingScale returns the scale of a group, or the default scale if the
+// group has no bands.
+func findGradingScale(ctx context.Context, tx *Tx, groupID int) (*api.GradingScale, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT min_percent, letter, points
+		FROM grade_bands
+		WHERE group_id = $1
+		ORDER BY min_percent DESC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scale := &api.GradingScale{GroupID: groupID, Bands: make([]*api.GradeBand, 0)}
+	for rows.Next() {
+		var b api.GradeBand
+		if err := rows.Scan(&b.MinPercent, &b.Letter, &b.Points); err != nil {
+			return nil, err
+		}
+		scale.Bands = append(scale.Bands, &b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(scale.Bands) == 0 {
+		return api.DefaultGradingScale(groupID), nil
+	}
+	return scale, nil
+}
+
+// updateGradingScale replaces the bands of a group. The default scale is
+// restored when bands is empty.
+func updateGradingScale(ctx context.Context, tx *Tx, groupID int, bands []*api.GradeBand) (*api.GradingScale, error) {
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return nil, err
+	}
+
+	scale := &api.GradingScale{GroupID: groupID, Bands: bands}
+	if len(bands) == 0 {
+		scale = api.DefaultGradingScale(groupID)
+	} else {
+		for _, b := range bands {
+			if b == nil {
+				return nil, api.Errorf(api.EINVALID, "Grade band required.")
+			}
+			b.Letter = strings.TrimSpace(b.Letter)
+		}
+		if err := scale.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM grade_bands WHERE group_id = $1`, groupID); err != nil {
+		return nil, FormatError(err)
+	}
+	if scale.Default {
+		return scale, nil
+	}
+
+	for _, b := range scale.Bands {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO grade_bands (min_percent, letter, points, group_id)
+			VALUES ($1, $2, $3, $4)
+		`, b.MinPercent, b.Letter, b.Points, groupID); err != nil {
+			return nil, FormatError(err)
+		}
+	}
+	return scale, nil
+}
+
+// createFinalGrades computes the gradebook of a group and stores the total and
+// letter grade of every student. When lock is set, the grades of every
+// homework and quiz of the group are locked as well.
+func createFinalGrades(ctx context.Context, tx *Tx, groupID int, lock bool) (*api.FinalGradeSnapshot, error) {
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return nil, err
+	}
+
+	g, err := findGradebook(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	scale, err := findGradingScale(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &api.FinalGradeSnapshot{
+		Locked:    lock,
+		CreatedAt: tx.now,
+		GroupID:   groupID,
+		TeacherID: api.UserIDFromContext(ctx),
+		Grades:    make([]*api.FinalGrade, 0, len(g.Rows)),
+	}
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO final_grade_snapshots (locked, created_at, group_id, teacher_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, snapshot.Locked, snapshot.CreatedAt, snapshot.GroupID, snapshot.TeacherID).Scan(&snapshot.ID); err != nil {
+		return nil, FormatError(err)
+	}
+
+	for _, row := range g.Rows {
+		grade := &api.FinalGrade{StudentID: row.Student.ID, Student: row.Student, Percent: row.Total}
+		if row.Total != nil {
+			if b := scale.Band(*row.Total); b != nil {
+				points := b.Points
+				grade.Letter, grade.Points = b.Letter, &points
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO final_grades (snapshot_id, student_id, percent, letter, points)
+			VALUES ($1, $2, $3, $4, $5)
+		`, snapshot.ID, grade.StudentID, grade.Percent, grade.Letter, grade.Points); err != nil {
+			return nil, FormatError(err)
+		}
+		snapshot.Grades = append(snapshot.Grades, grade)
+	}
+
+	if lock {
+		for _, table := range []string{"homeworks", "quizzes"} {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE `+table+`
+				SET grades_locked_at = $1
+				WHERE group_id = $2 AND grades_locked_at IS NULL
+			`, tx.now, groupID); err != nil {
+				return nil, FormatError(err)
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+// findFinalGradeSnapshots returns the snapshots of a group, newest first.
+// Students only receive their own grades.
+func findFinalGradeSnapshots(ctx context.Context, tx *Tx, groupID int) ([]*api.FinalGradeSnapshot, error) {
+	isTeacher, err := checkGradebookAccess(ctx, tx, groupID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, locked, created_at, group_id, teacher_id
+		FROM final_grade_snapshots
+		WHERE group_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := make([]*api.FinalGradeSnapshot, 0)
+	byID := make(map[int]*api.FinalGradeSnapshot)
+	for rows.Next() {
+		var teacherID sql.NullInt32
+
+		var s api.FinalGradeSnapshot
+		if err := rows.Scan(&s.ID, &s.Locked, &s.CreatedAt, &s.GroupID, &teacherID); err != nil {
+			return nil, err
+		}
+		s.TeacherID = int(teacherID.Int32)
+		s.Grades = make([]*api.FinalGrade, 0)
+
+		snapshots = append(snapshots, &s)
+		byID[s.ID] = &s
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	where, args := []string{"s.group_id = $1"}, []interface{}{groupID}
+	if !isTeacher {
+		where, args = append(where, "g.student_id = $2"), append(args, api.UserIDFromContext(ctx))
+	}
+	gradeRows, err := tx.QueryContext(ctx, `
+		SELECT g.snapshot_id, g.student_id, g.percent, g.letter, g.points
+		FROM final_grades g
+		JOIN final_grade_snapshots s ON s.id = g.snapshot_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY g.snapshot_id, g.student_id
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer gradeRows.Close()
+
+	var grades []*api.FinalGrade
+	for gradeRows.Next() {
+		var snapshotID int
+		var percent, points sql.NullFloat64
+
+		var g api.FinalGrade
+		if err := gradeRows.Scan(&snapshotID, &g.StudentID, &percent, &g.Letter, &points); err != nil {
+			return nil, err
+		}
+		if percent.Valid {
+			g.Percent = &percent.Float64
+		}
+		if points.Valid {
+			g.Points = &points.Float64
+		}
+
+		if s := byID[snapshotID]; s != nil {
+			s.Grades = append(s.Grades, &g)
+			grades = append(grades, &g)
+		}
+	}
+	if err := gradeRows.Err(); err != nil {
+		return nil, err
+	}
+	gradeRows.Close()
+
+	// Attach the students, fetching each of them once.
+	students := make(map[int]*api.User)
+	for _, g := range grades {
+		if students[g.StudentID] == nil {
+			if students[g.StudentID], err = findUserByID(ctx, tx, g.StudentID); err != nil {
+				return nil, err
+			}
+		}
+		g.Student = students[g.StudentID]
+	}
+	return snapshots, nil
+}
+
+// gradeItemTable returns the table of a kind of item that grades can be
+// locked on.
+func gradeItemTable(kind string) (string, error) {
+	switch kind {
+	case api.GradeItemHomework:
+		return "homeworks", nil
+	case api.GradeItemQuiz:
+		return "quizzes", nil
+	}
+	return "", api.Errorf(api.EINVALID, "Only homeworks and quizzes have grades.")
+}
+
+// setGradeItemLocked sets or clears the time the grades of a homework or quiz
+// of a group were locked. Unlocking locked grades is recorded as an override
+// with the given reason.
+func setGradeItemLocked(ctx context.Context, tx *Tx, groupID int, kind string, itemID int, locked bool, reason *string) error {
+	if _, err := checkGradebookAccess(ctx, tx, groupID, true); err != nil {
+		return err
+	}
+
+	table, err := gradeItemTable(kind)
+	if err != nil {
+		return err
+	}
+
+	if !locked {
+		var lockedAt sql.NullTime
+		if err := tx.QueryRowContext(ctx, `
+			SELECT grades_locked_at FROM `+table+` WHERE id = $1 AND group_id = $2 FOR UPDATE
+		`, itemID, groupID).Scan(&lockedAt); err == sql.ErrNoRows {
+			return &api.Error{Code: api.ENOTFOUND, Message: "Item not found."}
+		} else if err != nil {
+			return FormatError(err)
+		} else if !lockedAt.Valid {
+			return nil
+		}
+
+		if reason == nil || strings.TrimSpace(*reason) == "" {
+			return api.Errorf(api.EFORBIDDEN, "A reason is required to unlock grades.")
+		} else if len(*reason) > api.MaxOverrideReasonLen {
+			return api.Errorf(api.EINVALID, "Override reason too long.")
+		}
+
+		var teacherID *int
+		if userID := api.UserIDFromContext(ctx); userID != 0 {
+			teacherID = &userID
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO grade_overrides (kind, item_id, reason, created_at, group_id, teacher_id)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, kind, itemID, strings.TrimSpace(*reason), tx.now, groupID, teacherID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	// Relocking keeps the time the grades were first locked.
+	set, args := `grades_locked_at = NULL`, []interface{}{itemID, groupID}
+	if locked {
+		set, args = `grades_locked_at = COALESCE(grades_locked_at, $3)`, append(args, tx.now)
+	}
+
+	result, err := tx.ExecContext(ctx, `UPDATE `+table+` SET `+set+` WHERE id = $1 AND group_id = $2`, args...)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &api.Error{Code: api.ENOTFOUND, Message: "Item not found."}
+	}
+	return nil
+}
+
+// checkGradeLock returns an error if the grades of a homework or quiz are
+// locked and no reason is given for changing one. Otherwise the change of a
+// locked grade is recorded as an override. The new grade is nil if the
+// submission is deleted.
+func checkGradeLock(ctx context.Context, tx *Tx, kind string, itemID, submissionID int, oldGrade float32, newGrade *float32, reason *string) error {
+	table, err := gradeItemTable(kind)
+	if err != nil {
+		return err
+	}
+
+	// Items shared by link without a group cannot be locked.
+	var groupID sql.NullInt32
+	var lockedAt sql.NullTime
+	if err := tx.QueryRowContext(ctx, `
+		SELECT group_id, grades_locked_at FROM `+table+` WHERE id = $1
+	`, itemID).Scan(&groupID, &lockedAt); err != nil {
+		return FormatError(err)
+	} else if !groupID.Valid || !lockedAt.Valid {
+		return nil
+	}
+
+	if reason == nil || strings.TrimSpace(*reason) == "" {
+		return api.Errorf(api.EFORBIDDEN, "Grades are locked. A reason is required to override them.")
+	} else if len(*reason) > api.MaxOverrideReasonLen {
+		return api.Errorf(api.EINVALID, "Override reason too long.")
+	}
+
+	var teacherID *int
+	if userID := api.UserIDFromContext(ctx); userID != 0 {
+		teacherID = &userID
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO grade_overrides (kind, item_id, submission_id, old_grade, new_grade, reason, created_at, group_id, teacher_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, kind, itemID, submissionID, oldGrade, newGrade, strings.TrimSpace(*reason), tx.now, groupID.Int32, teacherID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// findGradeOverrides returns the overrides of locked grades in a group,
+// newest first.
+func findGradeOverrides(ctx context.Context, tx *Tx, groupID int) ([]*api.GradeOverride, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, kind, item_id, submission_id, old_grade, new_grade, reason, created_at, teacher_id
+		FROM grade_overrides
+		WHERE group_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]*api.GradeOverride, 0)
+	for rows.Next() {
+		var oldGrade, newGrade sql.NullFloat64
+		var submissionID, teacherID sql.NullInt32
+
+		var o api.GradeOverride
+		if err := rows.Scan(
+			&o.ID,
+			&o.Kind,
+			&o.ItemID,
+			&submissionID,
+			&oldGrade,
+			&newGrade,
+			&o.Reason,
+			&o.CreatedAt,
+			&teacherID,
+		); err != nil {
+			return nil, err
+		}
+		o.OldGrade, o.NewGrade = float32(oldGrade.Float64), float32(newGrade.Float64)
+		o.SubmissionID = int(submissionID.Int32)
+		o.Unlocked = !submissionID.Valid
+		o.Deleted = submissionID.Valid && !newGrade.Valid
+		o.TeacherID = int(teacherID.Int32)
+
+		overrides = append(overrides, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// gradeSubmissionTable returns the submissions table of a kind of item and
+// its column referencing the item.
+func gradeSubmissionTable(kind string) (string, string) {
+	if kind == api.GradeItemQuiz {
+		return "quiz_submissions", "quiz_id"
+	}
+	return "hw_submissions", "homework_id"
+}
+
+// checkGradeItemTeacher returns the title of a homework or quiz, or an error
+// if the current user is not its teacher.
+func checkGradeItemTeacher(ctx context.Context, tx *Tx, table string, itemID int) (string, error) {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return "", api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+
+	var title string
+	var teacherID sql.NullInt32
+	if err := tx.QueryRowContext(ctx, `
+		SELECT title, teacher_id FROM `+table+` WHERE id = $1
+	`, itemID).Scan(&title, &teacherID); err == sql.ErrNoRows {
+		return "", &api.Error{Code: api.ENOTFOUND, Message: "Item not found."}
+	} else if err != nil {
+		return "", FormatError(err)
+	} else if int(teacherID.Int32) != userID {
+		return "", api.Errorf(api.EUNAUTHORIZED, "You are not the teacher of this item.")
+	}
+	return title, nil
+}
+
+// releaseGrades sets the time the grades of an item are released from, which
+// is now if at is zero. Students who were not notified yet are notified right
+// away when the grades are released immediately.
+func releaseGrades(ctx context.Context, tx *Tx, kind string, itemID int, at time.Time, notify bool) error {
+	table, err := gradeItemTable(kind)
+	if err != nil {
+		return err
+	}
+	title, err := checkGradeItemTeacher(ctx, tx, table, itemID)
+	if err != nil {
+		return err
+	}
+
+	if at.IsZero() {
+		at = tx.now
+	}
+	var notified bool
+	if err := tx.QueryRowContext(ctx, `
+		UPDATE `+table+`
+		SET grades_release_at = $1,
+			grades_release_notify = $2
+		WHERE id = $3
+		RETURNING grades_notified_at IS NOT NULL
+	`, at.UTC(), notify, itemID).Scan(&notified); err != nil {
+		return FormatError(err)
+	}
+
+	if notify && !notified && !at.After(tx.now) {
+		return notifyGradeRelease(ctx, tx, kind, itemID, title, tx.now)
+	}
+	return nil
+}
+
+// withholdGrades clears the release time of the grades of an item. Students
+// are notified again if the grades are released later on.
+func withholdGrades(ctx context.Context, tx *Tx, kind string, itemID int) error {
+	table, err := gradeItemTable(kind)
+	if err != nil {
+		return err
+	} else if _, err := checkGradeItemTeacher(ctx, tx, table, itemID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE `+table+`
+		SET grades_release_at = NULL,
+			grades_release_notify = FALSE,
+			grades_notified_at = NULL
+		WHERE id = $1
+	`, itemID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// notifyGradeReleases notifies students of every item whose grades were
+// released up to now and who were not notified yet.
+func notifyGradeReleases(ctx context.Context, tx *Tx, now time.Time) (int, error) {
+	type item struct {
+		kind  string
+		id    int
+		title string
+	}
+
+	var items []item
+	for _, kind := range []string{api.GradeItemHomework, api.GradeItemQuiz} {
+		table, _ := gradeItemTable(kind)
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, title
+			FROM `+table+`
+			WHERE grades_release_notify AND grades_notified_at IS NULL AND grades_release_at <= $1
+			FOR UPDATE SKIP LOCKED
+		`, now)
+		if err != nil {
+			return 0, err
+		}
+		for rows.Next() {
+			it := item{kind: kind}
+			if err := rows.Scan(&it.id, &it.title); err != nil {
+				rows.Close()
+				return 0, err
+			}
+			items = append(items, it)
+		}
+		if err := rows.Err(); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		rows.Close()
+	}
+
+	for _, it := range items {
+		if err := notifyGradeRelease(ctx, tx, it.kind, it.id, it.title, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(items), nil
+}
+
+// notifyGradeRelease notifies every student with a submission to an item that
+// its grades were released, and records that they were notified.
+func notifyGradeRelease(ctx context.Context, tx *Tx, kind string, itemID int, title string, now time.Time) error {
+	table, _ := gradeItemTable(kind)
+	subTable, column := gradeSubmissionTable(kind)
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO notifications (kind, message, item_kind, item_id, created_at, user_id)
+		SELECT DISTINCT $1, $2, $3, $4::integer, $5::timestamp, student_id
+		FROM `+subTable+`
+		WHERE `+column+` = $4 AND student_id IS NOT NULL
+	`,
+		api.NotificationGradesReleased,
+		fmt.Sprintf("Grades for %s have been released.", title),
+		kind,
+		itemID,
+		now,
+	); err != nil {
+		return FormatError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE `+table+` SET grades_notified_at = $1 WHERE id = $2
+	`, now, itemID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
@@ -470,3 +470,26 @@ func deleteGroup(ctx context.Context, tx *Tx, id int) error {
 	}
 	return nil
 }
+
+// isGroupStudent returns true if the user is a student member of the group.
+func isGroupStudent(ctx context.Context, tx *Tx, groupID, userID int) (bool, error) {
+	var ok bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM students_groups WHERE group_id = $1 AND student_id = $2)
+	`, groupID, userID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// isGroupTeacher returns true if the user owns the group or teaches in it.
+func isGroupTeacher(ctx context.Context, tx *Tx, groupID, userID int) (bool, error) {
+	var ok bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM groups WHERE id = $1 AND owner_id = $2)
+		    OR EXISTS (SELECT 1 FROM teachers_groups WHERE group_id = $1 AND teacher_id = $2)
+	`, groupID, userID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
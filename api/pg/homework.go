@@ -145,6 +145,36 @@ func (s *HomeworkService) DeleteHomework(ctx context.Context, id int) error {
 	return tx.Commit()
 }
 
+// AddHomeworkAttachment attaches a stored file to a homework.
+// Returns EUNAUTHORIZED if current user does not teach the homework.
+func (s *HomeworkService) AddHomeworkAttachment(ctx context.Context, a *api.HomeworkAttachment) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := addHomeworkAttachment(ctx, tx, a); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RemoveHomeworkAttachment detaches a file from a homework.
+// Returns ENOTFOUND if the file is not attached to the homework.
+func (s *HomeworkService) RemoveHomeworkAttachment(ctx context.Context, homeworkID int, hash string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := removeHomeworkAttachment(ctx, tx, homeworkID, hash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 // findHomeworkByID is a helper function to fetch a homework by ID.
 // Returns ENOTFOUND if homework does not exist.
 func findHomeworkByID(ctx context.Context, tx *Tx, id int) (*api.Homework, error) {
@@ -239,6 +269,14 @@ func findHomeworks(ctx context.Context, tx *Tx, filter api.HomeworkFilter) (_ []
 			updated_at,
 			opened_at,
 			closed_at,
+			grades_release_at,
+			due_at,
+			grace_minutes,
+			late_policy,
+			late_penalty,
+			late_penalty_cap,
+			max_resubmissions,
+			rubric_id,
 			teacher_fullname,
 			teacher_id,
 			group_id,
@@ -262,6 +300,9 @@ func findHomeworks(ctx context.Context, tx *Tx, filter api.HomeworkFilter) (_ []
 		var updatedAt sql.NullTime
 		var openedAt sql.NullTime
 		var closedAt sql.NullTime
+		var gradesReleaseAt sql.NullTime
+		var dueAt sql.NullTime
+		var rubricID sql.NullInt32
 		var teacherID sql.NullInt32
 		var groupID sql.NullInt32
 
@@ -279,6 +320,14 @@ func findHomeworks(ctx context.Context, tx *Tx, filter api.HomeworkFilter) (_ []
 			&updatedAt,
 			&openedAt,
 			&closedAt,
+			&gradesReleaseAt,
+			&dueAt,
+			&hw.GraceMinutes,
+			&hw.LatePolicy,
+			&hw.LatePenalty,
+			&hw.LatePenaltyCap,
+			&hw.MaxResubmissions,
+			&rubricID,
 			&teacherFullname,
 			&teacherID,
 			&groupID,
@@ -302,6 +351,15 @@ func findHomeworks(ctx context.Context, tx *Tx, filter api.HomeworkFilter) (_ []
 		if closedAt.Valid {
 			hw.ClosedAt = closedAt.Time
 		}
+		if gradesReleaseAt.Valid {
+			hw.GradesReleaseAt = gradesReleaseAt.Time
+		}
+		if dueAt.Valid {
+			hw.DueAt = dueAt.Time
+		}
+		if rubricID.Valid {
+			hw.RubricID = int(rubricID.Int32)
+		}
 		if teacherID.Valid {
 			hw.TeacherID = int(teacherID.Int32)
 		}
@@ -325,11 +383,21 @@ func createHomework(ctx context.Context, tx *Tx, hw *api.Homework) error {
 	hw.CreatedAt = tx.now
 	hw.UpdatedAt = hw.CreatedAt
 
+	if hw.LatePolicy == "" {
+		hw.LatePolicy = api.LatePolicyNone
+	}
+
 	// Perform basic field validation.
 	if err := hw.Validate(); err != nil {
 		return err
 	}
 
+	if hw.RubricID != 0 {
+		if _, err := checkRubricAccess(ctx, tx, hw.RubricID, hw.TeacherID); err != nil {
+			return err
+		}
+	}
+
 	// Content is nullable so ensure we store blank fields as NULLs.
 	var content *string
 	if hw.Content != "" {
@@ -343,6 +411,10 @@ func createHomework(ctx context.Context, tx *Tx, hw *api.Homework) error {
 	if !hw.ClosedAt.IsZero() {
 		closedAt = &hw.ClosedAt
 	}
+	var dueAt *time.Time
+	if !hw.DueAt.IsZero() {
+		dueAt = &hw.DueAt
+	}
 	var teacherFullname *string
 	if hw.TeacherFullName != "" {
 		teacherFullname = &hw.TeacherFullName
@@ -355,6 +427,10 @@ func createHomework(ctx context.Context, tx *Tx, hw *api.Homework) error {
 	if hw.GroupID != 0 {
 		groupID = &hw.GroupID
 	}
+	var rubricID *int
+	if hw.RubricID != 0 {
+		rubricID = &hw.RubricID
+	}
 
 	// Execute insertion query.
 	row := tx.QueryRowContext(ctx, `
@@ -369,11 +445,18 @@ func createHomework(ctx context.Context, tx *Tx, hw *api.Homework) error {
 			created_at,
 			opened_at,
 			closed_at,
+			due_at,
+			grace_minutes,
+			late_policy,
+			late_penalty,
+			late_penalty_cap,
+			max_resubmissions,
+			rubric_id,
 			teacher_fullname,
 			teacher_id,
 			group_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
 		RETURNING id
 	`,
 		hw.Title,
@@ -386,6 +469,13 @@ func createHomework(ctx context.Context, tx *Tx, hw *api.Homework) error {
 		hw.CreatedAt,
 		openedAt,
 		closedAt,
+		dueAt,
+		hw.GraceMinutes,
+		hw.LatePolicy,
+		hw.LatePenalty,
+		hw.LatePenaltyCap,
+		hw.MaxResubmissions,
+		rubricID,
 		teacherFullname,
 		teacherID,
 		groupID,
@@ -433,6 +523,38 @@ func updateHomework(ctx context.Context, tx *Tx, id int, upd api.HomeworkUpdate)
 	if v := upd.ClosedAt; v != nil {
 		hw.ClosedAt = *v
 	}
+	if v := upd.DueAt; v != nil {
+		hw.DueAt = *v
+	}
+	if v := upd.GraceMinutes; v != nil {
+		hw.GraceMinutes = *v
+	}
+	if v := upd.LatePolicy; v != nil {
+		hw.LatePolicy = *v
+	}
+	if v := upd.LatePenalty; v != nil {
+		hw.LatePenalty = *v
+	}
+	if v := upd.LatePenaltyCap; v != nil {
+		hw.LatePenaltyCap = *v
+	}
+	if v := upd.MaxResubmissions; v != nil {
+		hw.MaxResubmissions = *v
+	}
+
+	// The rubric cannot be swapped once submissions are graded with it.
+	if v := upd.RubricID; v != nil && *v != hw.RubricID {
+		if graded, err := hasRubricScores(ctx, tx, hw.ID); err != nil {
+			return hw, err
+		} else if graded {
+			return hw, api.Errorf(api.ECONFLICT, "Submissions have already been graded with the rubric.")
+		} else if *v != 0 {
+			if _, err := checkRubricAccess(ctx, tx, *v, currentUserID); err != nil {
+				return hw, err
+			}
+		}
+		hw.RubricID = *v
+	}
 	if v := upd.TeacherFullName; v != nil {
 		hw.TeacherFullName = *v
 	}
@@ -464,6 +586,10 @@ func updateHomework(ctx context.Context, tx *Tx, id int, upd api.HomeworkUpdate)
 	if !hw.ClosedAt.IsZero() {
 		closedAt = &hw.ClosedAt
 	}
+	var dueAt *time.Time
+	if !hw.DueAt.IsZero() {
+		dueAt = &hw.DueAt
+	}
 	var teacherFullname *string
 	if hw.TeacherFullName != "" {
 		teacherFullname = &hw.TeacherFullName
@@ -476,6 +602,10 @@ func updateHomework(ctx context.Context, tx *Tx, id int, upd api.HomeworkUpdate)
 	if hw.GroupID != 0 {
 		groupID = &hw.GroupID
 	}
+	var rubricID *int
+	if hw.RubricID != 0 {
+		rubricID = &hw.RubricID
+	}
 
 	// Execute update query.
 	if _, err := tx.ExecContext(ctx, `
@@ -489,8 +619,15 @@ func updateHomework(ctx context.Context, tx *Tx, id int, upd api.HomeworkUpdate)
 		    closed_at = $7,
 		    teacher_fullname = $8,
 		    teacher_id = $9,
-		    group_id = $10
-		WHERE id = $11
+		    group_id = $10,
+		    due_at = $11,
+		    grace_minutes = $12,
+		    late_policy = $13,
+		    late_penalty = $14,
+		    late_penalty_cap = $15,
+		    rubric_id = $16,
+		    max_resubmissions = $17
+		WHERE id = $18
 	`,
 		hw.Title,
 		content,
@@ -502,6 +639,13 @@ func updateHomework(ctx context.Context, tx *Tx, id int, upd api.HomeworkUpdate)
 		teacherFullname,
 		teacherID,
 		groupID,
+		dueAt,
+		hw.GraceMinutes,
+		hw.LatePolicy,
+		hw.LatePenalty,
+		hw.LatePenaltyCap,
+		rubricID,
+		hw.MaxResubmissions,
 		id,
 	); err != nil {
 		return hw, FormatError(err)
@@ -528,8 +672,137 @@ func deleteHomework(ctx context.Context, tx *Tx, id int) error {
 	return nil
 }
 
+// isHomeworkTeacher returns true if the user set the homework or teaches in
+// its group.
+func isHomeworkTeacher(ctx context.Context, tx *Tx, hw *api.Homework, userID int) (bool, error) {
+	if userID == 0 {
+		return false, nil
+	} else if hw.TeacherID == userID {
+		return true, nil
+	} else if hw.GroupID == 0 {
+		return false, nil
+	}
+	return isGroupTeacher(ctx, tx, hw.GroupID, userID)
+}
+
+// addHomeworkAttachment attaches a stored file to a homework. Returns
+// EUNAUTHORIZED if current user does not teach the homework.
+func addHomeworkAttachment(ctx context.Context, tx *Tx, a *api.HomeworkAttachment) error {
+	hw, err := findHomeworkByID(ctx, tx, a.HomeworkID)
+	if err != nil {
+		return err
+	}
+
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	} else if ok, err := isHomeworkTeacher(ctx, tx, hw, userID); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to attach files to this homework.")
+	}
+
+	var n int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM homework_attachments WHERE homework_id = $1
+	`, a.HomeworkID).Scan(&n); err != nil {
+		return FormatError(err)
+	} else if n >= api.MaxHomeworkAttachments {
+		return api.Errorf(api.EINVALID, "Too many attachments.")
+	}
+
+	// Set timestamp to the current time.
+	a.CreatedAt = tx.now
+
+	// Execute insertion query.
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO homework_attachments (homework_id, attachment_hash, filename, content_type, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (homework_id, attachment_hash) DO NOTHING
+	`,
+		a.HomeworkID,
+		a.Hash,
+		a.Filename,
+		a.ContentType,
+		a.CreatedAt,
+	)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return api.Errorf(api.ECONFLICT, "File is already attached to this homework.")
+	}
+	return nil
+}
+
+// removeHomeworkAttachment detaches a file from a homework. Returns
+// EUNAUTHORIZED if current user does not teach the homework.
+func removeHomeworkAttachment(ctx context.Context, tx *Tx, homeworkID int, hash string) error {
+	hw, err := findHomeworkByID(ctx, tx, homeworkID)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := isHomeworkTeacher(ctx, tx, hw, api.UserIDFromContext(ctx)); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to remove files from this homework.")
+	}
+
+	// Remove row from database.
+	result, err := tx.ExecContext(ctx, `
+		DELETE FROM homework_attachments WHERE homework_id = $1 AND attachment_hash = $2
+	`, homeworkID, hash)
+	if err != nil {
+		return FormatError(err)
+	} else if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return &api.Error{Code: api.ENOTFOUND, Message: "Attachment not found."}
+	}
+	return nil
+}
+
+// findHomeworkAttachments returns the files attached to a homework.
+func findHomeworkAttachments(ctx context.Context, tx *Tx, homeworkID int) ([]*api.HomeworkAttachment, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.hash, a.extension, h.filename, h.content_type, h.created_at
+		FROM homework_attachments h
+		JOIN attachments a ON a.hash = h.attachment_hash
+		WHERE h.homework_id = $1
+		ORDER BY h.created_at, h.filename
+	`, homeworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*api.HomeworkAttachment
+	for rows.Next() {
+		a := api.HomeworkAttachment{HomeworkID: homeworkID}
+		if err := rows.Scan(&a.Hash, &a.Extension, &a.Filename, &a.ContentType, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
 // attachHomeworkAssociations attaches group and owner objects associated with the homework.
 func attachHomeworkAssociations(ctx context.Context, tx *Tx, hw *api.Homework) (err error) {
+	if hw.RubricID != 0 {
+		if hw.Rubric, err = findRubricByID(ctx, tx, hw.RubricID); err != nil {
+			return fmt.Errorf("attach homework rubric: %w", err)
+		}
+	}
+	if hw.Attachments, err = findHomeworkAttachments(ctx, tx, hw.ID); err != nil {
+		return fmt.Errorf("attach homework attachments: %w", err)
+	}
+
 	if hw.TeacherID == 0 {
 		return nil
 	} else if hw.Teacher, err = findUserByID(ctx, tx, hw.TeacherID); err != nil {
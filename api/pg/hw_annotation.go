@@ -0,0 +1,337 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.HWAnnotationService = (*HWAnnotationService)(nil)
+
+// HWAnnotationService represents a service for managing annotations of
+// homework submissions.
+type HWAnnotationService struct {
+	db *DB
+}
+
+// NewHWAnnotationService returns a new instance of HWAnnotationService.
+func NewHWAnnotationService(db *DB) *HWAnnotationService {
+	return &HWAnnotationService{db: db}
+}
+
+// FindHWAnnotations retrieves the annotations of a submission, anchored to
+// its current response. Students only see them once grades are released.
+func (s *HWAnnotationService) FindHWAnnotations(ctx context.Context, submissionID int) ([]*api.HWAnnotation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sub, err := findHWSubmissionByID(ctx, tx, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := api.UserIDFromContext(ctx)
+	if ok, err := isHomeworkTeacher(ctx, tx, hw, userID); err != nil {
+		return nil, err
+	} else if !ok && (userID == 0 || sub.StudentID != userID) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view the annotations of this submission.")
+	} else if !ok && !hw.GradesReleased(tx.now) {
+		return []*api.HWAnnotation{}, nil
+	}
+	return findHWAnnotations(ctx, tx, 0, sub)
+}
+
+// CreateHWAnnotation creates a new annotation by the current user.
+func (s *HWAnnotationService) CreateHWAnnotation(ctx context.Context, a *api.HWAnnotation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createHWAnnotation(ctx, tx, a); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateHWAnnotation updates an annotation.
+func (s *HWAnnotationService) UpdateHWAnnotation(ctx context.Context, id int, upd api.HWAnnotationUpdate) (*api.HWAnnotation, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, err := updateHWAnnotation(ctx, tx, id, upd)
+	if err != nil {
+		return a, err
+	} else if err := tx.Commit(); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// DeleteHWAnnotation permanently deletes an annotation.
+func (s *HWAnnotationService) DeleteHWAnnotation(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteHWAnnotation(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// findHWAnnotationByID is a helper function to fetch an annotation by ID,
+// anchored to the current response of its submission.
+// Returns ENOTFOUND if annotation does not exist.
+func findHWAnnotationByID(ctx context.Context, tx *Tx, id int) (*api.HWAnnotation, *api.HWSubmission, error) {
+	var submissionID int
+	if err := tx.QueryRowContext(ctx, `
+		SELECT hw_submission_id FROM hw_annotations WHERE id = $1
+	`, id).Scan(&submissionID); err == sql.ErrNoRows {
+		return nil, nil, &api.Error{Code: api.ENOTFOUND, Message: "Annotation not found."}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	sub, err := findHWSubmissionByID(ctx, tx, submissionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	a, err := findHWAnnotations(ctx, tx, id, sub)
+	if err != nil {
+		return nil, nil, err
+	} else if len(a) == 0 {
+		return nil, nil, &api.Error{Code: api.ENOTFOUND, Message: "Annotation not found."}
+	}
+	return a[0], sub, nil
+}
+
+// findHWAnnotations returns the annotation with the given ID, or all of the
+// annotations of a submission if id is zero, anchored to its response and
+// ordered by their spans.
+func findHWAnnotations(ctx context.Context, tx *Tx, id int, sub *api.HWSubmission) ([]*api.HWAnnotation, error) {
+	where, args := []string{"hw_submission_id = $1"}, []interface{}{sub.ID}
+	i := 2
+	if id != 0 {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, id)
+		i++
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			start_offset,
+			end_offset,
+			quote,
+			comment,
+			created_at,
+			updated_at,
+			teacher_id,
+			hw_submission_id
+		FROM hw_annotations
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+	`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	annotations := make([]*api.HWAnnotation, 0)
+	for rows.Next() {
+		var updatedAt sql.NullTime
+
+		var a api.HWAnnotation
+		if err := rows.Scan(
+			&a.ID,
+			&a.Start,
+			&a.End,
+			&a.Quote,
+			&a.Comment,
+			&a.CreatedAt,
+			&updatedAt,
+			&a.TeacherID,
+			&a.SubmissionID,
+		); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			a.UpdatedAt = updatedAt.Time
+		}
+		a.Anchor(sub.Response)
+
+		annotations = append(annotations, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Order by the current spans as the response may have moved them,
+	// orphaned annotations last.
+	sort.SliceStable(annotations, func(i, j int) bool {
+		x, y := annotations[i], annotations[j]
+		if x.Orphaned != y.Orphaned {
+			return !x.Orphaned
+		}
+		return x.Start < y.Start
+	})
+	return annotations, nil
+}
+
+// createHWAnnotation creates a new annotation by the current user. Returns
+// EUNAUTHORIZED if the current user does not teach the homework.
+func createHWAnnotation(ctx context.Context, tx *Tx, a *api.HWAnnotation) error {
+	sub, err := findHWSubmissionByID(ctx, tx, a.SubmissionID)
+	if err != nil {
+		return err
+	}
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return err
+	}
+
+	userID := api.UserIDFromContext(ctx)
+	if ok, err := isHomeworkTeacher(ctx, tx, hw, userID); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to annotate this submission.")
+	}
+	a.TeacherID = userID
+
+	// Set timestamps to the current time.
+	a.CreatedAt = tx.now
+	a.UpdatedAt = a.CreatedAt
+
+	// Perform basic field validation.
+	if err := a.Validate(); err != nil {
+		return err
+	} else if err := a.SetQuote(sub.Response); err != nil {
+		return err
+	}
+	a.Orphaned = false
+
+	// Execute insertion query.
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO hw_annotations (
+			start_offset,
+			end_offset,
+			quote,
+			comment,
+			created_at,
+			teacher_id,
+			hw_submission_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		a.Start,
+		a.End,
+		a.Quote,
+		a.Comment,
+		a.CreatedAt,
+		a.TeacherID,
+		a.SubmissionID,
+	).Scan(&a.ID); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// updateHWAnnotation updates fields on an annotation. Returns EUNAUTHORIZED
+// if current user is not the author.
+func updateHWAnnotation(ctx context.Context, tx *Tx, id int, upd api.HWAnnotationUpdate) (*api.HWAnnotation, error) {
+	// Fetch current object state, anchored to the current response.
+	a, sub, err := findHWAnnotationByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if a.TeacherID != api.UserIDFromContext(ctx) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this annotation.")
+	}
+
+	// Update fields.
+	if v := upd.Start; v != nil {
+		a.Start = *v
+	}
+	if v := upd.End; v != nil {
+		a.End = *v
+	}
+	if v := upd.Comment; v != nil {
+		a.Comment = *v
+	}
+
+	// Set last updated date to current time.
+	a.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := a.Validate(); err != nil {
+		return a, err
+	}
+
+	// Store the re-anchored span, or the new one along with its quote.
+	if upd.Start != nil || upd.End != nil {
+		if err := a.SetQuote(sub.Response); err != nil {
+			return a, err
+		}
+		a.Orphaned = false
+	}
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE hw_annotations
+		SET start_offset = $1,
+		    end_offset = $2,
+		    quote = $3,
+		    comment = $4,
+		    updated_at = $5
+		WHERE id = $6
+	`,
+		a.Start,
+		a.End,
+		a.Quote,
+		a.Comment,
+		a.UpdatedAt,
+		id,
+	); err != nil {
+		return a, FormatError(err)
+	}
+
+	return a, nil
+}
+
+// deleteHWAnnotation permanently removes an annotation by ID. Returns
+// EUNAUTHORIZED if current user is not the author.
+func deleteHWAnnotation(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists.
+	if a, _, err := findHWAnnotationByID(ctx, tx, id); err != nil {
+		return err
+	} else if a.TeacherID != api.UserIDFromContext(ctx) {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this annotation.")
+	}
+
+	// Remove row from database.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hw_annotations WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
@@ -0,0 +1,479 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.HWCommentService = (*HWCommentService)(nil)
+
+// HWCommentService represents a service for managing the feedback threads of
+// homework submissions.
+type HWCommentService struct {
+	db *DB
+}
+
+// NewHWCommentService returns a new instance of HWCommentService.
+func NewHWCommentService(db *DB) *HWCommentService {
+	return &HWCommentService{db: db}
+}
+
+// FindHWCommentByID retrieves a comment by ID along with its author.
+// Returns ENOTFOUND if comment does not exist.
+func (s *HWCommentService) FindHWCommentByID(ctx context.Context, id int) (*api.HWComment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := findHWCommentByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if _, err := checkHWSubmissionAccess(ctx, tx, c.SubmissionID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// FindHWComments retrieves the thread of a submission and marks it as read by
+// the current user.
+func (s *HWCommentService) FindHWComments(ctx context.Context, submissionID int) ([]*api.HWComment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkHWSubmissionAccess(ctx, tx, submissionID); err != nil {
+		return nil, err
+	}
+
+	comments, err := findHWComments(ctx, tx, 0, submissionID)
+	if err != nil {
+		return nil, err
+	} else if err := markHWCommentsRead(ctx, tx, submissionID); err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return comments, nil
+}
+
+// CreateHWComment creates a new comment by the current user.
+func (s *HWCommentService) CreateHWComment(ctx context.Context, c *api.HWComment) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createHWComment(ctx, tx, c); err != nil {
+		return err
+	} else if c.Author, err = findUserByID(ctx, tx, c.AuthorID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateHWComment updates the body of a comment and keeps the previous one.
+func (s *HWCommentService) UpdateHWComment(ctx context.Context, id int, upd api.HWCommentUpdate) (*api.HWComment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c, err := updateHWComment(ctx, tx, id, upd)
+	if err != nil {
+		return c, err
+	} else if err := tx.Commit(); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+// DeleteHWComment permanently deletes a comment.
+func (s *HWCommentService) DeleteHWComment(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteHWComment(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindHWCommentAttachments retrieves the files attached to the comments of a
+// submission or of every submission of a homework.
+func (s *HWCommentService) FindHWCommentAttachments(ctx context.Context, filter api.HWCommentAttachmentFilter) ([]*api.HWCommentAttachment, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findHWCommentAttachmentsByFilter(ctx, tx, filter)
+}
+
+// findHWCommentByID is a helper function to fetch a comment by ID.
+// Returns ENOTFOUND if comment does not exist.
+func findHWCommentByID(ctx context.Context, tx *Tx, id int) (*api.HWComment, error) {
+	a, err := findHWComments(ctx, tx, id, 0)
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Comment not found."}
+	}
+	return a[0], nil
+}
+
+// findHWComments returns the comment with the given ID, or the thread of a
+// submission if id is zero, along with their authors, attachments and edits.
+func findHWComments(ctx context.Context, tx *Tx, id, submissionID int) ([]*api.HWComment, error) {
+	where, args := []string{"1 = 1"}, []interface{}{}
+	i := 1
+	if id != 0 {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, id)
+		i++
+	}
+	if submissionID != 0 {
+		where, args = append(where, fmt.Sprintf("hw_submission_id = $%d", i)), append(args, submissionID)
+		i++
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			body,
+			created_at,
+			updated_at,
+			parent_id,
+			author_id,
+			hw_submission_id
+		FROM hw_comments
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at ASC, id ASC
+	`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := make([]*api.HWComment, 0)
+	for rows.Next() {
+		var updatedAt sql.NullTime
+		var parentID sql.NullInt32
+
+		var c api.HWComment
+		if err := rows.Scan(
+			&c.ID,
+			&c.Body,
+			&c.CreatedAt,
+			&updatedAt,
+			&parentID,
+			&c.AuthorID,
+			&c.SubmissionID,
+		); err != nil {
+			return nil, err
+		}
+		if updatedAt.Valid {
+			c.UpdatedAt = updatedAt.Time
+		}
+		if parentID.Valid {
+			c.ParentID = int(parentID.Int32)
+		}
+
+		comments = append(comments, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, c := range comments {
+		if err := attachHWCommentAssociations(ctx, tx, c); err != nil {
+			return nil, err
+		}
+	}
+	return comments, nil
+}
+
+// attachHWCommentAssociations attaches the author, attachments and edit history of a comment.
+func attachHWCommentAssociations(ctx context.Context, tx *Tx, c *api.HWComment) (err error) {
+	if c.Author, err = findUserByID(ctx, tx, c.AuthorID); err != nil {
+		return fmt.Errorf("attach comment author: %w", err)
+	} else if c.Attachments, err = findHWCommentAttachments(ctx, tx, c.ID); err != nil {
+		return fmt.Errorf("attach comment attachments: %w", err)
+	} else if c.Edits, err = findHWCommentEdits(ctx, tx, c.ID); err != nil {
+		return fmt.Errorf("attach comment edits: %w", err)
+	}
+	return nil
+}
+
+// findHWCommentAttachments returns the files attached to a comment.
+func findHWCommentAttachments(ctx context.Context, tx *Tx, commentID int) ([]*api.HWCommentAttachment, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.hash, a.extension, c.filename
+		FROM hw_comment_attachments c
+		JOIN attachments a ON a.hash = c.attachment_hash
+		WHERE c.comment_id = $1
+		ORDER BY c.filename, a.hash
+	`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*api.HWCommentAttachment
+	for rows.Next() {
+		var a api.HWCommentAttachment
+		if err := rows.Scan(&a.Hash, &a.Extension, &a.Filename); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// findHWCommentAttachmentsByFilter returns the files attached to the comments
+// of a submission or of every submission of a homework, once per comment. The
+// current user must be allowed to delete the submission or homework.
+func findHWCommentAttachmentsByFilter(ctx context.Context, tx *Tx, filter api.HWCommentAttachmentFilter) ([]*api.HWCommentAttachment, error) {
+	currentUserID := api.UserIDFromContext(ctx)
+
+	var where string
+	var args []interface{}
+	if v := filter.SubmissionID; v != nil {
+		sub, err := findHWSubmissionByID(ctx, tx, *v)
+		if err != nil {
+			return nil, err
+		} else if sub.Homework, err = findHomeworkByID(ctx, tx, sub.HomeworkID); err != nil {
+			return nil, err
+		} else if (currentUserID != 0 && sub.StudentID != 0 && sub.Homework.TeacherID != 0) && sub.Homework.TeacherID != currentUserID {
+			return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this homework submission.")
+		}
+		where, args = `s.id = $1`, []interface{}{sub.ID}
+	} else if v := filter.HomeworkID; v != nil {
+		hw, err := findHomeworkByID(ctx, tx, *v)
+		if err != nil {
+			return nil, err
+		} else if currentUserID != 0 && hw.TeacherID != 0 && hw.TeacherID != currentUserID {
+			return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this homework.")
+		}
+		where, args = `s.homework_id = $1`, []interface{}{hw.ID}
+	} else {
+		return nil, api.Errorf(api.EINVALID, "Submission or homework required.")
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.hash, a.extension, c.filename
+		FROM hw_comment_attachments c
+		JOIN attachments a ON a.hash = c.attachment_hash
+		JOIN hw_comments m ON m.id = c.comment_id
+		JOIN hw_submissions s ON s.id = m.hw_submission_id
+		WHERE `+where,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := make([]*api.HWCommentAttachment, 0)
+	for rows.Next() {
+		var a api.HWCommentAttachment
+		if err := rows.Scan(&a.Hash, &a.Extension, &a.Filename); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+// findHWCommentEdits returns the previous bodies of a comment, oldest first.
+func findHWCommentEdits(ctx context.Context, tx *Tx, commentID int) ([]*api.HWCommentEdit, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT body, edited_at
+		FROM hw_comment_edits
+		WHERE comment_id = $1
+		ORDER BY edited_at ASC, id ASC
+	`, commentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edits []*api.HWCommentEdit
+	for rows.Next() {
+		var e api.HWCommentEdit
+		if err := rows.Scan(&e.Body, &e.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return edits, nil
+}
+
+// createHWComment creates a new comment by the current user. Sets the new
+// database ID to c.ID and the timestamps to the current time.
+func createHWComment(ctx context.Context, tx *Tx, c *api.HWComment) error {
+	if _, err := checkHWSubmissionAccess(ctx, tx, c.SubmissionID); err != nil {
+		return err
+	}
+	c.AuthorID = api.UserIDFromContext(ctx)
+
+	// Set timestamps to the current time.
+	c.CreatedAt = tx.now
+	c.UpdatedAt = c.CreatedAt
+
+	// Perform basic field validation.
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	// Replies must stay within the thread of the submission.
+	var parentID *int
+	if c.ParentID != 0 {
+		if parent, err := findHWCommentByID(ctx, tx, c.ParentID); err != nil {
+			return err
+		} else if parent.SubmissionID != c.SubmissionID {
+			return api.Errorf(api.EINVALID, "Comment replies to another submission.")
+		}
+		parentID = &c.ParentID
+	}
+
+	// Execute insertion query.
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO hw_comments (
+			body,
+			created_at,
+			parent_id,
+			author_id,
+			hw_submission_id
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`,
+		c.Body,
+		c.CreatedAt,
+		parentID,
+		c.AuthorID,
+		c.SubmissionID,
+	).Scan(&c.ID); err != nil {
+		return FormatError(err)
+	}
+
+	for _, a := range c.Attachments {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO hw_comment_attachments (comment_id, attachment_hash, filename)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (comment_id, attachment_hash) DO NOTHING
+		`, c.ID, a.Hash, a.Filename); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	// Replying to a thread means having read it.
+	return markHWCommentsRead(ctx, tx, c.SubmissionID)
+}
+
+// updateHWComment replaces the body of a comment and records the previous one.
+// Returns EUNAUTHORIZED if current user is not the author.
+func updateHWComment(ctx context.Context, tx *Tx, id int, upd api.HWCommentUpdate) (*api.HWComment, error) {
+	// Fetch current object state.
+	c, err := findHWCommentByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if c.AuthorID != api.UserIDFromContext(ctx) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this comment.")
+	}
+
+	v := upd.Body
+	if v == nil || *v == c.Body {
+		return c, nil
+	}
+	prev := c.Body
+	c.Body = *v
+
+	// Set last updated date to current time.
+	c.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := c.Validate(); err != nil {
+		return c, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hw_comment_edits (body, edited_at, comment_id)
+		VALUES ($1, $2, $3)
+	`, prev, c.UpdatedAt, id); err != nil {
+		return c, FormatError(err)
+	}
+	c.Edits = append(c.Edits, &api.HWCommentEdit{Body: prev, EditedAt: c.UpdatedAt})
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE hw_comments
+		SET body = $1,
+		    updated_at = $2
+		WHERE id = $3
+	`,
+		c.Body,
+		c.UpdatedAt,
+		id,
+	); err != nil {
+		return c, FormatError(err)
+	}
+
+	return c, nil
+}
+
+// deleteHWComment permanently removes a comment by ID. Returns EUNAUTHORIZED
+// if current user is not the author.
+func deleteHWComment(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists.
+	if c, err := findHWCommentByID(ctx, tx, id); err != nil {
+		return err
+	} else if c.AuthorID != api.UserIDFromContext(ctx) {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this comment.")
+	}
+
+	// Remove row from database.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hw_comments WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// markHWCommentsRead records that the current user has read the thread of a
+// submission up to now.
+func markHWCommentsRead(ctx context.Context, tx *Tx, submissionID int) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hw_comment_reads (hw_submission_id, user_id, read_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (hw_submission_id, user_id) DO UPDATE
+		SET read_at = EXCLUDED.read_at
+	`, submissionID, api.UserIDFromContext(ctx), tx.now); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
@@ -103,17 +103,92 @@ func (s *HWSubmissionService) UpdateHWSubmission(ctx context.Context, id int, up
 	return sub, nil
 }
 
+// SubmitHWSubmission submits a draft.
+func (s *HWSubmissionService) SubmitHWSubmission(ctx context.Context, id int) (*api.HWSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sub, err := submitHWSubmission(ctx, tx, id)
+	if err != nil {
+		return sub, err
+	} else if err := attachHWSubmissionAssociations(ctx, tx, sub); err != nil {
+		return sub, err
+	} else if err := tx.Commit(); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// ReturnHWSubmission returns a submission to its student for revision.
+func (s *HWSubmissionService) ReturnHWSubmission(ctx context.Context, id int, feedback string) (*api.HWSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sub, err := returnHWSubmission(ctx, tx, id, feedback)
+	if err != nil {
+		return sub, err
+	} else if err := attachHWSubmissionAssociations(ctx, tx, sub); err != nil {
+		return sub, err
+	} else if err := tx.Commit(); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// FindHWSubmissionVersions retrieves the versions of the response of a submission.
+func (s *HWSubmissionService) FindHWSubmissionVersions(ctx context.Context, id int) ([]*api.HWSubmissionVersion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkHWSubmissionAccess(ctx, tx, id); err != nil {
+		return nil, err
+	}
+	return findHWSubmissionVersions(ctx, tx, id, 0)
+}
+
+// DiffHWSubmissionVersions compares two versions of the response of a submission.
+func (s *HWSubmissionService) DiffHWSubmissionVersions(ctx context.Context, id, from, to int) ([]*api.DiffLine, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := checkHWSubmissionAccess(ctx, tx, id); err != nil {
+		return nil, err
+	}
+
+	a, err := findHWSubmissionVersion(ctx, tx, id, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := findHWSubmissionVersion(ctx, tx, id, to)
+	if err != nil {
+		return nil, err
+	}
+	return api.Diff(a.Response, b.Response)
+}
+
 // DeleteHWSubmission permanently deletes a submission.
 // Returns EUNAUTHORIZED if current submission is not the submission being deleted.
 // Returns ENOTFOUND if submission does not exist.
-func (s *HWSubmissionService) DeleteHWSubmission(ctx context.Context, id int) error {
+func (s *HWSubmissionService) DeleteHWSubmission(ctx context.Context, id int, overrideReason *string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if err := deleteHWSubmission(ctx, tx, id); err != nil {
+	if err := deleteHWSubmission(ctx, tx, id, overrideReason); err != nil {
 		return err
 	}
 	return tx.Commit()
@@ -149,6 +224,10 @@ func findHWSubmissions(ctx context.Context, tx *Tx, filter api.HWSubmissionFilte
 		where, args = append(where, fmt.Sprintf("updated_at >= $%d", i)), append(args, *v)
 		i++
 	}
+	if v := filter.Status; v != nil {
+		where, args = append(where, fmt.Sprintf("status = $%d", i)), append(args, *v)
+		i++
+	}
 	if v := filter.StudentFullName; v != nil {
 		where, args = append(where, fmt.Sprintf("student_fullname = $%d", i)), append(args, *v)
 		i++
@@ -162,18 +241,36 @@ func findHWSubmissions(ctx context.Context, tx *Tx, filter api.HWSubmissionFilte
 		i++
 	}
 
+	// Count the comments of others the current user has not read yet.
+	args = append(args, api.UserIDFromContext(ctx))
+	unread := fmt.Sprintf(`(
+		SELECT COUNT(*)
+		FROM hw_comments c
+		LEFT JOIN hw_comment_reads r ON r.hw_submission_id = c.hw_submission_id AND r.user_id = $%[1]d
+		WHERE c.hw_submission_id = hw_submissions.id
+		AND c.author_id <> $%[1]d
+		AND (r.read_at IS NULL OR c.created_at > r.read_at)
+	)`, i)
+
 	// Execute query to fetch submission rows.
 	rows, err := tx.QueryContext(ctx, `
 		SELECT 
 		    id,
 		    response,
+			status,
 			grade,
 			comments,
 			submitted_at,
 			updated_at,
+			is_late,
+			late_seconds,
+			raw_grade,
+			version,
+			graded_version,
 			student_fullname,
 			student_id,
 			homework_id,
+			`+unread+`,
 		    COUNT(*) OVER()
 		FROM hw_submissions
 		WHERE `+strings.Join(where, " AND ")+`
@@ -192,20 +289,29 @@ func findHWSubmissions(ctx context.Context, tx *Tx, filter api.HWSubmissionFilte
 		var response sql.NullString
 		var studentFullname sql.NullString
 		var grade sql.NullFloat64
+		var rawGrade sql.NullFloat64
 		var updatedAt sql.NullTime
 		var studentID sql.NullInt32
+		var gradedVersion sql.NullInt32
 
 		var sub api.HWSubmission
 		if err := rows.Scan(
 			&sub.ID,
 			&response,
+			&sub.Status,
 			&grade,
 			&sub.Comments,
 			&sub.SubmittedAt,
 			&updatedAt,
+			&sub.Late,
+			&sub.LateSeconds,
+			&rawGrade,
+			&sub.Version,
+			&gradedVersion,
 			&studentFullname,
 			&studentID,
 			&sub.HomeworkID,
+			&sub.UnreadComments,
 			&n,
 		); err != nil {
 			return nil, 0, err
@@ -215,7 +321,10 @@ func findHWSubmissions(ctx context.Context, tx *Tx, filter api.HWSubmissionFilte
 			sub.Response = response.String
 		}
 		if grade.Valid {
-			sub.Grade = float32(grade.Float64)
+			sub.Grade, sub.Graded = float32(grade.Float64), true
+		}
+		if rawGrade.Valid {
+			sub.RawGrade = float32(rawGrade.Float64)
 		}
 		if studentFullname.Valid {
 			sub.StudentFullName = studentFullname.String
@@ -226,6 +335,9 @@ func findHWSubmissions(ctx context.Context, tx *Tx, filter api.HWSubmissionFilte
 		if studentID.Valid {
 			sub.StudentID = int(studentID.Int32)
 		}
+		if gradedVersion.Valid {
+			sub.GradedVersion = int(gradedVersion.Int32)
+		}
 
 		submissions = append(submissions, &sub)
 	}
@@ -242,11 +354,35 @@ func createHWSubmission(ctx context.Context, tx *Tx, sub *api.HWSubmission) erro
 	// Set timestamps to the current time.
 	sub.SubmittedAt = tx.now
 
+	// Submissions start out as drafts or submitted.
+	if sub.Status == "" {
+		sub.Status = api.HWSubmitted
+	} else if sub.Status != api.HWDraft && sub.Status != api.HWSubmitted {
+		return api.Errorf(api.EINVALID, "New submissions must be drafts or submitted.")
+	}
+
 	// Perform basic field validation.
 	if err := sub.Validate(); err != nil {
 		return err
 	}
 
+	// Reject submissions after the homework closes for the student and flag
+	// late ones.
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return err
+	} else if err := applyHomeworkDeadline(ctx, tx, hw, sub.StudentID); err != nil {
+		return err
+	} else if hw.Closed(tx.now) {
+		return api.Errorf(api.EFORBIDDEN, "Homework has already been closed.")
+	}
+	sub.RawGrade = sub.Grade
+	sub.Version, sub.GradedVersion = 0, 0
+	if sub.Status != api.HWDraft {
+		setHWSubmissionLateness(sub, hw, tx.now)
+		sub.Version = 1
+	}
+
 	// These fields are nullable so ensure we store blank fields as NULLs.
 	var response *string
 	if sub.Response != "" {
@@ -265,41 +401,68 @@ func createHWSubmission(ctx context.Context, tx *Tx, sub *api.HWSubmission) erro
 		studentID = &sub.StudentID
 	}
 	var grade *float32
+	var gradedVersion *int
 	if sub.Grade != 0 {
+		sub.Graded = true
+	}
+	if sub.Graded {
 		grade = &sub.Grade
 	}
+	if sub.Graded && sub.Version != 0 {
+		sub.GradedVersion = sub.Version
+		gradedVersion = &sub.GradedVersion
+	}
 
 	// Execute insertion query.
 	row := tx.QueryRowContext(ctx, `
 		INSERT INTO hw_submissions (
 			response,
+			status,
 			grade,
 			comments,
 			submitted_at,
 			updated_at,
+			is_late,
+			late_seconds,
+			raw_grade,
+			version,
+			graded_version,
 			student_fullname,
 			student_id,
 			homework_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id
 	`,
 		response,
+		sub.Status,
 		grade,
 		sub.Comments,
 		sub.SubmittedAt,
 		updatedAt,
+		sub.Late,
+		sub.LateSeconds,
+		grade,
+		sub.Version,
+		gradedVersion,
 		studentFullname,
 		studentID,
 		sub.HomeworkID,
 	)
 
-	err := row.Scan(&sub.ID)
+	err = row.Scan(&sub.ID)
 	if err != nil {
 		return FormatError(err)
 	}
 
-	return nil
+	if err := insertHWSubmissionTransition(ctx, tx, sub.ID, "", sub.Status); err != nil {
+		return err
+	} else if sub.Status == api.HWDraft {
+		return nil
+	}
+
+	// Keep the submitted response as the first version.
+	return insertHWSubmissionVersion(ctx, tx, sub)
 }
 
 // updateHWSubmission updates fields on a submission object. Returns EUNAUTHORIZED if current
@@ -317,13 +480,65 @@ func updateHWSubmission(ctx context.Context, tx *Tx, id int, upd api.HWSubmissio
 		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this homework submission.")
 	}
 
-	// Update fields.
-	if v := upd.Response; v != nil {
+	// Grades of late submissions are reduced by the late penalty. Changing a
+	// locked grade requires a recorded override.
+	if v := upd.Grade; v != nil {
+		if sub.Status == api.HWDraft {
+			return sub, api.Errorf(api.ECONFLICT, "Drafts cannot be graded.")
+		} else if err := setHWSubmissionStatus(ctx, tx, sub, api.HWGraded); err != nil {
+			return sub, err
+		}
+
+		// Grades are given for a version, which decides how late the work is.
+		version := &api.HWSubmissionVersion{Number: sub.Version, LateSeconds: sub.LateSeconds}
+		if upd.Version != nil && *upd.Version != sub.Version {
+			if version, err = findHWSubmissionVersion(ctx, tx, id, *upd.Version); err != nil {
+				return sub, err
+			}
+		}
+
+		grade := sub.Homework.LateGrade(*v, time.Duration(version.LateSeconds)*time.Second)
+		if grade != sub.Grade || !sub.Graded {
+			if err := checkGradeLock(ctx, tx, api.GradeItemHomework, sub.HomeworkID, sub.ID, sub.Grade, &grade, upd.OverrideReason); err != nil {
+				return sub, err
+			}
+		}
+		sub.RawGrade, sub.Grade, sub.Graded = *v, grade, true
+		sub.GradedVersion = version.Number
+	}
+
+	// Students may change their response until the homework closes, which
+	// makes it as late as the change. Work returned for revision may be
+	// changed even after that and keeps its lateness.
+	// Every change of a submitted response is kept as a new version, of
+	// which students may only make as many as the homework allows.
+	resubmitted := false
+	if v := upd.Response; v != nil && *v != sub.Response {
+		if (currentUserID == 0 || currentUserID == sub.StudentID) && sub.Status != api.HWReturned {
+			if err := applyHomeworkDeadline(ctx, tx, sub.Homework, sub.StudentID); err != nil {
+				return sub, err
+			} else if sub.Homework.Closed(tx.now) {
+				return sub, api.Errorf(api.EFORBIDDEN, "Homework has already been closed.")
+			} else if limit := sub.Homework.MaxResubmissions; limit > 0 && sub.Version > limit {
+				return sub, api.Errorf(api.EFORBIDDEN, "No resubmissions left.")
+			} else if sub.Status != api.HWDraft {
+				setHWSubmissionLateness(sub, sub.Homework, tx.now)
+			}
+		}
 		sub.Response = *v
 		sub.UpdatedAt = tx.now
-	}
-	if v := upd.Grade; v != nil {
-		sub.Grade = *v
+
+		if sub.Status != api.HWDraft {
+			next := api.HWResubmitted
+			if sub.Status == api.HWSubmitted {
+				next = api.HWSubmitted
+			}
+			if err := setHWSubmissionStatus(ctx, tx, sub, next); err != nil {
+				return sub, err
+			}
+			sub.Version++
+			resubmitted = true
+		}
 	}
 	if v := upd.Comments; v != nil {
 		sub.Comments = *v
@@ -345,8 +560,10 @@ func updateHWSubmission(ctx context.Context, tx *Tx, id int, upd api.HWSubmissio
 	if sub.Response != "" {
 		response = &sub.Response
 	}
+	// Zero grades, such as those of work too late to earn anything, are
+	// kept apart from missing ones.
 	var grade *float32
-	if sub.Grade != 0 {
+	if sub.Graded {
 		grade = &sub.Grade
 	}
 	var studentFullname *string
@@ -361,6 +578,14 @@ func updateHWSubmission(ctx context.Context, tx *Tx, id int, upd api.HWSubmissio
 	if !sub.UpdatedAt.IsZero() {
 		updatedAt = &sub.UpdatedAt
 	}
+	var rawGrade *float32
+	if sub.Graded {
+		rawGrade = &sub.RawGrade
+	}
+	var gradedVersion *int
+	if sub.GradedVersion != 0 {
+		gradedVersion = &sub.GradedVersion
+	}
 
 	// Execute update query.
 	if _, err = tx.ExecContext(ctx, `
@@ -370,8 +595,14 @@ func updateHWSubmission(ctx context.Context, tx *Tx, id int, upd api.HWSubmissio
 			comments = $3,
 			student_fullname = $4,
 			student_id = $5,
-			updated_at = $6
-		WHERE id = $7
+			updated_at = $6,
+			is_late = $7,
+			late_seconds = $8,
+			raw_grade = $9,
+			version = $10,
+			graded_version = $11,
+			status = $12
+		WHERE id = $13
 	`,
 		response,
 		grade,
@@ -379,20 +610,33 @@ func updateHWSubmission(ctx context.Context, tx *Tx, id int, upd api.HWSubmissio
 		studentFullname,
 		studentID,
 		updatedAt,
+		sub.Late,
+		sub.LateSeconds,
+		rawGrade,
+		sub.Version,
+		gradedVersion,
+		sub.Status,
 		id,
 	); err != nil {
 		return sub, FormatError(err)
 	}
 
+	if resubmitted {
+		if err := insertHWSubmissionVersion(ctx, tx, sub); err != nil {
+			return sub, err
+		}
+	}
+
 	return sub, nil
 }
 
 // deleteHWSubmission permanently removes a submission by ID. Returns EUNAUTHORIZED if current
 // submission is not the one being deleted.
-func deleteHWSubmission(ctx context.Context, tx *Tx, id int) error {
+func deleteHWSubmission(ctx context.Context, tx *Tx, id int, overrideReason *string) error {
 	// Verify object exists.
 	currentUserID := api.UserIDFromContext(ctx)
-	if sub, err := findHWSubmissionByID(ctx, tx, id); err != nil {
+	sub, err := findHWSubmissionByID(ctx, tx, id)
+	if err != nil {
 		return err
 	} else if sub.Homework, err = findHomeworkByID(ctx, tx, sub.HomeworkID); err != nil {
 		return err
@@ -400,6 +644,11 @@ func deleteHWSubmission(ctx context.Context, tx *Tx, id int) error {
 		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this homework submission.")
 	}
 
+	// Deleting a submission of locked grades requires a recorded override.
+	if err := checkGradeLock(ctx, tx, api.GradeItemHomework, sub.HomeworkID, sub.ID, sub.Grade, nil, overrideReason); err != nil {
+		return err
+	}
+
 	// Remove row from database.
 	if _, err := tx.ExecContext(ctx, `DELETE FROM hw_submissions WHERE id = $1`, id); err != nil {
 		return FormatError(err)
@@ -407,10 +656,297 @@ func deleteHWSubmission(ctx context.Context, tx *Tx, id int) error {
 	return nil
 }
 
-// attachHWSubmissionAssociations attaches homework and student objects associated with the submission.
+// checkHWSubmissionAccess returns a submission if the current user is its
+// student, the teacher of its homework or a teacher of the homework's group.
+func checkHWSubmissionAccess(ctx context.Context, tx *Tx, submissionID int) (*api.HWSubmission, error) {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+
+	sub, err := findHWSubmissionByID(ctx, tx, submissionID)
+	if err != nil {
+		return nil, err
+	} else if sub.StudentID == userID {
+		return sub, nil
+	}
+
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return nil, err
+	} else if ok, err := isHomeworkTeacher(ctx, tx, hw, userID); err != nil {
+		return nil, err
+	} else if ok {
+		return sub, nil
+	}
+	return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view this homework submission.")
+}
+
+// submitHWSubmission submits a draft, keeping its response as the first
+// version. Returns EUNAUTHORIZED if current user is not the student.
+func submitHWSubmission(ctx context.Context, tx *Tx, id int) (*api.HWSubmission, error) {
+	currentUserID := api.UserIDFromContext(ctx)
+	sub, err := findHWSubmissionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if sub.StudentID != currentUserID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to submit this homework submission.")
+	} else if sub.Status != api.HWDraft {
+		return nil, api.Errorf(api.ECONFLICT, "Homework submission has already been submitted.")
+	}
+
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return nil, err
+	} else if err := applyHomeworkDeadline(ctx, tx, hw, sub.StudentID); err != nil {
+		return nil, err
+	} else if hw.Closed(tx.now) {
+		return nil, api.Errorf(api.EFORBIDDEN, "Homework has already been closed.")
+	} else if err := setHWSubmissionStatus(ctx, tx, sub, api.HWSubmitted); err != nil {
+		return nil, err
+	}
+	setHWSubmissionLateness(sub, hw, tx.now)
+	sub.SubmittedAt, sub.Version = tx.now, 1
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE hw_submissions
+		SET status = $1,
+		    submitted_at = $2,
+		    is_late = $3,
+		    late_seconds = $4,
+		    version = $5
+		WHERE id = $6
+	`,
+		sub.Status,
+		sub.SubmittedAt,
+		sub.Late,
+		sub.LateSeconds,
+		sub.Version,
+		id,
+	); err != nil {
+		return sub, FormatError(err)
+	} else if err := insertHWSubmissionVersion(ctx, tx, sub); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// returnHWSubmission returns a submission to its student for revision and
+// adds the feedback to its thread. Returns EUNAUTHORIZED if current user does
+// not teach the homework.
+func returnHWSubmission(ctx context.Context, tx *Tx, id int, feedback string) (*api.HWSubmission, error) {
+	sub, err := findHWSubmissionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return nil, err
+	} else if ok, err := isHomeworkTeacher(ctx, tx, hw, api.UserIDFromContext(ctx)); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to return this homework submission.")
+	} else if sub.StudentID == 0 {
+		return nil, api.Errorf(api.ECONFLICT, "Anonymous submissions cannot be returned.")
+	} else if err := setHWSubmissionStatus(ctx, tx, sub, api.HWReturned); err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE hw_submissions SET status = $1 WHERE id = $2
+	`, sub.Status, id); err != nil {
+		return sub, FormatError(err)
+	}
+
+	if feedback != "" {
+		if err := createHWComment(ctx, tx, &api.HWComment{Body: feedback, SubmissionID: id}); err != nil {
+			return sub, err
+		}
+	}
+	return sub, nil
+}
+
+// setHWSubmissionStatus moves a submission to a status and records the change.
+// Returns ECONFLICT if the submission cannot move to the status.
+func setHWSubmissionStatus(ctx context.Context, tx *Tx, sub *api.HWSubmission, status string) error {
+	if !api.CanTransitionHW(sub.Status, status) {
+		return api.Errorf(api.ECONFLICT, "Homework submission cannot go from %s to %s.", sub.Status, status)
+	} else if err := insertHWSubmissionTransition(ctx, tx, sub.ID, sub.Status, status); err != nil {
+		return err
+	}
+	sub.Status = status
+	return nil
+}
+
+// insertHWSubmissionTransition records a change of the status of a submission
+// by the current user.
+func insertHWSubmissionTransition(ctx context.Context, tx *Tx, submissionID int, from, to string) error {
+	var userID *int
+	if v := api.UserIDFromContext(ctx); v != 0 {
+		userID = &v
+	}
+	var fromStatus *string
+	if from != "" {
+		fromStatus = &from
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hw_submission_transitions (from_status, to_status, transitioned_at, user_id, hw_submission_id)
+		VALUES ($1, $2, $3, $4, $5)
+	`, fromStatus, to, tx.now, userID, submissionID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// findHWSubmissionTransitions returns the changes of the status of a submission, oldest first.
+func findHWSubmissionTransitions(ctx context.Context, tx *Tx, submissionID int) ([]*api.HWSubmissionTransition, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT from_status, to_status, transitioned_at, user_id
+		FROM hw_submission_transitions
+		WHERE hw_submission_id = $1
+		ORDER BY transitioned_at ASC, id ASC
+	`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []*api.HWSubmissionTransition
+	for rows.Next() {
+		var from sql.NullString
+		var userID sql.NullInt32
+
+		var t api.HWSubmissionTransition
+		if err := rows.Scan(&from, &t.To, &t.At, &userID); err != nil {
+			return nil, err
+		}
+		t.From, t.UserID = from.String, int(userID.Int32)
+		transitions = append(transitions, &t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return transitions, nil
+}
+
+// findHWSubmissionVersion returns a version of the response of a submission.
+// Returns ENOTFOUND if the version does not exist.
+func findHWSubmissionVersion(ctx context.Context, tx *Tx, submissionID, number int) (*api.HWSubmissionVersion, error) {
+	a, err := findHWSubmissionVersions(ctx, tx, submissionID, number)
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Homework submission version not found."}
+	}
+	return a[0], nil
+}
+
+// findHWSubmissionVersions returns the versions of the response of a
+// submission, oldest first, or only the one with the given number if set.
+func findHWSubmissionVersions(ctx context.Context, tx *Tx, submissionID, number int) ([]*api.HWSubmissionVersion, error) {
+	where, args := []string{"hw_submission_id = $1"}, []interface{}{submissionID}
+	if number != 0 {
+		where, args = append(where, "number = $2"), append(args, number)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			number,
+			response,
+			submitted_at,
+			is_late,
+			late_seconds,
+			hw_submission_id
+		FROM hw_submission_versions
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY number ASC
+	`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]*api.HWSubmissionVersion, 0)
+	for rows.Next() {
+		var v api.HWSubmissionVersion
+		if err := rows.Scan(
+			&v.ID,
+			&v.Number,
+			&v.Response,
+			&v.SubmittedAt,
+			&v.Late,
+			&v.LateSeconds,
+			&v.SubmissionID,
+		); err != nil {
+			return nil, err
+		}
+		versions = append(versions, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// insertHWSubmissionVersion stores the current response of a submission as its
+// current version.
+func insertHWSubmissionVersion(ctx context.Context, tx *Tx, sub *api.HWSubmission) error {
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO hw_submission_versions (
+			number,
+			response,
+			submitted_at,
+			is_late,
+			late_seconds,
+			hw_submission_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`,
+		sub.Version,
+		sub.Response,
+		tx.now,
+		sub.Late,
+		sub.LateSeconds,
+		sub.ID,
+	); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// setHWSubmissionLateness flags a submission made at t as late if it is past
+// the due date and grace period of the homework.
+func setHWSubmissionLateness(sub *api.HWSubmission, hw *api.Homework, t time.Time) {
+	late := hw.Lateness(t)
+	sub.Late = late > 0
+	sub.LateSeconds = int(late / time.Second)
+}
+
+// applyHomeworkDeadline replaces the times of a homework with those of a student.
+func applyHomeworkDeadline(ctx context.Context, tx *Tx, hw *api.Homework, studentID int) error {
+	d := hw.Deadline()
+	if err := applyDeadline(ctx, tx, d, hw.GroupID, studentID); err != nil {
+		return err
+	}
+	hw.OpenedAt, hw.DueAt, hw.ClosedAt = d.OpenedAt, d.DueAt, d.ClosedAt
+	return nil
+}
+
+// attachHWSubmissionAssociations attaches homework, rubric score, annotation, transition and student objects associated with the submission.
 func attachHWSubmissionAssociations(ctx context.Context, tx *Tx, sub *api.HWSubmission) (err error) {
 	if sub.Homework, err = findHomeworkByID(ctx, tx, sub.HomeworkID); err != nil {
 		return fmt.Errorf("attach homework submission homework: %w", err)
+	} else if sub.RubricScores, err = findHWRubricScores(ctx, tx, sub.ID); err != nil {
+		return fmt.Errorf("attach homework submission rubric scores: %w", err)
+	} else if sub.Annotations, err = findHWAnnotations(ctx, tx, 0, sub); err != nil {
+		return fmt.Errorf("attach homework submission annotations: %w", err)
+	} else if sub.Transitions, err = findHWSubmissionTransitions(ctx, tx, sub.ID); err != nil {
+		return fmt.Errorf("attach homework submission transitions: %w", err)
 	} else if sub.StudentID == 0 {
 		return nil
 	} else if sub.Student, err = findUserByID(ctx, tx, sub.StudentID); err != nil {
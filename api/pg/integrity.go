@@ -0,0 +1,314 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.IntegrityService = (*IntegrityService)(nil)
+
+// IntegrityService represents a service for logging integrity events of quiz attempts.
+type IntegrityService struct {
+	db *DB
+}
+
+// NewIntegrityService returns a new instance of IntegrityService.
+func NewIntegrityService(db *DB) *IntegrityService {
+	return &IntegrityService{db: db}
+}
+
+// CreateIntegrityEvents stores a batch of events against a quiz attempt.
+func (s *IntegrityService) CreateIntegrityEvents(ctx context.Context, access *api.QuizAccess, events []*api.IntegrityEvent) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createIntegrityEvents(ctx, tx, access, events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindIntegrityEvents retrieves a list of events of a quiz.
+func (s *IntegrityService) FindIntegrityEvents(ctx context.Context, filter api.IntegrityEventFilter) ([]*api.IntegrityEvent, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findIntegrityEvents(ctx, tx, filter)
+}
+
+// SummarizeIntegrity summarizes the events of every attempt of a quiz.
+func (s *IntegrityService) SummarizeIntegrity(ctx context.Context, quizID int, threshold int) ([]*api.IntegritySummary, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return summarizeIntegrity(ctx, tx, quizID, threshold)
+}
+
+// checkIntegrityAccess returns an error if the current user is not the owner of the quiz.
+func checkIntegrityAccess(ctx context.Context, tx *Tx, quizID int) error {
+	if qz, err := findQuizByID(ctx, tx, quizID); err != nil {
+		return err
+	} else if qz.TeacherID != api.UserIDFromContext(ctx) {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view integrity events of this quiz.")
+	}
+	return nil
+}
+
+// createIntegrityEvents inserts the events of the attempt identified by the device token.
+func createIntegrityEvents(ctx context.Context, tx *Tx, access *api.QuizAccess, events []*api.IntegrityEvent) error {
+	if len(events) == 0 {
+		return nil
+	} else if len(events) > api.MaxIntegrityBatch {
+		return api.Errorf(api.EINVALID, "At most %d events can be sent at once.", api.MaxIntegrityBatch)
+	}
+	for _, e := range events {
+		if err := e.Validate(); err != nil {
+			return err
+		}
+	}
+
+	a, err := findQuizAttempt(ctx, tx, `quiz_id = $1 AND device_token = $2`, access.QuizID, access.DeviceToken)
+	if api.ErrorCode(err) == api.ENOTFOUND {
+		return api.Errorf(api.EFORBIDDEN, "Start the quiz before reporting events.")
+	} else if err != nil {
+		return err
+	} else if a.StudentID != api.UserIDFromContext(ctx) {
+		return api.Errorf(api.EFORBIDDEN, "Start the quiz before reporting events.")
+	}
+
+	var submissionID *int
+	if a.SubmissionID != 0 {
+		submissionID = &a.SubmissionID
+	}
+
+	for _, e := range events {
+		e.CreatedAt = tx.now
+		e.AttemptID = a.ID
+		e.SubmissionID = a.SubmissionID
+		e.QuizID = a.QuizID
+
+		var detail *string
+		if e.Detail != "" {
+			detail = &e.Detail
+		}
+		var clientAt *time.Time
+		if !e.ClientAt.IsZero() {
+			clientAt = &e.ClientAt
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO integrity_events (
+				type,
+				detail,
+				client_at,
+				created_at,
+				quiz_attempt_id,
+				quiz_submission_id,
+				quiz_id
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id
+		`,
+			e.Type,
+			detail,
+			clientAt,
+			e.CreatedAt,
+			e.AttemptID,
+			submissionID,
+			e.QuizID,
+		).Scan(&e.ID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+// findIntegrityEvents returns the events of a quiz matching a filter, oldest first.
+func findIntegrityEvents(ctx context.Context, tx *Tx, filter api.IntegrityEventFilter) (_ []*api.IntegrityEvent, n int, err error) {
+	if err := checkIntegrityAccess(ctx, tx, filter.QuizID); err != nil {
+		return nil, 0, err
+	}
+
+	// Build WHERE clause.
+	where, args := []string{"quiz_id = $1"}, []interface{}{filter.QuizID}
+	i := 2
+	if v := filter.AttemptID; v != nil {
+		where, args = append(where, fmt.Sprintf("quiz_attempt_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Type; v != nil {
+		where, args = append(where, fmt.Sprintf("type = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			type,
+			detail,
+			client_at,
+			created_at,
+			quiz_attempt_id,
+			quiz_submission_id,
+			quiz_id,
+			COUNT(*) OVER()
+		FROM integrity_events
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at ASC, id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	events := make([]*api.IntegrityEvent, 0)
+	for rows.Next() {
+		var detail sql.NullString
+		var clientAt sql.NullTime
+		var submissionID sql.NullInt32
+
+		var e api.IntegrityEvent
+		if err := rows.Scan(
+			&e.ID,
+			&e.Type,
+			&detail,
+			&clientAt,
+			&e.CreatedAt,
+			&e.AttemptID,
+			&submissionID,
+			&e.QuizID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		e.Detail = detail.String
+		e.ClientAt = clientAt.Time
+		if submissionID.Valid {
+			e.SubmissionID = int(submissionID.Int32)
+		}
+
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return events, n, nil
+}
+
+// summarizeIntegrity counts the events of every attempt of a quiz by type.
+// Attempts without events are included so that the teacher sees everyone.
+func summarizeIntegrity(ctx context.Context, tx *Tx, quizID int, threshold int) ([]*api.IntegritySummary, error) {
+	if err := checkIntegrityAccess(ctx, tx, quizID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			a.id,
+			a.quiz_submission_id,
+			a.student_id,
+			u.first_name,
+			u.last_name,
+			u.email,
+			e.type,
+			COUNT(e.id),
+			MIN(e.created_at),
+			MAX(e.created_at)
+		FROM quiz_attempts a
+		LEFT JOIN users u ON u.id = a.student_id
+		LEFT JOIN integrity_events e ON e.quiz_attempt_id = a.id
+		WHERE a.quiz_id = $1
+		GROUP BY a.id, u.id, e.type
+		ORDER BY a.id
+	`, quizID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make([]*api.IntegritySummary, 0)
+	for rows.Next() {
+		var attemptID, count int
+		var submissionID, studentID sql.NullInt32
+		var firstName, lastName, email, typ sql.NullString
+		var firstAt, lastAt sql.NullTime
+
+		if err := rows.Scan(
+			&attemptID,
+			&submissionID,
+			&studentID,
+			&firstName,
+			&lastName,
+			&email,
+			&typ,
+			&count,
+			&firstAt,
+			&lastAt,
+		); err != nil {
+			return nil, err
+		}
+
+		// Rows are ordered by attempt so each attempt's rows are adjacent.
+		var s *api.IntegritySummary
+		if len(summaries) > 0 && summaries[len(summaries)-1].AttemptID == attemptID {
+			s = summaries[len(summaries)-1]
+		} else {
+			s = &api.IntegritySummary{AttemptID: attemptID, Counts: make(map[string]int)}
+			if submissionID.Valid {
+				s.SubmissionID = int(submissionID.Int32)
+			}
+			if studentID.Valid {
+				s.StudentID = int(studentID.Int32)
+				s.Student = &api.User{
+					ID:        s.StudentID,
+					FirstName: firstName.String,
+					LastName:  lastName.String,
+					Email:     email.String,
+				}
+			}
+			summaries = append(summaries, s)
+		}
+
+		if !typ.Valid {
+			continue
+		}
+		s.Counts[typ.String] = count
+		if s.FirstAt.IsZero() || firstAt.Time.Before(s.FirstAt) {
+			s.FirstAt = firstAt.Time
+		}
+		if lastAt.Time.After(s.LastAt) {
+			s.LastAt = lastAt.Time
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range summaries {
+		s.Rate(threshold)
+	}
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Score > summaries[j].Score
+	})
+
+	return summaries, nil
+}
@@ -0,0 +1,726 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+)
+
+// Ensure service implements interface.
+var _ api.LiveSessionService = (*LiveSessionService)(nil)
+
+// LiveSessionService represents a service for managing live quiz sessions.
+type LiveSessionService struct {
+	db *DB
+}
+
+// NewLiveSessionService returns a new instance of LiveSessionService.
+func NewLiveSessionService(db *DB) *LiveSessionService {
+	return &LiveSessionService{db: db}
+}
+
+// FindLiveSessionByID retrieves a live session by ID along with its quiz.
+// Returns ENOTFOUND if live session does not exist.
+func (s *LiveSessionService) FindLiveSessionByID(ctx context.Context, id int) (*api.LiveSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ls, err := findLiveSessionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if ls.Quiz, err = findQuizByID(ctx, tx, ls.QuizID); err != nil {
+		return ls, err
+	}
+	return ls, nil
+}
+
+// FindLiveSessionByCode retrieves an unfinished live session by its join code along with its quiz.
+// Returns ENOTFOUND if live session does not exist.
+func (s *LiveSessionService) FindLiveSessionByCode(ctx context.Context, code string) (*api.LiveSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ls, err := findLiveSession(ctx, tx, `code = $1 AND state <> 'finished'`, code)
+	if err != nil {
+		return nil, err
+	} else if ls.Quiz, err = findQuizByID(ctx, tx, ls.QuizID); err != nil {
+		return ls, err
+	}
+	return ls, nil
+}
+
+// CreateLiveSession creates a new live session.
+func (s *LiveSessionService) CreateLiveSession(ctx context.Context, ls *api.LiveSession) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createLiveSession(ctx, tx, ls); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateLiveSession updates a live session object. Returns EUNAUTHORIZED if current user is
+// not the teacher running the session. Returns ENOTFOUND if live session does not exist.
+func (s *LiveSessionService) UpdateLiveSession(ctx context.Context, id int, upd api.LiveSessionUpdate) (*api.LiveSession, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ls, err := updateLiveSession(ctx, tx, id, upd)
+	if err != nil {
+		return ls, err
+	} else if err := tx.Commit(); err != nil {
+		return ls, err
+	}
+	return ls, nil
+}
+
+// JoinLiveSession adds a participant to a live session and creates their quiz submission.
+// A student who has already joined the session gets their existing participant back.
+func (s *LiveSessionService) JoinLiveSession(ctx context.Context, p *api.LiveParticipant) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := joinLiveSession(ctx, tx, p); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindLiveParticipantByToken retrieves a participant by their reconnect token.
+// Returns ENOTFOUND if participant does not exist.
+func (s *LiveSessionService) FindLiveParticipantByToken(ctx context.Context, token string) (*api.LiveParticipant, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findLiveParticipant(ctx, tx, `token = $1`, token)
+}
+
+// AnswerLiveQuestion grades and stores the participant's answer to the current question.
+// Returns ECONFLICT if the question has already been answered.
+func (s *LiveSessionService) AnswerLiveQuestion(ctx context.Context, participantID int, rn *api.Response) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := answerLiveQuestion(ctx, tx, participantID, rn); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindLiveStats computes the leaderboard and the answer distribution of a session.
+func (s *LiveSessionService) FindLiveStats(ctx context.Context, id int) (*api.LiveStats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findLiveStats(ctx, tx, id)
+}
+
+// findLiveSessionByID is a helper function to fetch a live session by ID.
+// Returns ENOTFOUND if live session does not exist.
+func findLiveSessionByID(ctx context.Context, tx *Tx, id int) (*api.LiveSession, error) {
+	return findLiveSession(ctx, tx, `id = $1`, id)
+}
+
+// findLiveSession returns the first live session matching a single-argument condition.
+// Returns ENOTFOUND if live session does not exist.
+func findLiveSession(ctx context.Context, tx *Tx, where string, arg interface{}) (*api.LiveSession, error) {
+	var questionStartedAt sql.NullTime
+	var updatedAt sql.NullTime
+	var teacherID sql.NullInt32
+
+	var ls api.LiveSession
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			id,
+			code,
+			state,
+			question_index,
+			question_started_at,
+			created_at,
+			updated_at,
+			teacher_id,
+			quiz_id
+		FROM live_sessions
+		WHERE `+where+`
+		ORDER BY id DESC
+		LIMIT 1
+	`,
+		arg,
+	).Scan(
+		&ls.ID,
+		&ls.Code,
+		&ls.State,
+		&ls.QuestionIndex,
+		&questionStartedAt,
+		&ls.CreatedAt,
+		&updatedAt,
+		&teacherID,
+		&ls.QuizID,
+	); err == sql.ErrNoRows {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Live session not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if questionStartedAt.Valid {
+		ls.QuestionStartedAt = questionStartedAt.Time
+	}
+	if updatedAt.Valid {
+		ls.UpdatedAt = updatedAt.Time
+	}
+	if teacherID.Valid {
+		ls.TeacherID = int(teacherID.Int32)
+	}
+
+	return &ls, nil
+}
+
+// createLiveSession creates a new live session in the lobby state. Sets the new
+// database ID to ls.ID and sets the timestamps to the current time.
+func createLiveSession(ctx context.Context, tx *Tx, ls *api.LiveSession) error {
+	var err error
+
+	// Only the quiz owner may run it live.
+	currentUserID := api.UserIDFromContext(ctx)
+	if ls.Quiz, err = findQuizByID(ctx, tx, ls.QuizID); err != nil {
+		return err
+	} else if currentUserID != 0 && ls.Quiz.TeacherID != 0 && ls.Quiz.TeacherID != currentUserID {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to run this quiz.")
+	}
+
+	ls.State = api.LiveWaiting
+	ls.QuestionIndex = 0
+	ls.CreatedAt = tx.now
+	ls.UpdatedAt = ls.CreatedAt
+	if ls.TeacherID == 0 {
+		ls.TeacherID = ls.Quiz.TeacherID
+	}
+
+	// Perform basic field validation.
+	if err := ls.Validate(); err != nil {
+		return err
+	}
+
+	var teacherID *int
+	if ls.TeacherID != 0 {
+		teacherID = &ls.TeacherID
+	}
+
+	// Execute insertion query.
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO live_sessions (
+			code,
+			state,
+			question_index,
+			created_at,
+			updated_at,
+			teacher_id,
+			quiz_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`,
+		ls.Code,
+		ls.State,
+		ls.QuestionIndex,
+		ls.CreatedAt,
+		ls.UpdatedAt,
+		teacherID,
+		ls.QuizID,
+	)
+
+	if err := row.Scan(&ls.ID); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// updateLiveSession moves a live session to another state or question. Finishing a
+// session stores the sum of the answer grades on every participant's submission.
+func updateLiveSession(ctx context.Context, tx *Tx, id int, upd api.LiveSessionUpdate) (*api.LiveSession, error) {
+	// Fetch current object state.
+	currentUserID := api.UserIDFromContext(ctx)
+	ls, err := findLiveSessionByID(ctx, tx, id)
+	if err != nil {
+		return ls, err
+	} else if currentUserID != 0 && ls.TeacherID != 0 && ls.TeacherID != currentUserID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this live session.")
+	} else if ls.State == api.LiveFinished {
+		return ls, api.Errorf(api.ECONFLICT, "Live session has already finished.")
+	}
+
+	// Update fields.
+	if v := upd.QuestionIndex; v != nil {
+		n, err := countQuizQuestions(ctx, tx, ls.QuizID)
+		if err != nil {
+			return ls, err
+		} else if *v < 0 || *v >= n {
+			return ls, api.Errorf(api.EINVALID, "Question index is out of range.")
+		}
+		ls.QuestionIndex = *v
+	}
+	if v := upd.State; v != nil {
+		ls.State = *v
+		if ls.State == api.LiveQuestion {
+			ls.QuestionStartedAt = tx.now
+		}
+	}
+
+	// Set last updated date to current time.
+	ls.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := ls.Validate(); err != nil {
+		return ls, err
+	}
+
+	var questionStartedAt *time.Time
+	if !ls.QuestionStartedAt.IsZero() {
+		questionStartedAt = &ls.QuestionStartedAt
+	}
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE live_sessions
+		SET state = $1,
+		    question_index = $2,
+		    question_started_at = $3,
+		    updated_at = $4
+		WHERE id = $5
+	`,
+		ls.State,
+		ls.QuestionIndex,
+		questionStartedAt,
+		ls.UpdatedAt,
+		id,
+	); err != nil {
+		return ls, FormatError(err)
+	}
+
+	// Persist the final results as ordinary quiz grades.
+	if ls.State == api.LiveFinished {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE quiz_submissions qs
+			SET grade = (SELECT SUM(r.grade) FROM responses r WHERE r.quiz_submission_id = qs.id),
+			    updated_at = $1
+			WHERE qs.id IN (SELECT quiz_submission_id FROM live_participants WHERE session_id = $2)
+		`,
+			tx.now,
+			id,
+		); err != nil {
+			return ls, FormatError(err)
+		}
+	}
+
+	return ls, nil
+}
+
+// findLiveParticipant returns the first participant matching a single-argument condition.
+// Returns ENOTFOUND if participant does not exist.
+func findLiveParticipant(ctx context.Context, tx *Tx, where string, args ...interface{}) (*api.LiveParticipant, error) {
+	var studentID sql.NullInt32
+
+	var p api.LiveParticipant
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			id,
+			token,
+			joined_at,
+			student_fullname,
+			student_id,
+			session_id,
+			quiz_submission_id
+		FROM live_participants
+		WHERE `+where+`
+	`,
+		args...,
+	).Scan(
+		&p.ID,
+		&p.Token,
+		&p.JoinedAt,
+		&p.StudentFullName,
+		&studentID,
+		&p.SessionID,
+		&p.SubmissionID,
+	); err == sql.ErrNoRows {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Participant not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if studentID.Valid {
+		p.StudentID = int(studentID.Int32)
+	}
+
+	return &p, nil
+}
+
+// joinLiveSession creates a participant together with the quiz submission that
+// will collect their answers.
+func joinLiveSession(ctx context.Context, tx *Tx, p *api.LiveParticipant) error {
+	ls, err := findLiveSessionByID(ctx, tx, p.SessionID)
+	if err != nil {
+		return err
+	} else if ls.State == api.LiveFinished {
+		return api.Errorf(api.ECONFLICT, "Live session has already finished.")
+	}
+
+	// Registered students rejoin as the same participant.
+	if p.StudentID != 0 {
+		if other, err := findLiveParticipant(ctx, tx, `session_id = $1 AND student_id = $2`, p.SessionID, p.StudentID); err == nil {
+			*p = *other
+			return nil
+		} else if api.ErrorCode(err) != api.ENOTFOUND {
+			return err
+		}
+	}
+
+	if p.StudentFullName == "" {
+		return api.Errorf(api.EINVALID, "Full name required.")
+	} else if p.Token == "" {
+		return api.Errorf(api.EINVALID, "Token required.")
+	}
+
+	sub := api.QuizSubmission{QuizID: ls.QuizID}
+	if p.StudentID != 0 {
+		sub.StudentID = p.StudentID
+	} else {
+		sub.StudentFullName = p.StudentFullName
+	}
+	if err := createQuizSubmission(ctx, tx, &sub); err != nil {
+		return err
+	}
+
+	p.SubmissionID = sub.ID
+	p.JoinedAt = tx.now
+
+	var studentID *int
+	if p.StudentID != 0 {
+		studentID = &p.StudentID
+	}
+
+	// Execute insertion query.
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO live_participants (
+			token,
+			joined_at,
+			student_fullname,
+			student_id,
+			session_id,
+			quiz_submission_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`,
+		p.Token,
+		p.JoinedAt,
+		p.StudentFullName,
+		studentID,
+		p.SessionID,
+		p.SubmissionID,
+	)
+
+	if err := row.Scan(&p.ID); err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// answerLiveQuestion stores a graded response to the question currently shown in
+// the participant's session. Correct answers are worth an equal share of the quiz's max grade.
+func answerLiveQuestion(ctx context.Context, tx *Tx, participantID int, rn *api.Response) error {
+	p, err := findLiveParticipant(ctx, tx, `id = $1`, participantID)
+	if err != nil {
+		return err
+	}
+	ls, err := findLiveSessionByID(ctx, tx, p.SessionID)
+	if err != nil {
+		return err
+	} else if ls.State != api.LiveQuestion {
+		return api.Errorf(api.ECONFLICT, "Answers are not accepted right now.")
+	} else if ls.Quiz, err = findQuizByID(ctx, tx, ls.QuizID); err != nil {
+		return err
+	}
+
+	questions, _, err := findQuestions(ctx, tx, api.QuestionFilter{QuizID: &ls.QuizID})
+	if err != nil {
+		return err
+	} else if ls.QuestionIndex >= len(questions) {
+		return api.Errorf(api.ECONFLICT, "Question is not available.")
+	}
+	q := questions[ls.QuestionIndex]
+
+	// Only the first answer to a question counts.
+	if _, n, err := findResponses(ctx, tx, api.ResponseFilter{SubmissionID: &p.SubmissionID, QuestionID: &q.ID}); err != nil {
+		return err
+	} else if n > 0 {
+		return api.Errorf(api.ECONFLICT, "Question has already been answered.")
+	}
+
+	rn.ID = 0
+	rn.Grade = 0
+	rn.Type = int(q.Type)
+	rn.QuestionID = q.ID
+	rn.SubmissionID = p.SubmissionID
+	rn.IsCorrect = q.Check(rn)
+	if rn.IsCorrect {
+		rn.Grade = ls.Quiz.MaxGrade / float32(len(questions))
+	}
+
+	return createResponse(ctx, tx, rn)
+}
+
+// findLiveStats computes the leaderboard of a session and the answer
+// distribution for its current question.
+func findLiveStats(ctx context.Context, tx *Tx, id int) (*api.LiveStats, error) {
+	ls, err := findLiveSessionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &api.LiveStats{Leaderboard: make([]*api.LiveParticipant, 0)}
+
+	// Sum the grades of each participant's answers.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			p.id,
+			p.joined_at,
+			p.student_fullname,
+			p.student_id,
+			p.session_id,
+			p.quiz_submission_id,
+			COALESCE(SUM(r.grade), 0)
+		FROM live_participants p
+		LEFT JOIN responses r ON r.quiz_submission_id = p.quiz_submission_id
+		WHERE p.session_id = $1
+		GROUP BY p.id
+		ORDER BY 7 DESC, p.joined_at ASC
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var studentID sql.NullInt32
+		var score float64
+
+		var p api.LiveParticipant
+		if err := rows.Scan(
+			&p.ID,
+			&p.JoinedAt,
+			&p.StudentFullName,
+			&studentID,
+			&p.SessionID,
+			&p.SubmissionID,
+			&score,
+		); err != nil {
+			return nil, err
+		}
+
+		if studentID.Valid {
+			p.StudentID = int(studentID.Int32)
+		}
+		p.Score = float32(score)
+
+		stats.Leaderboard = append(stats.Leaderboard, &p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Count the answers given to the current question.
+	questions, _, err := findQuestions(ctx, tx, api.QuestionFilter{QuizID: &ls.QuizID})
+	if err != nil {
+		return nil, err
+	} else if ls.QuestionIndex >= len(questions) {
+		return stats, nil
+	}
+	q := questions[ls.QuestionIndex]
+
+	switch q.Type {
+	case api.Single, api.Multiple:
+		stats.Distribution = make([]int, len(q.Choices))
+	case api.Truefalse:
+		stats.Distribution = make([]int, 2)
+	}
+
+	answers, err := tx.QueryContext(ctx, `
+		SELECT
+			r.truefalse_response,
+			r.multiplechoice_response,
+			r.singlechoice_response
+		FROM responses r
+		JOIN live_participants p ON p.quiz_submission_id = r.quiz_submission_id
+		WHERE p.session_id = $1 AND r.question_id = $2
+	`, id, q.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer answers.Close()
+
+	for answers.Next() {
+		var truefalseResponse sql.NullBool
+		var multiplechoiceResponse pgtype.Int4Array
+		var singlechoiceResponse sql.NullInt32
+
+		if err := answers.Scan(
+			&truefalseResponse,
+			&multiplechoiceResponse,
+			&singlechoiceResponse,
+		); err != nil {
+			return nil, err
+		}
+		stats.Answered++
+
+		var choices []int
+		switch q.Type {
+		case api.Single:
+			choices = []int{int(singlechoiceResponse.Int32)}
+		case api.Multiple:
+			if multiplechoiceResponse.Status != pgtype.Null {
+				multiplechoiceResponse.AssignTo(&choices)
+			}
+		case api.Truefalse:
+			if truefalseResponse.Bool {
+				choices = []int{1}
+			} else {
+				choices = []int{0}
+			}
+		}
+
+		for _, c := range choices {
+			if c >= 0 && c < len(stats.Distribution) {
+				stats.Distribution[c]++
+			}
+		}
+	}
+	if err := answers.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// countQuizQuestions returns the number of questions in a quiz.
+func countQuizQuestions(ctx context.Context, tx *Tx, quizID int) (n int, err error) {
+	err = tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM questions WHERE quiz_id = $1`, quizID).Scan(&n)
+	return n, err
+}
+
+// liveChannel is the Postgres notification channel used for live events.
+const liveChannel = "live_quiz"
+
+// Ensure broker implements interface.
+var _ api.LiveBroker = (*LiveBroker)(nil)
+
+// LiveBroker distributes live events between apid instances using Postgres
+// LISTEN/NOTIFY, so clients connected to any instance see the same session.
+type LiveBroker struct {
+	db *DB
+}
+
+// NewLiveBroker returns a new instance of LiveBroker.
+func NewLiveBroker(db *DB) *LiveBroker {
+	return &LiveBroker{db: db}
+}
+
+// Publish sends an event to every instance listening on the live channel.
+func (b *LiveBroker) Publish(ctx context.Context, e api.LiveEvent) error {
+	_, err := b.db.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, liveChannel, fmt.Sprint(e.SessionID))
+	return err
+}
+
+// Subscribe listens on the live channel using a dedicated connection. The
+// connection is re-established if it drops until ctx is done.
+func (b *LiveBroker) Subscribe(ctx context.Context) (<-chan api.LiveEvent, error) {
+	conn, err := b.listen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan api.LiveEvent, 64)
+	go func() {
+		defer close(ch)
+		for {
+			if conn == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Second):
+				}
+				if conn, err = b.listen(ctx); err != nil {
+					log.Printf("live broker: cannot listen: %s", err)
+					continue
+				}
+			}
+
+			n, err := conn.WaitForNotification(ctx)
+			if ctx.Err() != nil {
+				conn.Close(context.Background())
+				return
+			} else if err != nil {
+				log.Printf("live broker: connection lost: %s", err)
+				conn.Close(context.Background())
+				conn = nil
+				continue
+			}
+
+			var e api.LiveEvent
+			if _, err := fmt.Sscan(n.Payload, &e.SessionID); err != nil {
+				continue
+			}
+			select {
+			case ch <- e:
+			case <-ctx.Done():
+				conn.Close(context.Background())
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// listen opens a new connection and subscribes it to the live channel.
+func (b *LiveBroker) listen(ctx context.Context) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, b.db.DSN)
+	if err != nil {
+		return nil, err
+	} else if _, err := conn.Exec(ctx, `LISTEN `+liveChannel); err != nil {
+		conn.Close(ctx)
+		return nil, err
+	}
+	return conn, nil
+}
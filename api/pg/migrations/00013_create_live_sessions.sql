@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS live_sessions
+(
+    id                   serial NOT NULL,
+    code                 VARCHAR(16)  NOT NULL,
+    state                VARCHAR(16)  NOT NULL,
+    question_index       integer      NOT NULL DEFAULT 0,
+    question_started_at  TIMESTAMP    NULL,
+    created_at           TIMESTAMP    NOT NULL,
+    updated_at           TIMESTAMP    NULL,
+    teacher_id           integer  NULL DEFAULT NULL,
+    quiz_id              integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS live_sessions_active_code_idx ON live_sessions (code) WHERE state <> 'finished';
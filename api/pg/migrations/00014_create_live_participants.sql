@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS live_participants
+(
+    id                  serial NOT NULL,
+    token               VARCHAR(64)  NOT NULL UNIQUE,
+    joined_at           TIMESTAMP    NOT NULL,
+    student_fullname    VARCHAR(255) NOT NULL,
+    student_id          integer  NULL DEFAULT NULL,
+    session_id          integer  NOT NULL,
+    quiz_submission_id  integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (session_id, student_id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (session_id) REFERENCES live_sessions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (quiz_submission_id) REFERENCES quiz_submissions(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS surveys
+(
+    id                serial NOT NULL,
+    title             VARCHAR(255) NOT NULL,
+    content           TEXT         NULL,
+    student_link      VARCHAR(255) NOT NULL,
+    teacher_link      VARCHAR(255) NOT NULL,
+    mode              VARCHAR(16)  NOT NULL,
+    min_responses     integer      NOT NULL DEFAULT 5,
+    created_at        TIMESTAMP    NOT NULL,
+    updated_at        TIMESTAMP    NULL,
+    opened_at         TIMESTAMP    NULL,
+    closed_at         TIMESTAMP    NULL,
+    teacher_id        integer  NULL DEFAULT NULL,
+    group_id          integer  NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (student_link, teacher_link),
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
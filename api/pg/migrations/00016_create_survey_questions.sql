@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS survey_questions
+(
+    id                    serial NOT NULL,
+    content               TEXT           NOT NULL,
+    type                  smallint       NOT NULL,
+    choices               VARCHAR(255)[] NOT NULL,
+    survey_id             integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (survey_id) REFERENCES surveys(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,10 @@
+-- Responses deliberately have no reference to the student and only keep the
+-- day of submission so that they cannot be matched against other records.
+CREATE TABLE IF NOT EXISTS survey_responses
+(
+    id                serial NOT NULL,
+    submitted_on      DATE     NOT NULL,
+    survey_id         integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (survey_id) REFERENCES surveys(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS survey_answers
+(
+    id                            serial NOT NULL,
+    open_response                 TEXT         NULL,
+    truefalse_response            BOOLEAN      NULL,
+    multiplechoice_response       integer[]    NULL,
+    singlechoice_response         integer      NULL,
+    survey_response_id            integer  NOT NULL,
+    survey_question_id            integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (survey_response_id, survey_question_id),
+    FOREIGN KEY (survey_response_id) REFERENCES survey_responses(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (survey_question_id) REFERENCES survey_questions(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,9 @@
+-- Blind tokens record that a member has responded without revealing which
+-- response is theirs. They carry no id or timestamp to correlate with responses.
+CREATE TABLE IF NOT EXISTS survey_tokens
+(
+    token             VARCHAR(64)  NOT NULL,
+    survey_id         integer  NOT NULL,
+    PRIMARY KEY (survey_id, token),
+    FOREIGN KEY (survey_id) REFERENCES surveys(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
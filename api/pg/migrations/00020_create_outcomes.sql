@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS outcomes
+(
+    id                serial NOT NULL,
+    code              VARCHAR(32)  NOT NULL,
+    title             VARCHAR(255) NOT NULL,
+    description       TEXT         NULL,
+    created_at        TIMESTAMP    NOT NULL,
+    updated_at        TIMESTAMP    NULL,
+    group_id          integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (group_id, code),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS question_outcomes
+(
+    question_id           integer  NOT NULL,
+    outcome_id            integer  NOT NULL,
+    weight                DECIMAL(5,2) NOT NULL DEFAULT 1,
+    PRIMARY KEY (question_id, outcome_id),
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (outcome_id) REFERENCES outcomes(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS homework_outcomes
+(
+    homework_id           integer  NOT NULL,
+    outcome_id            integer  NOT NULL,
+    weight                DECIMAL(5,2) NOT NULL DEFAULT 1,
+    PRIMARY KEY (homework_id, outcome_id),
+    FOREIGN KEY (homework_id) REFERENCES homeworks(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (outcome_id) REFERENCES outcomes(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS practice_items
+(
+    id                serial NOT NULL,
+    easiness          DOUBLE PRECISION NOT NULL,
+    interval_days     integer      NOT NULL,
+    repetitions       integer      NOT NULL,
+    due_at            TIMESTAMP    NOT NULL,
+    answered_at       TIMESTAMP    NOT NULL,
+    student_id        integer  NOT NULL,
+    question_id       integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (student_id, question_id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS practice_attempts
+(
+    id                serial NOT NULL,
+    is_correct        BOOLEAN      NOT NULL,
+    quality           smallint     NOT NULL,
+    answered_at       TIMESTAMP    NOT NULL,
+    student_id        integer  NOT NULL,
+    question_id       integer  NOT NULL,
+    group_id          integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE ON UPDATE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON DELETE CASCADE ON UPDATE CASCADE
+);
@@ -0,0 +1,4 @@
+ALTER TABLE quizzes
+    ADD COLUMN IF NOT EXISTS access_code     VARCHAR(64)   NULL,
+    ADD COLUMN IF NOT EXISTS allowed_cidrs   VARCHAR(64)[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS single_session  BOOLEAN       NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS quiz_attempts
+(
+    id                   serial NOT NULL,
+    device_token         VARCHAR(64)  NOT NULL,
+    ip                   VARCHAR(64)  NULL,
+    started_at           TIMESTAMP    NOT NULL,
+    last_seen_at         TIMESTAMP    NOT NULL,
+    student_id           integer  NULL DEFAULT NULL,
+    quiz_id              integer  NOT NULL,
+    quiz_submission_id   integer  NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (device_token),
+    UNIQUE (quiz_id, student_id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (quiz_submission_id) REFERENCES quiz_submissions(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
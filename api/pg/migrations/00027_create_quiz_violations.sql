@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS quiz_violations
+(
+    id                serial NOT NULL,
+    reason            VARCHAR(32)  NOT NULL,
+    message           VARCHAR(255) NOT NULL,
+    ip                VARCHAR(64)  NULL,
+    created_at        TIMESTAMP    NOT NULL,
+    student_id        integer  NULL DEFAULT NULL,
+    quiz_id           integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS integrity_events
+(
+    id                   serial NOT NULL,
+    type                 VARCHAR(32)  NOT NULL,
+    detail               VARCHAR(255) NULL,
+    client_at            TIMESTAMP    NULL,
+    created_at           TIMESTAMP    NOT NULL,
+    quiz_attempt_id      integer  NOT NULL,
+    quiz_submission_id   integer  NULL DEFAULT NULL,
+    quiz_id              integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (quiz_attempt_id) REFERENCES quiz_attempts(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (quiz_submission_id) REFERENCES quiz_submissions(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (quiz_id) REFERENCES quizzes(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
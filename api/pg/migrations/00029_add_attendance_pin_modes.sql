@@ -0,0 +1,4 @@
+ALTER TABLE attendances
+    ADD COLUMN IF NOT EXISTS pin_mode    VARCHAR(16)  NOT NULL DEFAULT 'static',
+    ADD COLUMN IF NOT EXISTS pin_secret  VARCHAR(64)  NULL,
+    ADD COLUMN IF NOT EXISTS pin_period  integer      NOT NULL DEFAULT 30;
@@ -0,0 +1,4 @@
+ALTER TABLE att_submissions
+    ADD COLUMN IF NOT EXISTS check_in_method  VARCHAR(16)  NOT NULL DEFAULT 'pin';
+
+UPDATE attendances SET pin_secret = md5(random()::text || id::text) WHERE pin_secret IS NULL;
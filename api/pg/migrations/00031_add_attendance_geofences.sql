@@ -0,0 +1,12 @@
+ALTER TABLE attendances
+    ADD COLUMN IF NOT EXISTS geo_latitude   DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS geo_longitude  DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS geo_radius     DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS geo_policy     VARCHAR(16)       NULL;
+
+ALTER TABLE att_submissions
+    ADD COLUMN IF NOT EXISTS latitude   DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS longitude  DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS accuracy   DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS distance   DOUBLE PRECISION  NULL,
+    ADD COLUMN IF NOT EXISTS flagged    BOOLEAN           NOT NULL DEFAULT FALSE;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS timetables
+(
+    id                serial NOT NULL,
+    timezone          VARCHAR(64)  NOT NULL,
+    term_start        DATE         NOT NULL,
+    term_end          DATE         NOT NULL,
+    holidays          VARCHAR(10)[] NOT NULL DEFAULT '{}',
+    generated_until   DATE         NULL,
+    created_at        TIMESTAMP    NOT NULL,
+    updated_at        TIMESTAMP    NULL,
+    group_id          integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (group_id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
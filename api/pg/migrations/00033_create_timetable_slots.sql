@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS timetable_slots
+(
+    id                serial NOT NULL,
+    weekday           SMALLINT     NOT NULL,
+    start_time        VARCHAR(5)   NOT NULL,
+    end_time          VARCHAR(5)   NOT NULL,
+    title             VARCHAR(255) NULL,
+    timetable_id      integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (timetable_id) REFERENCES timetables(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
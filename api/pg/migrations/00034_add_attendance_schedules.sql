@@ -0,0 +1,6 @@
+ALTER TABLE attendances
+    ADD COLUMN IF NOT EXISTS slot_id       integer  NULL DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS scheduled_on  DATE     NULL,
+    ADD COLUMN IF NOT EXISTS cancelled     BOOLEAN  NOT NULL DEFAULT FALSE,
+    ADD FOREIGN KEY (slot_id) REFERENCES timetable_slots(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    ADD UNIQUE (slot_id, scheduled_on);
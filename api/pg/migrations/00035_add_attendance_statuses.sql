@@ -0,0 +1,16 @@
+ALTER TABLE attendances
+    ADD COLUMN IF NOT EXISTS late_grace          integer    NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS absences_marked_at  TIMESTAMP  NULL;
+
+-- Do not mark absences retroactively for attendances that already closed.
+UPDATE attendances SET absences_marked_at = closed_at WHERE closed_at < now() AT TIME ZONE 'UTC';
+
+ALTER TABLE att_submissions
+    ADD COLUMN IF NOT EXISTS status             VARCHAR(16)  NOT NULL DEFAULT 'present',
+    ADD COLUMN IF NOT EXISTS excuse_reason      TEXT         NULL,
+    ADD COLUMN IF NOT EXISTS excuse_attachment  VARCHAR(40)  NULL,
+    ADD FOREIGN KEY (excuse_attachment) REFERENCES attachments(hash) ON UPDATE CASCADE ON DELETE SET NULL;
+
+UPDATE att_submissions SET status = 'absent' WHERE NOT present;
+
+CREATE INDEX IF NOT EXISTS att_submissions_attendance_student_idx ON att_submissions (attendance_id, student_id);
@@ -0,0 +1,9 @@
+ALTER TABLE attendances
+    ADD COLUMN IF NOT EXISTS one_per_device  BOOLEAN  NOT NULL DEFAULT FALSE;
+
+ALTER TABLE att_submissions
+    ADD COLUMN IF NOT EXISTS device_id   VARCHAR(64)   NULL,
+    ADD COLUMN IF NOT EXISTS user_agent  VARCHAR(512)  NULL,
+    ADD COLUMN IF NOT EXISTS ip          VARCHAR(45)   NULL;
+
+CREATE INDEX IF NOT EXISTS att_submissions_attendance_device_idx ON att_submissions (attendance_id, device_id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS grade_categories
+(
+    id            serial NOT NULL,
+    name          VARCHAR(255)      NOT NULL,
+    kind          VARCHAR(16)       NOT NULL,
+    weight        DOUBLE PRECISION  NOT NULL DEFAULT 1,
+    drop_lowest   integer           NOT NULL DEFAULT 0,
+    created_at    TIMESTAMP         NOT NULL,
+    updated_at    TIMESTAMP         NULL,
+    group_id      integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
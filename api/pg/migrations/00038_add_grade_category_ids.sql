@@ -0,0 +1,11 @@
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS grade_category_id  integer  NULL DEFAULT NULL,
+    ADD FOREIGN KEY (grade_category_id) REFERENCES grade_categories(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+ALTER TABLE quizzes
+    ADD COLUMN IF NOT EXISTS grade_category_id  integer  NULL DEFAULT NULL,
+    ADD FOREIGN KEY (grade_category_id) REFERENCES grade_categories(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS homeworks_group_idx ON homeworks (group_id);
+CREATE INDEX IF NOT EXISTS quizzes_group_idx ON quizzes (group_id);
+CREATE INDEX IF NOT EXISTS attendances_group_idx ON attendances (group_id);
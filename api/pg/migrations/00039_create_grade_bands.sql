@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS grade_bands
+(
+    id           serial NOT NULL,
+    min_percent  DOUBLE PRECISION  NOT NULL,
+    letter       VARCHAR(8)        NOT NULL,
+    points       DOUBLE PRECISION  NOT NULL DEFAULT 0,
+    group_id     integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (group_id, min_percent),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS final_grade_snapshots
+(
+    id          serial NOT NULL,
+    locked      BOOLEAN    NOT NULL DEFAULT FALSE,
+    created_at  TIMESTAMP  NOT NULL,
+    group_id    integer  NOT NULL,
+    teacher_id  integer  NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS final_grades
+(
+    snapshot_id  integer  NOT NULL,
+    student_id   integer  NOT NULL,
+    percent      DOUBLE PRECISION  NULL,
+    letter       VARCHAR(8)        NOT NULL DEFAULT '',
+    points       DOUBLE PRECISION  NULL,
+    PRIMARY KEY (snapshot_id, student_id),
+    FOREIGN KEY (snapshot_id) REFERENCES final_grade_snapshots(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (student_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
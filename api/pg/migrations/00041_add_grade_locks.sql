@@ -0,0 +1,24 @@
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS grades_locked_at  TIMESTAMP  NULL DEFAULT NULL;
+
+ALTER TABLE quizzes
+    ADD COLUMN IF NOT EXISTS grades_locked_at  TIMESTAMP  NULL DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS grade_overrides
+(
+    id             serial NOT NULL,
+    kind           VARCHAR(16)    NOT NULL,
+    item_id        integer        NOT NULL,
+    submission_id  integer        NOT NULL,
+    old_grade      DECIMAL(5,2)   NULL,
+    new_grade      DECIMAL(5,2)   NULL,
+    reason         TEXT           NOT NULL,
+    created_at     TIMESTAMP      NOT NULL,
+    group_id       integer  NOT NULL,
+    teacher_id     integer  NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS grade_overrides_group_idx ON grade_overrides (group_id);
@@ -0,0 +1,16 @@
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS grades_release_at     TIMESTAMP  NULL DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS grades_release_notify BOOLEAN    NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS grades_notified_at    TIMESTAMP  NULL DEFAULT NULL;
+
+ALTER TABLE quizzes
+    ADD COLUMN IF NOT EXISTS grades_release_at     TIMESTAMP  NULL DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS grades_release_notify BOOLEAN    NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS grades_notified_at    TIMESTAMP  NULL DEFAULT NULL;
+
+-- Grades were visible as soon as they were entered, so keep them visible.
+UPDATE homeworks SET grades_release_at = created_at WHERE grades_release_at IS NULL;
+UPDATE quizzes SET grades_release_at = created_at WHERE grades_release_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS homeworks_grades_notify_idx ON homeworks (grades_release_at) WHERE grades_release_notify AND grades_notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS quizzes_grades_notify_idx ON quizzes (grades_release_at) WHERE grades_release_notify AND grades_notified_at IS NULL;
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS notifications
+(
+    id          serial NOT NULL,
+    kind        VARCHAR(32)  NOT NULL,
+    message     TEXT         NOT NULL,
+    item_kind   VARCHAR(16)  NULL,
+    item_id     integer      NULL,
+    created_at  TIMESTAMP    NOT NULL,
+    read_at     TIMESTAMP    NULL,
+    user_id     integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, created_at);
@@ -0,0 +1,13 @@
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS due_at            TIMESTAMP     NULL DEFAULT NULL,
+    ADD COLUMN IF NOT EXISTS grace_minutes     integer       NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS late_policy       VARCHAR(16)   NOT NULL DEFAULT 'none',
+    ADD COLUMN IF NOT EXISTS late_penalty      DECIMAL(5,2)  NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS late_penalty_cap  DECIMAL(5,2)  NOT NULL DEFAULT 0;
+
+ALTER TABLE hw_submissions
+    ADD COLUMN IF NOT EXISTS is_late       BOOLEAN       NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS late_seconds  integer       NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS raw_grade     DECIMAL(5,2)  NULL;
+
+UPDATE hw_submissions SET raw_grade = grade WHERE raw_grade IS NULL;
@@ -0,0 +1,26 @@
+ALTER TABLE quizzes
+    ADD COLUMN IF NOT EXISTS time_limit  integer  NOT NULL DEFAULT 0;
+
+ALTER TABLE students_groups
+    ADD COLUMN IF NOT EXISTS time_multiplier      DOUBLE PRECISION  NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS extension_hours      integer           NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS accommodation_notes  TEXT              NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS accommodated_at      TIMESTAMP         NULL DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS deadline_overrides
+(
+    id            serial NOT NULL,
+    item_kind     VARCHAR(16)  NOT NULL,
+    item_id       integer      NOT NULL,
+    opened_at     TIMESTAMP    NULL DEFAULT NULL,
+    due_at        TIMESTAMP    NULL DEFAULT NULL,
+    closed_at     TIMESTAMP    NULL DEFAULT NULL,
+    time_limit    integer      NOT NULL DEFAULT 0,
+    reason        TEXT         NOT NULL,
+    created_at    TIMESTAMP    NOT NULL,
+    updated_at    TIMESTAMP    NULL,
+    student_id    integer  NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (item_kind, item_id, student_id),
+    FOREIGN KEY (student_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
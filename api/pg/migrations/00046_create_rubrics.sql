@@ -0,0 +1,52 @@
+CREATE TABLE IF NOT EXISTS rubrics
+(
+    id            serial NOT NULL,
+    title         VARCHAR(255)  NOT NULL,
+    description   TEXT          NOT NULL DEFAULT '',
+    created_at    TIMESTAMP     NOT NULL,
+    updated_at    TIMESTAMP     NULL,
+    teacher_id    integer  NOT NULL,
+    group_id      integer  NULL DEFAULT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (group_id) REFERENCES groups(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS rubric_criteria
+(
+    id            serial NOT NULL,
+    title         VARCHAR(255)  NOT NULL,
+    description   TEXT          NOT NULL DEFAULT '',
+    position      integer       NOT NULL,
+    rubric_id     integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (rubric_id) REFERENCES rubrics(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS rubric_levels
+(
+    id            serial NOT NULL,
+    title         VARCHAR(255)  NOT NULL,
+    descriptor    TEXT          NOT NULL DEFAULT '',
+    points        DECIMAL(6,2)  NOT NULL,
+    position      integer       NOT NULL,
+    criterion_id  integer  NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (criterion_id) REFERENCES rubric_criteria(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS rubric_id  integer  NULL DEFAULT NULL REFERENCES rubrics(id) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS hw_rubric_scores
+(
+    hw_submission_id  integer  NOT NULL,
+    criterion_id      integer  NOT NULL,
+    level_id          integer  NOT NULL,
+    points            DECIMAL(6,2)  NOT NULL,
+    comment           TEXT          NOT NULL DEFAULT '',
+    PRIMARY KEY (hw_submission_id, criterion_id),
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (criterion_id) REFERENCES rubric_criteria(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (level_id) REFERENCES rubric_levels(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
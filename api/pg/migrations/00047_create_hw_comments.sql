@@ -0,0 +1,56 @@
+CREATE TABLE IF NOT EXISTS hw_comments
+(
+    id                serial NOT NULL,
+    body              TEXT       NOT NULL,
+    created_at        TIMESTAMP  NOT NULL,
+    updated_at        TIMESTAMP  NULL,
+    parent_id         integer    NULL DEFAULT NULL,
+    author_id         integer    NOT NULL,
+    hw_submission_id  integer    NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (parent_id) REFERENCES hw_comments(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS hw_comments_hw_submission_idx ON hw_comments (hw_submission_id, created_at);
+
+CREATE TABLE IF NOT EXISTS hw_comment_attachments
+(
+    comment_id       integer      NOT NULL,
+    attachment_hash  VARCHAR(40)  NOT NULL,
+    filename         VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (comment_id, attachment_hash),
+    FOREIGN KEY (comment_id) REFERENCES hw_comments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (attachment_hash) REFERENCES attachments(hash) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS hw_comment_edits
+(
+    id          serial NOT NULL,
+    body        TEXT       NOT NULL,
+    edited_at   TIMESTAMP  NOT NULL,
+    comment_id  integer    NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (comment_id) REFERENCES hw_comments(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS hw_comment_reads
+(
+    hw_submission_id  integer    NOT NULL,
+    user_id           integer    NOT NULL,
+    read_at           TIMESTAMP  NOT NULL,
+    PRIMARY KEY (hw_submission_id, user_id),
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Start the threads with the feedback teachers left in the old comments field.
+INSERT INTO hw_comments (body, created_at, updated_at, author_id, hw_submission_id)
+SELECT s.comments, COALESCE(s.updated_at, s.submitted_at), NULL, h.teacher_id, s.id
+FROM hw_submissions s
+JOIN homeworks h ON h.id = s.homework_id
+WHERE s.comments <> '' AND h.teacher_id IS NOT NULL;
+
+ALTER TABLE hw_submissions
+    ALTER COLUMN comments TYPE TEXT;
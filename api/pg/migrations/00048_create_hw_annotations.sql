@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS hw_annotations
+(
+    id                serial NOT NULL,
+    start_offset      integer    NOT NULL,
+    end_offset        integer    NOT NULL,
+    quote             TEXT       NOT NULL,
+    comment           TEXT       NOT NULL,
+    created_at        TIMESTAMP  NOT NULL,
+    updated_at        TIMESTAMP  NULL,
+    teacher_id        integer    NOT NULL,
+    hw_submission_id  integer    NOT NULL,
+    PRIMARY KEY (id),
+    CHECK (start_offset >= 0 AND end_offset > start_offset),
+    FOREIGN KEY (teacher_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS hw_annotations_hw_submission_idx ON hw_annotations (hw_submission_id);
@@ -0,0 +1,27 @@
+ALTER TABLE homeworks
+    ADD COLUMN IF NOT EXISTS max_resubmissions  integer  NOT NULL DEFAULT 0;
+
+ALTER TABLE hw_submissions
+    ADD COLUMN IF NOT EXISTS version         integer  NOT NULL DEFAULT 1,
+    ADD COLUMN IF NOT EXISTS graded_version  integer  NULL;
+
+UPDATE hw_submissions SET graded_version = version WHERE grade IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS hw_submission_versions
+(
+    id                serial NOT NULL,
+    number            integer    NOT NULL,
+    response          TEXT       NOT NULL,
+    submitted_at      TIMESTAMP  NOT NULL,
+    is_late           BOOLEAN    NOT NULL DEFAULT FALSE,
+    late_seconds      integer    NOT NULL DEFAULT 0,
+    hw_submission_id  integer    NOT NULL,
+    PRIMARY KEY (id),
+    UNIQUE (hw_submission_id, number),
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+-- Keep the current responses as the first versions.
+INSERT INTO hw_submission_versions (number, response, submitted_at, is_late, late_seconds, hw_submission_id)
+SELECT 1, COALESCE(response, ''), COALESCE(updated_at, submitted_at), is_late, late_seconds, id
+FROM hw_submissions;
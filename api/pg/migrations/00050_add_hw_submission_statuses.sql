@@ -0,0 +1,19 @@
+ALTER TABLE hw_submissions
+    ADD COLUMN IF NOT EXISTS status  VARCHAR(16)  NOT NULL DEFAULT 'submitted';
+
+UPDATE hw_submissions SET status = 'graded' WHERE grade IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS hw_submissions_homework_status_idx ON hw_submissions (homework_id, status);
+
+CREATE TABLE IF NOT EXISTS hw_submission_transitions
+(
+    id                serial NOT NULL,
+    from_status       VARCHAR(16)  NULL,
+    to_status         VARCHAR(16)  NOT NULL,
+    transitioned_at   TIMESTAMP    NOT NULL,
+    user_id           integer      NULL DEFAULT NULL,
+    hw_submission_id  integer      NOT NULL,
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    FOREIGN KEY (hw_submission_id) REFERENCES hw_submissions(id) ON UPDATE CASCADE ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS homework_attachments
+(
+    homework_id      integer      NOT NULL,
+    attachment_hash  VARCHAR(40)  NOT NULL,
+    filename         VARCHAR(255) NOT NULL DEFAULT '',
+    content_type     VARCHAR(255) NOT NULL DEFAULT '',
+    created_at       TIMESTAMP    NOT NULL,
+    PRIMARY KEY (homework_id, attachment_hash),
+    FOREIGN KEY (homework_id) REFERENCES homeworks(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    FOREIGN KEY (attachment_hash) REFERENCES attachments(hash) ON UPDATE CASCADE ON DELETE CASCADE
+);
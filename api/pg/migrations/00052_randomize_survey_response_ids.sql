@@ -0,0 +1,8 @@
+-- Responses and answers get random IDs so that their order cannot be matched
+-- with the order in which blind tokens were recorded. IDs stay below 2^53 so
+-- that they are exact in JSON clients.
+ALTER TABLE survey_answers ALTER COLUMN survey_response_id TYPE bigint;
+ALTER TABLE survey_responses ALTER COLUMN id TYPE bigint;
+ALTER TABLE survey_responses ALTER COLUMN id SET DEFAULT floor(random() * 9007199254740990)::bigint + 1;
+ALTER TABLE survey_answers ALTER COLUMN id TYPE bigint;
+ALTER TABLE survey_answers ALTER COLUMN id SET DEFAULT floor(random() * 9007199254740990)::bigint + 1;
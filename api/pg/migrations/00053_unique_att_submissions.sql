@@ -0,0 +1,11 @@
+-- Students check in to an attendance at most once. Keep the first of any
+-- duplicate check-ins.
+DELETE FROM att_submissions a
+USING att_submissions b
+WHERE a.attendance_id = b.attendance_id
+  AND a.student_id = b.student_id
+  AND a.id > b.id;
+
+DROP INDEX IF EXISTS att_submissions_attendance_student_idx;
+CREATE UNIQUE INDEX IF NOT EXISTS att_submissions_attendance_student_idx ON att_submissions (attendance_id, student_id)
+    WHERE student_id IS NOT NULL;
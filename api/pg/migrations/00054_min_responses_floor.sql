@@ -0,0 +1,2 @@
+-- Results of a survey question are never shown with fewer than five answers.
+UPDATE surveys SET min_responses = 5 WHERE min_responses < 5;
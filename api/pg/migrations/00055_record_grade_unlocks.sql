@@ -0,0 +1,4 @@
+-- Unlocking the grades of an item is recorded as an override without a
+-- submission.
+ALTER TABLE grade_overrides
+    ALTER COLUMN submission_id DROP NOT NULL;
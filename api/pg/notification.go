@@ -0,0 +1,148 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.NotificationService = (*NotificationService)(nil)
+
+// NotificationService represents a service for managing notifications.
+type NotificationService struct {
+	db *DB
+}
+
+// NewNotificationService returns a new instance of NotificationService.
+func NewNotificationService(db *DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// FindNotifications retrieves the notifications of the current user by filter.
+func (s *NotificationService) FindNotifications(ctx context.Context, filter api.NotificationFilter) ([]*api.Notification, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return nil, 0, api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+	return findNotifications(ctx, tx, userID, filter)
+}
+
+// MarkNotificationsRead marks notifications of the current user as read.
+func (s *NotificationService) MarkNotificationsRead(ctx context.Context, ids []int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := markNotificationsRead(ctx, tx, ids); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// findNotifications returns the notifications of a user matching the filter,
+// newest first.
+func findNotifications(ctx context.Context, tx *Tx, userID int, filter api.NotificationFilter) (_ []*api.Notification, n int, err error) {
+	// Build WHERE clause.
+	where, args := []string{"user_id = $1"}, []interface{}{userID}
+	if v := filter.Unread; v != nil {
+		if *v {
+			where = append(where, "read_at IS NULL")
+		} else {
+			where = append(where, "read_at IS NOT NULL")
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			kind,
+			message,
+			item_kind,
+			item_id,
+			created_at,
+			read_at,
+			user_id,
+			COUNT(*) OVER()
+		FROM notifications
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY created_at DESC, id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	notifications := make([]*api.Notification, 0)
+	for rows.Next() {
+		var itemKind sql.NullString
+		var itemID sql.NullInt32
+		var readAt sql.NullTime
+
+		var nt api.Notification
+		if err := rows.Scan(
+			&nt.ID,
+			&nt.Kind,
+			&nt.Message,
+			&itemKind,
+			&itemID,
+			&nt.CreatedAt,
+			&readAt,
+			&nt.UserID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		nt.ItemKind = itemKind.String
+		nt.ItemID = int(itemID.Int32)
+		nt.ReadAt = readAt.Time
+
+		notifications = append(notifications, &nt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	return notifications, n, nil
+}
+
+// markNotificationsRead sets the read time of unread notifications of the
+// current user, limited to ids unless it is empty.
+func markNotificationsRead(ctx context.Context, tx *Tx, ids []int) error {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+
+	where, args := []string{"user_id = $1", "read_at IS NULL"}, []interface{}{userID, tx.now}
+	if len(ids) > 0 {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			placeholders[i] = fmt.Sprintf("$%d", i+3)
+			args = append(args, id)
+		}
+		where = append(where, "id IN ("+strings.Join(placeholders, ", ")+")")
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE notifications
+		SET read_at = $2
+		WHERE `+strings.Join(where, " AND "),
+		args...,
+	); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
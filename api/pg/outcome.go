@@ -0,0 +1,593 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.OutcomeService = (*OutcomeService)(nil)
+
+// OutcomeService represents a service for managing learning outcomes.
+type OutcomeService struct {
+	db *DB
+}
+
+// NewOutcomeService returns a new instance of OutcomeService.
+func NewOutcomeService(db *DB) *OutcomeService {
+	return &OutcomeService{db: db}
+}
+
+// FindOutcomeByID retrieves an outcome by ID along with its tags.
+// Returns ENOTFOUND if outcome does not exist.
+func (s *OutcomeService) FindOutcomeByID(ctx context.Context, id int) (*api.Outcome, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := findOutcomeByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if err := attachOutcomeAssociations(ctx, tx, o); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// FindOutcomes retrieves a list of outcomes by filter. Also returns total count of
+// matching outcomes which may differ from returned results if filter.Limit is specified.
+func (s *OutcomeService) FindOutcomes(ctx context.Context, filter api.OutcomeFilter) ([]*api.Outcome, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findOutcomes(ctx, tx, filter)
+}
+
+// CreateOutcome creates a new outcome. Returns EUNAUTHORIZED if current user
+// does not teach in the group.
+func (s *OutcomeService) CreateOutcome(ctx context.Context, o *api.Outcome) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createOutcome(ctx, tx, o); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateOutcome updates an outcome object. Returns EUNAUTHORIZED if current user
+// does not teach in the group. Returns ENOTFOUND if outcome does not exist.
+func (s *OutcomeService) UpdateOutcome(ctx context.Context, id int, upd api.OutcomeUpdate) (*api.Outcome, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	o, err := updateOutcome(ctx, tx, id, upd)
+	if err != nil {
+		return o, err
+	} else if err := tx.Commit(); err != nil {
+		return o, err
+	}
+	return o, nil
+}
+
+// DeleteOutcome permanently deletes an outcome along with its tags.
+// Returns EUNAUTHORIZED if current user does not teach in the group.
+func (s *OutcomeService) DeleteOutcome(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteOutcome(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// TagOutcome tags a question or homework with an outcome, replacing the weight
+// if it is already tagged.
+func (s *OutcomeService) TagOutcome(ctx context.Context, tag *api.OutcomeTag) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := tagOutcome(ctx, tx, tag); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UntagOutcome removes a tag from a question or homework.
+func (s *OutcomeService) UntagOutcome(ctx context.Context, tag *api.OutcomeTag) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := untagOutcome(ctx, tx, tag); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindMasteryReport computes the mastery of the students of a group.
+func (s *OutcomeService) FindMasteryReport(ctx context.Context, filter api.MasteryFilter) (*api.MasteryReport, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findMasteryReport(ctx, tx, filter)
+}
+
+// findOutcomeByID is a helper function to fetch an outcome by ID.
+// Returns ENOTFOUND if outcome does not exist.
+func findOutcomeByID(ctx context.Context, tx *Tx, id int) (*api.Outcome, error) {
+	a, _, err := findOutcomes(ctx, tx, api.OutcomeFilter{ID: &id})
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Outcome not found."}
+	}
+	return a[0], nil
+}
+
+// findOutcomes returns a list of outcomes matching a filter. Also returns a count of
+// total matching outcomes which may differ if filter.Limit is set.
+func findOutcomes(ctx context.Context, tx *Tx, filter api.OutcomeFilter) (_ []*api.Outcome, n int, err error) {
+	// Build WHERE clause.
+	where, args := []string{"1 = 1"}, []interface{}{}
+	i := 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.Code; v != nil {
+		where, args = append(where, fmt.Sprintf("code = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.GroupID; v != nil {
+		where, args = append(where, fmt.Sprintf("group_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	// Execute query to fetch outcome rows.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			code,
+			title,
+			description,
+			created_at,
+			updated_at,
+			group_id,
+			COUNT(*) OVER()
+		FROM outcomes
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY code ASC, id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	// Deserialize rows into Outcome objects.
+	outcomes := make([]*api.Outcome, 0)
+	for rows.Next() {
+		var description sql.NullString
+		var updatedAt sql.NullTime
+
+		var o api.Outcome
+		if err := rows.Scan(
+			&o.ID,
+			&o.Code,
+			&o.Title,
+			&description,
+			&o.CreatedAt,
+			&updatedAt,
+			&o.GroupID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		if description.Valid {
+			o.Description = description.String
+		}
+		if updatedAt.Valid {
+			o.UpdatedAt = updatedAt.Time
+		}
+
+		outcomes = append(outcomes, &o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return outcomes, n, nil
+}
+
+// createOutcome creates a new outcome. Sets the new database ID to o.ID and sets
+// the timestamps to the current time.
+func createOutcome(ctx context.Context, tx *Tx, o *api.Outcome) error {
+	// Set timestamps to the current time.
+	o.CreatedAt = tx.now
+	o.UpdatedAt = o.CreatedAt
+
+	// Perform basic field validation.
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	if ok, err := isGroupTeacher(ctx, tx, o.GroupID, api.UserIDFromContext(ctx)); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of this group.")
+	}
+
+	// Description is nullable so ensure we store blank fields as NULLs.
+	var description *string
+	if o.Description != "" {
+		description = &o.Description
+	}
+
+	// Execute insertion query.
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO outcomes (
+			code,
+			title,
+			description,
+			created_at,
+			updated_at,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (group_id, code) DO NOTHING
+		RETURNING id
+	`,
+		o.Code,
+		o.Title,
+		description,
+		o.CreatedAt,
+		o.UpdatedAt,
+		o.GroupID,
+	)
+
+	if err := row.Scan(&o.ID); err == sql.ErrNoRows {
+		return api.Errorf(api.ECONFLICT, "Outcome code is already used in the group.")
+	} else if err != nil {
+		return FormatError(err)
+	}
+
+	return nil
+}
+
+// updateOutcome updates fields on an outcome object. Returns EUNAUTHORIZED if
+// current user does not teach in the group.
+func updateOutcome(ctx context.Context, tx *Tx, id int, upd api.OutcomeUpdate) (*api.Outcome, error) {
+	// Fetch current object state.
+	o, err := findOutcomeByID(ctx, tx, id)
+	if err != nil {
+		return o, err
+	} else if ok, err := isGroupTeacher(ctx, tx, o.GroupID, api.UserIDFromContext(ctx)); err != nil {
+		return o, err
+	} else if !ok {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this outcome.")
+	}
+
+	// Update fields.
+	if v := upd.Code; v != nil {
+		o.Code = *v
+	}
+	if v := upd.Title; v != nil {
+		o.Title = *v
+	}
+	if v := upd.Description; v != nil {
+		o.Description = *v
+	}
+
+	// Set last updated date to current time.
+	o.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := o.Validate(); err != nil {
+		return o, err
+	}
+
+	var description *string
+	if o.Description != "" {
+		description = &o.Description
+	}
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE outcomes
+		SET code = $1,
+		    title = $2,
+		    description = $3,
+		    updated_at = $4
+		WHERE id = $5
+	`,
+		o.Code,
+		o.Title,
+		description,
+		o.UpdatedAt,
+		id,
+	); err != nil {
+		return o, FormatError(err)
+	}
+
+	return o, nil
+}
+
+// deleteOutcome permanently removes an outcome by ID. Returns EUNAUTHORIZED if
+// current user does not teach in the group.
+func deleteOutcome(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists.
+	if o, err := findOutcomeByID(ctx, tx, id); err != nil {
+		return err
+	} else if ok, err := isGroupTeacher(ctx, tx, o.GroupID, api.UserIDFromContext(ctx)); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this outcome.")
+	}
+
+	// Remove row from database.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM outcomes WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// checkOutcomeTag verifies that the current user teaches in the group of the
+// outcome and that the tagged work belongs to that group.
+func checkOutcomeTag(ctx context.Context, tx *Tx, tag *api.OutcomeTag) error {
+	o, err := findOutcomeByID(ctx, tx, tag.OutcomeID)
+	if err != nil {
+		return err
+	} else if ok, err := isGroupTeacher(ctx, tx, o.GroupID, api.UserIDFromContext(ctx)); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to tag with this outcome.")
+	}
+
+	var groupID int
+	if tag.QuestionID != 0 {
+		q, err := findQuestionByID(ctx, tx, tag.QuestionID)
+		if err != nil {
+			return err
+		}
+		qz, err := findQuizByID(ctx, tx, q.QuizID)
+		if err != nil {
+			return err
+		}
+		groupID = qz.GroupID
+	} else {
+		hw, err := findHomeworkByID(ctx, tx, tag.HomeworkID)
+		if err != nil {
+			return err
+		}
+		groupID = hw.GroupID
+	}
+
+	if groupID != o.GroupID {
+		return api.Errorf(api.EINVALID, "Work does not belong to the group of the outcome.")
+	}
+	return nil
+}
+
+// tagOutcome links a question or homework to an outcome.
+func tagOutcome(ctx context.Context, tx *Tx, tag *api.OutcomeTag) error {
+	if tag.Weight == 0 {
+		tag.Weight = 1
+	}
+	if err := tag.Validate(); err != nil {
+		return err
+	} else if err := checkOutcomeTag(ctx, tx, tag); err != nil {
+		return err
+	}
+
+	var err error
+	if tag.QuestionID != 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO question_outcomes (question_id, outcome_id, weight)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (question_id, outcome_id) DO UPDATE SET weight = EXCLUDED.weight
+		`, tag.QuestionID, tag.OutcomeID, tag.Weight)
+	} else {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO homework_outcomes (homework_id, outcome_id, weight)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (homework_id, outcome_id) DO UPDATE SET weight = EXCLUDED.weight
+		`, tag.HomeworkID, tag.OutcomeID, tag.Weight)
+	}
+	return FormatError(err)
+}
+
+// untagOutcome removes the link between a question or homework and an outcome.
+func untagOutcome(ctx context.Context, tx *Tx, tag *api.OutcomeTag) error {
+	if err := checkOutcomeTag(ctx, tx, tag); err != nil {
+		return err
+	}
+
+	var err error
+	if tag.QuestionID != 0 {
+		_, err = tx.ExecContext(ctx, `DELETE FROM question_outcomes WHERE question_id = $1 AND outcome_id = $2`, tag.QuestionID, tag.OutcomeID)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM homework_outcomes WHERE homework_id = $1 AND outcome_id = $2`, tag.HomeworkID, tag.OutcomeID)
+	}
+	return FormatError(err)
+}
+
+// findMasteryReport computes the mastery of every student of a group on every
+// outcome of the group.
+//
+// A response counts as its grade divided by an equal share of the quiz's max
+// grade, or as 0 or 1 if it was only checked automatically. Ungraded work,
+// including open answers that cannot be checked automatically, is ignored so
+// that it does not lower the mastery. Students do not see grades that have not
+// been released yet.
+func findMasteryReport(ctx context.Context, tx *Tx, filter api.MasteryFilter) (*api.MasteryReport, error) {
+	currentUserID := api.UserIDFromContext(ctx)
+	isTeacher, err := isGroupTeacher(ctx, tx, filter.GroupID, currentUserID)
+	if err != nil {
+		return nil, err
+	} else if !isTeacher && (filter.StudentID == nil || *filter.StudentID != currentUserID) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view this report.")
+	}
+
+	report := &api.MasteryReport{GroupID: filter.GroupID}
+
+	if report.Outcomes, _, err = findOutcomes(ctx, tx, api.OutcomeFilter{GroupID: &filter.GroupID}); err != nil {
+		return nil, err
+	}
+	isStudent := false
+	if report.Students, _, err = findUsersByGroup(ctx, tx, api.MemberFilter{GroupID: &filter.GroupID, IsTeacher: &isStudent, UserID: filter.StudentID}); err != nil {
+		return nil, err
+	}
+
+	// Index rows and columns of the report.
+	studentIndex := make(map[int]int, len(report.Students))
+	for i, u := range report.Students {
+		studentIndex[u.ID] = i
+	}
+	outcomeIndex := make(map[int]int, len(report.Outcomes))
+	for j, o := range report.Outcomes {
+		outcomeIndex[o.ID] = j
+	}
+	report.Cells = make([][]*float64, len(report.Students))
+	for i := range report.Cells {
+		report.Cells[i] = make([]*float64, len(report.Outcomes))
+	}
+	report.ClassAverages = make([]*float64, len(report.Outcomes))
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH evidence AS (
+			SELECT
+				qs.student_id,
+				qo.outcome_id,
+				qo.weight,
+				CASE
+					WHEN r.grade IS NOT NULL AND qz.max_grade > 0
+						THEN LEAST(r.grade / (qz.max_grade / (SELECT COUNT(*) FROM questions WHERE quiz_id = qz.id)), 1)
+					WHEN r.is_correct IS NOT NULL AND q.type <> $2
+						THEN CASE WHEN r.is_correct THEN 1 ELSE 0 END
+				END AS score
+			FROM responses r
+			JOIN questions q ON q.id = r.question_id
+			JOIN quiz_submissions qs ON qs.id = r.quiz_submission_id
+			JOIN quizzes qz ON qz.id = qs.quiz_id
+			JOIN question_outcomes qo ON qo.question_id = r.question_id
+			JOIN outcomes o ON o.id = qo.outcome_id
+			WHERE o.group_id = $1 AND qs.student_id IS NOT NULL
+				AND ($3 OR qz.grades_release_at <= $4)
+
+			UNION ALL
+
+			SELECT
+				hs.student_id,
+				ho.outcome_id,
+				ho.weight,
+				LEAST(hs.grade / h.max_grade, 1) AS score
+			FROM hw_submissions hs
+			JOIN homeworks h ON h.id = hs.homework_id
+			JOIN homework_outcomes ho ON ho.homework_id = h.id
+			JOIN outcomes o ON o.id = ho.outcome_id
+			WHERE o.group_id = $1 AND hs.student_id IS NOT NULL AND hs.grade IS NOT NULL AND h.max_grade > 0
+				AND ($3 OR h.grades_release_at <= $4)
+		)
+		SELECT
+			student_id,
+			outcome_id,
+			SUM(score * weight) / SUM(weight)
+		FROM evidence
+		WHERE score IS NOT NULL
+		GROUP BY student_id, outcome_id
+	`, filter.GroupID, api.Open, isTeacher, tx.now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var studentID, outcomeID int
+		var mastery float64
+		if err := rows.Scan(&studentID, &outcomeID, &mastery); err != nil {
+			return nil, err
+		}
+
+		i, ok := studentIndex[studentID]
+		if !ok {
+			continue
+		}
+		j, ok := outcomeIndex[outcomeID]
+		if !ok {
+			continue
+		}
+		report.Cells[i][j] = &mastery
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Average each outcome over the students with graded work.
+	for j := range report.Outcomes {
+		var sum float64
+		var n int
+		for i := range report.Students {
+			if v := report.Cells[i][j]; v != nil {
+				sum += *v
+				n++
+			}
+		}
+		if n > 0 {
+			avg := sum / float64(n)
+			report.ClassAverages[j] = &avg
+		}
+	}
+
+	return report, nil
+}
+
+// attachOutcomeAssociations attaches the tags of the outcome.
+func attachOutcomeAssociations(ctx context.Context, tx *Tx, o *api.Outcome) error {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT question_id, 0, weight FROM question_outcomes WHERE outcome_id = $1
+		UNION ALL
+		SELECT 0, homework_id, weight FROM homework_outcomes WHERE outcome_id = $1
+	`, o.ID)
+	if err != nil {
+		return fmt.Errorf("attach outcome tags: %w", err)
+	}
+	defer rows.Close()
+
+	o.Tags = make([]*api.OutcomeTag, 0)
+	for rows.Next() {
+		tag := api.OutcomeTag{OutcomeID: o.ID}
+		if err := rows.Scan(&tag.QuestionID, &tag.HomeworkID, &tag.Weight); err != nil {
+			return fmt.Errorf("attach outcome tags: %w", err)
+		}
+		o.Tags = append(o.Tags, &tag)
+	}
+	return rows.Err()
+}
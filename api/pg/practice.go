@@ -0,0 +1,315 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.PracticeService = (*PracticeService)(nil)
+
+// PracticeService represents a service for practicing questions of a question bank.
+type PracticeService struct {
+	db *DB
+}
+
+// NewPracticeService returns a new instance of PracticeService.
+func NewPracticeService(db *DB) *PracticeService {
+	return &PracticeService{db: db}
+}
+
+// NextPracticeQuestion returns the next question the current student should practice.
+// Returns ENOTFOUND if the bank is empty.
+func (s *PracticeService) NextPracticeQuestion(ctx context.Context, groupID int) (*api.Question, *api.PracticeItem, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	return nextPracticeQuestion(ctx, tx, groupID)
+}
+
+// AnswerPracticeQuestion checks an answer of the current student and reschedules the question.
+func (s *PracticeService) AnswerPracticeQuestion(ctx context.Context, groupID int, rn *api.Response) (*api.PracticeResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := answerPracticeQuestion(ctx, tx, groupID, rn)
+	if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindPracticeAttempts retrieves the practice history of the current student.
+func (s *PracticeService) FindPracticeAttempts(ctx context.Context, filter api.PracticeAttemptFilter) ([]*api.PracticeAttempt, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findPracticeAttempts(ctx, tx, filter)
+}
+
+// checkPracticeAccess returns the current user ID if they are a student of the group.
+func checkPracticeAccess(ctx context.Context, tx *Tx, groupID int) (int, error) {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return 0, api.Errorf(api.EUNAUTHORIZED, "You must be logged in to practice.")
+	} else if ok, err := isGroupStudent(ctx, tx, groupID, userID); err != nil {
+		return 0, err
+	} else if !ok {
+		return 0, api.Errorf(api.EUNAUTHORIZED, "You are not in the group.")
+	}
+	return userID, nil
+}
+
+// nextPracticeQuestion picks the next question from the bank of a group. Only
+// questions of closed quizzes are used so that practice never reveals the
+// answers of a quiz that is still running.
+func nextPracticeQuestion(ctx context.Context, tx *Tx, groupID int) (*api.Question, *api.PracticeItem, error) {
+	userID, err := checkPracticeAccess(ctx, tx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var questionID int
+	var itemID sql.NullInt32
+	var easiness sql.NullFloat64
+	var interval, repetitions sql.NullInt32
+	var dueAt, answeredAt sql.NullTime
+
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			q.id,
+			pi.id,
+			pi.easiness,
+			pi.interval_days,
+			pi.repetitions,
+			pi.due_at,
+			pi.answered_at
+		FROM questions q
+		JOIN quizzes qz ON qz.id = q.quiz_id
+		LEFT JOIN practice_items pi ON pi.question_id = q.id AND pi.student_id = $1
+		WHERE qz.group_id = $2 AND qz.closed_at < $3 AND q.type <> $4
+		ORDER BY
+			CASE WHEN pi.due_at <= $3 THEN 0 WHEN pi.id IS NULL THEN 1 ELSE 2 END,
+			pi.due_at ASC NULLS LAST,
+			random()
+		LIMIT 1
+	`,
+		userID,
+		groupID,
+		tx.now,
+		api.Open,
+	).Scan(
+		&questionID,
+		&itemID,
+		&easiness,
+		&interval,
+		&repetitions,
+		&dueAt,
+		&answeredAt,
+	); err == sql.ErrNoRows {
+		return nil, nil, &api.Error{Code: api.ENOTFOUND, Message: "There are no questions to practice."}
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	q, err := findQuestionByID(ctx, tx, questionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	item := &api.PracticeItem{Easiness: api.DefaultEasiness, StudentID: userID, QuestionID: questionID}
+	if itemID.Valid {
+		item.ID = int(itemID.Int32)
+		item.Easiness = easiness.Float64
+		item.Interval = int(interval.Int32)
+		item.Repetitions = int(repetitions.Int32)
+		item.DueAt = dueAt.Time
+		item.AnsweredAt = answeredAt.Time
+	}
+
+	return q, item, nil
+}
+
+// findPracticeItem returns the schedule of a question for a student, or a new
+// schedule if the student has never practiced it.
+func findPracticeItem(ctx context.Context, tx *Tx, studentID, questionID int) (*api.PracticeItem, error) {
+	item := api.PracticeItem{StudentID: studentID, QuestionID: questionID}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT id, easiness, interval_days, repetitions, due_at, answered_at
+		FROM practice_items
+		WHERE student_id = $1 AND question_id = $2
+		FOR UPDATE
+	`,
+		studentID,
+		questionID,
+	).Scan(
+		&item.ID,
+		&item.Easiness,
+		&item.Interval,
+		&item.Repetitions,
+		&item.DueAt,
+		&item.AnsweredAt,
+	); err == sql.ErrNoRows {
+		item.Easiness = api.DefaultEasiness
+	} else if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// answerPracticeQuestion checks the response with the regular grading logic,
+// records the attempt and reschedules the question. Nothing is written to
+// quiz submissions or responses.
+func answerPracticeQuestion(ctx context.Context, tx *Tx, groupID int, rn *api.Response) (*api.PracticeResult, error) {
+	userID, err := checkPracticeAccess(ctx, tx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Verify the question is part of the group's bank.
+	q, err := findQuestionByID(ctx, tx, rn.QuestionID)
+	if err != nil {
+		return nil, err
+	} else if q.Type == api.Open {
+		return nil, api.Errorf(api.EINVALID, "Open questions cannot be practiced.")
+	} else if qz, err := findQuizByID(ctx, tx, q.QuizID); err != nil {
+		return nil, err
+	} else if qz.GroupID != groupID || qz.ClosedAt.IsZero() || !qz.ClosedAt.Before(tx.now) {
+		return nil, api.Errorf(api.EINVALID, "Question is not available for practice.")
+	}
+
+	result := &api.PracticeResult{IsCorrect: q.Check(rn), Question: q}
+	quality := api.PracticeQuality(result.IsCorrect)
+
+	if result.Item, err = findPracticeItem(ctx, tx, userID, q.ID); err != nil {
+		return nil, err
+	}
+	result.Item.Review(quality, tx.now)
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO practice_items (
+			easiness,
+			interval_days,
+			repetitions,
+			due_at,
+			answered_at,
+			student_id,
+			question_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (student_id, question_id) DO UPDATE
+		SET easiness = EXCLUDED.easiness,
+		    interval_days = EXCLUDED.interval_days,
+		    repetitions = EXCLUDED.repetitions,
+		    due_at = EXCLUDED.due_at,
+		    answered_at = EXCLUDED.answered_at
+	`,
+		result.Item.Easiness,
+		result.Item.Interval,
+		result.Item.Repetitions,
+		result.Item.DueAt,
+		result.Item.AnsweredAt,
+		userID,
+		q.ID,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO practice_attempts (
+			is_correct,
+			quality,
+			answered_at,
+			student_id,
+			question_id,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`,
+		result.IsCorrect,
+		quality,
+		tx.now,
+		userID,
+		q.ID,
+		groupID,
+	); err != nil {
+		return nil, FormatError(err)
+	}
+
+	return result, nil
+}
+
+// findPracticeAttempts returns the attempts of the current user matching a filter.
+// Also returns a count of total matching attempts which may differ if filter.Limit is set.
+func findPracticeAttempts(ctx context.Context, tx *Tx, filter api.PracticeAttemptFilter) (_ []*api.PracticeAttempt, n int, err error) {
+	// Build WHERE clause. Students only ever see their own history.
+	where, args := []string{"student_id = $1"}, []interface{}{api.UserIDFromContext(ctx)}
+	i := 2
+	if v := filter.GroupID; v != nil {
+		where, args = append(where, fmt.Sprintf("group_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.QuestionID; v != nil {
+		where, args = append(where, fmt.Sprintf("question_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	// Execute query to fetch attempt rows.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			is_correct,
+			quality,
+			answered_at,
+			student_id,
+			question_id,
+			group_id,
+			COUNT(*) OVER()
+		FROM practice_attempts
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY answered_at DESC, id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	// Deserialize rows into PracticeAttempt objects.
+	attempts := make([]*api.PracticeAttempt, 0)
+	for rows.Next() {
+		var a api.PracticeAttempt
+		if err := rows.Scan(
+			&a.ID,
+			&a.IsCorrect,
+			&a.Quality,
+			&a.AnsweredAt,
+			&a.StudentID,
+			&a.QuestionID,
+			&a.GroupID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		attempts = append(attempts, &a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, n, nil
+}
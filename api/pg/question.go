@@ -206,6 +206,9 @@ func findQuestions(ctx context.Context, tx *Tx, filter api.QuestionFilter) (_ []
 		if multiplechoiceAnswer.Status != pgtype.Null {
 			multiplechoiceAnswer.AssignTo(&q.MultipleChoiceAnswer)
 		}
+		if singlechoiceAnswer.Valid {
+			q.SingleChoiceAnswer = int(singlechoiceAnswer.Int32)
+		}
 		if updatedAt.Valid {
 			q.UpdatedAt = updatedAt.Time
 		}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/dori7879/senior-project/api"
+	"github.com/jackc/pgtype"
 )
 
 // Ensure service implements interface.
@@ -239,6 +240,11 @@ func findQuizzes(ctx context.Context, tx *Tx, filter api.QuizFilter) (_ []*api.Q
 			updated_at,
 			opened_at,
 			closed_at,
+			grades_release_at,
+			access_code,
+			allowed_cidrs,
+			single_session,
+			time_limit,
 			teacher_fullname,
 			teacher_id,
 			group_id,
@@ -262,6 +268,9 @@ func findQuizzes(ctx context.Context, tx *Tx, filter api.QuizFilter) (_ []*api.Q
 		var updatedAt sql.NullTime
 		var openedAt sql.NullTime
 		var closedAt sql.NullTime
+		var gradesReleaseAt sql.NullTime
+		var accessCode sql.NullString
+		var allowedCIDRs pgtype.VarcharArray
 		var teacherID sql.NullInt32
 		var groupID sql.NullInt32
 
@@ -279,6 +288,11 @@ func findQuizzes(ctx context.Context, tx *Tx, filter api.QuizFilter) (_ []*api.Q
 			&updatedAt,
 			&openedAt,
 			&closedAt,
+			&gradesReleaseAt,
+			&accessCode,
+			&allowedCIDRs,
+			&qz.SingleSession,
+			&qz.TimeLimit,
 			&teacherFullname,
 			&teacherID,
 			&groupID,
@@ -302,6 +316,15 @@ func findQuizzes(ctx context.Context, tx *Tx, filter api.QuizFilter) (_ []*api.Q
 		if closedAt.Valid {
 			qz.ClosedAt = closedAt.Time
 		}
+		if gradesReleaseAt.Valid {
+			qz.GradesReleaseAt = gradesReleaseAt.Time
+		}
+		if accessCode.Valid {
+			qz.AccessCode = accessCode.String
+		}
+		if allowedCIDRs.Status != pgtype.Null {
+			allowedCIDRs.AssignTo(&qz.AllowedCIDRs)
+		}
 		if teacherID.Valid {
 			qz.TeacherID = int(teacherID.Int32)
 		}
@@ -343,6 +366,13 @@ func createQuiz(ctx context.Context, tx *Tx, qz *api.Quiz) error {
 	if !qz.ClosedAt.IsZero() {
 		closedAt = &qz.ClosedAt
 	}
+	var accessCode *string
+	if qz.AccessCode != "" {
+		accessCode = &qz.AccessCode
+	}
+	if qz.AllowedCIDRs == nil {
+		qz.AllowedCIDRs = make([]string, 0)
+	}
 	var teacherFullname *string
 	if qz.TeacherFullName != "" {
 		teacherFullname = &qz.TeacherFullName
@@ -369,11 +399,15 @@ func createQuiz(ctx context.Context, tx *Tx, qz *api.Quiz) error {
 			created_at,
 			opened_at,
 			closed_at,
+			access_code,
+			allowed_cidrs,
+			single_session,
+			time_limit,
 			teacher_fullname,
 			teacher_id,
 			group_id
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 		RETURNING id
 	`,
 		qz.Title,
@@ -386,6 +420,10 @@ func createQuiz(ctx context.Context, tx *Tx, qz *api.Quiz) error {
 		qz.CreatedAt,
 		openedAt,
 		closedAt,
+		accessCode,
+		qz.AllowedCIDRs,
+		qz.SingleSession,
+		qz.TimeLimit,
 		teacherFullname,
 		teacherID,
 		groupID,
@@ -433,6 +471,18 @@ func updateQuiz(ctx context.Context, tx *Tx, id int, upd api.QuizUpdate) (*api.Q
 	if v := upd.ClosedAt; v != nil {
 		qz.ClosedAt = *v
 	}
+	if v := upd.AccessCode; v != nil {
+		qz.AccessCode = *v
+	}
+	if v := upd.AllowedCIDRs; v != nil {
+		qz.AllowedCIDRs = *v
+	}
+	if v := upd.SingleSession; v != nil {
+		qz.SingleSession = *v
+	}
+	if v := upd.TimeLimit; v != nil {
+		qz.TimeLimit = *v
+	}
 	if v := upd.TeacherFullName; v != nil {
 		qz.TeacherFullName = *v
 	}
@@ -464,6 +514,13 @@ func updateQuiz(ctx context.Context, tx *Tx, id int, upd api.QuizUpdate) (*api.Q
 	if !qz.ClosedAt.IsZero() {
 		closedAt = &qz.ClosedAt
 	}
+	var accessCode *string
+	if qz.AccessCode != "" {
+		accessCode = &qz.AccessCode
+	}
+	if qz.AllowedCIDRs == nil {
+		qz.AllowedCIDRs = make([]string, 0)
+	}
 	var teacherFullname *string
 	if qz.TeacherFullName != "" {
 		teacherFullname = &qz.TeacherFullName
@@ -487,10 +544,15 @@ func updateQuiz(ctx context.Context, tx *Tx, id int, upd api.QuizUpdate) (*api.Q
 		    mode = $5,
 		    opened_at = $6,
 		    closed_at = $7,
-		    teacher_fullname = $8,
-		    teacher_id = $9,
-		    group_id = $10
-		WHERE id = $11
+		    access_code = $8,
+		    allowed_cidrs = $9,
+		    single_session = $10,
+		    time_limit = $11,
+		    teacher_fullname = $12,
+		    teacher_id = $13,
+		    group_id = $14,
+		    updated_at = $15
+		WHERE id = $16
 	`,
 		qz.Title,
 		content,
@@ -499,9 +561,14 @@ func updateQuiz(ctx context.Context, tx *Tx, id int, upd api.QuizUpdate) (*api.Q
 		qz.Mode,
 		openedAt,
 		closedAt,
+		accessCode,
+		qz.AllowedCIDRs,
+		qz.SingleSession,
+		qz.TimeLimit,
 		teacherFullname,
 		teacherID,
 		groupID,
+		qz.UpdatedAt,
 		id,
 	); err != nil {
 		return qz, FormatError(err)
@@ -0,0 +1,458 @@
+package pg
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.QuizAttemptService = (*QuizAttemptService)(nil)
+
+// QuizAttemptService represents a service for managing attempts of restricted quizzes.
+type QuizAttemptService struct {
+	db *DB
+}
+
+// NewQuizAttemptService returns a new instance of QuizAttemptService.
+func NewQuizAttemptService(db *DB) *QuizAttemptService {
+	return &QuizAttemptService{db: db}
+}
+
+// StartQuizAttempt starts an attempt or resumes it from the device that started it.
+// Rejected requests are logged as violations and return EFORBIDDEN.
+func (s *QuizAttemptService) StartQuizAttempt(ctx context.Context, access *api.QuizAccess) (*api.QuizAttempt, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, v, err := startQuizAttempt(ctx, tx, access)
+	if v != nil {
+		return nil, s.reject(ctx, v)
+	} else if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// CheckQuizAttempt verifies that the request belongs to a started attempt of
+// the quiz and comes from an allowed IP address.
+func (s *QuizAttemptService) CheckQuizAttempt(ctx context.Context, access *api.QuizAccess) (*api.QuizAttempt, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	a, v, err := checkQuizAttempt(ctx, tx, access)
+	if v != nil {
+		return nil, s.reject(ctx, v)
+	} else if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// FinishQuizAttempt creates the submission an attempt ends with and links the
+// attempt to it.
+func (s *QuizAttemptService) FinishQuizAttempt(ctx context.Context, id int, sub *api.QuizSubmission) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Only one submission may finish an attempt, even if several were sent at once.
+	a, err := findQuizAttempt(ctx, tx, `id = $1 FOR UPDATE`, id)
+	if err != nil {
+		return err
+	} else if a.SubmissionID != 0 {
+		return api.Errorf(api.ECONFLICT, "This attempt has already been submitted.")
+	}
+
+	if err := createQuizSubmission(ctx, tx, sub); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quiz_attempts SET quiz_submission_id = $1, last_seen_at = $2 WHERE id = $3
+	`, sub.ID, tx.now, a.ID); err != nil {
+		return FormatError(err)
+	}
+
+	// Attach the events reported during the attempt to the submission.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE integrity_events SET quiz_submission_id = $1 WHERE quiz_attempt_id = $2
+	`, sub.ID, a.ID); err != nil {
+		return FormatError(err)
+	}
+	return tx.Commit()
+}
+
+// FindQuizViolations retrieves the access violations of a quiz.
+func (s *QuizAttemptService) FindQuizViolations(ctx context.Context, filter api.QuizViolationFilter) ([]*api.QuizViolation, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findQuizViolations(ctx, tx, filter)
+}
+
+// reject logs a violation in its own transaction, so that it is kept even though
+// the rejected request is rolled back, and returns it as an EFORBIDDEN error.
+func (s *QuizAttemptService) reject(ctx context.Context, v *api.QuizViolation) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createQuizViolation(ctx, tx, v); err != nil {
+		return err
+	} else if err := tx.Commit(); err != nil {
+		return err
+	}
+	return api.Errorf(api.EFORBIDDEN, v.Message)
+}
+
+// checkQuizAccess verifies the access code and the IP address of a request.
+// Returns the violation if the request is rejected.
+func checkQuizAccess(ctx context.Context, qz *api.Quiz, access *api.QuizAccess) *api.QuizViolation {
+	if qz.AccessCode != "" && subtle.ConstantTimeCompare([]byte(qz.AccessCode), []byte(access.AccessCode)) != 1 {
+		return newQuizViolation(ctx, qz.ID, access, api.ViolationAccessCode, "Access code is incorrect.")
+	} else if !qz.AllowsIP(net.ParseIP(access.IP)) {
+		return newQuizViolation(ctx, qz.ID, access, api.ViolationIP, "This quiz cannot be taken from your network.")
+	}
+	return nil
+}
+
+// newQuizViolation returns a violation of the current user.
+func newQuizViolation(ctx context.Context, quizID int, access *api.QuizAccess, reason, message string) *api.QuizViolation {
+	return &api.QuizViolation{
+		Reason:    reason,
+		Message:   message,
+		IP:        access.IP,
+		StudentID: api.UserIDFromContext(ctx),
+		QuizID:    quizID,
+	}
+}
+
+// startQuizAttempt checks the access restrictions and the times of a quiz and
+// returns the attempt of the current user, creating it on first start.
+func startQuizAttempt(ctx context.Context, tx *Tx, access *api.QuizAccess) (*api.QuizAttempt, *api.QuizViolation, error) {
+	qz, err := findQuizByID(ctx, tx, access.QuizID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	currentUserID := api.UserIDFromContext(ctx)
+	if qz.Mode == api.Registered && currentUserID == 0 {
+		return nil, nil, api.Errorf(api.EUNAUTHORIZED, "Log in to be able to start.")
+	} else if v := checkQuizAccess(ctx, qz, access); v != nil {
+		return nil, v, nil
+	}
+
+	// The quiz has to be open for the user, who may have their own times.
+	d := qz.Deadline()
+	if err := applyDeadline(ctx, tx, d, qz.GroupID, currentUserID); err != nil {
+		return nil, nil, err
+	} else if !d.Opened(tx.now) {
+		return nil, nil, api.Errorf(api.EFORBIDDEN, "Quiz has not been opened yet.")
+	} else if d.Closed(tx.now) {
+		return nil, nil, api.Errorf(api.EFORBIDDEN, "Quiz has already been closed.")
+	}
+
+	// Resume the existing attempt of the user or device.
+	var a *api.QuizAttempt
+	if currentUserID != 0 {
+		a, err = findQuizAttempt(ctx, tx, `quiz_id = $1 AND student_id = $2`, qz.ID, currentUserID)
+	} else if access.DeviceToken != "" {
+		a, err = findQuizAttempt(ctx, tx, `quiz_id = $1 AND device_token = $2 AND student_id IS NULL`, qz.ID, access.DeviceToken)
+	}
+	if err != nil && api.ErrorCode(err) != api.ENOTFOUND {
+		return nil, nil, err
+	}
+
+	if a != nil {
+		if a.SubmissionID != 0 {
+			return nil, nil, api.Errorf(api.ECONFLICT, "You have already submitted this quiz.")
+		} else if qz.SingleSession && subtle.ConstantTimeCompare([]byte(a.DeviceToken), []byte(access.DeviceToken)) != 1 {
+			return nil, newQuizViolation(ctx, qz.ID, access, api.ViolationDevice, "This quiz has already been started on another device."), nil
+		}
+		a.EndsAt = d.AttemptEndsAt(a.StartedAt)
+		return a, nil, touchQuizAttempt(ctx, tx, a, access.IP)
+	}
+
+	a = &api.QuizAttempt{
+		IP:         access.IP,
+		StartedAt:  tx.now,
+		LastSeenAt: tx.now,
+		StudentID:  currentUserID,
+		QuizID:     qz.ID,
+		EndsAt:     d.AttemptEndsAt(tx.now),
+	}
+	if a.DeviceToken, err = newDeviceToken(); err != nil {
+		return nil, nil, err
+	}
+
+	var studentID *int
+	if a.StudentID != 0 {
+		studentID = &a.StudentID
+	}
+
+	// Execute insertion query.
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO quiz_attempts (
+			device_token,
+			ip,
+			started_at,
+			last_seen_at,
+			student_id,
+			quiz_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`,
+		a.DeviceToken,
+		a.IP,
+		a.StartedAt,
+		a.LastSeenAt,
+		studentID,
+		a.QuizID,
+	).Scan(&a.ID); err != nil {
+		return nil, nil, FormatError(err)
+	}
+
+	return a, nil, nil
+}
+
+// checkQuizAttempt verifies that the device token belongs to an attempt of the
+// current user and that the IP address is still allowed.
+func checkQuizAttempt(ctx context.Context, tx *Tx, access *api.QuizAccess) (*api.QuizAttempt, *api.QuizViolation, error) {
+	qz, err := findQuizByID(ctx, tx, access.QuizID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a, err := findQuizAttempt(ctx, tx, `quiz_id = $1 AND device_token = $2`, qz.ID, access.DeviceToken)
+	if api.ErrorCode(err) == api.ENOTFOUND || (err == nil && a.StudentID != api.UserIDFromContext(ctx)) {
+		if qz.SingleSession && access.DeviceToken != "" {
+			return nil, newQuizViolation(ctx, qz.ID, access, api.ViolationDevice, "This quiz has been started on another device."), nil
+		}
+		return nil, nil, api.Errorf(api.EFORBIDDEN, "Start the quiz before submitting.")
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if a.SubmissionID != 0 {
+		return nil, nil, api.Errorf(api.ECONFLICT, "This attempt has already been submitted.")
+	} else if !qz.AllowsIP(net.ParseIP(access.IP)) {
+		return nil, newQuizViolation(ctx, qz.ID, access, api.ViolationIP, "This quiz cannot be taken from your network."), nil
+	}
+
+	return a, nil, touchQuizAttempt(ctx, tx, a, access.IP)
+}
+
+// findQuizAttempt returns the attempt matching a condition.
+// Returns ENOTFOUND if attempt does not exist.
+func findQuizAttempt(ctx context.Context, tx *Tx, where string, args ...interface{}) (*api.QuizAttempt, error) {
+	var ip sql.NullString
+	var studentID sql.NullInt32
+	var submissionID sql.NullInt32
+
+	var a api.QuizAttempt
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			id,
+			device_token,
+			ip,
+			started_at,
+			last_seen_at,
+			student_id,
+			quiz_id,
+			quiz_submission_id
+		FROM quiz_attempts
+		WHERE `+where,
+		args...,
+	).Scan(
+		&a.ID,
+		&a.DeviceToken,
+		&ip,
+		&a.StartedAt,
+		&a.LastSeenAt,
+		&studentID,
+		&a.QuizID,
+		&submissionID,
+	); err == sql.ErrNoRows {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Quiz attempt not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if ip.Valid {
+		a.IP = ip.String
+	}
+	if studentID.Valid {
+		a.StudentID = int(studentID.Int32)
+	}
+	if submissionID.Valid {
+		a.SubmissionID = int(submissionID.Int32)
+	}
+
+	return &a, nil
+}
+
+// touchQuizAttempt records the latest IP address and activity of an attempt.
+func touchQuizAttempt(ctx context.Context, tx *Tx, a *api.QuizAttempt, ip string) error {
+	a.IP, a.LastSeenAt = ip, tx.now
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE quiz_attempts SET ip = $1, last_seen_at = $2 WHERE id = $3
+	`, a.IP, a.LastSeenAt, a.ID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// createQuizViolation stores a violation and sets its ID and creation time.
+func createQuizViolation(ctx context.Context, tx *Tx, v *api.QuizViolation) error {
+	v.CreatedAt = tx.now
+
+	var studentID *int
+	if v.StudentID != 0 {
+		studentID = &v.StudentID
+	}
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO quiz_violations (
+			reason,
+			message,
+			ip,
+			created_at,
+			student_id,
+			quiz_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`,
+		v.Reason,
+		v.Message,
+		v.IP,
+		v.CreatedAt,
+		studentID,
+		v.QuizID,
+	).Scan(&v.ID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// findQuizViolations returns the violations of a quiz, newest first. Returns
+// EUNAUTHORIZED if current user is not the owner of the quiz.
+func findQuizViolations(ctx context.Context, tx *Tx, filter api.QuizViolationFilter) (_ []*api.QuizViolation, n int, err error) {
+	currentUserID := api.UserIDFromContext(ctx)
+	if qz, err := findQuizByID(ctx, tx, filter.QuizID); err != nil {
+		return nil, 0, err
+	} else if qz.TeacherID != currentUserID {
+		return nil, 0, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view violations of this quiz.")
+	}
+
+	// Build WHERE clause.
+	where, args := []string{"v.quiz_id = $1"}, []interface{}{filter.QuizID}
+	i := 2
+	if v := filter.StudentID; v != nil {
+		where, args = append(where, fmt.Sprintf("v.student_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			v.id,
+			v.reason,
+			v.message,
+			v.ip,
+			v.created_at,
+			v.student_id,
+			v.quiz_id,
+			u.first_name,
+			u.last_name,
+			u.email,
+			COUNT(*) OVER()
+		FROM quiz_violations v
+		LEFT JOIN users u ON u.id = v.student_id
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY v.created_at DESC, v.id DESC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	violations := make([]*api.QuizViolation, 0)
+	for rows.Next() {
+		var ip sql.NullString
+		var studentID sql.NullInt32
+		var firstName, lastName, email sql.NullString
+
+		var v api.QuizViolation
+		if err := rows.Scan(
+			&v.ID,
+			&v.Reason,
+			&v.Message,
+			&ip,
+			&v.CreatedAt,
+			&studentID,
+			&v.QuizID,
+			&firstName,
+			&lastName,
+			&email,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		if ip.Valid {
+			v.IP = ip.String
+		}
+		if studentID.Valid {
+			v.StudentID = int(studentID.Int32)
+			v.Student = &api.User{
+				ID:        v.StudentID,
+				FirstName: firstName.String,
+				LastName:  lastName.String,
+				Email:     email.String,
+			}
+		}
+
+		violations = append(violations, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return violations, n, nil
+}
+
+// newDeviceToken returns a random token identifying the device of an attempt.
+func newDeviceToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -102,14 +102,14 @@ func (s *QuizSubmissionService) UpdateQuizSubmission(ctx context.Context, id int
 // DeleteQuizSubmission permanently deletes a submission.
 // Returns EUNAUTHORIZED if current submission is not the submission being deleted.
 // Returns ENOTFOUND if submission does not exist.
-func (s *QuizSubmissionService) DeleteQuizSubmission(ctx context.Context, id int) error {
+func (s *QuizSubmissionService) DeleteQuizSubmission(ctx context.Context, id int, overrideReason *string) error {
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	if err := deleteQuizSubmission(ctx, tx, id); err != nil {
+	if err := deleteQuizSubmission(ctx, tx, id, overrideReason); err != nil {
 		return err
 	}
 	return tx.Commit()
@@ -301,6 +301,13 @@ func updateQuizSubmission(ctx context.Context, tx *Tx, id int, upd api.QuizSubmi
 		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this quiz submission.")
 	}
 
+	// Changing a locked grade requires a recorded override.
+	if v := upd.Grade; v != nil && *v != sub.Grade {
+		if err := checkGradeLock(ctx, tx, api.GradeItemQuiz, sub.QuizID, sub.ID, sub.Grade, v, upd.OverrideReason); err != nil {
+			return sub, err
+		}
+	}
+
 	// Update fields.
 	if v := upd.Grade; v != nil {
 		sub.Grade = *v
@@ -364,10 +371,11 @@ func updateQuizSubmission(ctx context.Context, tx *Tx, id int, upd api.QuizSubmi
 
 // deleteQuizSubmission permanently removes a submission by ID. Returns EUNAUTHORIZED if current
 // submission is not the one being deleted.
-func deleteQuizSubmission(ctx context.Context, tx *Tx, id int) error {
+func deleteQuizSubmission(ctx context.Context, tx *Tx, id int, overrideReason *string) error {
 	// Verify object exists.
 	currentUserID := api.UserIDFromContext(ctx)
-	if sub, err := findQuizSubmissionByID(ctx, tx, id); err != nil {
+	sub, err := findQuizSubmissionByID(ctx, tx, id)
+	if err != nil {
 		return err
 	} else if sub.Quiz, err = findQuizByID(ctx, tx, sub.QuizID); err != nil {
 		return err
@@ -375,6 +383,11 @@ func deleteQuizSubmission(ctx context.Context, tx *Tx, id int) error {
 		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this quiz submission.")
 	}
 
+	// Deleting a submission of locked grades requires a recorded override.
+	if err := checkGradeLock(ctx, tx, api.GradeItemQuiz, sub.QuizID, sub.ID, sub.Grade, nil, overrideReason); err != nil {
+		return err
+	}
+
 	// Remove row from database.
 	if _, err := tx.ExecContext(ctx, `DELETE FROM quiz_submissions WHERE id = $1`, id); err != nil {
 		return FormatError(err)
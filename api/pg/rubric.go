@@ -0,0 +1,690 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Ensure service implements interface.
+var _ api.RubricService = (*RubricService)(nil)
+
+// RubricService represents a service for managing rubrics and grading with them.
+type RubricService struct {
+	db *DB
+}
+
+// NewRubricService returns a new instance of RubricService.
+func NewRubricService(db *DB) *RubricService {
+	return &RubricService{db: db}
+}
+
+// FindRubricByID retrieves a rubric by ID along with its criteria and levels.
+func (s *RubricService) FindRubricByID(ctx context.Context, id int) (*api.Rubric, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return checkRubricAccess(ctx, tx, id, api.UserIDFromContext(ctx))
+}
+
+// FindRubrics retrieves the rubrics the current user may use by filter.
+func (s *RubricService) FindRubrics(ctx context.Context, filter api.RubricFilter) ([]*api.Rubric, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return nil, 0, api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+	return findRubrics(ctx, tx, filter, userID)
+}
+
+// CreateRubric creates a new rubric owned by the current user.
+func (s *RubricService) CreateRubric(ctx context.Context, rubric *api.Rubric) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createRubric(ctx, tx, rubric); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRubric updates a rubric.
+func (s *RubricService) UpdateRubric(ctx context.Context, id int, upd api.RubricUpdate) (*api.Rubric, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rubric, err := updateRubric(ctx, tx, id, upd)
+	if err != nil {
+		return rubric, err
+	} else if err := tx.Commit(); err != nil {
+		return rubric, err
+	}
+	return rubric, nil
+}
+
+// DeleteRubric permanently deletes a rubric.
+func (s *RubricService) DeleteRubric(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteRubric(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// CopyRubric copies a rubric into a group and optionally attaches the copy to a homework.
+func (s *RubricService) CopyRubric(ctx context.Context, id, groupID, homeworkID int) (*api.Rubric, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rubric, err := copyRubric(ctx, tx, id, groupID, homeworkID)
+	if err != nil {
+		return nil, err
+	} else if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rubric, nil
+}
+
+// GradeHWSubmission grades a homework submission with the rubric of its homework.
+func (s *RubricService) GradeHWSubmission(ctx context.Context, id int, scores []*api.RubricScore, overrideReason *string) (*api.HWSubmission, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sub, err := gradeHWSubmission(ctx, tx, id, scores, overrideReason)
+	if err != nil {
+		return sub, err
+	} else if err := attachHWSubmissionAssociations(ctx, tx, sub); err != nil {
+		return sub, err
+	} else if err := tx.Commit(); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// FindRubricStats retrieves the scores of the submissions to a homework per criterion.
+func (s *RubricService) FindRubricStats(ctx context.Context, homeworkID int) ([]*api.RubricCriterionStats, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findRubricStats(ctx, tx, homeworkID)
+}
+
+// findRubricByID is a helper function to fetch a rubric by ID.
+// Returns ENOTFOUND if rubric does not exist.
+func findRubricByID(ctx context.Context, tx *Tx, id int) (*api.Rubric, error) {
+	a, _, err := findRubrics(ctx, tx, api.RubricFilter{ID: &id}, 0)
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Rubric not found."}
+	}
+	return a[0], nil
+}
+
+// checkRubricAccess returns a rubric if the user owns it or teaches in its group.
+func checkRubricAccess(ctx context.Context, tx *Tx, id, userID int) (*api.Rubric, error) {
+	rubric, err := findRubricByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if userID != 0 && rubric.TeacherID == userID {
+		return rubric, nil
+	} else if userID != 0 && rubric.GroupID != 0 {
+		if ok, err := isGroupTeacher(ctx, tx, rubric.GroupID, userID); err != nil {
+			return nil, err
+		} else if ok {
+			return rubric, nil
+		}
+	}
+	return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to use this rubric.")
+}
+
+// findRubrics returns a list of rubrics matching a filter along with their
+// criteria. Unless userID is zero, only rubrics of the user and of the groups
+// the user teaches in are returned.
+func findRubrics(ctx context.Context, tx *Tx, filter api.RubricFilter, userID int) (_ []*api.Rubric, n int, err error) {
+	// Build WHERE clause.
+	where, args := []string{"1 = 1"}, []interface{}{}
+	i := 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.GroupID; v != nil {
+		where, args = append(where, fmt.Sprintf("group_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if userID != 0 {
+		where = append(where, fmt.Sprintf(`(
+			teacher_id = $%[1]d
+			OR group_id IN (SELECT id FROM groups WHERE owner_id = $%[1]d)
+			OR group_id IN (SELECT group_id FROM teachers_groups WHERE teacher_id = $%[1]d)
+		)`, i))
+		args = append(args, userID)
+		i++
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			title,
+			description,
+			created_at,
+			updated_at,
+			teacher_id,
+			group_id,
+			COUNT(*) OVER()
+		FROM rubrics
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY title ASC, id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	rubrics := make([]*api.Rubric, 0)
+	for rows.Next() {
+		var updatedAt sql.NullTime
+		var groupID sql.NullInt32
+
+		var r api.Rubric
+		if err := rows.Scan(
+			&r.ID,
+			&r.Title,
+			&r.Description,
+			&r.CreatedAt,
+			&updatedAt,
+			&r.TeacherID,
+			&groupID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+		r.UpdatedAt = updatedAt.Time
+		r.GroupID = int(groupID.Int32)
+
+		rubrics = append(rubrics, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	rows.Close()
+
+	for _, r := range rubrics {
+		if r.Criteria, err = findRubricCriteria(ctx, tx, r.ID); err != nil {
+			return nil, 0, err
+		}
+	}
+	return rubrics, n, nil
+}
+
+// findRubricCriteria returns the criteria of a rubric with their levels, in order.
+func findRubricCriteria(ctx context.Context, tx *Tx, rubricID int) ([]*api.RubricCriterion, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			c.id,
+			c.title,
+			c.description,
+			l.id,
+			l.title,
+			l.descriptor,
+			l.points
+		FROM rubric_criteria c
+		JOIN rubric_levels l ON l.criterion_id = c.id
+		WHERE c.rubric_id = $1
+		ORDER BY c.position, c.id, l.position, l.id
+	`, rubricID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	criteria := make([]*api.RubricCriterion, 0)
+	for rows.Next() {
+		var c api.RubricCriterion
+		var l api.RubricLevel
+		if err := rows.Scan(
+			&c.ID,
+			&c.Title,
+			&c.Description,
+			&l.ID,
+			&l.Title,
+			&l.Descriptor,
+			&l.Points,
+		); err != nil {
+			return nil, err
+		}
+
+		if n := len(criteria); n == 0 || criteria[n-1].ID != c.ID {
+			c.RubricID = rubricID
+			criteria = append(criteria, &c)
+		}
+		l.CriterionID = c.ID
+		last := criteria[len(criteria)-1]
+		last.Levels = append(last.Levels, &l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return criteria, nil
+}
+
+// createRubric creates a new rubric of the current user with its criteria.
+// Sets the new database IDs and the timestamps to the current time.
+func createRubric(ctx context.Context, tx *Tx, r *api.Rubric) error {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+	r.TeacherID = userID
+
+	// Set timestamps to the current time.
+	r.CreatedAt = tx.now
+	r.UpdatedAt = r.CreatedAt
+
+	// Perform basic field validation.
+	if err := r.Validate(); err != nil {
+		return err
+	}
+
+	// Only teachers of the group may share rubrics with it.
+	if r.GroupID != 0 {
+		if ok, err := isGroupTeacher(ctx, tx, r.GroupID, userID); err != nil {
+			return err
+		} else if !ok {
+			return api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of this group.")
+		}
+	}
+
+	var groupID *int
+	if r.GroupID != 0 {
+		groupID = &r.GroupID
+	}
+
+	// Execute insertion query.
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO rubrics (
+			title,
+			description,
+			created_at,
+			teacher_id,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`,
+		r.Title,
+		r.Description,
+		r.CreatedAt,
+		r.TeacherID,
+		groupID,
+	).Scan(&r.ID); err != nil {
+		return FormatError(err)
+	}
+
+	return insertRubricCriteria(ctx, tx, r)
+}
+
+// insertRubricCriteria inserts the criteria and levels of a rubric in the
+// order they are given.
+func insertRubricCriteria(ctx context.Context, tx *Tx, r *api.Rubric) error {
+	for i, c := range r.Criteria {
+		c.RubricID = r.ID
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO rubric_criteria (title, description, position, rubric_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`, c.Title, c.Description, i, c.RubricID).Scan(&c.ID); err != nil {
+			return FormatError(err)
+		}
+
+		for j, l := range c.Levels {
+			l.CriterionID = c.ID
+			if err := tx.QueryRowContext(ctx, `
+				INSERT INTO rubric_levels (title, descriptor, points, position, criterion_id)
+				VALUES ($1, $2, $3, $4, $5)
+				RETURNING id
+			`, l.Title, l.Descriptor, l.Points, j, l.CriterionID).Scan(&l.ID); err != nil {
+				return FormatError(err)
+			}
+		}
+	}
+	return nil
+}
+
+// updateRubric updates fields on a rubric. Returns EUNAUTHORIZED if current
+// user is not the owner of the rubric.
+func updateRubric(ctx context.Context, tx *Tx, id int, upd api.RubricUpdate) (*api.Rubric, error) {
+	// Fetch current object state.
+	r, err := findRubricByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if r.TeacherID != api.UserIDFromContext(ctx) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this rubric.")
+	}
+
+	// Update fields.
+	if v := upd.Title; v != nil {
+		r.Title = *v
+	}
+	if v := upd.Description; v != nil {
+		r.Description = *v
+	}
+	if v := upd.Criteria; v != nil {
+		if used, err := isRubricUsed(ctx, tx, id); err != nil {
+			return r, err
+		} else if used {
+			return r, api.Errorf(api.ECONFLICT, "Submissions have already been graded with this rubric. Copy it to change its criteria.")
+		}
+		r.Criteria = *v
+	}
+
+	// Set last updated date to current time.
+	r.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE rubrics
+		SET title = $1,
+		    description = $2,
+		    updated_at = $3
+		WHERE id = $4
+	`,
+		r.Title,
+		r.Description,
+		r.UpdatedAt,
+		id,
+	); err != nil {
+		return r, FormatError(err)
+	}
+
+	// Replace the criteria along with their levels.
+	if upd.Criteria != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM rubric_criteria WHERE rubric_id = $1`, id); err != nil {
+			return r, FormatError(err)
+		} else if err := insertRubricCriteria(ctx, tx, r); err != nil {
+			return r, err
+		}
+	}
+
+	return r, nil
+}
+
+// deleteRubric permanently removes a rubric by ID. Returns EUNAUTHORIZED if
+// current user is not the owner of the rubric.
+func deleteRubric(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists.
+	if r, err := findRubricByID(ctx, tx, id); err != nil {
+		return err
+	} else if r.TeacherID != api.UserIDFromContext(ctx) {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this rubric.")
+	}
+
+	var attached bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM homeworks WHERE rubric_id = $1)
+	`, id).Scan(&attached); err != nil {
+		return err
+	} else if attached {
+		return api.Errorf(api.ECONFLICT, "Rubric is used by a homework.")
+	}
+
+	// Remove row from database.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM rubrics WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// copyRubric creates a copy of a rubric owned by the current user, shared
+// with a group unless groupID is zero, and attaches it to a homework unless
+// homeworkID is zero.
+func copyRubric(ctx context.Context, tx *Tx, id, groupID, homeworkID int) (*api.Rubric, error) {
+	src, err := checkRubricAccess(ctx, tx, id, api.UserIDFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &api.Rubric{
+		Title:       src.Title,
+		Description: src.Description,
+		GroupID:     groupID,
+	}
+	for _, c := range src.Criteria {
+		cc := &api.RubricCriterion{Title: c.Title, Description: c.Description}
+		for _, l := range c.Levels {
+			cc.Levels = append(cc.Levels, &api.RubricLevel{Title: l.Title, Descriptor: l.Descriptor, Points: l.Points})
+		}
+		r.Criteria = append(r.Criteria, cc)
+	}
+	if err := createRubric(ctx, tx, r); err != nil {
+		return nil, err
+	}
+
+	if homeworkID != 0 {
+		if _, err := updateHomework(ctx, tx, homeworkID, api.HomeworkUpdate{RubricID: &r.ID}); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// isRubricUsed returns true if a submission has been graded with the rubric.
+func isRubricUsed(ctx context.Context, tx *Tx, rubricID int) (bool, error) {
+	var ok bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM hw_rubric_scores s
+			JOIN rubric_criteria c ON c.id = s.criterion_id
+			WHERE c.rubric_id = $1
+		)
+	`, rubricID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// hasRubricScores returns true if a submission to the homework has been
+// graded with a rubric.
+func hasRubricScores(ctx context.Context, tx *Tx, homeworkID int) (bool, error) {
+	var ok bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM hw_rubric_scores s
+			JOIN hw_submissions h ON h.id = s.hw_submission_id
+			WHERE h.homework_id = $1
+		)
+	`, homeworkID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// gradeHWSubmission stores the rubric scores of a submission and sets its
+// grade to the one they earn. Returns EUNAUTHORIZED if current user is not
+// the teacher of the homework.
+func gradeHWSubmission(ctx context.Context, tx *Tx, id int, scores []*api.RubricScore, overrideReason *string) (*api.HWSubmission, error) {
+	sub, err := findHWSubmissionByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	hw, err := findHomeworkByID(ctx, tx, sub.HomeworkID)
+	if err != nil {
+		return nil, err
+	} else if userID := api.UserIDFromContext(ctx); userID == 0 || hw.TeacherID != userID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to grade this homework submission.")
+	} else if hw.RubricID == 0 {
+		return nil, api.Errorf(api.EINVALID, "Homework has no rubric.")
+	}
+
+	rubric, err := findRubricByID(ctx, tx, hw.RubricID)
+	if err != nil {
+		return nil, err
+	}
+	grade, err := rubric.Grade(scores, hw.MaxGrade)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the previous scores of the submission.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM hw_rubric_scores WHERE hw_submission_id = $1`, id); err != nil {
+		return nil, FormatError(err)
+	}
+	for _, s := range scores {
+		s.SubmissionID = id
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO hw_rubric_scores (hw_submission_id, criterion_id, level_id, points, comment)
+			VALUES ($1, $2, $3, $4, $5)
+		`, s.SubmissionID, s.CriterionID, s.LevelID, s.Points, s.Comment); err != nil {
+			return nil, FormatError(err)
+		}
+	}
+
+	// Grade like any other grade so that late penalties and locks apply.
+	if sub, err = updateHWSubmission(ctx, tx, id, api.HWSubmissionUpdate{
+		Grade:          &grade,
+		OverrideReason: overrideReason,
+	}); err != nil {
+		return sub, err
+	}
+	return sub, nil
+}
+
+// findHWRubricScores returns the rubric scores of a submission.
+func findHWRubricScores(ctx context.Context, tx *Tx, submissionID int) ([]*api.RubricScore, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.criterion_id, s.level_id, s.points, s.comment
+		FROM hw_rubric_scores s
+		JOIN rubric_criteria c ON c.id = s.criterion_id
+		WHERE s.hw_submission_id = $1
+		ORDER BY c.position, c.id
+	`, submissionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scores []*api.RubricScore
+	for rows.Next() {
+		s := api.RubricScore{SubmissionID: submissionID}
+		if err := rows.Scan(&s.CriterionID, &s.LevelID, &s.Points, &s.Comment); err != nil {
+			return nil, err
+		}
+		scores = append(scores, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// findRubricStats returns the number of submissions to a homework given each
+// level of its rubric and their mean points per criterion. Returns
+// EUNAUTHORIZED if current user is not the teacher of the homework.
+func findRubricStats(ctx context.Context, tx *Tx, homeworkID int) ([]*api.RubricCriterionStats, error) {
+	hw, err := findHomeworkByID(ctx, tx, homeworkID)
+	if err != nil {
+		return nil, err
+	} else if userID := api.UserIDFromContext(ctx); userID == 0 || hw.TeacherID != userID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view the statistics of this homework.")
+	} else if hw.RubricID == 0 {
+		return nil, api.Errorf(api.EINVALID, "Homework has no rubric.")
+	}
+
+	rubric, err := findRubricByID(ctx, tx, hw.RubricID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT s.level_id, COUNT(*)
+		FROM hw_rubric_scores s
+		JOIN hw_submissions h ON h.id = s.hw_submission_id
+		WHERE h.homework_id = $1
+		GROUP BY s.level_id
+	`, homeworkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int)
+	for rows.Next() {
+		var levelID, n int
+		if err := rows.Scan(&levelID, &n); err != nil {
+			return nil, err
+		}
+		counts[levelID] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := make([]*api.RubricCriterionStats, 0, len(rubric.Criteria))
+	for _, c := range rubric.Criteria {
+		cs := &api.RubricCriterionStats{
+			CriterionID: c.ID,
+			Title:       c.Title,
+			MaxPoints:   c.MaxPoints(),
+			Levels:      make([]*api.RubricLevelStats, 0, len(c.Levels)),
+		}
+
+		var points float32
+		for _, l := range c.Levels {
+			n := counts[l.ID]
+			cs.Levels = append(cs.Levels, &api.RubricLevelStats{
+				LevelID: l.ID,
+				Title:   l.Title,
+				Points:  l.Points,
+				N:       n,
+			})
+			cs.N += n
+			points += float32(n) * l.Points
+		}
+		if cs.N > 0 {
+			cs.MeanPoints = points / float32(cs.N)
+		}
+
+		stats = append(stats, cs)
+	}
+	return stats, nil
+}
@@ -0,0 +1,919 @@
+package pg
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/jackc/pgtype"
+)
+
+// Ensure service implements interface.
+var _ api.SurveyService = (*SurveyService)(nil)
+
+// SurveyService represents a service for managing surveys.
+type SurveyService struct {
+	db *DB
+
+	// Key used to derive blind tokens of respondents.
+	key []byte
+}
+
+// NewSurveyService returns a new instance of SurveyService.
+func NewSurveyService(db *DB, key []byte) *SurveyService {
+	return &SurveyService{db: db, key: key}
+}
+
+// FindSurveyByID retrieves a survey by ID along with its questions.
+// Returns ENOTFOUND if survey does not exist.
+func (s *SurveyService) FindSurveyByID(ctx context.Context, id int) (*api.Survey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sv, err := findSurveyByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if err := attachSurveyAssociations(ctx, tx, sv); err != nil {
+		return sv, err
+	}
+	return sv, nil
+}
+
+// FindSurveyByStudentLink retrieves a survey by student link along with its questions.
+// Returns ENOTFOUND if survey does not exist.
+func (s *SurveyService) FindSurveyByStudentLink(ctx context.Context, link string) (*api.Survey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sv, err := findSurvey(ctx, tx, api.SurveyFilter{StudentLink: &link})
+	if err != nil {
+		return nil, err
+	} else if err := attachSurveyAssociations(ctx, tx, sv); err != nil {
+		return sv, err
+	}
+	return sv, nil
+}
+
+// FindSurveyByTeacherLink retrieves a survey by teacher link along with its questions.
+// Returns ENOTFOUND if survey does not exist.
+func (s *SurveyService) FindSurveyByTeacherLink(ctx context.Context, link string) (*api.Survey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sv, err := findSurvey(ctx, tx, api.SurveyFilter{TeacherLink: &link})
+	if err != nil {
+		return nil, err
+	} else if err := attachSurveyAssociations(ctx, tx, sv); err != nil {
+		return sv, err
+	}
+	return sv, nil
+}
+
+// FindSurveys retrieves a list of surveys by filter. Also returns total count of
+// matching surveys which may differ from returned results if filter.Limit is specified.
+func (s *SurveyService) FindSurveys(ctx context.Context, filter api.SurveyFilter) ([]*api.Survey, int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+	return findSurveys(ctx, tx, filter)
+}
+
+// CreateSurvey creates a new survey along with its questions.
+func (s *SurveyService) CreateSurvey(ctx context.Context, sv *api.Survey) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := createSurvey(ctx, tx, sv); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateSurvey updates a survey object. Returns EUNAUTHORIZED if current user is
+// not the owner of the survey. Returns ENOTFOUND if survey does not exist.
+func (s *SurveyService) UpdateSurvey(ctx context.Context, id int, upd api.SurveyUpdate) (*api.Survey, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sv, err := updateSurvey(ctx, tx, id, upd)
+	if err != nil {
+		return sv, err
+	} else if err := tx.Commit(); err != nil {
+		return sv, err
+	}
+	return sv, nil
+}
+
+// DeleteSurvey permanently deletes a survey and all of its responses.
+// Returns EUNAUTHORIZED if current user is not the owner of the survey.
+func (s *SurveyService) DeleteSurvey(ctx context.Context, id int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteSurvey(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SubmitSurveyResponse stores an anonymous response. Logged in users may only
+// respond once and get ECONFLICT on the second attempt.
+func (s *SurveyService) SubmitSurveyResponse(ctx context.Context, rs *api.SurveyResponse) error {
+	// The blind token is recorded in a transaction of its own so that it
+	// cannot be matched with the response through transaction IDs.
+	token, err := s.claimSurveyToken(ctx, rs)
+	if err != nil {
+		return err
+	}
+
+	if err := s.insertSurveyResponse(ctx, rs); err != nil {
+		if token != "" {
+			// Let the user respond again as the response was not stored.
+			if err := s.releaseSurveyToken(ctx, rs.SurveyID, token); err != nil {
+				return fmt.Errorf("release survey token: %w", err)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// claimSurveyToken checks a response and records the blind token of the
+// current user. Returns an empty token for anonymous respondents.
+func (s *SurveyService) claimSurveyToken(ctx context.Context, rs *api.SurveyResponse) (string, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	token, err := claimSurveyToken(ctx, tx, s.key, rs)
+	if err != nil {
+		return "", err
+	}
+	return token, tx.Commit()
+}
+
+// insertSurveyResponse stores a response which has been checked and counted
+// by claimSurveyToken.
+func (s *SurveyService) insertSurveyResponse(ctx context.Context, rs *api.SurveyResponse) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := submitSurveyResponse(ctx, tx, rs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// releaseSurveyToken removes a blind token whose response could not be stored.
+func (s *SurveyService) releaseSurveyToken(ctx context.Context, surveyID int, token string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM survey_tokens WHERE survey_id = $1 AND token = $2
+	`, surveyID, token); err != nil {
+		return FormatError(err)
+	}
+	return tx.Commit()
+}
+
+// FindSurveyResults aggregates the responses to a survey. Returns EUNAUTHORIZED
+// if current user is not the owner of the survey.
+func (s *SurveyService) FindSurveyResults(ctx context.Context, id int) (*api.SurveyResults, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return findSurveyResults(ctx, tx, id)
+}
+
+// findSurveyByID is a helper function to fetch a survey by ID.
+// Returns ENOTFOUND if survey does not exist.
+func findSurveyByID(ctx context.Context, tx *Tx, id int) (*api.Survey, error) {
+	return findSurvey(ctx, tx, api.SurveyFilter{ID: &id})
+}
+
+// findSurvey returns the first survey matching a filter.
+// Returns ENOTFOUND if survey does not exist.
+func findSurvey(ctx context.Context, tx *Tx, filter api.SurveyFilter) (*api.Survey, error) {
+	a, _, err := findSurveys(ctx, tx, filter)
+	if err != nil {
+		return nil, err
+	} else if len(a) == 0 {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Survey not found."}
+	}
+	return a[0], nil
+}
+
+// findSurveys returns a list of surveys matching a filter. Also returns a count of
+// total matching surveys which may differ if filter.Limit is set.
+func findSurveys(ctx context.Context, tx *Tx, filter api.SurveyFilter) (_ []*api.Survey, n int, err error) {
+	// Build WHERE clause.
+	where, args := []string{"1 = 1"}, []interface{}{}
+	i := 1
+	if v := filter.ID; v != nil {
+		where, args = append(where, fmt.Sprintf("id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.StudentLink; v != nil {
+		where, args = append(where, fmt.Sprintf("student_link = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.TeacherLink; v != nil {
+		where, args = append(where, fmt.Sprintf("teacher_link = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.TeacherID; v != nil {
+		where, args = append(where, fmt.Sprintf("teacher_id = $%d", i)), append(args, *v)
+		i++
+	}
+	if v := filter.GroupID; v != nil {
+		where, args = append(where, fmt.Sprintf("group_id = $%d", i)), append(args, *v)
+		i++
+	}
+
+	// Execute query to fetch survey rows.
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			id,
+			title,
+			content,
+			student_link,
+			teacher_link,
+			mode,
+			min_responses,
+			created_at,
+			updated_at,
+			opened_at,
+			closed_at,
+			teacher_id,
+			group_id,
+			COUNT(*) OVER()
+		FROM surveys
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY id ASC
+		`+FormatLimitOffset(filter.Limit, filter.Offset),
+		args...,
+	)
+	if err != nil {
+		return nil, n, err
+	}
+	defer rows.Close()
+
+	// Deserialize rows into Survey objects.
+	surveys := make([]*api.Survey, 0)
+	for rows.Next() {
+		var content sql.NullString
+		var updatedAt sql.NullTime
+		var openedAt sql.NullTime
+		var closedAt sql.NullTime
+		var teacherID sql.NullInt32
+		var groupID sql.NullInt32
+
+		var sv api.Survey
+		if err := rows.Scan(
+			&sv.ID,
+			&sv.Title,
+			&content,
+			&sv.StudentLink,
+			&sv.TeacherLink,
+			&sv.Mode,
+			&sv.MinResponses,
+			&sv.CreatedAt,
+			&updatedAt,
+			&openedAt,
+			&closedAt,
+			&teacherID,
+			&groupID,
+			&n,
+		); err != nil {
+			return nil, 0, err
+		}
+
+		if content.Valid {
+			sv.Content = content.String
+		}
+		if updatedAt.Valid {
+			sv.UpdatedAt = updatedAt.Time
+		}
+		if openedAt.Valid {
+			sv.OpenedAt = openedAt.Time
+		}
+		if closedAt.Valid {
+			sv.ClosedAt = closedAt.Time
+		}
+		if teacherID.Valid {
+			sv.TeacherID = int(teacherID.Int32)
+		}
+		if groupID.Valid {
+			sv.GroupID = int(groupID.Int32)
+		}
+
+		surveys = append(surveys, &sv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return surveys, n, nil
+}
+
+// createSurvey creates a new survey and its questions. Sets the new database ID
+// to sv.ID and sets the timestamps to the current time.
+func createSurvey(ctx context.Context, tx *Tx, sv *api.Survey) error {
+	// Set timestamps to the current time.
+	sv.CreatedAt = tx.now
+	sv.UpdatedAt = sv.CreatedAt
+
+	if sv.MinResponses == 0 {
+		sv.MinResponses = api.DefaultMinResponses
+	}
+
+	// Perform basic field validation.
+	if err := sv.Validate(); err != nil {
+		return err
+	}
+	for _, q := range sv.Questions {
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Only teachers of the group may survey it.
+	if sv.GroupID != 0 {
+		if ok, err := isGroupTeacher(ctx, tx, sv.GroupID, sv.TeacherID); err != nil {
+			return err
+		} else if !ok {
+			return api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of this group.")
+		}
+	}
+
+	// These fields are nullable so ensure we store blank fields as NULLs.
+	var content *string
+	if sv.Content != "" {
+		content = &sv.Content
+	}
+	var openedAt *time.Time
+	if !sv.OpenedAt.IsZero() {
+		openedAt = &sv.OpenedAt
+	}
+	var closedAt *time.Time
+	if !sv.ClosedAt.IsZero() {
+		closedAt = &sv.ClosedAt
+	}
+	var teacherID *int
+	if sv.TeacherID != 0 {
+		teacherID = &sv.TeacherID
+	}
+	var groupID *int
+	if sv.GroupID != 0 {
+		groupID = &sv.GroupID
+	}
+
+	// Execute insertion query.
+	row := tx.QueryRowContext(ctx, `
+		INSERT INTO surveys (
+			title,
+			content,
+			student_link,
+			teacher_link,
+			mode,
+			min_responses,
+			created_at,
+			opened_at,
+			closed_at,
+			teacher_id,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING id
+	`,
+		sv.Title,
+		content,
+		sv.StudentLink,
+		sv.TeacherLink,
+		sv.Mode,
+		sv.MinResponses,
+		sv.CreatedAt,
+		openedAt,
+		closedAt,
+		teacherID,
+		groupID,
+	)
+
+	if err := row.Scan(&sv.ID); err != nil {
+		return FormatError(err)
+	}
+
+	// Insert the questions of the survey.
+	for _, q := range sv.Questions {
+		q.SurveyID = sv.ID
+		if len(q.Choices) == 0 {
+			q.Choices = make([]string, 0)
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO survey_questions (content, type, choices, survey_id)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id
+		`,
+			q.Content,
+			q.Type,
+			q.Choices,
+			q.SurveyID,
+		).Scan(&q.ID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+// updateSurvey updates fields on a survey object. Returns EUNAUTHORIZED if current
+// user is not the owner of the survey.
+func updateSurvey(ctx context.Context, tx *Tx, id int, upd api.SurveyUpdate) (*api.Survey, error) {
+	// Fetch current object state.
+	currentUserID := api.UserIDFromContext(ctx)
+	sv, err := findSurveyByID(ctx, tx, id)
+	if err != nil {
+		return sv, err
+	} else if currentUserID != 0 && sv.TeacherID != 0 && sv.TeacherID != currentUserID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to update this survey.")
+	}
+
+	// Update fields.
+	if v := upd.Title; v != nil {
+		sv.Title = *v
+	}
+	if v := upd.Content; v != nil {
+		sv.Content = *v
+	}
+	if v := upd.Mode; v != nil {
+		sv.Mode = *v
+	}
+	if v := upd.OpenedAt; v != nil {
+		sv.OpenedAt = *v
+	}
+	if v := upd.ClosedAt; v != nil {
+		sv.ClosedAt = *v
+	}
+	if v := upd.MinResponses; v != nil && *v < sv.MinResponses {
+		// Lowering the threshold would reveal results that were hidden.
+		var n int
+		if err := tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM survey_responses WHERE survey_id = $1
+		`, sv.ID).Scan(&n); err != nil {
+			return sv, FormatError(err)
+		} else if n > 0 {
+			return sv, api.Errorf(api.ECONFLICT, "Minimum number of responses cannot be lowered once the survey has responses.")
+		}
+	}
+	if v := upd.MinResponses; v != nil {
+		sv.MinResponses = *v
+	}
+	if v := upd.GroupID; v != nil {
+		sv.GroupID = *v
+	}
+
+	// Set last updated date to current time.
+	sv.UpdatedAt = tx.now
+
+	// Perform basic field validation.
+	if err := sv.Validate(); err != nil {
+		return sv, err
+	}
+
+	if v := upd.GroupID; v != nil && *v != 0 {
+		if ok, err := isGroupTeacher(ctx, tx, *v, sv.TeacherID); err != nil {
+			return sv, err
+		} else if !ok {
+			return sv, api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of this group.")
+		}
+	}
+
+	// These fields are nullable so ensure we store blank fields as NULLs.
+	var content *string
+	if sv.Content != "" {
+		content = &sv.Content
+	}
+	var openedAt *time.Time
+	if !sv.OpenedAt.IsZero() {
+		openedAt = &sv.OpenedAt
+	}
+	var closedAt *time.Time
+	if !sv.ClosedAt.IsZero() {
+		closedAt = &sv.ClosedAt
+	}
+	var groupID *int
+	if sv.GroupID != 0 {
+		groupID = &sv.GroupID
+	}
+
+	// Execute update query.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE surveys
+		SET title = $1,
+		    content = $2,
+		    mode = $3,
+		    opened_at = $4,
+		    closed_at = $5,
+		    min_responses = $6,
+		    group_id = $7,
+		    updated_at = $8
+		WHERE id = $9
+	`,
+		sv.Title,
+		content,
+		sv.Mode,
+		openedAt,
+		closedAt,
+		sv.MinResponses,
+		groupID,
+		sv.UpdatedAt,
+		id,
+	); err != nil {
+		return sv, FormatError(err)
+	}
+
+	return sv, nil
+}
+
+// deleteSurvey permanently removes a survey by ID. Returns EUNAUTHORIZED if current
+// user is not the owner of the survey.
+func deleteSurvey(ctx context.Context, tx *Tx, id int) error {
+	// Verify object exists.
+	currentUserID := api.UserIDFromContext(ctx)
+	if sv, err := findSurveyByID(ctx, tx, id); err != nil {
+		return err
+	} else if currentUserID != 0 && sv.TeacherID != 0 && sv.TeacherID != currentUserID {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not allowed to delete this survey.")
+	}
+
+	// Remove row from database.
+	if _, err := tx.ExecContext(ctx, `DELETE FROM surveys WHERE id = $1`, id); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// findSurveyQuestions returns the questions of a survey in creation order.
+func findSurveyQuestions(ctx context.Context, tx *Tx, surveyID int) (_ []*api.SurveyQuestion, err error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, content, type, choices, survey_id
+		FROM survey_questions
+		WHERE survey_id = $1
+		ORDER BY id ASC
+	`, surveyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := make([]*api.SurveyQuestion, 0)
+	for rows.Next() {
+		var choices pgtype.VarcharArray
+
+		var q api.SurveyQuestion
+		if err := rows.Scan(
+			&q.ID,
+			&q.Content,
+			&q.Type,
+			&choices,
+			&q.SurveyID,
+		); err != nil {
+			return nil, err
+		}
+
+		if choices.Status != pgtype.Null {
+			choices.AssignTo(&q.Choices)
+		}
+
+		questions = append(questions, &q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return questions, nil
+}
+
+// checkSurveyResponse returns the questions of a survey by ID if the current
+// user may respond to it with the given answers.
+func checkSurveyResponse(ctx context.Context, tx *Tx, rs *api.SurveyResponse) (map[int]*api.SurveyQuestion, error) {
+	sv, err := findSurveyByID(ctx, tx, rs.SurveyID)
+	if err != nil {
+		return nil, err
+	} else if !sv.OpenedAt.IsZero() && tx.now.Before(sv.OpenedAt) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "Survey has not been opened yet.")
+	} else if !sv.ClosedAt.IsZero() && tx.now.After(sv.ClosedAt) {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "Survey has already been closed.")
+	}
+
+	currentUserID := api.UserIDFromContext(ctx)
+	if sv.Mode == api.Registered && currentUserID == 0 {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "Log in to be able to respond.")
+	} else if sv.GroupID != 0 {
+		if ok, err := isGroupStudent(ctx, tx, sv.GroupID, currentUserID); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, api.Errorf(api.EUNAUTHORIZED, "You are not in the group.")
+		}
+	}
+
+	// Index the questions so that every answer can be checked against its question.
+	questions, err := findSurveyQuestions(ctx, tx, sv.ID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int]*api.SurveyQuestion, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	answered := make(map[int]bool, len(rs.Answers))
+	for _, a := range rs.Answers {
+		q := byID[a.QuestionID]
+		if q == nil {
+			return nil, api.Errorf(api.EINVALID, "Question is not part of the survey.")
+		} else if answered[q.ID] {
+			return nil, api.Errorf(api.EINVALID, "Question is answered more than once.")
+		} else if err := a.Validate(q); err != nil {
+			return nil, err
+		}
+		answered[q.ID] = true
+	}
+	return byID, nil
+}
+
+// claimSurveyToken checks a response and records that the current user has
+// responded with a blind token so that they cannot respond twice. Returns an
+// empty token for anonymous respondents.
+func claimSurveyToken(ctx context.Context, tx *Tx, key []byte, rs *api.SurveyResponse) (string, error) {
+	if _, err := checkSurveyResponse(ctx, tx, rs); err != nil {
+		return "", err
+	}
+
+	currentUserID := api.UserIDFromContext(ctx)
+	if currentUserID == 0 {
+		return "", nil
+	}
+
+	token := blindToken(key, rs.SurveyID, currentUserID)
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO survey_tokens (token, survey_id)
+		VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, token, rs.SurveyID)
+	if err != nil {
+		return "", FormatError(err)
+	} else if n, err := res.RowsAffected(); err != nil {
+		return "", err
+	} else if n == 0 {
+		return "", api.Errorf(api.ECONFLICT, "You have already responded to this survey.")
+	}
+	return token, nil
+}
+
+// submitSurveyResponse stores a response without any reference to its author.
+// Responses and answers get random IDs so that their order does not reveal
+// the order in which blind tokens were recorded.
+func submitSurveyResponse(ctx context.Context, tx *Tx, rs *api.SurveyResponse) error {
+	byID, err := checkSurveyResponse(ctx, tx, rs)
+	if err != nil {
+		return err
+	}
+
+	// Only the day is kept so that responses cannot be matched with login times.
+	y, m, d := tx.now.Date()
+	rs.SubmittedOn = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO survey_responses (submitted_on, survey_id)
+		VALUES ($1, $2)
+		RETURNING id
+	`,
+		rs.SubmittedOn,
+		rs.SurveyID,
+	).Scan(&rs.ID); err != nil {
+		return FormatError(err)
+	}
+
+	for _, a := range rs.Answers {
+		a.ResponseID = rs.ID
+
+		var openResponse *string
+		var truefalseResponse *bool
+		var multiplechoiceResponse *[]int
+		var singlechoiceResponse *int
+		switch byID[a.QuestionID].Type {
+		case api.Open:
+			openResponse = &a.OpenResponse
+		case api.Truefalse:
+			truefalseResponse = &a.TrueFalseResponse
+		case api.Multiple:
+			if a.MultipleChoiceResponse == nil {
+				a.MultipleChoiceResponse = make([]int, 0)
+			}
+			multiplechoiceResponse = &a.MultipleChoiceResponse
+		case api.Single:
+			singlechoiceResponse = &a.SingleChoiceResponse
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO survey_answers (
+				open_response,
+				truefalse_response,
+				multiplechoice_response,
+				singlechoice_response,
+				survey_response_id,
+				survey_question_id
+			)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`,
+			openResponse,
+			truefalseResponse,
+			multiplechoiceResponse,
+			singlechoiceResponse,
+			a.ResponseID,
+			a.QuestionID,
+		).Scan(&a.ID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+// findSurveyResults aggregates the answers to every question of a survey. Questions
+// with fewer answers than the survey threshold are returned without results.
+func findSurveyResults(ctx context.Context, tx *Tx, id int) (*api.SurveyResults, error) {
+	currentUserID := api.UserIDFromContext(ctx)
+	sv, err := findSurveyByID(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	} else if currentUserID != 0 && sv.TeacherID != 0 && sv.TeacherID != currentUserID {
+		return nil, api.Errorf(api.EUNAUTHORIZED, "You are not allowed to view results of this survey.")
+	}
+
+	results := &api.SurveyResults{SurveyID: sv.ID, Questions: make([]*api.SurveyQuestionResult, 0)}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM survey_responses WHERE survey_id = $1
+	`, sv.ID).Scan(&results.N); err != nil {
+		return nil, err
+	}
+
+	questions, err := findSurveyQuestions(ctx, tx, sv.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, q := range questions {
+		qr, err := findSurveyQuestionResult(ctx, tx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		if qr.N < sv.MinResponses {
+			qr.Suppressed = true
+			qr.Distribution = nil
+			qr.OpenResponses = nil
+		}
+
+		results.Questions = append(results.Questions, qr)
+	}
+
+	return results, nil
+}
+
+// findSurveyQuestionResult aggregates the answers to a single survey question.
+func findSurveyQuestionResult(ctx context.Context, tx *Tx, q *api.SurveyQuestion) (*api.SurveyQuestionResult, error) {
+	qr := &api.SurveyQuestionResult{Question: q}
+	switch q.Type {
+	case api.Single, api.Multiple:
+		qr.Distribution = make([]int, len(q.Choices))
+	case api.Truefalse:
+		qr.Distribution = make([]int, 2)
+	case api.Open:
+		qr.OpenResponses = make([]string, 0)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT
+			open_response,
+			truefalse_response,
+			multiplechoice_response,
+			singlechoice_response
+		FROM survey_answers
+		WHERE survey_question_id = $1
+	`, q.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var openResponse sql.NullString
+		var truefalseResponse sql.NullBool
+		var multiplechoiceResponse pgtype.Int4Array
+		var singlechoiceResponse sql.NullInt32
+
+		if err := rows.Scan(
+			&openResponse,
+			&truefalseResponse,
+			&multiplechoiceResponse,
+			&singlechoiceResponse,
+		); err != nil {
+			return nil, err
+		}
+		qr.N++
+
+		var choices []int
+		switch q.Type {
+		case api.Open:
+			if openResponse.String != "" {
+				qr.OpenResponses = append(qr.OpenResponses, openResponse.String)
+			}
+		case api.Truefalse:
+			if truefalseResponse.Bool {
+				choices = []int{1}
+			} else {
+				choices = []int{0}
+			}
+		case api.Multiple:
+			if multiplechoiceResponse.Status != pgtype.Null {
+				multiplechoiceResponse.AssignTo(&choices)
+			}
+		case api.Single:
+			choices = []int{int(singlechoiceResponse.Int32)}
+		}
+
+		for _, c := range choices {
+			if c >= 0 && c < len(qr.Distribution) {
+				qr.Distribution[c]++
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Do not reveal the order in which open answers were given.
+	rand.Shuffle(len(qr.OpenResponses), func(i, j int) {
+		qr.OpenResponses[i], qr.OpenResponses[j] = qr.OpenResponses[j], qr.OpenResponses[i]
+	})
+
+	return qr, nil
+}
+
+// attachSurveyAssociations attaches the questions of the survey.
+func attachSurveyAssociations(ctx context.Context, tx *Tx, sv *api.Survey) (err error) {
+	if sv.Questions, err = findSurveyQuestions(ctx, tx, sv.ID); err != nil {
+		return fmt.Errorf("attach survey questions: %w", err)
+	}
+	return nil
+}
+
+// blindToken returns a token which identifies that a user responded to a
+// survey without storing the user ID next to it.
+func blindToken(key []byte, surveyID, userID int) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%d", surveyID, userID)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,584 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+	"github.com/jackc/pgtype"
+)
+
+// timetableLockID is the advisory lock taken while generating sessions so that
+// only one instance generates them at a time.
+const timetableLockID = 35001
+
+// Ensure service implements interface.
+var _ api.TimetableService = (*TimetableService)(nil)
+
+// TimetableService represents a service for managing timetables of groups.
+type TimetableService struct {
+	db *DB
+}
+
+// NewTimetableService returns a new instance of TimetableService.
+func NewTimetableService(db *DB) *TimetableService {
+	return &TimetableService{db: db}
+}
+
+// FindTimetable retrieves the timetable of a group.
+// Returns ENOTFOUND if the group has none.
+func (s *TimetableService) FindTimetable(ctx context.Context, groupID int) (*api.Timetable, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkTimetableAccess(ctx, tx, groupID, false); err != nil {
+		return nil, err
+	}
+	return findTimetableByGroupID(ctx, tx, groupID)
+}
+
+// SetTimetable creates or replaces the timetable of a group.
+func (s *TimetableService) SetTimetable(ctx context.Context, t *api.Timetable) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setTimetable(ctx, tx, t); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteTimetable permanently deletes the timetable of a group.
+func (s *TimetableService) DeleteTimetable(ctx context.Context, groupID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteTimetable(ctx, tx, groupID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FindSessions retrieves the attendances created from the timetable of a group.
+func (s *TimetableService) FindSessions(ctx context.Context, groupID int, from, to string) ([]*api.Attendance, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := checkTimetableAccess(ctx, tx, groupID, true); err != nil {
+		return nil, err
+	}
+
+	scheduled := true
+	filter := api.AttendanceFilter{GroupID: &groupID, Scheduled: &scheduled}
+	if from != "" {
+		filter.ScheduledFrom = &from
+	}
+	if to != "" {
+		filter.ScheduledTo = &to
+	}
+	attendances, _, err := findAttendances(ctx, tx, filter)
+	return attendances, err
+}
+
+// GenerateSessions creates the upcoming attendances of all timetables.
+func (s *TimetableService) GenerateSessions(ctx context.Context, now time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n, err := generateSessions(ctx, tx, now.UTC().Truncate(time.Second))
+	if err != nil {
+		return 0, err
+	} else if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// checkTimetableAccess returns an error if the current user is not in the
+// group. If teacher is set, the user must be one of its teachers.
+func checkTimetableAccess(ctx context.Context, tx *Tx, groupID int, teacher bool) error {
+	userID := api.UserIDFromContext(ctx)
+	if userID == 0 {
+		return api.Errorf(api.EUNAUTHORIZED, "You must be logged in.")
+	}
+
+	if ok, err := isGroupTeacher(ctx, tx, groupID, userID); err != nil {
+		return err
+	} else if ok {
+		return nil
+	} else if teacher {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not a teacher of the group.")
+	}
+
+	if ok, err := isGroupStudent(ctx, tx, groupID, userID); err != nil {
+		return err
+	} else if !ok {
+		return api.Errorf(api.EUNAUTHORIZED, "You are not in the group.")
+	}
+	return nil
+}
+
+// findTimetableByGroupID returns the timetable of a group along with its slots.
+// Returns ENOTFOUND if the group has none.
+func findTimetableByGroupID(ctx context.Context, tx *Tx, groupID int) (*api.Timetable, error) {
+	var termStart, termEnd time.Time
+	var holidays pgtype.VarcharArray
+	var updatedAt sql.NullTime
+
+	t := api.Timetable{GroupID: groupID}
+	if err := tx.QueryRowContext(ctx, `
+		SELECT
+			id,
+			timezone,
+			term_start,
+			term_end,
+			holidays,
+			created_at,
+			updated_at
+		FROM timetables
+		WHERE group_id = $1
+	`, groupID).Scan(
+		&t.ID,
+		&t.Timezone,
+		&termStart,
+		&termEnd,
+		&holidays,
+		&t.CreatedAt,
+		&updatedAt,
+	); err == sql.ErrNoRows {
+		return nil, &api.Error{Code: api.ENOTFOUND, Message: "Timetable not found."}
+	} else if err != nil {
+		return nil, err
+	}
+
+	t.TermStart = termStart.Format(api.DateLayout)
+	t.TermEnd = termEnd.Format(api.DateLayout)
+	t.Holidays = make([]string, 0)
+	if holidays.Status != pgtype.Null {
+		holidays.AssignTo(&t.Holidays)
+	}
+	if updatedAt.Valid {
+		t.UpdatedAt = updatedAt.Time
+	}
+
+	var err error
+	if t.Slots, err = findTimetableSlots(ctx, tx, t.ID); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// findTimetableSlots returns the slots of a timetable in weekly order.
+func findTimetableSlots(ctx context.Context, tx *Tx, timetableID int) ([]*api.TimetableSlot, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, weekday, start_time, end_time, title, timetable_id
+		FROM timetable_slots
+		WHERE timetable_id = $1
+		ORDER BY weekday ASC, start_time ASC, id ASC
+	`, timetableID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slots := make([]*api.TimetableSlot, 0)
+	for rows.Next() {
+		var title sql.NullString
+
+		var slot api.TimetableSlot
+		if err := rows.Scan(
+			&slot.ID,
+			&slot.Weekday,
+			&slot.StartTime,
+			&slot.EndTime,
+			&title,
+			&slot.TimetableID,
+		); err != nil {
+			return nil, err
+		}
+		slot.Title = title.String
+
+		slots = append(slots, &slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return slots, nil
+}
+
+// setTimetable upserts the timetable of a group and replaces its slots. The
+// upcoming sessions are removed unless someone has already checked in or they
+// were cancelled, and are generated again from the new slots.
+func setTimetable(ctx context.Context, tx *Tx, t *api.Timetable) error {
+	if err := checkTimetableAccess(ctx, tx, t.GroupID, true); err != nil {
+		return err
+	}
+
+	if t.Holidays == nil {
+		t.Holidays = make([]string, 0)
+	}
+	if t.Slots == nil {
+		t.Slots = make([]*api.TimetableSlot, 0)
+	}
+
+	// Perform basic field validation.
+	if err := t.Validate(); err != nil {
+		return err
+	}
+
+	t.CreatedAt, t.UpdatedAt = tx.now, tx.now
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO timetables (
+			timezone,
+			term_start,
+			term_end,
+			holidays,
+			generated_until,
+			created_at,
+			group_id
+		)
+		VALUES ($1, $2, $3, $4, NULL, $5, $6)
+		ON CONFLICT (group_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone,
+		    term_start = EXCLUDED.term_start,
+		    term_end = EXCLUDED.term_end,
+		    holidays = EXCLUDED.holidays,
+		    generated_until = NULL,
+		    updated_at = EXCLUDED.created_at
+		RETURNING id, created_at
+	`,
+		t.Timezone,
+		t.TermStart,
+		t.TermEnd,
+		t.Holidays,
+		t.CreatedAt,
+		t.GroupID,
+	).Scan(&t.ID, &t.CreatedAt); err != nil {
+		return FormatError(err)
+	}
+
+	// Match the new slots to the current ones so that unchanged slots are
+	// updated in place and their past sessions stay linked to them. A slot is
+	// matched by its ID or else by its weekday and times.
+	current, err := findTimetableSlots(ctx, tx, t.ID)
+	if err != nil {
+		return err
+	}
+	kept := make(map[int]bool, len(current))
+	for _, slot := range t.Slots {
+		id := slot.ID
+		slot.ID = 0
+		for _, c := range current {
+			if kept[c.ID] {
+				continue
+			} else if c.ID == id || (id == 0 && c.Weekday == slot.Weekday && c.StartTime == slot.StartTime && c.EndTime == slot.EndTime) {
+				slot.ID = c.ID
+				kept[c.ID] = true
+				break
+			}
+		}
+	}
+
+	// Cancelled sessions of the kept slots stay so that they are not
+	// generated again. The sessions of removed slots all go with them.
+	if err := deleteUpcomingSessions(ctx, tx, t.ID, false); err != nil {
+		return err
+	}
+	for _, c := range current {
+		if kept[c.ID] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM attendances a
+			WHERE a.slot_id = $1
+			  AND a.opened_at > $2
+			  AND NOT EXISTS (SELECT 1 FROM att_submissions sub WHERE sub.attendance_id = a.id)
+		`, c.ID, tx.now); err != nil {
+			return FormatError(err)
+		} else if _, err := tx.ExecContext(ctx, `DELETE FROM timetable_slots WHERE id = $1`, c.ID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	for _, slot := range t.Slots {
+		slot.TimetableID = t.ID
+
+		var title *string
+		if slot.Title != "" {
+			title = &slot.Title
+		}
+
+		if slot.ID != 0 {
+			if _, err := tx.ExecContext(ctx, `
+				UPDATE timetable_slots SET weekday = $1, start_time = $2, end_time = $3, title = $4
+				WHERE id = $5
+			`,
+				slot.Weekday,
+				slot.StartTime,
+				slot.EndTime,
+				title,
+				slot.ID,
+			); err != nil {
+				return FormatError(err)
+			}
+			continue
+		}
+
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO timetable_slots (weekday, start_time, end_time, title, timetable_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`,
+			slot.Weekday,
+			slot.StartTime,
+			slot.EndTime,
+			title,
+			slot.TimetableID,
+		).Scan(&slot.ID); err != nil {
+			return FormatError(err)
+		}
+	}
+
+	return nil
+}
+
+// deleteTimetable removes the timetable of a group and its upcoming sessions.
+func deleteTimetable(ctx context.Context, tx *Tx, groupID int) error {
+	if err := checkTimetableAccess(ctx, tx, groupID, true); err != nil {
+		return err
+	}
+
+	t, err := findTimetableByGroupID(ctx, tx, groupID)
+	if err != nil {
+		return err
+	} else if err := deleteUpcomingSessions(ctx, tx, t.ID, true); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM timetables WHERE id = $1`, t.ID); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// deleteUpcomingSessions removes the sessions of a timetable that have not
+// started yet and have no check-ins. Cancelled sessions are only removed if
+// cancelled is true.
+func deleteUpcomingSessions(ctx context.Context, tx *Tx, timetableID int, cancelled bool) error {
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM attendances a
+		USING timetable_slots s
+		WHERE a.slot_id = s.id
+		  AND s.timetable_id = $1
+		  AND a.opened_at > $2
+		  AND ($3 OR NOT a.cancelled)
+		  AND NOT EXISTS (SELECT 1 FROM att_submissions sub WHERE sub.attendance_id = a.id)
+	`, timetableID, tx.now, cancelled); err != nil {
+		return FormatError(err)
+	}
+	return nil
+}
+
+// pendingTimetable is a timetable whose sessions may need to be generated.
+type pendingTimetable struct {
+	groupID        int
+	generatedUntil sql.NullTime
+	ownerID        int
+	title          string
+}
+
+// generateSessions creates the attendances of every timetable from the day
+// after it was last generated until SessionHorizon days ahead. Only sessions
+// that have not started yet are created. A timetable that fails is logged and
+// skipped so that the others still get their sessions.
+func generateSessions(ctx context.Context, tx *Tx, now time.Time) (n int, err error) {
+	// Skip if another instance is already generating.
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, timetableLockID).Scan(&locked); err != nil {
+		return 0, err
+	} else if !locked {
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT t.group_id, t.generated_until, g.owner_id, g.title
+		FROM timetables t
+		JOIN groups g ON g.id = t.group_id
+		WHERE t.term_end >= $1::date - 1
+		ORDER BY t.id ASC
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	var timetables []pendingTimetable
+	for rows.Next() {
+		var ownerID sql.NullInt32
+
+		var p pendingTimetable
+		if err := rows.Scan(&p.groupID, &p.generatedUntil, &ownerID, &p.title); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if ownerID.Valid {
+			p.ownerID = int(ownerID.Int32)
+		}
+		timetables = append(timetables, p)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	for _, p := range timetables {
+		// A savepoint lets the transaction go on after a failed timetable.
+		if _, err := tx.ExecContext(ctx, `SAVEPOINT timetable_sessions`); err != nil {
+			return n, err
+		}
+
+		created, err := generateTimetableSessions(ctx, tx, p, now)
+		if err != nil {
+			log.Printf("timetable sessions: group %d: %s", p.groupID, err)
+			api.ReportError(ctx, err)
+			if _, err := tx.ExecContext(ctx, `ROLLBACK TO SAVEPOINT timetable_sessions`); err != nil {
+				return n, err
+			}
+			continue
+		}
+		n += created
+
+		if _, err := tx.ExecContext(ctx, `RELEASE SAVEPOINT timetable_sessions`); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// generateTimetableSessions creates the upcoming attendances of the timetable
+// of one group and returns their number.
+func generateTimetableSessions(ctx context.Context, tx *Tx, p pendingTimetable, now time.Time) (n int, err error) {
+	t, err := findTimetableByGroupID(ctx, tx, p.groupID)
+	if err != nil {
+		return 0, err
+	}
+
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	today := now.In(loc)
+
+	from := today.Format(api.DateLayout)
+	if p.generatedUntil.Valid {
+		if next := p.generatedUntil.Time.AddDate(0, 0, 1).Format(api.DateLayout); next > from {
+			from = next
+		}
+	}
+	to := today.AddDate(0, 0, api.SessionHorizon).Format(api.DateLayout)
+	if from > to {
+		return 0, nil
+	}
+
+	sessions, err := t.Sessions(from, to)
+	if err != nil {
+		return 0, err
+	}
+
+	// Sessions kept across timetable edits, such as cancelled ones or those
+	// with check-ins, are not generated again.
+	existing, err := findScheduledSessions(ctx, tx, t.ID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, session := range sessions {
+		if !session.OpenedAt.After(now) || existing[scheduledSessionKey(session.Slot.ID, session.Date)] {
+			continue
+		}
+
+		title := session.Slot.Title
+		if title == "" {
+			title = p.title
+		}
+
+		att := &api.Attendance{
+			Title:       title,
+			PIN:         api.RandDigitSeq(api.PINLength),
+			StudentLink: api.RandStringSeq(11),
+			TeacherLink: api.RandStringSeq(11),
+			CourseTitle: p.title,
+			Mode:        api.Registered,
+			OpenedAt:    session.OpenedAt,
+			ClosedAt:    session.ClosedAt,
+			SlotID:      session.Slot.ID,
+			ScheduledOn: session.Date,
+			TeacherID:   p.ownerID,
+			GroupID:     p.groupID,
+		}
+		if err := createAttendance(ctx, tx, att); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE timetables SET generated_until = $1 WHERE id = $2
+	`, to, t.ID); err != nil {
+		return n, FormatError(err)
+	}
+	return n, nil
+}
+
+// findScheduledSessions returns the keys of the sessions of a timetable
+// scheduled on or after a date.
+func findScheduledSessions(ctx context.Context, tx *Tx, timetableID int, from string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.slot_id, a.scheduled_on
+		FROM attendances a
+		JOIN timetable_slots s ON s.id = a.slot_id
+		WHERE s.timetable_id = $1 AND a.scheduled_on >= $2
+	`, timetableID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+	for rows.Next() {
+		var slotID int
+		var scheduledOn time.Time
+		if err := rows.Scan(&slotID, &scheduledOn); err != nil {
+			return nil, err
+		}
+		keys[scheduledSessionKey(slotID, scheduledOn.Format(api.DateLayout))] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// scheduledSessionKey identifies the session of a slot on a date.
+func scheduledSessionKey(slotID int, date string) string {
+	return fmt.Sprintf("%d/%s", slotID, date)
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Default and lowest easiness factors of the SM-2 algorithm.
+const (
+	DefaultEasiness = 2.5
+	MinEasiness     = 1.3
+)
+
+// PracticeItem represents the spaced-repetition schedule of a question for a
+// student. Practice is never graded and does not affect quiz submissions.
+type PracticeItem struct {
+	ID int `json:"ID"`
+
+	Easiness    float64   `json:"Easiness"`
+	Interval    int       `json:"Interval"` // in days
+	Repetitions int       `json:"Repetitions"`
+	DueAt       time.Time `json:"DueAt"`
+	AnsweredAt  time.Time `json:"AnsweredAt"`
+
+	StudentID  int `json:"StudentID"`
+	QuestionID int `json:"QuestionID"`
+}
+
+// Review updates the schedule of the item after an answer of the given quality
+// using the SM-2 algorithm. Quality ranges from 0 (complete blackout) to 5
+// (perfect response); answers below 3 restart the repetitions.
+func (u *PracticeItem) Review(quality int, now time.Time) {
+	if u.Easiness == 0 {
+		u.Easiness = DefaultEasiness
+	}
+
+	if quality >= 3 {
+		switch u.Repetitions {
+		case 0:
+			u.Interval = 1
+		case 1:
+			u.Interval = 6
+		default:
+			u.Interval = int(math.Round(float64(u.Interval) * u.Easiness))
+		}
+		u.Repetitions++
+	} else {
+		u.Repetitions = 0
+		u.Interval = 1
+	}
+
+	q := float64(5 - quality)
+	u.Easiness += 0.1 - q*(0.08+q*0.02)
+	if u.Easiness < MinEasiness {
+		u.Easiness = MinEasiness
+	}
+
+	u.AnsweredAt = now
+	u.DueAt = now.AddDate(0, 0, u.Interval)
+}
+
+// PracticeQuality returns the SM-2 quality of an automatically checked answer.
+func PracticeQuality(correct bool) int {
+	if correct {
+		return 5
+	}
+	return 1
+}
+
+// PracticeAttempt represents a single answer given in practice mode.
+type PracticeAttempt struct {
+	ID int `json:"ID"`
+
+	IsCorrect  bool      `json:"IsCorrect"`
+	Quality    int       `json:"Quality"`
+	AnsweredAt time.Time `json:"AnsweredAt"`
+
+	StudentID  int `json:"StudentID"`
+	QuestionID int `json:"QuestionID"`
+	GroupID    int `json:"GroupID"`
+}
+
+// PracticeResult represents the outcome of answering a practice question. The
+// question is returned with its answers so that the student can learn from it.
+type PracticeResult struct {
+	IsCorrect bool          `json:"IsCorrect"`
+	Question  *Question     `json:"Question"`
+	Item      *PracticeItem `json:"Item"`
+}
+
+// PracticeService represents a service for practicing questions of a group's
+// question bank. The bank consists of the automatically gradable questions of
+// the group's quizzes that have already closed.
+type PracticeService interface {
+	// Returns the next question the current student should practice. Questions
+	// that are due come first, then new questions, then the ones due soonest.
+	// Returns ENOTFOUND if the bank is empty.
+	NextPracticeQuestion(ctx context.Context, groupID int) (*Question, *PracticeItem, error)
+
+	// Checks an answer of the current student and reschedules the question.
+	AnswerPracticeQuestion(ctx context.Context, groupID int, response *Response) (*PracticeResult, error)
+
+	// Retrieves the practice history of the current student. Also returns the
+	// total count of matching attempts which may differ if filter.Limit is specified.
+	FindPracticeAttempts(ctx context.Context, filter PracticeAttemptFilter) ([]*PracticeAttempt, int, error)
+}
+
+// PracticeAttemptFilter represents a filter passed to FindPracticeAttempts().
+type PracticeAttemptFilter struct {
+	// Filtering fields.
+	GroupID    *int `json:"GroupID"`
+	QuestionID *int `json:"QuestionID"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
@@ -45,6 +45,20 @@ func (u *Question) Validate() error {
 	return nil
 }
 
+// Check reports whether the response answers the question correctly. Open
+// questions cannot be checked automatically and always report false.
+func (u *Question) Check(rn *Response) bool {
+	switch u.Type {
+	case Single:
+		return u.SingleChoiceAnswer == rn.SingleChoiceResponse
+	case Multiple:
+		return Equal(u.MultipleChoiceAnswer, rn.MultipleChoiceResponse)
+	case Truefalse:
+		return u.TrueFalseAnswer == rn.TrueFalseResponse
+	}
+	return false
+}
+
 // QuestionService represents a service for managing questions.
 type QuestionService interface {
 	// Retrieves a question by ID.
@@ -2,9 +2,13 @@ package api
 
 import (
 	"context"
+	"net"
 	"time"
 )
 
+// MaxTimeLimit is the longest time limit of a quiz, in minutes.
+const MaxTimeLimit = 24 * 60
+
 // Quiz represents a quiz in the system.
 type Quiz struct {
 	ID int `json:"ID"`
@@ -21,6 +25,20 @@ type Quiz struct {
 	OpenedAt    time.Time `json:"OpenedAt"`
 	ClosedAt    time.Time `json:"ClosedAt"`
 
+	// Time from which students see their grades and comments. Zero until the
+	// teacher releases them.
+	GradesReleaseAt time.Time `json:"GradesReleaseAt"`
+
+	// Optional access restrictions for proctored quizzes. A quiz with any of
+	// them set must be started through StartQuizAttempt before submitting.
+	AccessCode    string   `json:"-"` // only shown to teachers
+	AllowedCIDRs  []string `json:"AllowedCIDRs"`
+	SingleSession bool     `json:"SingleSession"`
+
+	// Minutes a student has to submit after starting the quiz. Zero means
+	// no limit.
+	TimeLimit int `json:"TimeLimit"`
+
 	TeacherFullName string `json:"TeacherFullName" db:"teacher_fullname"`
 	TeacherID       int    `json:"TeacherID"`
 	Teacher         *User  `json:"Teacher"`
@@ -39,10 +57,58 @@ func (q *Quiz) Validate() error {
 		return Errorf(EINVALID, "Title required.")
 	} else if q.Mode != All && q.Mode != Registered {
 		return Errorf(EINVALID, "Mode is incorrect.")
+	} else if q.SingleSession && q.Mode != Registered {
+		return Errorf(EINVALID, "Single session quizzes must be for registered users only.")
+	} else if q.TimeLimit < 0 || q.TimeLimit > MaxTimeLimit {
+		return Errorf(EINVALID, "Time limit must be between 0 and %d minutes.", MaxTimeLimit)
+	}
+	for _, v := range q.AllowedCIDRs {
+		if _, _, err := net.ParseCIDR(v); err != nil {
+			return Errorf(EINVALID, "Allowed IP range %q is incorrect.", v)
+		}
 	}
 	return nil
 }
 
+// Restricted returns true if the quiz has to be started before submitting,
+// which is also the case for timed quizzes so that the start is recorded.
+func (q *Quiz) Restricted() bool {
+	return q.AccessCode != "" || len(q.AllowedCIDRs) > 0 || q.SingleSession || q.TimeLimit > 0
+}
+
+// AllowsIP returns true if the quiz accepts requests from the IP address. Quizzes
+// without allowed ranges accept any address.
+func (q *Quiz) AllowsIP(ip net.IP) bool {
+	if len(q.AllowedCIDRs) == 0 {
+		return true
+	} else if ip == nil {
+		return false
+	}
+	for _, v := range q.AllowedCIDRs {
+		if _, ipnet, err := net.ParseCIDR(v); err == nil && ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Deadline returns the opening and closing times and the time limit of the
+// quiz before any per-student changes.
+func (q *Quiz) Deadline() *Deadline {
+	return &Deadline{
+		ItemKind:  GradeItemQuiz,
+		ItemID:    q.ID,
+		OpenedAt:  q.OpenedAt,
+		ClosedAt:  q.ClosedAt,
+		TimeLimit: q.TimeLimit,
+	}
+}
+
+// GradesReleased returns true if students may see their grades at now.
+func (q *Quiz) GradesReleased(now time.Time) bool {
+	return !q.GradesReleaseAt.IsZero() && !now.Before(q.GradesReleaseAt)
+}
+
 // QuizService represents a service for managing quizzes.
 type QuizService interface {
 	// Retrieves a quiz by ID.
@@ -99,6 +165,11 @@ type QuizUpdate struct {
 	OpenedAt    *time.Time `json:"OpenedAt"`
 	ClosedAt    *time.Time `json:"ClosedAt"`
 
+	AccessCode    *string   `json:"AccessCode"`
+	AllowedCIDRs  *[]string `json:"AllowedCIDRs"`
+	SingleSession *bool     `json:"SingleSession"`
+	TimeLimit     *int      `json:"TimeLimit"`
+
 	TeacherFullName *string `json:"TeacherFullName"`
 	TeacherID       *int    `json:"TeacherID"`
 	GroupID         *int    `json:"GroupID"`
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Reasons of quiz access violations.
+const (
+	ViolationAccessCode = "access_code"
+	ViolationIP         = "ip"
+	ViolationDevice     = "device"
+)
+
+// QuizAttempt represents a student taking a restricted quiz. An attempt is
+// bound to the device that started it through its device token.
+type QuizAttempt struct {
+	ID int `json:"ID"`
+
+	// Secret handed to the device which started the attempt. It must be sent
+	// along with every later request of the attempt.
+	DeviceToken string    `json:"DeviceToken,omitempty"`
+	IP          string    `json:"IP"`
+	StartedAt   time.Time `json:"StartedAt"`
+	LastSeenAt  time.Time `json:"LastSeenAt"`
+
+	// Time the attempt has to be submitted by, given the time limit and the
+	// closing time of the quiz for the student. Zero if there is neither.
+	EndsAt time.Time `json:"EndsAt"`
+
+	StudentID int `json:"StudentID,omitempty"`
+
+	QuizID       int `json:"QuizID"`
+	SubmissionID int `json:"SubmissionID,omitempty"`
+}
+
+// QuizAccess represents the credentials a client presents to access a
+// restricted quiz.
+type QuizAccess struct {
+	QuizID      int    `json:"QuizID"`
+	AccessCode  string `json:"AccessCode"`
+	DeviceToken string `json:"DeviceToken"`
+	IP          string `json:"-"`
+}
+
+// QuizViolation represents a rejected attempt to access a restricted quiz.
+type QuizViolation struct {
+	ID int `json:"ID"`
+
+	Reason    string    `json:"Reason"`
+	Message   string    `json:"Message"`
+	IP        string    `json:"IP"`
+	CreatedAt time.Time `json:"CreatedAt"`
+
+	StudentID int   `json:"StudentID,omitempty"`
+	Student   *User `json:"Student,omitempty"`
+
+	QuizID int `json:"QuizID"`
+}
+
+// QuizAttemptService represents a service for managing attempts of restricted quizzes.
+type QuizAttemptService interface {
+	// Starts an attempt or resumes it from the device that started it. Returns
+	// EFORBIDDEN if the access code, IP address or device is rejected, in which
+	// case the violation is logged for the teacher.
+	StartQuizAttempt(ctx context.Context, access *QuizAccess) (*QuizAttempt, error)
+
+	// Verifies that the request belongs to a started attempt of the quiz and
+	// comes from an allowed IP address. Returns EFORBIDDEN otherwise.
+	CheckQuizAttempt(ctx context.Context, access *QuizAccess) (*QuizAttempt, error)
+
+	// Creates the submission an attempt ends with and links the attempt to it.
+	// Returns ECONFLICT if the attempt has already been submitted.
+	FinishQuizAttempt(ctx context.Context, id int, sub *QuizSubmission) error
+
+	// Retrieves the access violations of a quiz. Returns EUNAUTHORIZED if
+	// current user is not the owner of the quiz.
+	FindQuizViolations(ctx context.Context, filter QuizViolationFilter) ([]*QuizViolation, int, error)
+}
+
+// QuizViolationFilter represents a filter passed to FindQuizViolations().
+type QuizViolationFilter struct {
+	// Filtering fields.
+	QuizID    int  `json:"QuizID"`
+	StudentID *int `json:"StudentID"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
@@ -33,6 +33,15 @@ func (u *QuizSubmission) Validate() error {
 	return nil
 }
 
+// RedactGrade clears the grade, comments and the grades of the responses,
+// which students may not see before the grades of the quiz are released.
+func (u *QuizSubmission) RedactGrade() {
+	u.Grade, u.Comments = 0, ""
+	for _, r := range u.Responses {
+		r.Grade, r.IsCorrect = 0, false
+	}
+}
+
 // QuizSubmissionService represents a service for managing quiz submissions.
 type QuizSubmissionService interface {
 	// Retrieves a quiz submission by ID.
@@ -52,8 +61,9 @@ type QuizSubmissionService interface {
 
 	// Permanently deletes a quiz submission and all owned dials. Returns EUNAUTHORIZED
 	// if current quiz submission is not the quiz submission being deleted. Returns ENOTFOUND if
-	// quiz submission does not exist.
-	DeleteQuizSubmission(ctx context.Context, id int) error
+	// quiz submission does not exist. Submissions of a quiz whose grades are
+	// locked are only deleted with an override reason, which is recorded.
+	DeleteQuizSubmission(ctx context.Context, id int, overrideReason *string) error
 }
 
 // QuizSubmissionFilter represents a filter passed to FindQuizSubmissions().
@@ -77,6 +87,9 @@ type QuizSubmissionUpdate struct {
 	Grade    *float32 `json:"Grade"`
 	Comments *string  `json:"Comments"`
 
+	// Required to change the grade when the grades of the quiz are locked.
+	OverrideReason *string `json:"OverrideReason"`
+
 	StudentFullName *string `json:"StudentFullName"`
 	StudentID       *int    `json:"StudentID"`
 
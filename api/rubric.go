@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Rubric represents a reusable grading guide made of criteria, each with
+// performance levels worth points. A rubric belongs to the teacher who made it
+// and, optionally, to a group whose teachers may use it as well.
+type Rubric struct {
+	ID int `json:"ID"`
+
+	Title       string    `json:"Title"`
+	Description string    `json:"Description"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+
+	TeacherID int `json:"TeacherID"`
+	GroupID   int `json:"GroupID,omitempty"`
+
+	Criteria []*RubricCriterion `json:"Criteria"`
+}
+
+// Validate returns an error if the rubric or one of its criteria contains
+// invalid fields.
+func (r *Rubric) Validate() error {
+	if r.Title == "" {
+		return Errorf(EINVALID, "Title required.")
+	} else if len(r.Criteria) == 0 {
+		return Errorf(EINVALID, "Rubric must have at least one criterion.")
+	}
+	for _, c := range r.Criteria {
+		if err := c.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxPoints returns the points of a submission reaching the top level of
+// every criterion.
+func (r *Rubric) MaxPoints() float32 {
+	var points float32
+	for _, c := range r.Criteria {
+		points += c.MaxPoints()
+	}
+	return points
+}
+
+// Grade checks that scores select a level of every criterion, sets their
+// points and returns the grade they earn out of maxGrade. The grade is the
+// sum of the points, scaled to maxGrade if it is set, rounded to hundredths.
+func (r *Rubric) Grade(scores []*RubricScore, maxGrade float32) (float32, error) {
+	selected := make(map[int]*RubricScore, len(scores))
+	for _, s := range scores {
+		if _, ok := selected[s.CriterionID]; ok {
+			return 0, Errorf(EINVALID, "Criterion %d is scored more than once.", s.CriterionID)
+		}
+		selected[s.CriterionID] = s
+	}
+
+	var points float32
+	for _, c := range r.Criteria {
+		s, ok := selected[c.ID]
+		if !ok {
+			return 0, Errorf(EINVALID, "Criterion %q is not scored.", c.Title)
+		}
+		delete(selected, c.ID)
+
+		l := c.Level(s.LevelID)
+		if l == nil {
+			return 0, Errorf(EINVALID, "Level %d is not a level of criterion %q.", s.LevelID, c.Title)
+		}
+		s.Points = l.Points
+		points += l.Points
+	}
+	if len(selected) > 0 {
+		return 0, Errorf(EINVALID, "Scores include criteria of another rubric.")
+	}
+
+	if total := r.MaxPoints(); maxGrade > 0 && total > 0 {
+		points = points / total * maxGrade
+	}
+	return float32(math.Round(float64(points)*100) / 100), nil
+}
+
+// RubricCriterion represents an aspect of the work a rubric grades.
+type RubricCriterion struct {
+	ID int `json:"ID"`
+
+	Title       string `json:"Title"`
+	Description string `json:"Description"`
+
+	RubricID int `json:"RubricID"`
+
+	Levels []*RubricLevel `json:"Levels"`
+}
+
+// Validate returns an error if the criterion or one of its levels contains
+// invalid fields.
+func (c *RubricCriterion) Validate() error {
+	if c.Title == "" {
+		return Errorf(EINVALID, "Criterion title required.")
+	} else if len(c.Levels) == 0 {
+		return Errorf(EINVALID, "Criterion %q must have at least one level.", c.Title)
+	}
+	for _, l := range c.Levels {
+		if l.Title == "" {
+			return Errorf(EINVALID, "Level title required.")
+		} else if l.Points < 0 {
+			return Errorf(EINVALID, "Level points cannot be negative.")
+		}
+	}
+	return nil
+}
+
+// MaxPoints returns the points of the top level of the criterion.
+func (c *RubricCriterion) MaxPoints() float32 {
+	var points float32
+	for _, l := range c.Levels {
+		if l.Points > points {
+			points = l.Points
+		}
+	}
+	return points
+}
+
+// Level returns the level of the criterion with the given ID, or nil.
+func (c *RubricCriterion) Level(id int) *RubricLevel {
+	for _, l := range c.Levels {
+		if l.ID == id {
+			return l
+		}
+	}
+	return nil
+}
+
+// RubricLevel represents a level of performance on a criterion.
+type RubricLevel struct {
+	ID int `json:"ID"`
+
+	Title      string  `json:"Title"`
+	Descriptor string  `json:"Descriptor"`
+	Points     float32 `json:"Points"`
+
+	CriterionID int `json:"CriterionID"`
+}
+
+// RubricScore represents the level a submission was given on a criterion.
+type RubricScore struct {
+	CriterionID int     `json:"CriterionID"`
+	LevelID     int     `json:"LevelID"`
+	Points      float32 `json:"Points"`
+	Comment     string  `json:"Comment"`
+
+	SubmissionID int `json:"SubmissionID"`
+}
+
+// RubricCriterionStats represents how the submissions to a homework scored on
+// a criterion of its rubric.
+type RubricCriterionStats struct {
+	CriterionID int     `json:"CriterionID"`
+	Title       string  `json:"Title"`
+	MaxPoints   float32 `json:"MaxPoints"`
+
+	// Number of scored submissions and their mean points.
+	N          int     `json:"N"`
+	MeanPoints float32 `json:"MeanPoints"`
+
+	Levels []*RubricLevelStats `json:"Levels"`
+}
+
+// RubricLevelStats represents the number of submissions given a level.
+type RubricLevelStats struct {
+	LevelID int     `json:"LevelID"`
+	Title   string  `json:"Title"`
+	Points  float32 `json:"Points"`
+	N       int     `json:"N"`
+}
+
+// RubricService represents a service for managing rubrics and grading with them.
+type RubricService interface {
+	// Retrieves a rubric by ID along with its criteria and levels. Returns
+	// ENOTFOUND if rubric does not exist.
+	FindRubricByID(ctx context.Context, id int) (*Rubric, error)
+
+	// Retrieves the rubrics the current user may use by filter. Also returns
+	// total count of matching rubrics.
+	FindRubrics(ctx context.Context, filter RubricFilter) ([]*Rubric, int, error)
+
+	// Creates a new rubric owned by the current user.
+	CreateRubric(ctx context.Context, rubric *Rubric) error
+
+	// Updates a rubric. Criteria can only be replaced until a submission is
+	// graded with the rubric, after which ECONFLICT is returned.
+	UpdateRubric(ctx context.Context, id int, upd RubricUpdate) (*Rubric, error)
+
+	// Permanently deletes a rubric. Returns ECONFLICT if a homework uses it.
+	DeleteRubric(ctx context.Context, id int) error
+
+	// Copies a rubric into a group, or into the rubrics of the current user
+	// if groupID is zero, and attaches the copy to a homework if homeworkID
+	// is set.
+	CopyRubric(ctx context.Context, id, groupID, homeworkID int) (*Rubric, error)
+
+	// Grades a homework submission by selecting a level of every criterion
+	// of the homework's rubric. The grade is computed from the points and is
+	// subject to late penalties and grade locks like any other grade.
+	GradeHWSubmission(ctx context.Context, id int, scores []*RubricScore, overrideReason *string) (*HWSubmission, error)
+
+	// Retrieves the scores of the submissions to a homework per criterion.
+	FindRubricStats(ctx context.Context, homeworkID int) ([]*RubricCriterionStats, error)
+}
+
+// RubricFilter represents a filter passed to FindRubrics().
+type RubricFilter struct {
+	// Filtering fields.
+	ID      *int `json:"ID"`
+	GroupID *int `json:"GroupID"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
+
+// RubricUpdate represents a set of fields to be updated via UpdateRubric().
+type RubricUpdate struct {
+	Title       *string `json:"Title"`
+	Description *string `json:"Description"`
+
+	// Replaces all criteria and their levels.
+	Criteria *[]*RubricCriterion `json:"Criteria"`
+}
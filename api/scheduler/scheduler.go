@@ -0,0 +1,97 @@
+// Package scheduler runs periodic background tasks of the application.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/dori7879/senior-project/api"
+)
+
+// Task represents a function run periodically by the scheduler.
+type Task struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context, now time.Time) error
+}
+
+// Scheduler runs tasks in the background until it is closed. Each task runs
+// once when the scheduler opens and then on every tick of its interval.
+type Scheduler struct {
+	tasks []Task
+
+	ctx    context.Context
+	cancel func()
+	wg     sync.WaitGroup
+
+	// Returns the current time. Can be replaced to mock time.
+	Now func() time.Time
+}
+
+// NewScheduler returns a new instance of Scheduler.
+func NewScheduler() *Scheduler {
+	s := &Scheduler{Now: time.Now}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	return s
+}
+
+// Add registers a task. Tasks must be added before the scheduler is opened.
+func (s *Scheduler) Add(name string, interval time.Duration, fn func(ctx context.Context, now time.Time) error) {
+	s.tasks = append(s.tasks, Task{Name: name, Interval: interval, Run: fn})
+}
+
+// Open starts running the tasks in the background.
+func (s *Scheduler) Open() error {
+	for _, task := range s.tasks {
+		if task.Interval <= 0 {
+			return fmt.Errorf("scheduler: task %q has no interval", task.Name)
+		}
+
+		s.wg.Add(1)
+		go func(task Task) {
+			defer s.wg.Done()
+			s.loop(task)
+		}(task)
+	}
+	return nil
+}
+
+// Close stops the scheduler and waits for running tasks to finish.
+func (s *Scheduler) Close() error {
+	s.cancel()
+	s.wg.Wait()
+	return nil
+}
+
+// loop runs a task on every tick until the scheduler is closed.
+func (s *Scheduler) loop(task Task) {
+	ticker := time.NewTicker(task.Interval)
+	defer ticker.Stop()
+
+	for {
+		s.run(task)
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run executes a task once, reporting errors and panics instead of stopping.
+func (s *Scheduler) run(task Task) {
+	defer func() {
+		if err := recover(); err != nil {
+			api.ReportPanic(err)
+		}
+	}()
+
+	if err := task.Run(s.ctx, s.Now()); err != nil && s.ctx.Err() == nil {
+		log.Printf("scheduler: task %q failed: %s", task.Name, err)
+		api.ReportError(s.ctx, err)
+	}
+}
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultMinResponses is the number of answers a question needs before its
+// results are shown when a survey does not set its own threshold.
+const DefaultMinResponses = 5
+
+// Survey represents a course evaluation or a poll. Surveys reuse the question
+// types of quizzes but have no correct answers and are never graded.
+type Survey struct {
+	ID int `json:"ID"`
+
+	Title       string    `json:"Title"`
+	Content     string    `json:"Content"`
+	StudentLink string    `json:"StudentLink"`
+	TeacherLink string    `json:"TeacherLink"`
+	Mode        string    `json:"Mode"`
+	CreatedAt   time.Time `json:"CreatedAt"`
+	UpdatedAt   time.Time `json:"UpdatedAt"`
+	OpenedAt    time.Time `json:"OpenedAt"`
+	ClosedAt    time.Time `json:"ClosedAt"`
+
+	// Results of a question are hidden until it has at least this many
+	// answers so that individual students cannot be identified. It is never
+	// below DefaultMinResponses and cannot be lowered once the survey has
+	// responses.
+	MinResponses int `json:"MinResponses"`
+
+	TeacherID int `json:"TeacherID"`
+
+	GroupID int `json:"GroupID"`
+
+	Questions []*SurveyQuestion `json:"Questions"`
+}
+
+// Validate returns an error if the survey contains invalid fields.
+// This only performs basic validation.
+func (u *Survey) Validate() error {
+	if u.Title == "" {
+		return Errorf(EINVALID, "Title required.")
+	} else if u.Mode != All && u.Mode != Registered {
+		return Errorf(EINVALID, "Mode is incorrect.")
+	} else if u.MinResponses < DefaultMinResponses {
+		return Errorf(EINVALID, "Minimum number of responses must be at least %d.", DefaultMinResponses)
+	} else if u.GroupID != 0 && u.Mode != Registered {
+		return Errorf(EINVALID, "Group surveys must be for registered users only.")
+	}
+	return nil
+}
+
+// SurveyQuestion represents a question of a survey.
+type SurveyQuestion struct {
+	ID int `json:"ID"`
+
+	Content string       `json:"Content"`
+	Type    QuestionType `json:"Type"`
+	Choices []string     `json:"Choices"`
+
+	SurveyID int `json:"SurveyID"`
+}
+
+// Validate returns an error if the survey question contains invalid fields.
+// This only performs basic validation.
+func (u *SurveyQuestion) Validate() error {
+	if u.Content == "" {
+		return Errorf(EINVALID, "Content required.")
+	} else if u.Type < Single || u.Type > Open {
+		return Errorf(EINVALID, "Type is incorrect.")
+	} else if (u.Type == Single || u.Type == Multiple) && len(u.Choices) == 0 {
+		return Errorf(EINVALID, "Choices required.")
+	}
+	return nil
+}
+
+// SurveyResponse represents an anonymous set of answers to a survey. It never
+// references the student who submitted it and only records the submission day.
+type SurveyResponse struct {
+	ID int `json:"ID"`
+
+	SubmittedOn time.Time `json:"SubmittedOn"`
+
+	SurveyID int `json:"SurveyID"`
+
+	Answers []*SurveyAnswer `json:"Answers"`
+}
+
+// SurveyAnswer represents an answer to a single survey question.
+type SurveyAnswer struct {
+	ID int `json:"ID"`
+
+	OpenResponse           string `json:"OpenResponse"`
+	TrueFalseResponse      bool   `json:"TrueFalseResponse"`
+	MultipleChoiceResponse []int  `json:"MultipleChoiceResponse"`
+	SingleChoiceResponse   int    `json:"SingleChoiceResponse"`
+
+	ResponseID int `json:"ResponseID"`
+	QuestionID int `json:"QuestionID"`
+}
+
+// Validate returns an error if the answer does not fit its question.
+func (u *SurveyAnswer) Validate(q *SurveyQuestion) error {
+	switch q.Type {
+	case Single:
+		if u.SingleChoiceResponse < 0 || u.SingleChoiceResponse >= len(q.Choices) {
+			return Errorf(EINVALID, "Choice is out of range.")
+		}
+	case Multiple:
+		for _, v := range u.MultipleChoiceResponse {
+			if v < 0 || v >= len(q.Choices) {
+				return Errorf(EINVALID, "Choice is out of range.")
+			}
+		}
+	}
+	return nil
+}
+
+// SurveyResults represents the aggregated answers to a survey.
+type SurveyResults struct {
+	SurveyID int `json:"SurveyID"`
+
+	// Total number of responses.
+	N int `json:"N"`
+
+	Questions []*SurveyQuestionResult `json:"Questions"`
+}
+
+// SurveyQuestionResult represents the aggregated answers to a survey question.
+// When fewer answers than the survey threshold were given, only the question
+// itself is returned and Suppressed is set.
+type SurveyQuestionResult struct {
+	Question *SurveyQuestion `json:"Question"`
+
+	N          int  `json:"N"`
+	Suppressed bool `json:"Suppressed"`
+
+	// Distribution holds the number of answers per choice for choice
+	// questions and the number of false/true answers for true/false questions.
+	Distribution []int `json:"Distribution"`
+
+	// Answers to open questions in random order.
+	OpenResponses []string `json:"OpenResponses"`
+}
+
+// SurveyService represents a service for managing surveys.
+type SurveyService interface {
+	// Retrieves a survey by ID along with its questions.
+	// Returns ENOTFOUND if survey does not exist.
+	FindSurveyByID(ctx context.Context, id int) (*Survey, error)
+
+	FindSurveyByStudentLink(ctx context.Context, link string) (*Survey, error)
+
+	FindSurveyByTeacherLink(ctx context.Context, link string) (*Survey, error)
+
+	// Retrieves a list of surveys by filter. Also returns total count of matching
+	// surveys which may differ from returned results if filter.Limit is specified.
+	FindSurveys(ctx context.Context, filter SurveyFilter) ([]*Survey, int, error)
+
+	// Creates a new survey along with its questions.
+	CreateSurvey(ctx context.Context, survey *Survey) error
+
+	// Updates a survey object. Returns EUNAUTHORIZED if current user is not
+	// the owner of the survey. Returns ENOTFOUND if survey does not exist.
+	UpdateSurvey(ctx context.Context, id int, upd SurveyUpdate) (*Survey, error)
+
+	// Permanently deletes a survey and all of its responses. Returns EUNAUTHORIZED
+	// if current user is not the owner of the survey. Returns ENOTFOUND if
+	// survey does not exist.
+	DeleteSurvey(ctx context.Context, id int) error
+
+	// Stores an anonymous response. Logged in users may only respond once and
+	// get ECONFLICT on the second attempt.
+	SubmitSurveyResponse(ctx context.Context, response *SurveyResponse) error
+
+	// Aggregates the responses to a survey. Returns EUNAUTHORIZED if current
+	// user is not the owner of the survey.
+	FindSurveyResults(ctx context.Context, id int) (*SurveyResults, error)
+}
+
+// SurveyFilter represents a filter passed to FindSurveys().
+type SurveyFilter struct {
+	// Filtering fields.
+	ID          *int    `json:"ID"`
+	StudentLink *string `json:"StudentLink"`
+	TeacherLink *string `json:"TeacherLink"`
+
+	TeacherID *int `json:"TeacherID"`
+	GroupID   *int `json:"GroupID"`
+
+	// Restrict to subset of results.
+	Offset int `json:"Offset"`
+	Limit  int `json:"Limit"`
+}
+
+// SurveyUpdate represents a set of fields to be updated via UpdateSurvey().
+type SurveyUpdate struct {
+	Title        *string    `json:"Title"`
+	Content      *string    `json:"Content"`
+	Mode         *string    `json:"Mode"`
+	OpenedAt     *time.Time `json:"OpenedAt"`
+	ClosedAt     *time.Time `json:"ClosedAt"`
+	MinResponses *int       `json:"MinResponses"`
+	GroupID      *int       `json:"GroupID"`
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Layouts of the dates and times of a timetable.
+const (
+	DateLayout      = "2006-01-02"
+	ClockTimeLayout = "15:04"
+)
+
+// SessionHorizon is how many days ahead sessions are created from a timetable,
+// so that teachers can edit or cancel them before they take place.
+const SessionHorizon = 7
+
+// Timetable represents the weekly schedule of a group. Attendances are created
+// for every slot between the term start and end, except on holidays.
+type Timetable struct {
+	ID int `json:"ID"`
+
+	Timezone  string   `json:"Timezone"`
+	TermStart string   `json:"TermStart"` // 2006-01-02
+	TermEnd   string   `json:"TermEnd"`   // 2006-01-02
+	Holidays  []string `json:"Holidays"`  // 2006-01-02
+
+	CreatedAt time.Time `json:"CreatedAt"`
+	UpdatedAt time.Time `json:"UpdatedAt"`
+
+	GroupID int `json:"GroupID"`
+
+	Slots []*TimetableSlot `json:"Slots"`
+}
+
+// TimetableSlot represents a weekly recurring class.
+type TimetableSlot struct {
+	ID int `json:"ID"`
+
+	Weekday   time.Weekday `json:"Weekday"`   // 0 is Sunday
+	StartTime string       `json:"StartTime"` // 15:04, local to the timetable
+	EndTime   string       `json:"EndTime"`   // 15:04, local to the timetable
+	Title     string       `json:"Title"`
+
+	TimetableID int `json:"TimetableID"`
+}
+
+// Validate returns an error if the timetable contains invalid fields.
+// This only performs basic validation.
+func (t *Timetable) Validate() error {
+	if _, err := time.LoadLocation(t.Timezone); err != nil || t.Timezone == "" {
+		return Errorf(EINVALID, "Timezone is incorrect.")
+	}
+
+	start, err := time.Parse(DateLayout, t.TermStart)
+	if err != nil {
+		return Errorf(EINVALID, "Term start is incorrect.")
+	}
+	end, err := time.Parse(DateLayout, t.TermEnd)
+	if err != nil {
+		return Errorf(EINVALID, "Term end is incorrect.")
+	} else if end.Before(start) {
+		return Errorf(EINVALID, "Term cannot end before it starts.")
+	}
+
+	for _, v := range t.Holidays {
+		if _, err := time.Parse(DateLayout, v); err != nil {
+			return Errorf(EINVALID, "Holiday %q is incorrect.", v)
+		}
+	}
+
+	for _, s := range t.Slots {
+		if err := s.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Validate returns an error if the slot contains invalid fields.
+func (s *TimetableSlot) Validate() error {
+	if s.Weekday < time.Sunday || s.Weekday > time.Saturday {
+		return Errorf(EINVALID, "Weekday is incorrect.")
+	}
+
+	start, err := time.Parse(ClockTimeLayout, s.StartTime)
+	if err != nil {
+		return Errorf(EINVALID, "Start time is incorrect.")
+	}
+	end, err := time.Parse(ClockTimeLayout, s.EndTime)
+	if err != nil {
+		return Errorf(EINVALID, "End time is incorrect.")
+	} else if !end.After(start) {
+		return Errorf(EINVALID, "Slot must end after it starts.")
+	}
+	return nil
+}
+
+// Session represents a single occurrence of a timetable slot.
+type Session struct {
+	Slot     *TimetableSlot
+	Date     string // 2006-01-02, local to the timetable
+	OpenedAt time.Time
+	ClosedAt time.Time
+}
+
+// Sessions returns the occurrences of the slots on the dates from and to,
+// inclusive, limited to the term and skipping holidays. Times are returned
+// in UTC. The timetable must be valid.
+func (t *Timetable) Sessions(from, to string) ([]*Session, error) {
+	loc, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clamp the range to the term. Dates compare correctly as strings.
+	if from < t.TermStart {
+		from = t.TermStart
+	}
+	if to > t.TermEnd {
+		to = t.TermEnd
+	}
+
+	start, err := time.ParseInLocation(DateLayout, from, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := time.ParseInLocation(DateLayout, to, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	holidays := make(map[string]bool, len(t.Holidays))
+	for _, v := range t.Holidays {
+		holidays[v] = true
+	}
+
+	var sessions []*Session
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		if holidays[date] {
+			continue
+		}
+
+		for _, slot := range t.Slots {
+			if slot.Weekday != day.Weekday() {
+				continue
+			}
+
+			openedAt, err := time.ParseInLocation(DateLayout+" "+ClockTimeLayout, date+" "+slot.StartTime, loc)
+			if err != nil {
+				return nil, err
+			}
+			closedAt, err := time.ParseInLocation(DateLayout+" "+ClockTimeLayout, date+" "+slot.EndTime, loc)
+			if err != nil {
+				return nil, err
+			}
+
+			sessions = append(sessions, &Session{
+				Slot:     slot,
+				Date:     date,
+				OpenedAt: openedAt.UTC(),
+				ClosedAt: closedAt.UTC(),
+			})
+		}
+	}
+	return sessions, nil
+}
+
+// TimetableService represents a service for managing timetables of groups.
+type TimetableService interface {
+	// Retrieves the timetable of a group. Returns ENOTFOUND if the group has
+	// none. Returns EUNAUTHORIZED if current user is not in the group.
+	FindTimetable(ctx context.Context, groupID int) (*Timetable, error)
+
+	// Creates or replaces the timetable of a group. Upcoming sessions that
+	// nobody has checked in to yet are recreated from the new timetable.
+	// Returns EUNAUTHORIZED if current user is not a teacher of the group.
+	SetTimetable(ctx context.Context, timetable *Timetable) error
+
+	// Permanently deletes the timetable of a group along with its upcoming
+	// sessions that nobody has checked in to yet. Returns EUNAUTHORIZED if
+	// current user is not a teacher of the group.
+	DeleteTimetable(ctx context.Context, groupID int) error
+
+	// Retrieves the attendances created from the timetable of a group between
+	// the dates from and to, inclusive. Returns EUNAUTHORIZED if current user
+	// is not a teacher of the group.
+	FindSessions(ctx context.Context, groupID int, from, to string) ([]*Attendance, error)
+
+	// Creates the attendances of all timetables up to SessionHorizon days
+	// ahead of now. Returns the number of attendances created. Meant to be
+	// run periodically by the scheduler.
+	GenerateSessions(ctx context.Context, now time.Time) (int, error)
+}
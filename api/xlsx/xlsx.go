@@ -0,0 +1,383 @@
+// Package xlsx reads and writes the single worksheet Office Open XML
+// spreadsheets that grades are exchanged in.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MaxPartSize is the largest uncompressed part of a workbook that is read.
+const MaxPartSize = 32 << 20
+
+// MaxSheetNameLen is the longest worksheet name spreadsheet programs accept.
+const MaxSheetNameLen = 31
+
+// Size limits of a worksheet, the same as those of spreadsheet programs.
+const (
+	MaxRows    = 1 << 20
+	MaxColumns = 1 << 14
+)
+
+const (
+	nsMain          = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	nsRelationships = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	nsPackageRels   = "http://schemas.openxmlformats.org/package/2006/relationships"
+)
+
+const contentTypesXML = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const rootRelsXML = xml.Header + `<Relationships xmlns="` + nsPackageRels + `">` +
+	`<Relationship Id="rId1" Type="` + nsRelationships + `/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbookRelsXML = xml.Header + `<Relationships xmlns="` + nsPackageRels + `">` +
+	`<Relationship Id="rId1" Type="` + nsRelationships + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="` + nsRelationships + `/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// stylesXML declares a regular and a bold cell format. The header row uses the
+// bold one.
+const stylesXML = xml.Header + `<styleSheet xmlns="` + nsMain + `">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
+
+// Write writes rows as a workbook with a single worksheet. The first row is
+// written in bold. Cells may be strings, integers, floats, float pointers or
+// nil; nil pointers and nil are left blank.
+func Write(w io.Writer, sheet string, rows [][]interface{}) error {
+	zw := zip.NewWriter(w)
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<workbook xmlns="` + nsMain + `" xmlns:r="` + nsRelationships + `"><sheets><sheet name="`)
+	xml.EscapeText(&buf, []byte(sheetName(sheet)))
+	buf.WriteString(`" sheetId="1" r:id="rId1"/></sheets></workbook>`)
+	workbook := buf.String()
+
+	buf.Reset()
+	if err := writeSheet(&buf, rows); err != nil {
+		return err
+	}
+
+	for _, part := range []struct {
+		name, body string
+	}{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", rootRelsXML},
+		{"xl/workbook.xml", workbook},
+		{"xl/_rels/workbook.xml.rels", workbookRelsXML},
+		{"xl/styles.xml", stylesXML},
+		{"xl/worksheets/sheet1.xml", buf.String()},
+	} {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, part.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeSheet writes the worksheet part. Strings are stored inline so the
+// workbook needs no shared strings table.
+func writeSheet(buf *bytes.Buffer, rows [][]interface{}) error {
+	buf.WriteString(xml.Header)
+	buf.WriteString(`<worksheet xmlns="` + nsMain + `"><sheetData>`)
+	for i, row := range rows {
+		fmt.Fprintf(buf, `<row r="%d">`, i+1)
+		style := ""
+		if i == 0 {
+			style = ` s="1"`
+		}
+		for j, v := range row {
+			ref := ColumnName(j) + strconv.Itoa(i+1)
+			switch v := v.(type) {
+			case nil:
+				continue
+			case string:
+				fmt.Fprintf(buf, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">`, ref, style)
+				xml.EscapeText(buf, []byte(v))
+				buf.WriteString(`</t></is></c>`)
+			case int:
+				fmt.Fprintf(buf, `<c r="%s"%s><v>%d</v></c>`, ref, style, v)
+			case float32:
+				fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(float64(v), 'f', -1, 32))
+			case float64:
+				fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+			case *float64:
+				if v != nil {
+					fmt.Fprintf(buf, `<c r="%s"%s><v>%s</v></c>`, ref, style, strconv.FormatFloat(*v, 'f', -1, 64))
+				}
+			case bool:
+				b := 0
+				if v {
+					b = 1
+				}
+				fmt.Fprintf(buf, `<c r="%s" t="b"%s><v>%d</v></c>`, ref, style, b)
+			default:
+				return fmt.Errorf("xlsx: unsupported cell type %T", v)
+			}
+		}
+		buf.WriteString(`</row>`)
+	}
+	buf.WriteString(`</sheetData></worksheet>`)
+	return nil
+}
+
+// sheetName returns name without the characters worksheet names may not
+// contain, shortened to the longest name allowed.
+func sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if r := []rune(name); len(r) > MaxSheetNameLen {
+		name = string(r[:MaxSheetNameLen])
+	}
+	if strings.TrimSpace(name) == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+// ColumnName returns the letters of the zero based column i, e.g. "A" for 0
+// and "AA" for 26.
+func ColumnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// columnIndex returns the zero based column of a cell reference such as "B12",
+// or -1 if ref has no column letters.
+func columnIndex(ref string) int {
+	i := 0
+	n := 0
+	for ; n < len(ref) && ref[n] >= 'A' && ref[n] <= 'Z'; n++ {
+		i = i*26 + int(ref[n]-'A') + 1
+		if i > MaxColumns {
+			return MaxColumns
+		}
+	}
+	if n == 0 {
+		return -1
+	}
+	return i - 1
+}
+
+// Read returns the cells of the first worksheet of a workbook as text. Rows
+// are padded to their last non-blank cell and missing rows are left empty.
+// Numbers are returned as stored, booleans as "TRUE" or "FALSE".
+func Read(r io.ReaderAt, size int64) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []richText `xml:"si"`
+		}
+		if err := decodePart(f, &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			shared = append(shared, si.String())
+		}
+	}
+
+	f, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("xlsx: missing worksheet %s", sheetPath)
+	}
+	var ws struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R  string   `xml:"r,attr"`
+				T  string   `xml:"t,attr"`
+				V  string   `xml:"v"`
+				IS richText `xml:"is"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := decodePart(f, &ws); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range ws.Rows {
+		// Rows without a reference follow the previous one.
+		n := row.R - 1
+		if row.R == 0 {
+			n = len(rows)
+		}
+		if n < len(rows) {
+			return nil, fmt.Errorf("xlsx: rows out of order")
+		} else if n >= MaxRows {
+			return nil, fmt.Errorf("xlsx: too many rows")
+		}
+		for len(rows) <= n {
+			rows = append(rows, nil)
+		}
+
+		var cells []string
+		for _, c := range row.Cells {
+			col := columnIndex(c.R)
+			if col < 0 {
+				col = len(cells)
+			}
+			if col < len(cells) {
+				return nil, fmt.Errorf("xlsx: cells out of order")
+			} else if col >= MaxColumns {
+				return nil, fmt.Errorf("xlsx: too many columns")
+			}
+
+			var v string
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(c.V)
+				if err != nil || i < 0 || i >= len(shared) {
+					return nil, fmt.Errorf("xlsx: invalid shared string %q", c.V)
+				}
+				v = shared[i]
+			case "inlineStr":
+				v = c.IS.String()
+			case "b":
+				v = "FALSE"
+				if c.V == "1" {
+					v = "TRUE"
+				}
+			default:
+				v = c.V
+			}
+			if v == "" {
+				continue
+			}
+			for len(cells) < col {
+				cells = append(cells, "")
+			}
+			cells = append(cells, v)
+		}
+		rows[n] = cells
+	}
+	return rows, nil
+}
+
+// firstSheetPath returns the path of the first worksheet listed in the
+// workbook.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	f, ok := files["xl/workbook.xml"]
+	if !ok {
+		return "", fmt.Errorf("xlsx: missing workbook")
+	}
+	var wb struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(f, &wb); err != nil {
+		return "", err
+	} else if len(wb.Sheets) == 0 {
+		return "", fmt.Errorf("xlsx: workbook has no worksheets")
+	}
+
+	f, ok = files["xl/_rels/workbook.xml.rels"]
+	if !ok {
+		return "xl/worksheets/sheet1.xml", nil
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(f, &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != wb.Sheets[0].ID {
+			continue
+		}
+		// Targets are relative to the xl directory unless absolute.
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("xlsx: missing worksheet relationship %s", wb.Sheets[0].ID)
+}
+
+// decodePart unmarshals a part of the workbook, refusing parts larger than
+// MaxPartSize.
+func decodePart(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(io.LimitReader(rc, MaxPartSize+1))
+	if err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	} else if len(data) > MaxPartSize {
+		return fmt.Errorf("xlsx: %s too large", f.Name)
+	}
+	if err := xml.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("xlsx: %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// richText is a string item that is either plain text or a list of
+// formatted runs.
+type richText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String returns the text of the item without formatting.
+func (t richText) String() string {
+	s := t.T
+	for _, r := range t.Runs {
+		s += r.T
+	}
+	return s
+}